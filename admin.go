@@ -0,0 +1,240 @@
+package log4
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AdminHandler returns an http.Handler exposing runtime control of the logger.
+// Mount it on an internal-only listener, optionally under a prefix with
+// http.StripPrefix:
+//
+//	GET    /level                      global, per-package and effective levels
+//	PUT    /level?level=DEBUG          set the global level
+//	PUT    /level?package=db&level=DEBUG   override one package
+//	PUT    /level?level=DEBUG&duration=10m  change a level temporarily
+//	DELETE /level?package=db           remove a package override
+//	GET    /stats                      Stats as JSON
+//	GET    /metrics                    Stats in the Prometheus text format
+//	POST   /flush                      write queued entries, flush frames and sinks
+//	POST   /rotate                     rotate all open log files
+//	GET    /entries?n=50&level=ERROR&package=db   recent entries (needs Config.RecentEntries)
+//	GET    /entries?follow=true&level=ERROR       recent entries, then new ones as they are logged
+//
+// With follow, entries are streamed as JSON lines until the client
+// disconnects or the logger closes. The handler performs no authentication.
+func (cl *ChannelLogger) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /level", cl.adminGetLevel)
+	mux.HandleFunc("PUT /level", cl.adminSetLevel)
+	mux.HandleFunc("POST /level", cl.adminSetLevel)
+	mux.HandleFunc("DELETE /level", cl.adminClearLevel)
+	mux.HandleFunc("GET /stats", cl.adminStats)
+	mux.HandleFunc("GET /metrics", cl.adminMetrics)
+	mux.HandleFunc("POST /flush", cl.adminAction(cl.Flush))
+	mux.HandleFunc("POST /rotate", cl.adminAction(cl.Rotate))
+	mux.HandleFunc("GET /entries", cl.adminEntries)
+	return mux
+}
+
+// levelsResponse is the body of GET /level
+type levelsResponse struct {
+	Level     LogLevel            `json:"level"`
+	Packages  map[string]LogLevel `json:"packages"`
+	Effective map[string]LogLevel `json:"effective"`
+}
+
+func (cl *ChannelLogger) adminGetLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, levelsResponse{
+		Level:     cl.GetMinLevel(),
+		Packages:  cl.PackageLevels(),
+		Effective: cl.EffectiveLevels(),
+	})
+}
+
+func (cl *ChannelLogger) adminSetLevel(w http.ResponseWriter, r *http.Request) {
+	var level LogLevel
+	if err := level.UnmarshalText([]byte(r.URL.Query().Get("level"))); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pkg := r.URL.Query().Get("package")
+	if s := r.URL.Query().Get("duration"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", s))
+			return
+		}
+		if pkg != "" {
+			cl.WithTemporaryPackageLevel(pkg, level, d)
+		} else {
+			cl.WithTemporaryLevel(level, d)
+		}
+	} else if pkg != "" {
+		cl.SetPackageLevel(pkg, level)
+	} else {
+		cl.SetMinLevel(level)
+	}
+	cl.adminGetLevel(w, r)
+}
+
+func (cl *ChannelLogger) adminClearLevel(w http.ResponseWriter, r *http.Request) {
+	pkg := r.URL.Query().Get("package")
+	if pkg == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("package parameter is required"))
+		return
+	}
+	cl.ClearPackageLevel(pkg)
+	cl.adminGetLevel(w, r)
+}
+
+func (cl *ChannelLogger) adminStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, cl.Stats())
+}
+
+// adminMetrics writes the counters of Stats, and the latency histogram if
+// Config.LatencyHistogram is set, in the Prometheus text exposition format
+func (cl *ChannelLogger) adminMetrics(w http.ResponseWriter, r *http.Request) {
+	stats := cl.Stats()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("log4_written_total", "counter", "Entries written.", stats.Written)
+	metric("log4_dropped_total", "counter", "Entries dropped.", stats.Dropped)
+	metric("log4_errors_total", "counter", "Internal errors reported.", stats.Errors)
+	metric("log4_error_overflow_total", "counter", "Internal errors that overflowed the error handler queue.", stats.ErrorOverflow)
+	metric("log4_queue_length", "gauge", "Entries waiting to be written.", stats.QueueLength)
+	metric("log4_queue_capacity", "gauge", "Size of the queue.", stats.QueueCapacity)
+
+	if h := stats.Latency; h != nil {
+		const name = "log4_write_latency_seconds"
+		fmt.Fprintf(w, "# HELP %s Time from queueing an entry to writing it.\n# TYPE %s histogram\n", name, name)
+		for _, b := range h.Buckets {
+			fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(b.Le.Seconds(), 'g', -1, 64), b.Count)
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+		fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+	}
+}
+
+// adminAction wraps a logger operation as a POST endpoint
+func (cl *ChannelLogger) adminAction(action func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := action(); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (cl *ChannelLogger) adminEntries(w http.ResponseWriter, r *http.Request) {
+	if cl.recent == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("recent entries are disabled, set Config.RecentEntries"))
+		return
+	}
+
+	query := r.URL.Query()
+	var minLevel LogLevel
+	if s := query.Get("level"); s != "" {
+		if err := minLevel.UnmarshalText([]byte(s)); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	pkg := query.Get("package")
+	match := func(e RecentEntry) bool {
+		return e.Level >= minLevel && (pkg == "" || e.Package == pkg)
+	}
+
+	n := -1
+	if s := query.Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid n %q", s))
+			return
+		}
+	}
+	last := func(entries []RecentEntry) []RecentEntry {
+		if n >= 0 && n < len(entries) {
+			return entries[len(entries)-n:]
+		}
+		return entries
+	}
+
+	if follow, _ := strconv.ParseBool(query.Get("follow")); follow {
+		cl.followEntries(w, r, match, last)
+		return
+	}
+
+	entries := make([]RecentEntry, 0)
+	for _, e := range cl.Recent() {
+		if match(e) {
+			entries = append(entries, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, last(entries))
+}
+
+// followEntries streams the matching recent entries, the last of them chosen
+// by last, then every matching entry logged until the request ends or the
+// logger closes, one JSON object per line
+func (cl *ChannelLogger) followEntries(w http.ResponseWriter, r *http.Request, match func(RecentEntry) bool, last func([]RecentEntry) []RecentEntry) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	var pos uint64
+	first := true
+	for {
+		entries, next, wake := cl.recent.follow(cl.formatter, pos)
+		pos = next
+		if wake != nil {
+			first = false
+			select {
+			case <-wake:
+				continue
+			case <-r.Context().Done():
+				return
+			case <-cl.done:
+				return
+			}
+		}
+
+		matched := entries[:0]
+		for _, e := range entries {
+			if match(e) {
+				matched = append(matched, e)
+			}
+		}
+		if first {
+			matched, first = last(matched), false
+		}
+		for _, e := range matched {
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,161 @@
+package log4
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func adminRequest(t *testing.T, h http.Handler, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestAdminLevels(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+	defer logger.Close()
+	h := logger.AdminHandler()
+
+	if rec := adminRequest(t, h, "PUT", "/level?level=error"); rec.Code != http.StatusOK {
+		t.Fatalf("Set level failed: %d %s", rec.Code, rec.Body)
+	}
+	if logger.GetMinLevel() != ERROR {
+		t.Errorf("Expected global level ERROR, got %v", logger.GetMinLevel())
+	}
+
+	rec := adminRequest(t, h, "PUT", "/level?package=db&level=DEBUG")
+	var levels levelsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&levels); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if levels.Level != ERROR || levels.Packages["db"] != DEBUG {
+		t.Errorf("Unexpected levels: %+v", levels)
+	}
+
+	logger.Debug("db", "Debug from db")
+	logger.Debug("api", "Debug from api")
+	time.Sleep(100 * time.Millisecond)
+
+	if content := readFile(t, filepath.Join(tempDir, "db.log")); !strings.Contains(content, "Debug from db") {
+		t.Error("Package override should allow debug messages")
+	}
+	if fileExists(filepath.Join(tempDir, "api.log")) {
+		t.Error("Global level should still filter other packages")
+	}
+
+	adminRequest(t, h, "DELETE", "/level?package=db")
+	if len(logger.PackageLevels()) != 0 {
+		t.Errorf("Override should be cleared, got %v", logger.PackageLevels())
+	}
+
+	if rec := adminRequest(t, h, "PUT", "/level?level=LOUD"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid level, got %d", rec.Code)
+	}
+}
+
+func TestAdminStatsAndEntries(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.RecentEntries = 2
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+	h := logger.AdminHandler()
+
+	logger.Info("api", "one")
+	logger.Error("api", "two")
+	logger.Info("db", "three")
+	time.Sleep(100 * time.Millisecond)
+
+	var stats Stats
+	json.NewDecoder(adminRequest(t, h, "GET", "/stats").Body).Decode(&stats)
+	if stats.Written != 3 || stats.OpenFiles != 2 || stats.QueueCapacity != DefaultBufferSize {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	var entries []RecentEntry
+	json.NewDecoder(adminRequest(t, h, "GET", "/entries").Body).Decode(&entries)
+	if len(entries) != 2 || !strings.HasSuffix(entries[0].Line, "two") || entries[1].Package != "db" {
+		t.Errorf("Expected the last two entries, got %+v", entries)
+	}
+
+	json.NewDecoder(adminRequest(t, h, "GET", "/entries?level=ERROR").Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].Level != ERROR {
+		t.Errorf("Expected only the error entry, got %+v", entries)
+	}
+
+	if rec := adminRequest(t, h, "POST", "/rotate"); rec.Code != http.StatusNoContent {
+		t.Errorf("Rotate failed: %d", rec.Code)
+	}
+	if !fileExists(filepath.Join(tempDir, "api.log.1")) {
+		t.Error("Rotate endpoint should rotate log files")
+	}
+}
+
+func TestAdminFollowEntries(t *testing.T) {
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.RecentEntries = 10
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("api", "before")
+	logger.Info("db", "skipped")
+
+	server := httptest.NewServer(logger.AdminHandler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/entries?follow=true&package=api")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	var e RecentEntry
+	if err := dec.Decode(&e); err != nil || !strings.HasSuffix(e.Line, "before") {
+		t.Fatalf("Expected the recent entry first, got %+v (%v)", e, err)
+	}
+	logger.Info("db", "other package")
+	logger.Info("api", "after")
+	if err := dec.Decode(&e); err != nil || !strings.HasSuffix(e.Line, "after") {
+		t.Fatalf("Expected the new entry to be streamed, got %+v (%v)", e, err)
+	}
+
+	logger.Close()
+	if err := dec.Decode(&e); err != io.EOF {
+		t.Errorf("Expected the stream to end on Close, got %v", err)
+	}
+}
+
+func TestAdminAfterClose(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+	h := logger.AdminHandler()
+	logger.Close()
+
+	rec := adminRequest(t, h, "POST", "/flush")
+	body, _ := io.ReadAll(rec.Body)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(string(body), ErrLoggerClosed) {
+		t.Errorf("Expected 503 after close, got %d %s", rec.Code, body)
+	}
+	if rec := adminRequest(t, h, "GET", "/entries"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when recent entries are disabled, got %d", rec.Code)
+	}
+}
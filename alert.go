@@ -0,0 +1,314 @@
+package log4
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for AlertOptions
+const (
+	DefaultAlertWindow     = time.Minute
+	DefaultAlertMaxSamples = 10
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Alert summarizes the entries at or above the alert threshold that were
+// logged during one aggregation window
+type Alert struct {
+	Count    int            // Entries in the window
+	First    time.Time      // Timestamp of the first entry
+	Last     time.Time      // Timestamp of the last entry
+	Level    LogLevel       // Highest level seen
+	Packages map[string]int // Entries per package
+	Samples  []RecentEntry  // The first entries of the window
+}
+
+// Title is a one line description of the alert
+func (a *Alert) Title() string {
+	pkgs := make([]string, 0, len(a.Packages))
+	for pkg := range a.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for i, pkg := range pkgs {
+		pkgs[i] = fmt.Sprintf("%s: %d", pkg, a.Packages[pkg])
+	}
+
+	if a.Count == 1 && len(a.Samples) == 1 {
+		return fmt.Sprintf("%s in %s: %s", a.Level, a.Samples[0].Package, strings.TrimSpace(a.Samples[0].Line))
+	}
+	return fmt.Sprintf("%d %s+ entries between %s and %s (%s)", a.Count, a.Level,
+		a.First.Format(time.RFC3339), a.Last.Format(time.RFC3339), strings.Join(pkgs, ", "))
+}
+
+// Text is the title followed by the sample lines
+func (a *Alert) Text() string {
+	var sb strings.Builder
+	sb.WriteString(a.Title())
+	for _, s := range a.Samples {
+		sb.WriteString("\n")
+		sb.WriteString(s.Package)
+		sb.WriteString(" ")
+		sb.WriteString(s.Line)
+	}
+	if more := a.Count - len(a.Samples); more > 0 {
+		fmt.Fprintf(&sb, "\n... and %d more", more)
+	}
+	return sb.String()
+}
+
+// Notifier delivers alerts to people, e.g. through chat, email or paging
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// AlertOptions configures an AlertSink
+type AlertOptions struct {
+	MinLevel   LogLevel      // Lowest level that triggers an alert (default, and if DEBUG: ERROR)
+	Window     time.Duration // Entries are aggregated for this long (default: DefaultAlertWindow)
+	MaxSamples int           // Entries quoted in an alert (default: DefaultAlertMaxSamples)
+	Timeout    time.Duration // Timeout per notification (default: DefaultSinkTimeout)
+
+	Notifiers    []Notifier
+	ErrorHandler func(error) // Notification errors (default: printed to stderr)
+}
+
+// AlertSink notifies about entries at or above a level. The first matching
+// entry opens an aggregation window; when it ends a single alert covering all
+// entries of the window is sent to every notifier, so an error storm results
+// in one alert per window rather than one per entry.
+type AlertSink struct {
+	opts    AlertOptions
+	onError func(error)
+
+	mu      sync.Mutex
+	pending *Alert
+	timer   *time.Timer
+	sending sync.WaitGroup
+	closed  bool
+}
+
+// NewAlertSink creates an alerting sink
+func NewAlertSink(opts AlertOptions) *AlertSink {
+	if opts.MinLevel == DEBUG {
+		opts.MinLevel = ERROR
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultAlertWindow
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = DefaultAlertMaxSamples
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultSinkTimeout
+	}
+	return &AlertSink{opts: opts, onError: sinkErrorHandler(opts.ErrorHandler)}
+}
+
+// Write adds entries at or above MinLevel to the current window
+func (s *AlertSink) Write(entry *LogEntry, line []byte) error {
+	if entry.Level < s.opts.MinLevel {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+
+	if s.pending == nil {
+		s.pending = &Alert{First: entry.Timestamp, Level: entry.Level, Packages: make(map[string]int)}
+		s.timer = time.AfterFunc(s.opts.Window, s.flush)
+	}
+	a := s.pending
+	a.Count++
+	a.Last = entry.Timestamp
+	a.Level = max(a.Level, entry.Level)
+	a.Packages[entry.Package]++
+	if len(a.Samples) < s.opts.MaxSamples {
+		a.Samples = append(a.Samples, RecentEntry{
+			Package:   entry.Package,
+			Tenant:    entry.Tenant,
+			Level:     entry.Level,
+			Timestamp: entry.Timestamp,
+			Line:      string(line),
+		})
+	}
+	return nil
+}
+
+// textRecords asks the logger for entries without Config.RecordSeparator
+func (s *AlertSink) textRecords() bool { return true }
+
+// Close sends the alert of the open window, if any
+func (s *AlertSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.mu.Unlock()
+
+	s.flush()
+	s.sending.Wait()
+	return nil
+}
+
+// flush ends the current window and notifies about it
+func (s *AlertSink) flush() {
+	s.mu.Lock()
+	alert := s.pending
+	s.pending, s.timer = nil, nil
+	if alert != nil {
+		s.sending.Add(1)
+	}
+	s.mu.Unlock()
+
+	if alert == nil {
+		return
+	}
+	defer s.sending.Done()
+
+	for _, n := range s.opts.Notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+		if err := n.Notify(ctx, alert); err != nil {
+			s.onError(fmt.Errorf("alert notification failed: %w", err))
+		}
+		cancel()
+	}
+}
+
+// SlackNotifier posts alerts to a Slack incoming webhook. Any webhook that
+// accepts {"text": "..."} works, e.g. Mattermost or Rocket.Chat.
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client // Optional (default: http.DefaultClient)
+}
+
+// Notify posts the alert text
+func (n *SlackNotifier) Notify(ctx context.Context, alert *Alert) error {
+	return postJSON(ctx, n.Client, n.WebhookURL, map[string]string{"text": alert.Text()})
+}
+
+// PagerDutyNotifier triggers PagerDuty incidents through the Events API v2
+type PagerDutyNotifier struct {
+	RoutingKey string
+	Source     string       // Affected system (default: hostname)
+	URL        string       // Events endpoint (default: DefaultPagerDutyURL)
+	Severity   string       // critical, error, warning or info (default: error)
+	Client     *http.Client // Optional (default: http.DefaultClient)
+}
+
+// Notify sends a trigger event
+func (n *PagerDutyNotifier) Notify(ctx context.Context, alert *Alert) error {
+	url := n.URL
+	if url == "" {
+		url = DefaultPagerDutyURL
+	}
+	source := n.Source
+	if source == "" {
+		source, _ = os.Hostname()
+	}
+	severity := n.Severity
+	if severity == "" {
+		severity = "error"
+	}
+
+	samples := make([]string, len(alert.Samples))
+	for i, s := range alert.Samples {
+		samples[i] = s.Package + " " + s.Line
+	}
+	summary := alert.Title()
+	if len(summary) > 1024 {
+		summary = summary[:1024]
+	}
+
+	return postJSON(ctx, n.Client, url, map[string]interface{}{
+		"routing_key":  n.RoutingKey,
+		"event_action": "trigger",
+		"payload": map[string]interface{}{
+			"summary":   summary,
+			"source":    source,
+			"severity":  severity,
+			"timestamp": alert.First.Format(time.RFC3339),
+			"custom_details": map[string]interface{}{
+				"count":    alert.Count,
+				"packages": alert.Packages,
+				"samples":  samples,
+			},
+		},
+	})
+}
+
+// SMTPNotifier emails alerts
+type SMTPNotifier struct {
+	Addr string    // host:port of the mail server
+	Auth smtp.Auth // Optional, e.g. smtp.PlainAuth
+	From string
+	To   []string
+}
+
+// Notify sends the alert as a plain text email
+func (n *SMTPNotifier) Notify(ctx context.Context, alert *Alert) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(n.Addr, n.Auth, n.From, n.To, n.message(alert))
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message builds the RFC 5322 email for an alert
+func (n *SMTPNotifier) message(alert *Alert) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&b, "Subject: [log4] %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(alert.Title()))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(alert.Text(), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// postJSON posts v and treats any non-2xx status as an error
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
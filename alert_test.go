@@ -0,0 +1,118 @@
+package log4
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier keeps every alert it receives
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []*Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert *Alert) error {
+	n.mu.Lock()
+	n.alerts = append(n.alerts, alert)
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *recordingNotifier) received() []*Alert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Alert(nil), n.alerts...)
+}
+
+func TestAlertSinkAggregates(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	notifier := &recordingNotifier{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{NewAlertSink(AlertOptions{
+		Window:     100 * time.Millisecond,
+		MaxSamples: 2,
+		Notifiers:  []Notifier{notifier},
+	})}
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.Info("db", "Not alerted")
+	for i := 0; i < 50; i++ {
+		logger.Error("db", "Connection refused")
+	}
+	logger.Error("api", "Upstream failed")
+	time.Sleep(200 * time.Millisecond)
+
+	alerts := notifier.received()
+	if len(alerts) != 1 {
+		t.Fatalf("Expected one aggregated alert, got %d", len(alerts))
+	}
+	a := alerts[0]
+	if a.Count != 51 || a.Packages["db"] != 50 || a.Packages["api"] != 1 || len(a.Samples) != 2 {
+		t.Errorf("Unexpected alert %+v", a)
+	}
+	if !strings.Contains(a.Text(), "... and 49 more") {
+		t.Errorf("Unexpected alert text %q", a.Text())
+	}
+
+	// A later error opens a new window, flushed by Close
+	logger.Error("db", "Connection refused again")
+	logger.Close()
+	if alerts := notifier.received(); len(alerts) != 2 || alerts[1].Count != 1 {
+		t.Errorf("Expected a second alert on Close, got %d alerts", len(alerts))
+	}
+}
+
+func TestAlertNotifiers(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	alert := &Alert{
+		Count:    1,
+		First:    time.Now(),
+		Last:     time.Now(),
+		Level:    ERROR,
+		Packages: map[string]int{"db": 1},
+		Samples:  []RecentEntry{{Package: "db", Level: ERROR, Line: "ERROR: disk full"}},
+	}
+
+	slack := &SlackNotifier{WebhookURL: server.URL}
+	if err := slack.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Slack notify failed: %v", err)
+	}
+	if body := <-bodies; !strings.Contains(body["text"].(string), "disk full") {
+		t.Errorf("Unexpected Slack payload %v", body)
+	}
+
+	pd := &PagerDutyNotifier{RoutingKey: "key", URL: server.URL, Source: "web-1"}
+	if err := pd.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("PagerDuty notify failed: %v", err)
+	}
+	body := <-bodies
+	payload, _ := body["payload"].(map[string]interface{})
+	if body["routing_key"] != "key" || body["event_action"] != "trigger" ||
+		payload["source"] != "web-1" || !strings.Contains(payload["summary"].(string), "disk full") {
+		t.Errorf("Unexpected PagerDuty payload %v", body)
+	}
+
+	mail := (&SMTPNotifier{From: "log4@example.com", To: []string{"ops@example.com"}}).message(alert)
+	if !strings.Contains(string(mail), "Subject: [log4] ERROR in db: ERROR: disk full\r\n") {
+		t.Errorf("Unexpected email %q", mail)
+	}
+}
@@ -0,0 +1,65 @@
+package log4
+
+import (
+	"os"
+	"time"
+)
+
+// fileCheckInterval limits how often an open file is compared with the file
+// on disk
+const fileCheckInterval = time.Second
+
+// fileCheck records when an open file was last compared with the file on disk
+// and the size it had then
+type fileCheck struct {
+	at   time.Time
+	size int64
+}
+
+// fileReplaced reports how the open file of a key was changed by another
+// process since the last check, or "" if it was not: moved or removed, e.g.
+// by logrotate or an operator removing LogDir, replaced, or truncated in
+// place as with logrotate's copytruncate. Writes to a file that was moved or
+// removed succeed on most systems, so they cannot be relied on to report it.
+// Checks are rate limited to fileCheckInterval; cl.mu must be held.
+func (cl *ChannelLogger) fileReplaced(key string) string {
+	f, ok := cl.files[key]
+	if !ok {
+		return ""
+	}
+	last := cl.checks[key]
+	if time.Since(last.at) < fileCheckInterval {
+		return ""
+	}
+
+	onDisk, change := fileMoved(cl.config.fileSystem(), f)
+	if change != "" || onDisk == nil {
+		return change
+	}
+	if onDisk.Size() < last.size {
+		return "truncated"
+	}
+	cl.checks[key] = fileCheck{at: time.Now(), size: onDisk.Size()}
+	if cl.config.SharedFiles {
+		cl.fileSizes[key] = onDisk.Size() // Count what other processes wrote
+	}
+	return ""
+}
+
+// fileMoved reports how the path of f in fsys stopped referring to f, or ""
+// along with the file's current info if it still does (nil if f cannot be
+// stat'ed)
+func fileMoved(fsys FS, f File) (os.FileInfo, string) {
+	open, err := f.Stat()
+	if err != nil {
+		return nil, ""
+	}
+	onDisk, err := fsys.Stat(f.Name())
+	switch {
+	case err != nil:
+		return nil, "moved or removed"
+	case !fsys.SameFile(open, onDisk):
+		return nil, "replaced"
+	}
+	return onDisk, ""
+}
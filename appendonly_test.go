@@ -0,0 +1,98 @@
+package log4
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppendOnlyRefusesRotation(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var errs []error
+	var mu sync.Mutex
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.AppendOnly = true
+	config.MaxFileSize = 1
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	for i := 0; i < 3; i++ {
+		logger.Info("audit", "Record")
+	}
+	logger.Flush() // Rotate only rotates files already opened
+	logger.Rotate()
+	logger.Close()
+
+	if n := countLines(readFile(t, filepath.Join(tempDir, "audit.log"))); n != 3 {
+		t.Errorf("Expected all entries in the active file, got %d", n)
+	}
+	if fileExists(filepath.Join(tempDir, "audit.log.1")) {
+		t.Error("Append-only files must not be rotated")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var rotation *ErrRotation
+	if len(errs) != 1 || !errors.As(errs[0], &rotation) || rotation.Err.Error() != ErrAppendOnly {
+		t.Errorf("Expected Rotate to be refused once, got %v", errs)
+	}
+}
+
+func TestAppendOnlyReopensReplacedFile(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var errs []error
+	var mu sync.Mutex
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.AppendOnly = true
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	path := filepath.Join(tempDir, "audit.log")
+	logger.Info("audit", "Before")
+	logger.Flush()
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	// Skip the wait between checks
+	logger.mu.Lock()
+	logger.checks["audit"] = fileCheck{at: time.Now().Add(-fileCheckInterval)}
+	logger.mu.Unlock()
+
+	logger.Info("audit", "After")
+	logger.Close()
+
+	if content := readFile(t, path); countLines(content) != 1 || !strings.Contains(content, "After") {
+		t.Errorf("Expected the file to be reopened, got %q", content)
+	}
+	if content := readFile(t, path+".old"); !strings.Contains(content, "Before") {
+		t.Errorf("Expected the moved file to keep earlier entries, got %q", content)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "by another process, reopening") {
+		t.Errorf("Expected the replacement to be reported, got %v", errs)
+	}
+}
@@ -0,0 +1,293 @@
+package log4
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for BatchOptions
+const (
+	DefaultSinkBatchSize    = 512
+	DefaultSinkBatchTimeout = time.Second
+	DefaultSinkQueueSize    = 4096
+	DefaultSinkMaxRetries   = 5
+	DefaultSinkRetryBackoff = 500 * time.Millisecond
+	DefaultSinkTimeout      = 10 * time.Second
+)
+
+// maxRetryBackoff caps the delay between export retries
+const maxRetryBackoff = 30 * time.Second
+
+// BatchOptions controls batching and retries of the network sinks
+type BatchOptions struct {
+	BatchSize    int           // Entries per export (default: DefaultSinkBatchSize)
+	BatchTimeout time.Duration // Longest an entry waits for its batch (default: DefaultSinkBatchTimeout)
+	QueueSize    int           // Entries held before new ones are dropped (default: DefaultSinkQueueSize)
+	MaxRetries   int           // Retries of a failed export (default: DefaultSinkMaxRetries, -1 disables)
+	RetryBackoff time.Duration // First retry delay, doubled per attempt (default: DefaultSinkRetryBackoff)
+	Timeout      time.Duration // Timeout per export request (default: DefaultSinkTimeout)
+
+	// DeadLetter receives the entries of exports that failed permanently
+	DeadLetter *DeadLetter
+
+	// Seal signs, and optionally encrypts, every batch sent
+	Seal *Seal
+
+	// TLS secures the connections of TCP and HTTP sinks (default: Config.TLS
+	// of the logger the sink is added to). TLSServerName overrides the
+	// ServerName of whichever is used, e.g. to reach a collector through an
+	// address its certificate does not name.
+	TLS           *tls.Config
+	TLSServerName string
+
+	// Dialer opens the connections of TCP and HTTP sinks (default:
+	// Config.Dialer of the logger the sink is added to, or a net.Dialer)
+	Dialer Dialer
+
+	// DropCancelled drops entries logged with a context (see LogWithContext)
+	// once that context is done, if their batch was not exported yet. They
+	// are counted by the sink's Dropped.
+	DropCancelled bool
+}
+
+// withDefaults returns o with unset options defaulted
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultSinkBatchSize
+	}
+	if o.BatchTimeout <= 0 {
+		o.BatchTimeout = DefaultSinkBatchTimeout
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultSinkQueueSize
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = DefaultSinkMaxRetries
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = DefaultSinkRetryBackoff
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultSinkTimeout
+	}
+	return o
+}
+
+// batcher queues items written by a network sink and hands them to export in
+// batches from a background goroutine, so the logging goroutine never waits
+// on the network. A batch is exported once it is full or interval has passed.
+type batcher[T any] struct {
+	size     int
+	limit    int
+	interval time.Duration
+	export   func(ctx context.Context, batch []T) error
+	onError  func(error)
+	ctx      func(T) context.Context // Context of an item, with DropCancelled
+	shutdown time.Duration           // Longest close waits for the queue to be exported
+
+	// exportCtx is passed to every export, and cancelled once shutdown has
+	// passed since close, so retries of a failing export do not hold it up
+	exportCtx context.Context
+	cancel    context.CancelFunc
+
+	mu      sync.Mutex
+	queue   []T
+	closed  bool
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// newBatcher starts a batcher configured by opts. ctx returns the context an
+// item was logged with, for BatchOptions.DropCancelled.
+func newBatcher[T any](opts BatchOptions, export func(context.Context, []T) error, onError func(error), ctx func(T) context.Context) *batcher[T] {
+	b := &batcher[T]{
+		size:     opts.BatchSize,
+		limit:    opts.QueueSize,
+		interval: opts.BatchTimeout,
+		export:   export,
+		onError:  onError,
+		shutdown: ShutdownTimeout,
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if opts.DropCancelled {
+		b.ctx = ctx
+	}
+	b.exportCtx, b.cancel = context.WithCancel(context.Background())
+	go b.run()
+	return b
+}
+
+// cancelled reports whether item was logged with a context that is done, if
+// such items are dropped
+func (b *batcher[T]) cancelled(item T) bool {
+	if b.ctx == nil {
+		return false
+	}
+	ctx := b.ctx(item)
+	return ctx != nil && ctx.Err() != nil
+}
+
+// add queues an item; it is dropped if the queue is full or closed
+func (b *batcher[T]) add(item T) {
+	b.mu.Lock()
+	if b.closed || len(b.queue) >= b.limit || b.cancelled(item) {
+		b.mu.Unlock()
+		b.dropped.Add(1)
+		return
+	}
+	b.queue = append(b.queue, item)
+	full := len(b.queue) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// close exports the queued items, waiting at most ShutdownTimeout including
+// an export already running
+func (b *batcher[T]) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	timer := time.AfterFunc(b.shutdown, b.cancel)
+	defer timer.Stop()
+	defer b.cancel()
+	close(b.stop)
+	<-b.done
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.kick:
+			b.exportQueued(b.exportCtx, false)
+		case <-ticker.C:
+			b.exportQueued(b.exportCtx, true)
+		case <-b.stop:
+			b.exportQueued(b.exportCtx, true)
+			return
+		}
+	}
+}
+
+// exportQueued exports full batches, and the final partial batch if partial
+// is set
+func (b *batcher[T]) exportQueued(ctx context.Context, partial bool) {
+	for {
+		b.mu.Lock()
+		n := len(b.queue)
+		if n == 0 || (n < b.size && !partial) {
+			b.mu.Unlock()
+			return
+		}
+		n = min(n, b.size)
+		batch := make([]T, 0, n)
+		for _, item := range b.queue[:n] {
+			if b.cancelled(item) {
+				b.dropped.Add(1)
+				continue
+			}
+			batch = append(batch, item)
+		}
+		b.queue = b.queue[n:]
+		b.mu.Unlock()
+		if len(batch) == 0 {
+			continue
+		}
+
+		if err := b.export(ctx, batch); err != nil {
+			failed := len(batch)
+			var pf *partialFailure
+			if errors.As(err, &pf) {
+				failed = pf.failed
+			}
+			b.dropped.Add(uint64(failed))
+			b.onError(err)
+		}
+	}
+}
+
+// partialFailure reports an export that delivered part of its batch
+type partialFailure struct {
+	failed int
+	err    error
+}
+
+func (e *partialFailure) Error() string { return e.err.Error() }
+func (e *partialFailure) Unwrap() error { return e.err }
+
+// exportError describes a failed export attempt
+type exportError struct {
+	msg        string
+	retryable  bool
+	retryAfter time.Duration // Delay requested by the server, if any
+}
+
+func (e *exportError) Error() string { return e.msg }
+
+// retryable wraps a transient failure so retryExport tries again
+func retryable(err error) error {
+	return &exportError{msg: err.Error(), retryable: true}
+}
+
+// retryExport calls send until it succeeds, fails permanently or maxRetries
+// retries are used up, doubling the delay after every attempt. A negative
+// maxRetries disables retries.
+func retryExport(ctx context.Context, maxRetries int, backoff time.Duration, send func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := send()
+		if err == nil {
+			return nil
+		}
+
+		var exportErr *exportError
+		if !errors.As(err, &exportErr) || !exportErr.retryable || attempt >= maxRetries {
+			return err
+		}
+
+		wait := backoff
+		if exportErr.retryAfter > 0 {
+			wait = exportErr.retryAfter
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// sinkErrorHandler returns handler, or one printing to stderr like the logger
+func sinkErrorHandler(handler func(error)) func(error) {
+	if handler != nil {
+		return handler
+	}
+	return func(err error) {
+		fmt.Fprintf(os.Stderr, "Logger error: %v\n", err)
+	}
+}
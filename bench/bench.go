@@ -0,0 +1,228 @@
+// Package bench measures the throughput of the log4 pipeline, from the
+// logging call through the channel to the package files, under varied
+// message sizes, field counts, package cardinality and contention, and
+// compares the results of two runs to catch performance regressions.
+//
+// Example usage:
+//
+//	go test -run '^$' -bench . -benchmem -count 5 ./bench > new.txt
+//	log4ctl benchcmp bench/testdata/baseline.txt new.txt
+//
+// Scenarios can also be run from benchmarks of other packages, e.g. to
+// measure a Formatter or Sink of the application:
+//
+//	func BenchmarkAuditSink(b *testing.B) {
+//		bench.Run(b, bench.Scenario{Fields: 8, Configure: func(c *log4.Config) {
+//			c.Sinks = []log4.Sink{newAuditSink()}
+//		}})
+//	}
+package bench
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MhunterDev/log4"
+)
+
+// Defaults of Scenario
+const (
+	DefaultMessageSize = 64
+	DefaultBufferSize  = 10000
+)
+
+// Scenario describes a workload logged by Run
+type Scenario struct {
+	Name        string             // Sub-benchmark name used by Scenarios
+	MessageSize int                // Bytes per message (default: DefaultMessageSize)
+	Fields      int                // Fields per entry, alternating strings and integers
+	Packages    int                // Distinct packages, each with its own file (default: 1)
+	Goroutines  int                // Goroutines logging concurrently (default: 1)
+	Configure   func(*log4.Config) // Adjusts the configuration, e.g. to enable rotation
+}
+
+// Scenarios is the matrix run by the benchmarks of this package
+var Scenarios = []Scenario{
+	{Name: "Message/16B", MessageSize: 16},
+	{Name: "Message/256B", MessageSize: 256},
+	{Name: "Message/4KB", MessageSize: 4096},
+	{Name: "Fields/0"},
+	{Name: "Fields/4", Fields: 4},
+	{Name: "Fields/16", Fields: 16},
+	{Name: "Fields/64", Fields: 64},
+	{Name: "Packages/1", Packages: 1},
+	{Name: "Packages/16", Packages: 16},
+	{Name: "Packages/256", Packages: 256},
+	{Name: "Goroutines/1", Goroutines: 1},
+	{Name: "Goroutines/8", Goroutines: 8},
+	{Name: "Goroutines/64", Goroutines: 64},
+	{Name: "Format/JSON", Fields: 4, Configure: func(c *log4.Config) { c.Formatter = &log4.JSONFormatter{} }},
+	{Name: "Rotation/1MB", Fields: 4, Configure: func(c *log4.Config) { c.MaxFileSize = 1 << 20; c.MaxFiles = 2 }},
+}
+
+// Run logs b.N entries of the scenario to a temporary directory and closes
+// the logger, so the time measured includes writing every entry to its file.
+// Entries are logged with a context that has a deadline, so callers wait for
+// room in the queue instead of dropping entries. Besides the standard metrics
+// it reports the entries dropped per operation, which make a run look faster
+// than it is and should stay at 0.
+func Run(b *testing.B, s Scenario) {
+	config := log4.DefaultConfig()
+	config.LogDir = b.TempDir()
+	config.DisableConsole = true
+	config.BufferSize = DefaultBufferSize
+	if s.Configure != nil {
+		s.Configure(config)
+	}
+	logger := log4.NewChannelLoggerWithConfig(config)
+
+	size := s.MessageSize
+	if size <= 0 {
+		size = DefaultMessageSize
+	}
+	message := strings.Repeat("x", size)
+	fields := make(map[string]interface{}, s.Fields)
+	for i := 0; i < s.Fields; i++ {
+		if i%2 == 0 {
+			fields[fmt.Sprintf("field%d", i)] = "value"
+		} else {
+			fields[fmt.Sprintf("field%d", i)] = i
+		}
+	}
+	packages := make([]*log4.PackageLogger, max(s.Packages, 1))
+	for i := range packages {
+		packages[i] = logger.Package(fmt.Sprintf("pkg%d", i)).With(fields)
+	}
+	goroutines := max(s.Goroutines, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		n := b.N / goroutines
+		if g < b.N%goroutines {
+			n++
+		}
+		wg.Add(1)
+		go func(g, n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				packages[(g+i*goroutines)%len(packages)].LogWithContext(ctx, "INFO", message)
+			}
+		}(g, n)
+	}
+	wg.Wait()
+	logger.Close()
+
+	b.StopTimer()
+	b.ReportMetric(float64(logger.Stats().Dropped)/float64(b.N), "dropped/op")
+}
+
+// Result is the mean of the runs of one benchmark
+type Result struct {
+	Name    string             // Without the GOMAXPROCS suffix, e.g. "BenchmarkPipeline/Fields/4"
+	Runs    int                // Lines averaged, one per -count
+	Metrics map[string]float64 // Mean value per unit, e.g. "ns/op"
+}
+
+// ParseResults reads the output of go test -bench, averaging the runs of
+// each benchmark. Lines other than benchmark results are ignored.
+func ParseResults(r io.Reader) (map[string]*Result, error) {
+	results := make(map[string]*Result)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") || len(f)%2 != 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(f[1]); err != nil {
+			continue
+		}
+
+		name := f[0]
+		if i := strings.LastIndexByte(name, '-'); i > 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+		res := results[name]
+		if res == nil {
+			res = &Result{Name: name, Metrics: make(map[string]float64)}
+			results[name] = res
+		}
+		res.Runs++
+		for i := 2; i < len(f); i += 2 {
+			v, err := strconv.ParseFloat(f[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchmark %s: invalid value %q", name, f[i])
+			}
+			// Running mean, so later lines do not need their own storage
+			res.Metrics[f[i+1]] += (v - res.Metrics[f[i+1]]) / float64(res.Runs)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Delta compares a metric of one benchmark between two runs
+type Delta struct {
+	Name     string
+	Unit     string
+	Old, New float64
+	Change   float64 // (New - Old) / Old; +Inf if Old is 0 and New is not
+}
+
+// Regressed reports whether the metric got worse by more than threshold,
+// e.g. 0.1 for 10%. Throughput units ending in "/s" are better when higher,
+// all others when lower.
+func (d Delta) Regressed(threshold float64) bool {
+	if strings.HasSuffix(d.Unit, "/s") {
+		return d.Change < -threshold
+	}
+	return d.Change > threshold
+}
+
+// Compare returns the change of every metric present in both runs, sorted
+// by benchmark name and unit
+func Compare(old, new map[string]*Result) []Delta {
+	var deltas []Delta
+	for name, o := range old {
+		n, ok := new[name]
+		if !ok {
+			continue
+		}
+		for unit, ov := range o.Metrics {
+			nv, ok := n.Metrics[unit]
+			if !ok {
+				continue
+			}
+			d := Delta{Name: name, Unit: unit, Old: ov, New: nv}
+			switch {
+			case ov != 0:
+				d.Change = (nv - ov) / ov
+			case nv != 0:
+				d.Change = math.Inf(1)
+			}
+			deltas = append(deltas, d)
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].Name != deltas[j].Name {
+			return deltas[i].Name < deltas[j].Name
+		}
+		return deltas[i].Unit < deltas[j].Unit
+	})
+	return deltas
+}
@@ -0,0 +1,102 @@
+package bench
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, s := range Scenarios {
+		b.Run(s.Name, func(b *testing.B) {
+			Run(b, s)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Runs a benchmark")
+	}
+	res := testing.Benchmark(func(b *testing.B) {
+		Run(b, Scenario{Fields: 4, Packages: 4, Goroutines: 4})
+	})
+	if res.N == 0 || res.NsPerOp() <= 0 {
+		t.Fatalf("Expected a measurement, got %+v", res)
+	}
+	if _, ok := res.Extra["dropped/op"]; !ok {
+		t.Errorf("Expected the dropped/op metric, got %v", res.Extra)
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	out := `goos: linux
+goarch: amd64
+pkg: github.com/MhunterDev/log4/bench
+BenchmarkPipeline/Fields/4-8   	 1000000	      1000 ns/op	  64.00 MB/s	 0 dropped/op	     200 B/op	       3 allocs/op
+BenchmarkPipeline/Fields/4-8   	 1000000	      1200 ns/op	  53.33 MB/s	 0 dropped/op	     200 B/op	       3 allocs/op
+BenchmarkPipeline/Message/4KB-8	  100000	     20000 ns/op
+PASS
+ok  	github.com/MhunterDev/log4/bench	3.012s
+`
+	results, err := ParseResults(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 benchmarks, got %v", results)
+	}
+	res := results["BenchmarkPipeline/Fields/4"]
+	if res == nil || res.Runs != 2 || res.Metrics["ns/op"] != 1100 || res.Metrics["allocs/op"] != 3 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+
+	if _, err := ParseResults(strings.NewReader("BenchmarkX-8 10 abc ns/op\n")); err == nil {
+		t.Error("Expected an error for an invalid value")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := map[string]*Result{
+		"BenchmarkA": {Name: "BenchmarkA", Metrics: map[string]float64{"ns/op": 1000, "MB/s": 100, "dropped/op": 0}},
+		"BenchmarkB": {Name: "BenchmarkB", Metrics: map[string]float64{"ns/op": 1000}},
+	}
+	new := map[string]*Result{
+		"BenchmarkA": {Name: "BenchmarkA", Metrics: map[string]float64{"ns/op": 1050, "MB/s": 80, "dropped/op": 0.5}},
+		"BenchmarkC": {Name: "BenchmarkC", Metrics: map[string]float64{"ns/op": 1000}},
+	}
+
+	deltas := Compare(old, new)
+	if len(deltas) != 3 {
+		t.Fatalf("Expected the 3 metrics of BenchmarkA, got %+v", deltas)
+	}
+	regressed := map[string]bool{}
+	for _, d := range deltas {
+		regressed[d.Unit] = d.Regressed(0.1)
+	}
+	if regressed["ns/op"] || !regressed["MB/s"] || !regressed["dropped/op"] {
+		t.Errorf("Unexpected regressions %v in %+v", regressed, deltas)
+	}
+	if deltas[1].Unit != "dropped/op" || !math.IsInf(deltas[1].Change, 1) {
+		t.Errorf("Expected an infinite change from 0, got %+v", deltas[1])
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	f, err := os.Open("testdata/baseline.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := ParseResults(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range Scenarios {
+		if _, ok := results["BenchmarkPipeline/"+s.Name]; !ok {
+			t.Errorf("Baseline lacks scenario %s", s.Name)
+		}
+	}
+}
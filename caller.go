@@ -0,0 +1,35 @@
+package log4
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// FieldCaller is the field set by Config.Caller
+const FieldCaller = "caller"
+
+// packageDir is the source directory of this package; frames from files in
+// it belong to the logger rather than to its caller
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerOutsidePackage returns "dir/file.go:line" of the first frame on the
+// stack that is not part of this package
+func callerOutsidePackage() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			dir := filepath.Base(filepath.Dir(frame.File))
+			return dir + "/" + filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package log4
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FieldEventID is the field holding the event class of an entry, written as
+// the Signature ID of CEFFormatter and the Event ID of LEEFFormatter. Entries
+// without it use their package.
+const FieldEventID = "event_id"
+
+// LEEFTimestampFormat is the layout of the devTime attribute written by
+// LEEFFormatter, declared to the SIEM in devTimeFormat
+const LEEFTimestampFormat = "Jan 02 2006 15:04:05.000 -0700"
+
+// leefTimeFormat is LEEFTimestampFormat in the notation of devTimeFormat
+const leefTimeFormat = "MMM dd yyyy HH:mm:ss.SSS Z"
+
+// DefaultCEFExtensions maps the fields of access logs (see CLFFormatter) to
+// CEF extension keys
+var DefaultCEFExtensions = map[string]string{
+	FieldRemoteAddr: "src",
+	FieldUser:       "suser",
+	FieldMethod:     "requestMethod",
+	FieldPath:       "request",
+	FieldUserAgent:  "requestClientApplication",
+	FieldBytes:      "out",
+}
+
+// DefaultLEEFAttributes maps the fields of access logs to LEEF attributes
+var DefaultLEEFAttributes = map[string]string{
+	FieldRemoteAddr: "src",
+	FieldUser:       "usrName",
+	FieldBytes:      "dstBytes",
+}
+
+// CEFFormatter renders entries in the ArcSight Common Event Format, so that
+// security events can be sent to a SIEM as they are logged:
+//
+//	CEF:0|Acme|Billing|2.1|login_failed|Login failed|7|rt=1714564800000 cat=auth suser=frank src=10.0.0.1
+//
+// The Signature ID is FieldEventID, the Name is the message and the severity
+// comes from Severities. rt is the timestamp and cat the package. Fields are
+// written as the extension keys Extensions maps them to, sorted by field
+// name; unmapped fields keep their own name unless OmitUnmapped is set. A
+// field mapped to a custom extension, such as "cs1", is labelled with its
+// name in "cs1Label". Pipes are escaped in the header, and equals signs and
+// line breaks in extension values.
+type CEFFormatter struct {
+	Vendor       string            // Device Vendor (default: "log4")
+	Product      string            // Device Product (default: "log4")
+	Version      string            // Device Version
+	Extensions   map[string]string // Field names to extension keys (default: DefaultCEFExtensions)
+	OmitUnmapped bool              // Drop fields that Extensions does not map
+	Severities   SeverityMap       // Level mapping (default: DefaultSeverities)
+	Encoding     FieldEncoding     // How field values of common types are written
+}
+
+// Format appends the CEF record of entry to dst
+func (f *CEFFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	dst = append(dst, "CEF:0|"...)
+	dst = appendSIEMHeader(dst, f.Vendor, f.Product, f.Version, entry)
+	dst = appendSIEMHeaderField(dst, entry.Message)
+	dst = strconv.AppendInt(dst, int64(f.Severities.Lookup(entry.Level).CEF), 10)
+	dst = append(dst, "|rt="...)
+	dst = strconv.AppendInt(dst, entry.Timestamp.UnixMilli(), 10)
+	if entry.Package != "" {
+		dst = append(dst, " cat="...)
+		dst = appendCEFValue(dst, entry.Package)
+	}
+
+	extensions := f.Extensions
+	if extensions == nil {
+		extensions = DefaultCEFExtensions
+	}
+	for _, k := range sortedFieldKeys(entry.Fields) {
+		key, ok := extensions[k]
+		if k == FieldEventID || !ok && f.OmitUnmapped {
+			continue
+		}
+		if !ok {
+			key = k
+		}
+		dst = append(dst, ' ')
+		dst = appendSIEMKey(dst, key)
+		dst = append(dst, '=')
+		dst = appendCEFValue(dst, f.Encoding.text(resolveValue(entry.Fields[k])))
+		if label := cefLabelKey(key); label != "" {
+			dst = append(dst, ' ')
+			dst = append(dst, label...)
+			dst = append(dst, '=')
+			dst = appendCEFValue(dst, k)
+		}
+	}
+	return dst
+}
+
+// LEEFFormatter renders entries in the IBM QRadar Log Event Extended Format,
+// version 2.0:
+//
+//	LEEF:2.0|Acme|Billing|2.1|login_failed|x09|devTime=May 01 2024 12:00:00.000 +0000	devTimeFormat=MMM dd yyyy HH:mm:ss.SSS Z	sev=7	cat=auth	msg=Login failed	usrName=frank
+//
+// The Event ID is FieldEventID, sev comes from Severities, cat is the package
+// and msg the message. Fields are written as the attributes Attributes maps
+// them to, sorted by field name; unmapped fields keep their own name unless
+// OmitUnmapped is set. LEEF has no escaping for attribute values, so the
+// delimiter and control characters in them are written as spaces.
+type LEEFFormatter struct {
+	Vendor       string            // Vendor (default: "log4")
+	Product      string            // Product name (default: "log4")
+	Version      string            // Product version
+	Delimiter    byte              // Separator of attributes (default: tab)
+	Attributes   map[string]string // Field names to attribute keys (default: DefaultLEEFAttributes)
+	OmitUnmapped bool              // Drop fields that Attributes does not map
+	Severities   SeverityMap       // Level mapping (default: DefaultSeverities)
+	Encoding     FieldEncoding     // How field values of common types are written
+}
+
+// Format appends the LEEF record of entry to dst
+func (f *LEEFFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	delim := f.Delimiter
+	if delim == 0 {
+		delim = '\t'
+	}
+
+	dst = append(dst, "LEEF:2.0|"...)
+	dst = appendSIEMHeader(dst, f.Vendor, f.Product, f.Version, entry)
+	dst = append(dst, 'x', "0123456789abcdef"[delim>>4], "0123456789abcdef"[delim&0xf], '|')
+	dst = append(dst, "devTime="...)
+	dst = entry.Timestamp.AppendFormat(dst, LEEFTimestampFormat)
+	dst = append(dst, delim)
+	dst = append(dst, "devTimeFormat="+leefTimeFormat...)
+	dst = append(dst, delim)
+	dst = append(dst, "sev="...)
+	dst = strconv.AppendInt(dst, int64(f.Severities.Lookup(entry.Level).CEF), 10)
+	if entry.Package != "" {
+		dst = append(dst, delim)
+		dst = append(dst, "cat="...)
+		dst = appendLEEFValue(dst, entry.Package, delim)
+	}
+	dst = append(dst, delim)
+	dst = append(dst, "msg="...)
+	dst = appendLEEFValue(dst, entry.Message, delim)
+
+	attributes := f.Attributes
+	if attributes == nil {
+		attributes = DefaultLEEFAttributes
+	}
+	for _, k := range sortedFieldKeys(entry.Fields) {
+		key, ok := attributes[k]
+		if k == FieldEventID || !ok && f.OmitUnmapped {
+			continue
+		}
+		if !ok {
+			key = k
+		}
+		dst = append(dst, delim)
+		dst = appendSIEMKey(dst, key)
+		dst = append(dst, '=')
+		dst = appendLEEFValue(dst, f.Encoding.text(resolveValue(entry.Fields[k])), delim)
+	}
+	return dst
+}
+
+// appendSIEMHeader appends the vendor, product, version and event class
+// fields of a CEF or LEEF header, each followed by a pipe
+func appendSIEMHeader(dst []byte, vendor, product, version string, entry *LogEntry) []byte {
+	if vendor == "" {
+		vendor = "log4"
+	}
+	if product == "" {
+		product = "log4"
+	}
+	event := clfValue(entry, FieldEventID)
+	if event == "" {
+		event = entry.Package
+	}
+	for _, s := range []string{vendor, product, version, event} {
+		dst = appendSIEMHeaderField(dst, s)
+	}
+	return dst
+}
+
+// appendSIEMHeaderField appends a header field with pipes and backslashes
+// escaped, and control characters as spaces, followed by a pipe
+func appendSIEMHeaderField(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '|' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c < 0x20 || c == 0x7f:
+			dst = append(dst, ' ')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '|')
+}
+
+// appendSIEMKey appends an extension key or attribute, which may only hold
+// letters, digits, dots and underscores; others are written as underscores
+func appendSIEMKey(dst []byte, key string) []byte {
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_':
+			dst = append(dst, c)
+		default:
+			dst = append(dst, '_')
+		}
+	}
+	return dst
+}
+
+// appendCEFValue appends an extension value with backslashes, equals signs
+// and line breaks escaped, and other control characters as spaces
+func appendCEFValue(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' || c == '=':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, `\n`...)
+		case c == '\r':
+			dst = append(dst, `\r`...)
+		case c < 0x20 || c == 0x7f:
+			dst = append(dst, ' ')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// appendLEEFValue appends an attribute value with the delimiter and control
+// characters as spaces
+func appendLEEFValue(dst []byte, s string, delim byte) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == delim || c < 0x20 || c == 0x7f {
+			dst = append(dst, ' ')
+		} else {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// cefCustomKeys are the CEF extensions whose meaning is given by a label
+var cefCustomKeys = []string{"cs", "cn", "cfp", "flexString", "flexNumber", "flexDate", "deviceCustomDate"}
+
+// cefLabelKey returns the label key of a custom extension such as "cs1", or
+// "" if key is a standard extension
+func cefLabelKey(key string) string {
+	prefix := strings.TrimRight(key, "0123456789")
+	if prefix == key {
+		return ""
+	}
+	for _, k := range cefCustomKeys {
+		if prefix == k {
+			return key + "Label"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package log4
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCEFFormatter(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := &LogEntry{
+		Level:     ERROR,
+		Package:   "auth",
+		Message:   "Login failed",
+		Timestamp: ts,
+		Fields: map[string]interface{}{
+			FieldEventID:    "login_failed",
+			FieldUser:       "frank",
+			FieldRemoteAddr: "10.0.0.1",
+			"attempts":      3,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		formatter *CEFFormatter
+		entry     *LogEntry
+		want      string
+	}{
+		{
+			name:      "Defaults",
+			formatter: &CEFFormatter{Vendor: "Acme", Product: "Billing", Version: "2.1"},
+			entry:     entry,
+			want:      "CEF:0|Acme|Billing|2.1|login_failed|Login failed|7|rt=1714564800000 cat=auth attempts=3 src=10.0.0.1 suser=frank",
+		},
+		{
+			name: "Custom extensions",
+			formatter: &CEFFormatter{
+				Extensions:   map[string]string{FieldUser: "duser", "attempts": "cn1"},
+				OmitUnmapped: true,
+				Severities:   DefaultSeverities.With(SeverityMap{ERROR: {CEF: 9}}),
+			},
+			entry: entry,
+			want:  "CEF:0|log4|log4||login_failed|Login failed|9|rt=1714564800000 cat=auth cn1=3 cn1Label=attempts duser=frank",
+		},
+		{
+			name:      "Escaping",
+			formatter: &CEFFormatter{Vendor: "A|B"},
+			entry: &LogEntry{Level: INFO, Package: "app", Message: "a\\b|c\nd", Timestamp: ts, Fields: map[string]interface{}{
+				"query": "x=1\ny\\z", "bad key": "v",
+			}},
+			want: `CEF:0|A\|B|log4||app|a\\b\|c d|3|rt=1714564800000 cat=app bad_key=v query=x\=1\ny\\z`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.formatter.Format(nil, tt.entry)); got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLEEFFormatter(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := &LogEntry{
+		Level:     INFO,
+		Package:   "auth",
+		Message:   "Login\tok",
+		Timestamp: ts,
+		Fields: map[string]interface{}{
+			FieldUser:  "frank",
+			"session":  "a^b",
+			FieldBytes: 512,
+		},
+	}
+
+	got := string((&LEEFFormatter{Vendor: "Acme", Product: "Billing", Version: "2.1"}).Format(nil, entry))
+	want := "LEEF:2.0|Acme|Billing|2.1|auth|x09|devTime=May 01 2024 12:00:00.000 +0000\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS Z\t" +
+		"sev=3\tcat=auth\tmsg=Login ok\tdstBytes=512\tsession=a^b\tusrName=frank"
+	if got != want {
+		t.Errorf("Format() =\n%q\nwant\n%q", got, want)
+	}
+
+	got = string((&LEEFFormatter{Delimiter: '^', Attributes: map[string]string{"session": "sessionId"}, OmitUnmapped: true}).Format(nil, entry))
+	want = "LEEF:2.0|log4|log4||auth|x5e|devTime=May 01 2024 12:00:00.000 +0000^devTimeFormat=MMM dd yyyy HH:mm:ss.SSS Z^" +
+		"sev=3^cat=auth^msg=Login ok^sessionId=a b"
+	if got != want {
+		t.Errorf("Format() with a custom delimiter =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCEFPackage(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.FileHeader = true
+	config.Packages = map[string]PackageConfig{"audit": {Formatter: &CEFFormatter{Vendor: "Acme", Product: "Billing"}}}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Package("audit").ErrorWithFields("Permission denied", map[string]interface{}{
+		FieldEventID: "acl_denied", FieldUser: "bob",
+	})
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, "audit.log"))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and an entry, got %q", lines)
+	}
+	if h, err := ParseHeader(lines[0]); err != nil || h.Format != FormatCEF {
+		t.Errorf("Expected a header of the CEF format, got %+v, %v", h, err)
+	}
+	if !strings.HasPrefix(lines[1], "CEF:0|Acme|Billing||acl_denied|Permission denied|7|rt=") || !strings.HasSuffix(lines[1], " cat=audit suser=bob") {
+		t.Errorf("Unexpected CEF line %q", lines[1])
+	}
+}
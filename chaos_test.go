@@ -0,0 +1,251 @@
+package log4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// errChaos is the error of writes failed by chaos
+var errChaos = errors.New("chaos: injected write failure")
+
+// chaos is a faultInjector failing, slowing down and rotating at random. It
+// is only called by the logging goroutine, so rng needs no lock; the
+// counters are read by the test.
+type chaos struct {
+	FailRate   float64       // Fraction of writes that fail
+	SlowRate   float64       // Fraction of writes delayed by Delay
+	RotateRate float64       // Fraction of writes preceded by a rotation
+	Delay      time.Duration // default: 100µs
+
+	rng      *rand.Rand
+	failures sync.Map // File key -> *atomic.Int64
+}
+
+// withChaos installs c on the logging goroutine of logger
+func withChaos(t *testing.T, logger *ChannelLogger, c *chaos) {
+	t.Helper()
+	c.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	if c.Delay == 0 {
+		c.Delay = 100 * time.Microsecond
+	}
+	if err := logger.do(func() { logger.faults = c }); err != nil {
+		t.Fatalf("Failed to install chaos: %v", err)
+	}
+}
+
+func (c *chaos) roll(rate float64) bool {
+	return rate > 0 && c.rng.Float64() < rate
+}
+
+func (c *chaos) rotate(key string) bool {
+	return c.roll(c.RotateRate)
+}
+
+func (c *chaos) wrap(key string, w io.Writer) io.Writer {
+	return &chaosWriter{c: c, key: key, w: w}
+}
+
+// failed returns the number of writes failed for a file key
+func (c *chaos) failed(key string) int64 {
+	n, ok := c.failures.Load(key)
+	if !ok {
+		return 0
+	}
+	return n.(*atomic.Int64).Load()
+}
+
+type chaosWriter struct {
+	c   *chaos
+	key string
+	w   io.Writer
+}
+
+func (w *chaosWriter) Write(p []byte) (int, error) {
+	if w.c.roll(w.c.FailRate) {
+		n, _ := w.c.failures.LoadOrStore(w.key, new(atomic.Int64))
+		n.(*atomic.Int64).Add(1)
+		return 0, errChaos
+	}
+	if w.c.roll(w.c.SlowRate) {
+		time.Sleep(w.c.Delay)
+	}
+	return w.w.Write(p)
+}
+
+// logLines returns the lines of the active and rotated files of a package,
+// checking that each is a complete entry
+func logLines(t *testing.T, dir, pkg string) []*LogEntry {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, pkg+".log*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*LogEntry
+	for _, path := range paths {
+		for _, line := range strings.Split(strings.TrimSpace(readFile(t, path)), "\n") {
+			if line == "" {
+				continue
+			}
+			entry, err := ParseLine(line, DefaultConfig().TimestampFormat)
+			if err != nil || entry.Message != "Chaos" {
+				t.Fatalf("Torn or unexpected line %q in %s: %v", line, path, err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// TestChaosStress logs from many goroutines while writes fail, stall and
+// rotate at random and other goroutines rotate, flush, reconfigure and read
+// the logger, then checks that every entry was written exactly once or
+// reported as failed. Run it with -race.
+func TestChaosStress(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	const goroutines, perGoroutine, packages = 16, 300, 4
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.BufferSize = goroutines * perGoroutine // Nothing dropped
+	config.MaxFiles = 1000                        // Nothing removed
+	config.ErrorHandler = func(err error) {
+		if !errors.Is(err, errChaos) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	c := &chaos{FailRate: 0.01, SlowRate: 0.01, RotateRate: 0.02}
+	withChaos(t, logger, c)
+
+	stop := make(chan struct{})
+	var busy sync.WaitGroup
+	for _, fn := range []func(i int){
+		func(int) { logger.Rotate() },
+		func(int) { logger.Flush() },
+		func(i int) { logger.SetPackageLevel(fmt.Sprintf("pkg%d", i%packages), DEBUG) },
+		func(int) { logger.Stats(); logger.EffectiveLevels() },
+	} {
+		busy.Add(1)
+		go func() {
+			defer busy.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					fn(i)
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				logger.LogWithFields(fmt.Sprintf("pkg%d", (g+i)%packages), INFO, "Chaos", map[string]interface{}{"g": g, "i": i})
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(stop)
+	busy.Wait()
+	logger.Close()
+
+	stats := logger.Stats()
+	if stats.Dropped != 0 || stats.Written != goroutines*perGoroutine {
+		t.Fatalf("Expected %d entries written and none dropped, got %+v", goroutines*perGoroutine, stats)
+	}
+
+	seen := make(map[string]bool)
+	var lines, failed int64
+	for p := 0; p < packages; p++ {
+		pkg := fmt.Sprintf("pkg%d", p)
+		for _, entry := range logLines(t, tempDir, pkg) {
+			id := fmt.Sprintf("%v/%v", entry.Fields["g"], entry.Fields["i"])
+			if seen[id] {
+				t.Errorf("Entry %s written twice", id)
+			}
+			seen[id] = true
+			lines++
+		}
+		failed += c.failed(pkg)
+	}
+	if lines+failed != goroutines*perGoroutine {
+		t.Errorf("Expected %d lines and failures, got %d lines and %d failures", goroutines*perGoroutine, lines, failed)
+	}
+	if int64(stats.Errors) != failed {
+		t.Errorf("Expected an error per failed write, got %d errors for %d failures", stats.Errors, failed)
+	}
+	if rotated, _ := filepath.Glob(filepath.Join(tempDir, "pkg0.log.*")); len(rotated) == 0 {
+		t.Error("Expected files rotated by chaos")
+	}
+}
+
+// TestChaosClose closes the logger while goroutines are still logging, each
+// entry having to be written or reported to OnDrop, never lost or torn.
+func TestChaosClose(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	const goroutines = 16
+	var dropped, logged atomic.Int64
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.BufferSize = 64
+	config.MaxFileSize = 16 << 10 // Rotating throughout
+	config.MaxFiles = 1000
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		dropped.Add(1)
+	}
+	config.ErrorHandler = func(error) {} // Overflow drops are counted by OnDrop
+	logger := NewChannelLoggerWithConfig(config)
+	withChaos(t, logger, &chaos{SlowRate: 0.05})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				logger.LogWithFields("app", INFO, "Chaos", map[string]interface{}{"g": g, "i": i})
+				logged.Add(1)
+			}
+		}(g)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	logger.Close()
+	time.Sleep(10 * time.Millisecond) // Some entries logged after Close
+	close(stop)
+	wg.Wait()
+
+	lines := int64(len(logLines(t, tempDir, "app")))
+	if lines+dropped.Load() != logged.Load() {
+		t.Errorf("Expected %d entries written or dropped, got %d lines and %d drops", logged.Load(), lines, dropped.Load())
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "app.log.1")); err != nil {
+		t.Errorf("Expected rotations during the test: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package log4
+
+// Fields of an HTTP request read by CLFFormatter
+const (
+	FieldRemoteAddr = "remote_addr" // Client address, e.g. "127.0.0.1"
+	FieldUser       = "user"        // Authenticated user
+	FieldMethod     = "method"      // e.g. "GET"
+	FieldPath       = "path"        // Request URI, e.g. "/index.html?page=2"
+	FieldProto      = "proto"       // e.g. "HTTP/1.1"
+	FieldStatus     = "status"      // Response status code
+	FieldBytes      = "bytes"       // Response body size
+	FieldReferer    = "referer"
+	FieldUserAgent  = "user_agent"
+)
+
+// CLFTimestampFormat is the timestamp layout of the Common Log Format
+const CLFTimestampFormat = "02/Jan/2006:15:04:05 -0700"
+
+// CLFFormatter renders access log entries in the NCSA Common Log Format, or
+// in the Combined Log Format with Combined, so that tools such as GoAccess
+// and AWStats read them as they read Apache and nginx logs:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"
+//
+// Values are taken from the fields named FieldRemoteAddr, FieldUser and so
+// on; missing ones, and a size of 0, are written as "-". The request line is
+// built from FieldMethod, FieldPath and FieldProto, or is the message if the
+// entry has no FieldMethod. Other fields, the level and the package are not
+// written. Quotes, backslashes and control characters in quoted values are
+// escaped as Apache does.
+type CLFFormatter struct {
+	Combined bool
+}
+
+// Format appends the Common or Combined Log Format line of entry to dst
+func (f *CLFFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	dst = appendCLFField(dst, entry, FieldRemoteAddr)
+	dst = append(dst, " - "...)
+	dst = appendCLFField(dst, entry, FieldUser)
+	dst = append(dst, " ["...)
+	dst = entry.Timestamp.AppendFormat(dst, CLFTimestampFormat)
+	dst = append(dst, "] "...)
+
+	request := entry.Message
+	if method := clfValue(entry, FieldMethod); method != "" {
+		request = method + " " + clfValue(entry, FieldPath)
+		if proto := clfValue(entry, FieldProto); proto != "" {
+			request += " " + proto
+		}
+	}
+	dst = appendCLFQuoted(dst, request)
+	dst = append(dst, ' ')
+	dst = appendCLFField(dst, entry, FieldStatus)
+	dst = append(dst, ' ')
+	if size := clfValue(entry, FieldBytes); size != "" && size != "0" {
+		dst = append(dst, size...)
+	} else {
+		dst = append(dst, '-')
+	}
+
+	if f.Combined {
+		for _, key := range []string{FieldReferer, FieldUserAgent} {
+			dst = append(dst, ' ')
+			if v := clfValue(entry, key); v != "" {
+				dst = appendCLFQuoted(dst, v)
+			} else {
+				dst = append(dst, `"-"`...)
+			}
+		}
+	}
+	return dst
+}
+
+// clfValue returns a field as text, or "" if the entry does not have it
+func clfValue(entry *LogEntry, key string) string {
+	v, ok := entry.Fields[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return FieldEncoding{}.text(resolveValue(v))
+}
+
+// appendCLFField appends an unquoted field, "-" if it is missing. Spaces
+// would shift the columns of the line and are escaped.
+func appendCLFField(dst []byte, entry *LogEntry, key string) []byte {
+	v := clfValue(entry, key)
+	if v == "" {
+		return append(dst, '-')
+	}
+	return appendCLFEscaped(dst, v, ' ')
+}
+
+// appendCLFQuoted appends s in double quotes
+func appendCLFQuoted(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = appendCLFEscaped(dst, s, '"')
+	return append(dst, '"')
+}
+
+// appendCLFEscaped appends s with backslashes, control characters and the
+// delimiter escaped, as in Apache's access logs
+func appendCLFEscaped(dst []byte, s string, delim byte) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			if c == '"' && delim != '"' {
+				dst = append(dst, c)
+				continue
+			}
+			dst = append(dst, '\\', c)
+		case '\b':
+			dst = append(dst, `\b`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\t':
+			dst = append(dst, `\t`...)
+		case '\v':
+			dst = append(dst, `\v`...)
+		default:
+			if c < 0x20 || c == 0x7f || c == delim {
+				dst = append(dst, `\x`...)
+				dst = append(dst, "0123456789abcdef"[c>>4], "0123456789abcdef"[c&0xf])
+				continue
+			}
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
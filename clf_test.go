@@ -0,0 +1,107 @@
+package log4
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCLFFormatter(t *testing.T) {
+	ts := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+	entry := &LogEntry{
+		Level:     INFO,
+		Message:   "request",
+		Timestamp: ts,
+		Fields: map[string]interface{}{
+			FieldRemoteAddr: "127.0.0.1",
+			FieldUser:       "frank",
+			FieldMethod:     "GET",
+			FieldPath:       "/apache_pb.gif",
+			FieldProto:      "HTTP/1.0",
+			FieldStatus:     200,
+			FieldBytes:      int64(2326),
+			FieldReferer:    "http://www.example.com/start.html",
+			FieldUserAgent:  "Mozilla/4.08",
+			"request_id":    "abc",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		formatter *CLFFormatter
+		entry     *LogEntry
+		want      string
+	}{
+		{
+			name:      "Common",
+			formatter: &CLFFormatter{},
+			entry:     entry,
+			want:      `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+		},
+		{
+			name:      "Combined",
+			formatter: &CLFFormatter{Combined: true},
+			entry:     entry,
+			want:      `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+		},
+		{
+			name:      "Missing fields",
+			formatter: &CLFFormatter{Combined: true},
+			entry: &LogEntry{Message: "GET / HTTP/1.1", Timestamp: ts, Fields: map[string]interface{}{
+				FieldStatus: 304, FieldBytes: 0,
+			}},
+			want: `- - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 304 - "-" "-"`,
+		},
+		{
+			name:      "Escaping",
+			formatter: &CLFFormatter{Combined: true},
+			entry: &LogEntry{Timestamp: ts, Fields: map[string]interface{}{
+				FieldRemoteAddr: "::1", FieldUser: "bob smith", FieldMethod: "GET", FieldPath: "/a\"b\\c\n",
+				FieldStatus: 400, FieldUserAgent: "evil\x01\"agent",
+			}},
+			want: `::1 - bob\x20smith [10/Oct/2000:13:55:36 -0700] "GET /a\"b\\c\n" 400 - "-" "evil\x01\"agent"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.formatter.Format(nil, tt.entry)); got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLFPackage(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.FileHeader = true
+	config.Packages = map[string]PackageConfig{"access": {Formatter: &CLFFormatter{Combined: true}}}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Package("access").InfoWithFields("request", map[string]interface{}{
+		FieldRemoteAddr: "10.0.0.1", FieldMethod: "POST", FieldPath: "/login", FieldProto: "HTTP/2.0",
+		FieldStatus: 302, FieldBytes: 0, FieldUserAgent: "curl/8.5.0",
+	})
+	logger.Info("app", "Started")
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, "access.log"))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and an entry, got %q", lines)
+	}
+	if h, err := ParseHeader(lines[0]); err != nil || h.Format != FormatCombined {
+		t.Errorf("Expected a header of the combined format, got %+v, %v", h, err)
+	}
+	if !strings.HasPrefix(lines[1], "10.0.0.1 - - [") || !strings.HasSuffix(lines[1], `] "POST /login HTTP/2.0" 302 - "-" "curl/8.5.0"`) {
+		t.Errorf("Unexpected access log line %q", lines[1])
+	}
+
+	if h, err := ParseHeader(strings.SplitN(readFile(t, filepath.Join(tempDir, "app.log")), "\n", 2)[0]); err != nil || h.Format != FormatText {
+		t.Errorf("Expected other packages to keep the text format, got %+v, %v", h, err)
+	}
+}
@@ -0,0 +1,395 @@
+// Command log4ctl inspects and manages log directories written by log4.
+//
+// Usage:
+//
+//	log4ctl cat [-level LEVEL] [-package a,b] [-since T] [-until T] [-keys k=v,...] [-color] PATH...
+//	log4ctl du [DIR]
+//	log4ctl verify [-head CHAIN] [DIR]
+//	log4ctl rotate -pid PID [-signal HUP]
+//	log4ctl doctor [-max-size BYTES] [-max-files N] [-append-only] [DIR]
+//	log4ctl benchcmp [-threshold PERCENT] OLD NEW
+//
+// cat pretty-prints text and JSON logs, reading directories, rotated and
+// compressed files. -since and -until accept RFC 3339 timestamps or durations
+// relative to now, e.g. -since 2h. -keys names the JSON keys of a logger
+// with custom key names, e.g. -keys msg=message,time=ts, when its files have
+// no header recording them. verify checks the hash chain of the rotation
+// manifest and the rotated files against it (see log4.VerifyManifest);
+// -head also requires the last link of the chain to match one kept
+// elsewhere. rotate signals a process that called
+// ChannelLogger.RotateOnSignal. doctor runs log4.ValidateEnvironment for a
+// log directory and exits with status 1 if a check fails. benchcmp compares
+// two outputs of go test -bench, such as bench/testdata/baseline.txt and a new
+// run of the bench package, and exits with status 1 if a metric regressed by
+// more than the threshold.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MhunterDev/log4"
+	"github.com/MhunterDev/log4/bench"
+	"github.com/MhunterDev/log4/reader"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "cat":
+		err = runCat(args[1:], stdout)
+	case "du":
+		err = runDu(args[1:], stdout)
+	case "verify":
+		err = runVerify(args[1:], stdout)
+	case "rotate":
+		err = runRotate(args[1:])
+	case "doctor":
+		err = runDoctor(args[1:], stdout)
+	case "benchcmp":
+		err = runBenchcmp(args[1:], stdout)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "log4ctl: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "log4ctl %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: log4ctl <command> [flags]
+
+Commands:
+  cat       pretty-print and filter log files or directories
+  du        report disk usage per package
+  verify    check rotated files against the manifest and its hash chain
+  rotate    signal a running process to rotate its log files
+  doctor    check that a log directory can be written
+  benchcmp  compare two benchmark runs and report regressions
+`)
+}
+
+// ANSI colors used by cat -color
+var levelColors = map[log4.LogLevel]string{
+	log4.DEBUG: "\x1b[90m",
+	log4.INFO:  "\x1b[36m",
+	log4.ERROR: "\x1b[31m",
+}
+
+func runCat(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	level := fs.String("level", "DEBUG", "minimum level")
+	packages := fs.String("package", "", "comma separated packages to include")
+	since := fs.String("since", "", "only entries after this time (RFC 3339 or duration ago)")
+	until := fs.String("until", "", "only entries before this time (RFC 3339 or duration ago)")
+	format := fs.String("timestamp-format", log4.DefaultConfig().TimestampFormat, "timestamp layout used by the writer")
+	keys := fs.String("keys", "", "comma separated JSON key names used by the writer, e.g. msg=message,time=ts")
+	color := fs.Bool("color", false, "colorize levels")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	minLevel, err := log4.ParseLogLevelStrict(*level)
+	if err != nil {
+		return err
+	}
+	q := reader.Query{
+		MinLevel:        minLevel,
+		TimestampFormat: *format,
+	}
+	if *packages != "" {
+		q.Packages = strings.Split(*packages, ",")
+	}
+	if *keys != "" {
+		q.Keys = make(map[string]string)
+		for _, pair := range strings.Split(*keys, ",") {
+			def, name, ok := strings.Cut(pair, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("invalid key %q: use default=name, e.g. msg=message", pair)
+			}
+			q.Keys[def] = name
+		}
+	}
+	if q.Since, err = parseTime(*since); err != nil {
+		return err
+	}
+	if q.Until, err = parseTime(*until); err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	formatter := &log4.TextFormatter{TimestampFormat: *format}
+	var buf []byte
+	print := func(entry *log4.LogEntry) error {
+		buf = formatter.Format(buf[:0], entry)
+		line := string(buf)
+		if *color {
+			if c, ok := levelColors[entry.Level]; ok {
+				line = c + line + "\x1b[0m"
+			}
+		}
+		_, err := fmt.Fprintf(stdout, "%-16s %s\n", entry.Package, line)
+		return err
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			entries, err := reader.Read(path, q)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if err := print(entry); err != nil {
+					return err
+				}
+			}
+		} else if err := reader.ScanFile(path, q, print); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runVerify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	head := fs.String("head", "", "expected chain of the last manifest entry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	entries, err := log4.ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+	if err := log4.VerifyManifest(dir); err != nil {
+		return err
+	}
+	last := ""
+	if len(entries) > 0 {
+		last = entries[len(entries)-1].Chain
+	}
+	if *head != "" && *head != last {
+		return fmt.Errorf("manifest ends at %s, expected %s: entries were removed or rewritten", last, *head)
+	}
+	fmt.Fprintf(stdout, "%d rotations verified, chain head %s\n", len(entries), last)
+	return nil
+}
+
+// parseTime accepts an RFC 3339 timestamp or a duration before now
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or a duration such as 2h", s)
+	}
+	return t, nil
+}
+
+func runDu(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("du", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	files, err := reader.Files(dir)
+	if err != nil {
+		return err
+	}
+
+	type usage struct {
+		files int
+		bytes int64
+	}
+	totals := make(map[string]*usage)
+	var names []string
+	var total int64
+	for _, f := range files {
+		u, ok := totals[f.Package]
+		if !ok {
+			u = &usage{}
+			totals[f.Package] = u
+			names = append(names, f.Package)
+		}
+		u.files++
+		u.bytes += f.Size
+		total += f.Size
+	}
+
+	// Largest packages first
+	sort.SliceStable(names, func(i, j int) bool {
+		return totals[names[i]].bytes > totals[names[j]].bytes
+	})
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tFILES\tSIZE")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", name, totals[name].files, humanSize(totals[name].bytes))
+	}
+	fmt.Fprintf(tw, "total\t%d\t%s\n", len(files), humanSize(total))
+	return tw.Flush()
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func runRotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	pid := fs.Int("pid", 0, "process to signal")
+	sig := fs.String("signal", "HUP", "signal the process rotates on (HUP or a number)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pid <= 0 {
+		return errors.New("-pid is required")
+	}
+
+	var s os.Signal
+	if name := strings.TrimPrefix(strings.ToUpper(*sig), "SIG"); name == "HUP" {
+		if hangup == nil {
+			return errors.New("SIGHUP is not supported on this platform: use a signal number")
+		}
+		s = hangup
+	} else if n, err := strconv.Atoi(name); err == nil {
+		s = syscall.Signal(n)
+	} else {
+		return fmt.Errorf("unsupported signal %q: use HUP or a signal number", *sig)
+	}
+
+	proc, err := os.FindProcess(*pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(s)
+}
+
+func runDoctor(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	maxSize := fs.Int64("max-size", log4.DefaultMaxFileSize, "MaxFileSize of the logger in bytes")
+	maxFiles := fs.Int("max-files", log4.DefaultMaxFiles, "MaxFiles of the logger")
+	appendOnly := fs.Bool("append-only", false, "the logger uses AppendOnly")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config := log4.DefaultConfig()
+	if fs.NArg() > 0 {
+		config.LogDir = fs.Arg(0)
+	}
+	config.MaxFileSize = *maxSize
+	config.MaxFiles = *maxFiles
+	config.AppendOnly = *appendOnly
+
+	report := log4.ValidateEnvironment(config)
+	fmt.Fprint(stdout, report)
+	if !report.OK() {
+		return errors.New("some checks failed")
+	}
+	return nil
+}
+
+func runBenchcmp(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("benchcmp", flag.ContinueOnError)
+	threshold := fs.Float64("threshold", 10, "percentage by which a metric may get worse")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("expected the OLD and NEW benchmark outputs")
+	}
+
+	var runs [2]map[string]*bench.Result
+	for i, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		runs[i], err = bench.ParseResults(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	deltas := bench.Compare(runs[0], runs[1])
+	if len(deltas) == 0 {
+		return errors.New("no benchmarks in common")
+	}
+	regressions := 0
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BENCHMARK\tUNIT\tOLD\tNEW\tDELTA\t")
+	for _, d := range deltas {
+		mark := ""
+		if d.Regressed(*threshold / 100) {
+			mark = "regressed"
+			regressions++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.4g\t%.4g\t%+.1f%%\t%s\n", d.Name, d.Unit, d.Old, d.New, d.Change*100, mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if regressions > 0 {
+		return fmt.Errorf("%d metrics regressed by more than %g%%", regressions, *threshold)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MhunterDev/log4"
+)
+
+func writeLogs(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api.log": "[2025-06-23 10:00:00] INFO: Request served\n" +
+			"[2025-06-23 10:00:01] ERROR: Request failed | status=500\n",
+		"db.log": "[2025-06-23 10:00:02] DEBUG: Query executed\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestCat(t *testing.T) {
+	dir := writeLogs(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-level", "ERROR", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("cat exited with %d: %s", code, stderr.String())
+	}
+
+	out := stdout.String()
+	if !strings.Contains(out, "api") || !strings.Contains(out, "ERROR: Request failed | status=500") {
+		t.Errorf("Expected error entry in output, got %q", out)
+	}
+	if strings.Contains(out, "Request served") || strings.Contains(out, "Query executed") {
+		t.Errorf("Lower level entries should be filtered, got %q", out)
+	}
+}
+
+func TestCatUnknownLevel(t *testing.T) {
+	dir := writeLogs(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-level", "WRN", dir}, &stdout, &stderr); code == 0 {
+		t.Errorf("Expected cat to reject an unknown level, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "WRN") {
+		t.Errorf("Expected the unknown level in the error, got %q", stderr.String())
+	}
+}
+
+func TestCatPackageFilter(t *testing.T) {
+	dir := writeLogs(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-package", "db", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("cat exited with %d: %s", code, stderr.String())
+	}
+	if lines := strings.Count(stdout.String(), "\n"); lines != 1 {
+		t.Errorf("Expected 1 line, got %q", stdout.String())
+	}
+}
+
+func TestCatKeys(t *testing.T) {
+	dir := t.TempDir()
+	line := `{"ts":"2025-06-23T10:00:00Z","severity":"ERROR","message":"Request failed","package":"api"}` + "\n"
+	os.WriteFile(filepath.Join(dir, "api.log"), []byte(line), 0644)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-level", "ERROR", "-keys", "time=ts,level=severity,msg=message", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("cat exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "ERROR: Request failed") {
+		t.Errorf("Expected the renamed keys to be parsed, got %q", stdout.String())
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	config := log4.DefaultConfig()
+	config.LogDir = dir
+	config.Manifest = true
+	config.DisableConsole = true
+	logger := log4.NewChannelLoggerWithConfig(config)
+	for _, msg := range []string{"first", "second"} {
+		logger.Info("app", msg)
+		logger.Flush()
+		logger.Rotate()
+	}
+	logger.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"verify", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("verify exited with %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "2 rotations verified") {
+		t.Errorf("Unexpected output %q", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := run([]string{"verify", "-head", "0000", dir}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "rewritten") {
+		t.Errorf("Expected a different head to fail, exited with %d: %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	os.WriteFile(filepath.Join(dir, "app.log.1"), []byte("tampered\n"), 0644)
+	if code := run([]string{"verify", dir}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "app.log.1") {
+		t.Errorf("Expected the tampered file to fail, exited with %d: %s", code, stderr.String())
+	}
+}
+
+func TestDu(t *testing.T) {
+	dir := writeLogs(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"du", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("du exited with %d: %s", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "api") || !strings.HasPrefix(lines[3], "total") {
+		t.Errorf("Unexpected du output:\n%s", stdout.String())
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	dir := writeLogs(t)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"doctor", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("doctor exited with %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "ok    log dir "+dir+": exists and is writable") {
+		t.Errorf("Expected the directory to pass, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	file := filepath.Join(dir, "api.log")
+	if code := run([]string{"doctor", file}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected a file given as directory to fail, exited with %d", code)
+	}
+	if !strings.Contains(stdout.String(), "fail  log dir "+file+": is not a directory") {
+		t.Errorf("Expected the failed check in the report, got %q", stdout.String())
+	}
+}
+
+func TestBenchcmp(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.txt")
+	new := filepath.Join(dir, "new.txt")
+	os.WriteFile(old, []byte("BenchmarkPipeline/Fields/4-8 1000000 1000 ns/op 3 allocs/op\n"), 0644)
+	os.WriteFile(new, []byte("BenchmarkPipeline/Fields/4-8 1000000 1050 ns/op 4 allocs/op\n"), 0644)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"benchcmp", old, new}, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected a regression of allocs/op, exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "+33.3%  regressed") || strings.Contains(stdout.String(), "+5.0%  regressed") {
+		t.Errorf("Expected only allocs/op to be flagged, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"benchcmp", "-threshold", "50", old, new}, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected no regression with a 50%% threshold, exited with %d: %s", code, stderr.String())
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"bogus"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if code := run([]string{"rotate"}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected rotate without -pid to fail, got %d", code)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// hangup is nil, as there is no SIGHUP on this platform
+var hangup os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// hangup is the signal rotate sends for -signal HUP
+var hangup os.Signal = syscall.SIGHUP
@@ -0,0 +1,250 @@
+package log4
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultFrameSize is the amount of uncompressed data written into a single
+// compressed frame before it is closed and a new one started
+const DefaultFrameSize = 64 * 1024
+
+// Codec compresses a sink's output stream inline.
+//
+// Output is written as a sequence of frames, each a complete and independently
+// decodable compressed stream. Frames are closed once DefaultFrameSize bytes
+// have been written and when the file rotates or the logger closes. When the
+// logger goes idle the open frame is flushed without closing it if the
+// codec's writer has a Flush method, so a reader tailing the file can decode
+// everything written so far. gzip, zstd and the snappy
+// framing format all allow frames to be concatenated, which keeps the files
+// readable by standard tools such as zcat or zstdcat.
+//
+// GzipCodec, ZstdCodec and SnappyCodec ship with this package; others can be
+// plugged in by implementing Codec and calling RegisterCodec.
+type Codec interface {
+	// Name returns a short identifier such as "gzip"
+	Name() string
+	// Extension returns the file extension including the dot, e.g. ".gz"
+	Extension() string
+	// NewWriter starts a new frame on w
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader decodes a stream of concatenated frames from r
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// FrameCodec is implemented by codecs that can decode a single frame, which
+// lets Tail resume after the last complete frame of a file instead of
+// decoding it from the start on every poll
+type FrameCodec interface {
+	Codec
+	// NewFrameReader decodes the frame at the start of r without reading
+	// past its end. A frame still being written ends in io.ErrUnexpectedEOF.
+	NewFrameReader(r flate.Reader) (io.ReadCloser, error)
+}
+
+// GzipCodec compresses output with gzip at the given level
+type GzipCodec struct {
+	Level int // gzip.DefaultCompression when zero
+}
+
+// Name returns "gzip"
+func (c GzipCodec) Name() string { return "gzip" }
+
+// Extension returns ".gz"
+func (c GzipCodec) Extension() string { return ".gz" }
+
+// NewWriter starts a new gzip member on w
+func (c GzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// NewReader decodes concatenated gzip members from r
+func (c GzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// NewFrameReader decodes the single gzip member at the start of r
+func (c GzipCodec) NewFrameReader(r flate.Reader) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	zr.Multistream(false)
+	return zr, nil
+}
+
+// ZstdCodec compresses output with zstd at the given level
+type ZstdCodec struct {
+	Level int // Standard zstd level, 1 to 22 (default: 3)
+}
+
+// Name returns "zstd"
+func (c ZstdCodec) Name() string { return "zstd" }
+
+// Extension returns ".zst"
+func (c ZstdCodec) Extension() string { return ".zst" }
+
+// NewWriter starts a new zstd frame on w
+func (c ZstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := zstd.SpeedDefault
+	if c.Level != 0 {
+		level = zstd.EncoderLevelFromZstd(c.Level)
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+}
+
+// NewReader decodes concatenated zstd frames from r
+func (c ZstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// SnappyCodec compresses output with the snappy framing format
+type SnappyCodec struct{}
+
+// Name returns "snappy"
+func (c SnappyCodec) Name() string { return "snappy" }
+
+// Extension returns ".sz"
+func (c SnappyCodec) Extension() string { return ".sz" }
+
+// NewWriter starts a new snappy stream on w
+func (c SnappyCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return s2.NewWriter(w, s2.WriterSnappyCompat(), s2.WriterConcurrency(1)), nil
+}
+
+// NewReader decodes concatenated snappy streams from r
+func (c SnappyCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(s2.NewReader(r)), nil
+}
+
+// compressPayload compresses p into a single frame of c, for the network
+// sinks
+func compressPayload(c Codec, p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := c.NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s frame: %w", c.Name(), err)
+	}
+	if _, err := w.Write(p); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{".gz": GzipCodec{}, ".zst": ZstdCodec{}, ".sz": SnappyCodec{}}
+)
+
+// RegisterCodec makes a codec known to the readers in this package, which pick
+// a codec by file extension. Registering an extension twice replaces the
+// earlier codec.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Extension()] = c
+}
+
+// CodecForFile returns the registered codec matching the extension of the
+// base name of name, or nil if the file is not compressed with a known codec.
+// Rotated files such as "app.log.gz.2" are matched on the extension before
+// the rotation suffix.
+func CodecForFile(name string) Codec {
+	name = filepath.Base(name)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	for ext, c := range codecs {
+		if strings.HasSuffix(name, ext) {
+			return c
+		}
+	}
+	return nil
+}
+
+// frameWriter writes data through a codec as a series of self-contained frames
+type frameWriter struct {
+	dst       io.Writer
+	codec     Codec
+	frameSize int
+	cw        io.WriteCloser // open frame, nil between frames
+	pending   int            // uncompressed bytes in the open frame
+}
+
+func newFrameWriter(dst io.Writer, codec Codec, frameSize int) *frameWriter {
+	if frameSize <= 0 {
+		frameSize = DefaultFrameSize
+	}
+	return &frameWriter{dst: dst, codec: codec, frameSize: frameSize}
+}
+
+// Write compresses p into the current frame, starting one if necessary
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	if fw.cw == nil {
+		cw, err := fw.codec.NewWriter(fw.dst)
+		if err != nil {
+			return 0, fmt.Errorf("failed to start %s frame: %w", fw.codec.Name(), err)
+		}
+		fw.cw = cw
+	}
+
+	n, err := fw.cw.Write(p)
+	fw.pending += n
+	if err != nil {
+		return n, err
+	}
+
+	if fw.pending >= fw.frameSize {
+		return n, fw.Close()
+	}
+	return n, nil
+}
+
+// Flush pushes the data of the open frame to the underlying writer without
+// closing the frame, so that a reader can decode everything written so far.
+// Codecs whose writers cannot flush keep the data until the frame closes.
+func (fw *frameWriter) Flush() error {
+	if f, ok := fw.cw.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close finishes the current frame; the underlying writer is left open
+func (fw *frameWriter) Close() error {
+	if fw.cw == nil {
+		return nil
+	}
+	err := fw.cw.Close()
+	fw.cw = nil
+	fw.pending = 0
+	return err
+}
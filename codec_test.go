@@ -0,0 +1,223 @@
+package log4
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decompressFile(t *testing.T, path string, codec Codec) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	r, err := codec.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to create %s reader: %v", codec.Name(), err)
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress %s: %v", path, err)
+	}
+	return string(content)
+}
+
+// Test that compressed files can be read while the logger is still running
+func TestCompressedFileTailable(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Compression = GzipCodec{}
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Info("zipped", "First message")
+	time.Sleep(100 * time.Millisecond)
+	logger.Info("zipped", "Second message")
+	time.Sleep(100 * time.Millisecond)
+
+	logFile := filepath.Join(tempDir, "zipped.log.gz")
+	if !fileExists(logFile) {
+		t.Fatal("Compressed log file was not created")
+	}
+
+	f, err := os.Open(logFile)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", logFile, err)
+	}
+	defer f.Close()
+	r, err := GzipCodec{}.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	// The frame stays open while the logger runs, so the stream ends early
+	content, err := io.ReadAll(r)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected the frame to still be open, got %v", err)
+	}
+	if !strings.Contains(string(content), "First message") || !strings.Contains(string(content), "Second message") {
+		t.Errorf("Expected both messages in decompressed output, got %q", content)
+	}
+}
+
+func TestFrameWriterFlushKeepsFrame(t *testing.T) {
+	var buf bytes.Buffer
+	fw := newFrameWriter(&buf, GzipCodec{}, DefaultFrameSize)
+
+	for i := 0; i < 3; i++ {
+		fw.Write([]byte("idle line\n"))
+		if err := fw.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+	}
+	flushed := buf.Len()
+	if frames := bytes.Count(buf.Bytes(), []byte{0x1f, 0x8b, 0x08}); frames != 1 {
+		t.Errorf("Expected idle flushes to keep a single frame, got %d", frames)
+	}
+
+	fw.Close()
+	if buf.Len() <= flushed {
+		t.Error("Expected Close to finish the frame")
+	}
+	r, err := GzipCodec{}.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil || countLines(string(content)) != 3 {
+		t.Errorf("Expected 3 lines, got %q (%v)", content, err)
+	}
+}
+
+func TestFrameWriterSplitsFrames(t *testing.T) {
+	var buf bytes.Buffer
+	fw := newFrameWriter(&buf, GzipCodec{}, 16)
+
+	for i := 0; i < 4; i++ {
+		if _, err := fw.Write([]byte("0123456789abcdef\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Each gzip member starts with the magic bytes 0x1f 0x8b
+	if frames := bytes.Count(buf.Bytes(), []byte{0x1f, 0x8b, 0x08}); frames != 4 {
+		t.Errorf("Expected 4 frames, got %d", frames)
+	}
+
+	r, err := GzipCodec{}.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	if countLines(string(content)) != 4 {
+		t.Errorf("Expected 4 lines across frames, got %q", content)
+	}
+}
+
+func TestCodecsFlushAndConcatenate(t *testing.T) {
+	for _, codec := range []Codec{ZstdCodec{}, ZstdCodec{Level: 19}, SnappyCodec{}} {
+		var buf bytes.Buffer
+		fw := newFrameWriter(&buf, codec, 16)
+
+		fw.Write([]byte("flushed line\n"))
+		if err := fw.Flush(); err != nil {
+			t.Fatalf("%s: Flush failed: %v", codec.Name(), err)
+		}
+		r, err := codec.NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: NewReader failed: %v", codec.Name(), err)
+		}
+		// The open frame ends early, but what was flushed is readable
+		content, _ := io.ReadAll(r)
+		r.Close()
+		if string(content) != "flushed line\n" {
+			t.Errorf("%s: Expected the flushed line in the open frame, got %q", codec.Name(), content)
+		}
+
+		for i := 0; i < 3; i++ {
+			fw.Write([]byte("0123456789abcdef\n"))
+		}
+		fw.Close()
+		r, err = codec.NewReader(&buf)
+		if err != nil {
+			t.Fatalf("%s: NewReader failed: %v", codec.Name(), err)
+		}
+		content, err = io.ReadAll(r)
+		r.Close()
+		if err != nil || countLines(string(content)) != 4 {
+			t.Errorf("%s: Expected 4 lines across frames, got %q (%v)", codec.Name(), content, err)
+		}
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var plain, zipped bytes.Buffer
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{
+		NewWriterSink(&plain, nil),
+		NewWriterSink(&zipped, GzipCodec{}),
+	}
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.Info("sinktest", "Sink message")
+	logger.Close()
+
+	if !strings.Contains(plain.String(), "INFO: Sink message\n") {
+		t.Errorf("Plain sink missing message, got %q", plain.String())
+	}
+
+	r, err := GzipCodec{}.NewReader(&zipped)
+	if err != nil {
+		t.Fatalf("Compressed sink output is not valid gzip: %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	if !strings.Contains(string(content), "Sink message") {
+		t.Errorf("Compressed sink missing message, got %q", content)
+	}
+}
+
+func TestCodecForFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"app.log.gz", "gzip"},
+		{"app.log.gz.3", "gzip"},
+		{"app.log.zst", "zstd"},
+		{"app.log.sz.2", "snappy"},
+		{"app.log", ""},
+		{filepath.Join("logs", "x.gz.d", "app.log"), ""},
+		{filepath.Join("build.sz.tmp", "app.log.1"), ""},
+		{filepath.Join("logs", "app.log.zst.4"), "zstd"},
+	}
+
+	for _, test := range tests {
+		got := ""
+		if c := CodecForFile(test.name); c != nil {
+			got = c.Name()
+		}
+		if got != test.expected {
+			t.Errorf("CodecForFile(%s) = %q, want %q", test.name, got, test.expected)
+		}
+	}
+}
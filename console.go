@@ -0,0 +1,21 @@
+package log4
+
+// ANSI escape sequences used by Config.ConsoleColor
+const (
+	colorGray  = "\x1b[90m"
+	colorCyan  = "\x1b[36m"
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+// levelColor returns the console color of a level
+func levelColor(level LogLevel) string {
+	switch {
+	case level >= ERROR:
+		return colorRed
+	case level >= INFO:
+		return colorCyan
+	default:
+		return colorGray
+	}
+}
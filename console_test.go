@@ -0,0 +1,122 @@
+package log4
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDevelopmentConfig(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DevelopmentConfig()
+	config.LogDir = tempDir
+	logger := NewChannelLoggerWithConfig(config)
+	var console bytes.Buffer
+	logger.stdout = &console
+
+	logger.Debug("dev", "Debugging")
+	logger.Package("dev").Error("Broken")
+	logger.Close()
+
+	out := console.String()
+	if !strings.HasPrefix(out, colorGray) || !strings.Contains(out, colorRed) || !strings.Contains(out, colorReset+"\n") {
+		t.Errorf("Expected colored console output, got %q", out)
+	}
+	if !strings.Contains(out, "/console_test.go:") {
+		t.Errorf("Expected caller of the test, got %q", out)
+	}
+
+	content := readFile(t, filepath.Join(tempDir, "dev.log"))
+	if strings.Contains(content, "\x1b[") || countLines(content) != 2 {
+		t.Errorf("File output should be plain, got %q", content)
+	}
+}
+
+func TestDisableConsole(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+	var console bytes.Buffer
+	logger.stdout = &console
+
+	logger.Info("quiet", "File only")
+	logger.Close()
+
+	if console.Len() != 0 {
+		t.Errorf("Expected no console output, got %q", console.String())
+	}
+	if !strings.Contains(readFile(t, filepath.Join(tempDir, "quiet.log")), "File only") {
+		t.Error("Expected the entry in the file")
+	}
+}
+
+func TestOutputLevels(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var shipped bytes.Buffer
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = DEBUG
+	config.ConsoleLevel = ERROR
+	config.FileLevel = DEBUG
+	config.Sinks = []Sink{LevelSink(NewWriterSink(&shipped, nil), INFO)}
+	logger := NewChannelLoggerWithConfig(config)
+	var console bytes.Buffer
+	logger.stdout = &console
+
+	logger.Debug("api", "Parsing request")
+	logger.Info("api", "Request handled")
+	logger.Error("api", "Upstream failed")
+	logger.Close()
+
+	if n := countLines(readFile(t, filepath.Join(tempDir, "api.log"))); n != 3 {
+		t.Errorf("Expected the file to keep all 3 entries, got %d", n)
+	}
+	if n := countLines(shipped.String()); n != 2 || strings.Contains(shipped.String(), "DEBUG") {
+		t.Errorf("Expected INFO and above in the sink, got %q", shipped.String())
+	}
+	if out := console.String(); countLines(out) != 1 || !strings.Contains(out, "Upstream failed") {
+		t.Errorf("Expected only the error on the console, got %q", out)
+	}
+}
+
+func TestConsoleWriter(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var first, second bytes.Buffer
+	logger := NewLogger(WithDir(tempDir), WithConsoleWriter(&first))
+
+	// Entries still queued may go to either writer
+	logger.Info("app", "to first")
+	time.Sleep(50 * time.Millisecond)
+	if err := logger.SetConsoleWriter(&second); err != nil {
+		t.Fatalf("SetConsoleWriter failed: %v", err)
+	}
+	logger.Info("app", "to second")
+	time.Sleep(50 * time.Millisecond)
+	if err := logger.SetConsoleWriter(nil); err != nil {
+		t.Fatalf("SetConsoleWriter failed: %v", err)
+	}
+	logger.Info("app", "discarded")
+	logger.Close()
+
+	if out := first.String(); !strings.Contains(out, "to first") || strings.Contains(out, "to second") {
+		t.Errorf("Unexpected output of the configured writer: %q", out)
+	}
+	if out := second.String(); !strings.Contains(out, "to second") || strings.Contains(out, "discarded") {
+		t.Errorf("Unexpected output after SetConsoleWriter: %q", out)
+	}
+	if err := logger.SetConsoleWriter(&first); err == nil {
+		t.Error("Expected SetConsoleWriter to fail after Close")
+	}
+}
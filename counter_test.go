@@ -0,0 +1,55 @@
+package log4
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCounters(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.CounterInterval = 50 * time.Millisecond
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	cache := logger.Package("cache")
+	for i := 0; i < 100; i++ {
+		cache.Count("miss")
+	}
+	cache.CountN("evicted", 7)
+	logger.Package("db").Count("retry")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logger.Stats().Written < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cache.Count("miss")
+	logger.Close()
+	cache.Count("miss") // Ignored once closed
+
+	if len(sink.entries) != 3 {
+		t.Fatalf("Expected 3 counter entries, got %d", len(sink.entries))
+	}
+	first := sink.entries[0]
+	if first.Package != "cache" || first.Message != "counters" || first.Level != INFO {
+		t.Errorf("Unexpected entry %s %s %q", first.Package, first.Level, first.Message)
+	}
+	if first.Fields["miss"] != uint64(100) || first.Fields["evicted"] != uint64(7) {
+		t.Errorf("Unexpected counts %v", first.Fields)
+	}
+	if ms, _ := first.Fields[FieldDuration].(float64); ms < 40 {
+		t.Errorf("Expected the interval as %s, got %v", FieldDuration, first.Fields)
+	}
+	if sink.entries[1].Package != "db" || sink.entries[1].Fields["retry"] != uint64(1) {
+		t.Errorf("Unexpected entry %s %v", sink.entries[1].Package, sink.entries[1].Fields)
+	}
+	if last := sink.entries[2]; last.Fields["miss"] != uint64(1) {
+		t.Errorf("Expected Close to log the remaining count, got %v", last.Fields)
+	}
+}
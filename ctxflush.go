@@ -0,0 +1,33 @@
+package log4
+
+import "context"
+
+// flushOnDone arranges for the queue to be written and flushed once ctx is
+// done, for Config.FlushOnContextDone. Contexts sharing a Done channel, such
+// as those derived with context.WithValue, are flushed once.
+func (cl *ChannelLogger) flushOnDone(ctx context.Context) {
+	done := ctx.Done()
+	if done == nil {
+		return // Never done
+	}
+	// Registered before AfterFunc, which may run the flush right away
+	if _, registered := cl.ctxFlushes.LoadOrStore(done, nil); registered {
+		return
+	}
+	stop := context.AfterFunc(ctx, func() {
+		cl.ctxFlushes.Delete(done)
+		cl.Flush() // Fails once the logger is closed
+	})
+	cl.ctxFlushes.CompareAndSwap(done, nil, stop) // Unless already run
+}
+
+// stopContextFlushes drops the flushes of contexts that are not done yet
+func (cl *ChannelLogger) stopContextFlushes() {
+	cl.ctxFlushes.Range(func(done, stop any) bool {
+		if stop, ok := stop.(func() bool); ok {
+			stop()
+		}
+		cl.ctxFlushes.Delete(done)
+		return true
+	})
+}
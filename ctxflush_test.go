@@ -0,0 +1,72 @@
+package log4
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// flushSignalSink reports each Flush on a channel
+type flushSignalSink struct {
+	flushes chan struct{}
+}
+
+func (s flushSignalSink) Write(*LogEntry, []byte) error { return nil }
+func (s flushSignalSink) Close() error                  { return nil }
+
+func (s flushSignalSink) Flush() error {
+	select {
+	case s.flushes <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+type flushTestKey struct{}
+
+func TestFlushOnContextDone(t *testing.T) {
+	sink := flushSignalSink{flushes: make(chan struct{}, 10)}
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.FlushOnContextDone = true
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	// Flushes of an idle queue
+	drain := func() int {
+		n := 0
+		for {
+			select {
+			case <-sink.flushes:
+				n++
+			case <-time.After(50 * time.Millisecond):
+				return n
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pl := logger.Package("api")
+	pl.LogWithContext(ctx, "INFO", "Handling request")
+	logger.LogWithContext(context.WithValue(ctx, flushTestKey{}, "derived"), "api", "INFO", "Same request")
+	logger.Flush()
+	drain()
+
+	cancel()
+	if n := drain(); n != 1 {
+		t.Errorf("Expected one flush when the request ended, got %d", n)
+	}
+
+	// Contexts that end after Close are not flushed
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	pl.LogWithContext(ctx, "INFO", "Unfinished request")
+	logger.Close()
+	n := 0
+	logger.ctxFlushes.Range(func(any, any) bool { n++; return true })
+	if n != 0 {
+		t.Errorf("Expected Close to drop pending flushes, got %d", n)
+	}
+}
@@ -0,0 +1,88 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Fields added to entries written to a dead-letter file
+const (
+	DeadLetterKeySink   = "dead_letter_sink"
+	DeadLetterKeyReason = "dead_letter_reason"
+	DeadLetterKeyTime   = "dead_letter_time"
+)
+
+// DeadLetter is a file receiving entries that a sink failed to accept, either
+// because Write returned an error or because a network sink gave up after its
+// retries. Entries are written as JSON lines carrying the sink and the
+// failure reason, and can be sent again with ReplayDeadLetter.
+//
+// One DeadLetter may be shared by the logger (Config.DeadLetter) and any
+// number of sinks (BatchOptions.DeadLetter); close it after the logger.
+type DeadLetter struct {
+	mu   sync.Mutex
+	file *os.File
+	buf  []byte
+	json JSONFormatter
+}
+
+// OpenDeadLetter opens or creates a dead-letter file for appending
+func OpenDeadLetter(path string) (*DeadLetter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, DefaultFileMode)
+	if err != nil {
+		return nil, fmt.Errorf(ErrOpenLogFile, path, err)
+	}
+	return &DeadLetter{file: f}, nil
+}
+
+// Write records an entry that sink failed to accept
+func (d *DeadLetter) Write(entry *LogEntry, sink string, reason error) error {
+	failed := entry.Clone()
+	for _, k := range failed.unshadowedLabels() {
+		failed.Fields[k] = failed.Labels[k] // Replayed as fields
+	}
+	failed.Fields[DeadLetterKeySink] = sink
+	failed.Fields[DeadLetterKeyReason] = reason.Error()
+	failed.Fields[DeadLetterKeyTime] = time.Now().Format(time.RFC3339Nano)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.buf = append(d.json.Format(d.buf[:0], failed), '\n')
+	_, err := d.file.Write(d.buf)
+	return err
+}
+
+// Close closes the file
+func (d *DeadLetter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
+
+// record writes entries to d if it is set, reporting write failures to onError
+func (d *DeadLetter) record(entries []*LogEntry, sink string, reason error, onError func(error)) {
+	if d == nil {
+		return
+	}
+	for _, entry := range entries {
+		if err := d.Write(entry, sink, reason); err != nil {
+			onError(fmt.Errorf("failed to write dead letter: %w", err))
+			return
+		}
+	}
+}
+
+// ReplayDeadLetter sends the entries of a dead-letter file to sink without
+// the dead-letter fields and returns how many were accepted. See Replay.
+func ReplayDeadLetter(path string, sink Sink) (int, error) {
+	return ReplayFile(path, sink, &ReplayOptions{
+		Transform: func(entry *LogEntry) bool {
+			delete(entry.Fields, DeadLetterKeySink)
+			delete(entry.Fields, DeadLetterKeyReason)
+			delete(entry.Fields, DeadLetterKeyTime)
+			return true
+		},
+	})
+}
@@ -0,0 +1,103 @@
+package log4
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingSink rejects every entry
+type failingSink struct{}
+
+func (failingSink) Write(entry *LogEntry, line []byte) error {
+	return errors.New("disk quota exceeded")
+}
+func (failingSink) Close() error { return nil }
+
+func TestDeadLetterAndReplay(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	path := filepath.Join(tempDir, "dead.jsonl")
+	dl, err := OpenDeadLetter(path)
+	if err != nil {
+		t.Fatalf("OpenDeadLetter failed: %v", err)
+	}
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{failingSink{}}
+	config.DeadLetter = dl
+	config.ErrorHandler = func(error) {}
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.LogWithFields("orders", ERROR, "Payment failed", map[string]interface{}{"order": "A-1"})
+	logger.Info("orders", "Order shipped")
+	logger.Close()
+	dl.Close()
+
+	content := readFile(t, path)
+	if countLines(content) != 2 || !strings.Contains(content, `"dead_letter_reason":"disk quota exceeded"`) ||
+		!strings.Contains(content, `"dead_letter_sink":"log4.failingSink"`) {
+		t.Fatalf("Unexpected dead-letter file:\n%s", content)
+	}
+
+	sink := &retainingSink{}
+	n, err := ReplayDeadLetter(path, sink)
+	if err != nil || n != 2 {
+		t.Fatalf("Replay returned %d, %v", n, err)
+	}
+	first := sink.entries[0]
+	if first.Message != "Payment failed" || first.Level != ERROR || first.Package != "orders" || first.Fields["order"] != "A-1" {
+		t.Errorf("Unexpected replayed entry %+v", first)
+	}
+	if _, ok := first.Fields[DeadLetterKeyReason]; ok {
+		t.Error("Replayed entry should not carry dead-letter fields")
+	}
+
+	if n, err := ReplayDeadLetter(path, failingSink{}); err == nil || n != 0 {
+		t.Errorf("Expected replay into a failing sink to stop, got %d, %v", n, err)
+	}
+}
+
+func TestDeadLetterNetworkSink(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(tempDir, "dead.jsonl")
+	dl, err := OpenDeadLetter(path)
+	if err != nil {
+		t.Fatalf("OpenDeadLetter failed: %v", err)
+	}
+	defer dl.Close()
+
+	sink, _ := NewOTLPSink(OTLPOptions{
+		Endpoint: server.URL,
+		BatchOptions: BatchOptions{
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+			DeadLetter:   dl,
+		},
+		ErrorHandler: func(error) {},
+	})
+	sink.Write(&LogEntry{Package: "otlp", Level: INFO, Message: "Never delivered", Timestamp: time.Now(),
+		Fields: map[string]interface{}{}}, nil)
+	sink.Close()
+
+	content := readFile(t, path)
+	if !strings.Contains(content, "Never delivered") || !strings.Contains(content, `"dead_letter_sink":"otlp"`) ||
+		!strings.Contains(content, "503") {
+		t.Errorf("Unexpected dead-letter file:\n%s", content)
+	}
+}
@@ -0,0 +1,264 @@
+package log4
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// minOpenFiles is the open file limit below which ValidateEnvironment warns;
+// every package, tenant and route keeps a file open
+const minOpenFiles = 1024
+
+// CheckStatus is the outcome of an environment check
+type CheckStatus int
+
+const (
+	CheckOK CheckStatus = iota
+	CheckWarn
+	CheckFail
+)
+
+func (s CheckStatus) String() string {
+	switch s {
+	case CheckOK:
+		return "ok"
+	case CheckWarn:
+		return "warn"
+	default:
+		return "fail"
+	}
+}
+
+// Check is one finding of ValidateEnvironment
+type Check struct {
+	Name   string // What was checked, e.g. "log dir"
+	Status CheckStatus
+	Detail string
+}
+
+// EnvironmentReport lists the findings of ValidateEnvironment in the order
+// they were checked
+type EnvironmentReport struct {
+	Checks []Check
+}
+
+// OK reports whether no check failed; warnings do not count
+func (r *EnvironmentReport) OK() bool {
+	return r.Err() == nil
+}
+
+// Err returns the failed checks joined with errors.Join, or nil
+func (r *EnvironmentReport) Err() error {
+	var errs []error
+	for _, c := range r.Checks {
+		if c.Status == CheckFail {
+			errs = append(errs, fmt.Errorf("%s: %s", c.Name, c.Detail))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// String returns one line per check:
+//
+//	ok    log dir: /var/log/myapp exists
+//	warn  disk space: 120.0MB free, less than one package's 100.0MB x 6 files
+func (r *EnvironmentReport) String() string {
+	var sb strings.Builder
+	for _, c := range r.Checks {
+		fmt.Fprintf(&sb, "%-5s %s: %s\n", c.Status, c.Name, c.Detail)
+	}
+	return sb.String()
+}
+
+func (r *EnvironmentReport) add(name string, status CheckStatus, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// ValidateEnvironment checks that a logger with config can write its files
+// before it is created: the configuration itself, that the log directory and
+// the directories of routes exist or can be created and are writable, the
+// modes files and directories are created with, existing files that could
+// not be reopened, free disk space and the open file limit where the platform
+// reports them, and whether the rotation settings agree with each other.
+// Config is not modified.
+func ValidateEnvironment(config *Config) *EnvironmentReport {
+	c := *config
+	r := &EnvironmentReport{}
+	if err := c.Validate(); err != nil {
+		r.add("config", CheckFail, "%v", err)
+		return r
+	}
+	r.add("config", CheckOK, "valid")
+	if c.DryRun {
+		r.add("log dir", CheckOK, "not written in DryRun mode")
+		return r
+	}
+
+	if c.FileMode&0o200 == 0 {
+		r.add("file mode", CheckFail, "%v lacks owner write permission; files could not be reopened", c.FileMode)
+	}
+	if c.DirMode&0o300 != 0o300 {
+		r.add("dir mode", CheckFail, "%v lacks owner write or search permission", c.DirMode)
+	}
+
+	dirs := []string{c.LogDir}
+	for _, route := range c.Routes {
+		if route.Dir != "" {
+			dirs = append(dirs, route.Dir)
+		}
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+		if checkDir(r, dir) {
+			checkFiles(r, dir)
+		}
+	}
+
+	if free, ok := diskFree(existingParent(c.LogDir)); ok {
+		if need := c.MaxFileSize * int64(c.MaxFiles+1); free < need {
+			r.add("disk space", CheckWarn, "%s free, less than one package's %s x %d files",
+				humanSize(free), humanSize(c.MaxFileSize), c.MaxFiles+1)
+		} else {
+			r.add("disk space", CheckOK, "%s free", humanSize(free))
+		}
+	}
+	if limit, ok := openFileLimit(); ok {
+		if limit < minOpenFiles {
+			r.add("open files", CheckWarn, "limit of %d; every package, tenant and route keeps a file open", limit)
+		} else {
+			r.add("open files", CheckOK, "limit of %d", limit)
+		}
+	}
+
+	checkRotation(r, &c)
+	return r
+}
+
+// checkDir checks that dir exists and is writable, or that its nearest
+// existing parent is, reporting whether dir exists
+func checkDir(r *EnvironmentReport, dir string) bool {
+	name := "log dir " + dir
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		parent := existingParent(dir)
+		if err := probeWrite(parent); err != nil {
+			r.add(name, CheckFail, "does not exist and cannot be created in %s: %v", parent, err)
+		} else {
+			r.add(name, CheckOK, "does not exist, will be created")
+		}
+		return false
+	case err != nil:
+		r.add(name, CheckFail, "%v", err)
+		return false
+	case !info.IsDir():
+		r.add(name, CheckFail, "is not a directory")
+		return false
+	}
+	if err := probeWrite(dir); err != nil {
+		r.add(name, CheckFail, "not writable: %v", err)
+		return true
+	}
+	r.add(name, CheckOK, "exists and is writable")
+	return true
+}
+
+// checkFiles reports log files in dir the logger could not append to
+func checkFiles(r *EnvironmentReport, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".log") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			r.add("log file "+path, CheckFail, "cannot be appended to: %v", err)
+			continue
+		}
+		f.Close()
+	}
+}
+
+// checkRotation reports rotation settings that contradict each other or
+// leave files beyond MaxFiles
+func checkRotation(r *EnvironmentReport, c *Config) {
+	issues := 0
+	warn := func(format string, args ...interface{}) {
+		r.add("rotation", CheckWarn, format, args...)
+		issues++
+	}
+	if c.MaxFileSize < 64<<10 {
+		warn("MaxFileSize of %d bytes rotates after a few hundred entries", c.MaxFileSize)
+	}
+	if c.AppendOnly && c.RotateOnStart {
+		warn("RotateOnStart has no effect with AppendOnly, which never rotates")
+	}
+	if c.AppendOnly && c.Manifest {
+		warn("Manifest is never written with AppendOnly, which never rotates")
+	}
+	entries, _ := os.ReadDir(cmp.Or(c.LogDir, "."))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".log") {
+			continue
+		}
+		rotated, err := c.rotationNamer().Rotated(filepath.Join(c.LogDir, e.Name()))
+		if err == nil && len(rotated) > c.MaxFiles {
+			warn("%s has %d rotated files, more than MaxFiles; the oldest are removed at its next rotation", e.Name(), len(rotated))
+		}
+	}
+	if issues == 0 {
+		r.add("rotation", CheckOK, "%s per file, %d rotated files kept", humanSize(c.MaxFileSize), c.MaxFiles)
+	}
+}
+
+// existingParent returns dir or its nearest ancestor that exists
+func existingParent(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// probeWrite creates and removes a temporary file in dir
+func probeWrite(dir string) error {
+	f, err := os.CreateTemp(dir, ".log4-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// humanSize formats n with a binary unit, e.g. "1.5GB"
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || linux)
+
+package log4
+
+// diskFree is not reported on this platform
+func diskFree(dir string) (int64, bool) {
+	return 0, false
+}
+
+// openFileLimit is not reported on this platform
+func openFileLimit() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,68 @@
+package log4
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateEnvironment(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = filepath.Join(tempDir, "logs")
+	report := ValidateEnvironment(config)
+	if !report.OK() {
+		t.Fatalf("Expected a missing but creatable directory to pass, got:\n%s", report)
+	}
+	if !strings.Contains(report.String(), "will be created") {
+		t.Errorf("Expected the directory to be reported as created later, got:\n%s", report)
+	}
+	if _, err := os.Stat(config.LogDir); !os.IsNotExist(err) {
+		t.Error("Expected the check not to create the directory")
+	}
+
+	// A file in the way of the directory and contradicting settings
+	if err := os.WriteFile(config.LogDir, nil, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	config.AppendOnly = true
+	config.Manifest = true
+	report = ValidateEnvironment(config)
+	if report.OK() || !strings.Contains(report.Err().Error(), "is not a directory") {
+		t.Errorf("Expected the file to fail the check, got:\n%s", report)
+	}
+	if !strings.Contains(report.String(), "warn  rotation: Manifest is never written") {
+		t.Errorf("Expected a rotation warning, got:\n%s", report)
+	}
+
+	config = DefaultConfig()
+	config.BufferSize = -1
+	if report := ValidateEnvironment(config); report.OK() || len(report.Checks) != 1 {
+		t.Errorf("Expected an invalid config to stop the checks, got:\n%s", report)
+	}
+}
+
+func TestValidateEnvironmentRotatedFiles(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	for _, name := range []string{"app.log", "app.log.1", "app.log.2", "app.log.3"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MaxFiles = 2
+	config.MaxFileSize = 0 // Defaulted by Validate
+	report := ValidateEnvironment(config)
+	if !report.OK() || !strings.Contains(report.String(), "app.log has 3 rotated files") {
+		t.Errorf("Expected a warning about rotated files beyond MaxFiles, got:\n%s", report)
+	}
+	if config.MaxFileSize != 0 {
+		t.Error("Expected the config not to be modified")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build darwin || linux
+
+package log4
+
+import "syscall"
+
+// diskFree returns the bytes available to unprivileged users on the file
+// system holding dir
+func diskFree(dir string) (int64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return int64(st.Bavail) * int64(st.Bsize), true
+}
+
+// openFileLimit returns the soft limit on open files of the process
+func openFileLimit() (uint64, bool) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, false
+	}
+	return rl.Cur, true
+}
@@ -0,0 +1,55 @@
+package log4
+
+// DropReason describes why an entry was discarded instead of written
+type DropReason int
+
+const (
+	// DropOverflow means the queue stayed full for longer than the caller waits
+	DropOverflow DropReason = iota
+	// DropContextDone means the caller's context ended while waiting to queue
+	DropContextDone
+	// DropClosed means the entry was logged after Close
+	DropClosed
+	// DropQuota means the entry's tenant exceeded its disk quota
+	DropQuota
+	// DropSampled means the entry was discarded by Config.Sampling
+	DropSampled
+	// DropThrottled means the entry was discarded while Config.Throttle was
+	// in effect
+	DropThrottled
+	// DropStale means the entry waited in the queue for longer than
+	// Config.MaxEntryAge
+	DropStale
+	// DropPanic means a Formatter or Sink panicked while writing the entry,
+	// or had panicked too often for it to be written (see
+	// Config.MaxWorkerPanics)
+	DropPanic
+	// DropCancelled means the entry's context was already done when it was
+	// logged and Config.LogOnCancelledContext is false
+	DropCancelled
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case DropOverflow:
+		return "overflow"
+	case DropContextDone:
+		return "context_done"
+	case DropClosed:
+		return "closed"
+	case DropQuota:
+		return "quota"
+	case DropSampled:
+		return "sampled"
+	case DropThrottled:
+		return "throttled"
+	case DropStale:
+		return "stale"
+	case DropPanic:
+		return "panic"
+	case DropCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,110 @@
+package log4
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// dropSummaries accumulates the entries dropped per package between reports
+// of Config.DropSummaries
+type dropSummaries struct {
+	mu    sync.Mutex
+	drops map[counterKey]*dropSummary
+	start sync.Once // Starts the reporting goroutine on first use
+}
+
+// dropSummary counts the entries of one package dropped since the last report
+type dropSummary struct {
+	reasons     map[DropReason]uint64
+	total       uint64
+	first, last time.Time
+}
+
+// summarizeDrop counts a dropped entry for the next summary of its package.
+// Entries dropped on Close are not summarized, nor are those of tenants over
+// their quota, whose files take no more entries.
+func (cl *ChannelLogger) summarizeDrop(entry *LogEntry, reason DropReason) {
+	if reason == DropClosed || reason == DropQuota {
+		return
+	}
+	d := &cl.dropSums
+	d.start.Do(cl.reportDrops)
+
+	key := counterKey{entry.Tenant, entry.Package}
+	now := time.Now()
+	d.mu.Lock()
+	if d.drops == nil {
+		d.drops = make(map[counterKey]*dropSummary)
+	}
+	s := d.drops[key]
+	if s == nil {
+		s = &dropSummary{reasons: make(map[DropReason]uint64), first: now}
+		d.drops[key] = s
+	}
+	s.reasons[reason]++
+	s.total++
+	s.last = now
+	d.mu.Unlock()
+}
+
+// reportDrops starts the goroutine logging drop summaries every interval
+// until the logger is closed
+func (cl *ChannelLogger) reportDrops() {
+	go func() {
+		ticker := time.NewTicker(cl.config.DropSummaries)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cl.flushDrops()
+			case <-cl.done:
+				return
+			}
+		}
+	}()
+}
+
+// flushDrops writes a summary entry to the file of every package that lost
+// entries since the last report, bypassing levels, sampling and throttling:
+//
+//	INFO: dropped 523 entries between 2024-05-01 12:00:01 and 2024-05-01 12:00:09 | dropped=523, dropped_overflow=500, dropped_stale=23
+func (cl *ChannelLogger) flushDrops() {
+	d := &cl.dropSums
+	d.mu.Lock()
+	drops := d.drops
+	d.drops = nil
+	d.mu.Unlock()
+
+	keys := make([]counterKey, 0, len(drops))
+	for key := range drops {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b counterKey) int {
+		return cmp.Or(cmp.Compare(a.tenant, b.tenant), cmp.Compare(a.pkg, b.pkg))
+	})
+
+	cl.sendMu.RLock()
+	defer cl.sendMu.RUnlock()
+	for _, key := range keys {
+		s := drops[key]
+		entry := getLogEntry()
+		entry.Tenant = key.tenant
+		entry.Package = key.pkg
+		entry.Level = INFO
+		entry.Message = fmt.Sprintf("dropped %d entries between %s and %s",
+			s.total, s.first.Format(cl.config.TimestampFormat), s.last.Format(cl.config.TimestampFormat))
+		entry.Timestamp = time.Now()
+		entry.Fields["dropped"] = s.total
+		for reason, n := range s.reasons {
+			entry.Fields["dropped_"+reason.String()] = n
+		}
+		if cl.closed.Load() {
+			entry.Release()
+			continue
+		}
+		cl.enqueue(entry)
+	}
+}
@@ -0,0 +1,44 @@
+package log4
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDropSummaries(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.DropSummaries = time.Hour // Only the summary written by Close
+	config.Sampling = &Sampling{Initial: 2, Thereafter: 0, Tick: time.Hour}
+	logger := NewChannelLoggerWithConfig(config)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("app", "repeated")
+	}
+	logger.Info("db", "once")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "app.log"))
+	if countLines(content) != 3 {
+		t.Fatalf("Expected 2 entries and a summary, got %q", content)
+	}
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	entry, err := ParseLine(lines[2], config.TimestampFormat)
+	if err != nil {
+		t.Fatalf("Failed to parse summary: %v", err)
+	}
+	if !strings.HasPrefix(entry.Message, "dropped 8 entries between ") ||
+		entry.Fields["dropped"] != "8" || entry.Fields["dropped_sampled"] != "8" {
+		t.Errorf("Unexpected summary %q %v", entry.Message, entry.Fields)
+	}
+
+	if content := readFile(t, filepath.Join(tempDir, "db.log")); strings.Contains(content, "dropped") {
+		t.Errorf("Expected no summary for a package without drops, got %q", content)
+	}
+}
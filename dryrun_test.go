@@ -0,0 +1,33 @@
+package log4
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var console bytes.Buffer
+	sink := &bufferSink{}
+	logDir := filepath.Join(tempDir, "logs")
+	logger := NewLogger(WithDir(logDir), WithDryRun(), WithSink(sink), WithConsoleWriter(&console))
+
+	logger.Info("app", "first")
+	logger.Error("db", "second")
+	logger.Rotate()
+	logger.Close()
+
+	if _, err := os.Stat(logDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no log directory in dry-run mode, got %v", err)
+	}
+	if countLines(sink.buf.String()) != 2 || console.String() != sink.buf.String() {
+		t.Errorf("Expected the console and sinks to receive both entries, got %q and %q", console.String(), sink.buf.String())
+	}
+	if stats := logger.Stats(); stats.Written != 2 || stats.OpenFiles != 0 {
+		t.Errorf("Expected 2 entries written and no open files, got %+v", stats)
+	}
+}
@@ -0,0 +1,64 @@
+package log4
+
+import (
+	"sync/atomic"
+)
+
+// Entry lifecycle
+//
+// Entries handed to sinks, OnDrop and other callbacks belong to an internal
+// pool and are reset and reused as soon as the callback returns. Code that
+// needs an entry afterwards, for example a sink that writes asynchronously,
+// either calls Clone to take an independent copy or calls Retain and later
+// Release to keep the pooled entry alive. A retained entry must not be
+// modified; it is shared with the logger until every reference is released.
+
+// Clone returns a deep copy of the entry that is not owned by the pool and may
+// be kept and modified freely. Field values are copied shallowly.
+func (e *LogEntry) Clone() *LogEntry {
+	clone := &LogEntry{
+		Tenant:    e.Tenant,
+		Package:   e.Package,
+		Level:     e.Level,
+		Message:   e.Message,
+		Fields:    make(map[string]interface{}, len(e.Fields)),
+		Context:   e.Context,
+		Timestamp: e.Timestamp,
+	}
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	for k, v := range e.Labels {
+		clone.SetLabel(k, v)
+	}
+	return clone
+}
+
+// Retain keeps a pooled entry from being reused until a matching Release.
+// It is a no-op for entries that do not come from the pool and panics if the
+// entry has already been returned to the pool.
+func (e *LogEntry) Retain() {
+	switch n := atomic.LoadInt32(&e.refs); {
+	case n == 0:
+		return
+	case n < 0:
+		panic(ErrEntryReleased)
+	}
+	atomic.AddInt32(&e.refs, 1)
+}
+
+// Release drops a reference taken by Retain and returns the entry to the pool
+// once the last reference is gone. Releasing more often than retaining
+// panics, since the entry may already be in use by another log call. It is a
+// no-op for entries that do not come from the pool.
+func (e *LogEntry) Release() {
+	if atomic.LoadInt32(&e.refs) == 0 {
+		return
+	}
+	switch n := atomic.AddInt32(&e.refs, -1); {
+	case n == 0:
+		putLogEntry(e)
+	case n < 0:
+		panic(ErrEntryReleased)
+	}
+}
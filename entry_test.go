@@ -0,0 +1,85 @@
+package log4
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// retainingSink keeps every entry and reads it after Write has returned
+type retainingSink struct {
+	mu      sync.Mutex
+	entries []*LogEntry
+}
+
+func (s *retainingSink) Write(entry *LogEntry, line []byte) error {
+	entry.Retain()
+	s.mu.Lock()
+	s.entries = append(s.entries, entry)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *retainingSink) Close() error { return nil }
+
+func TestSinkRetainsEntries(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	for i := 0; i < 50; i++ {
+		logger.LogWithFields("retain", INFO, "Retained message", map[string]interface{}{"n": i})
+	}
+	logger.Close()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != 50 {
+		t.Fatalf("Expected 50 retained entries, got %d", len(sink.entries))
+	}
+	for i, entry := range sink.entries {
+		if entry.Message != "Retained message" || entry.Fields["n"] != i {
+			t.Errorf("Retained entry %d was reused: %+v", i, entry)
+		}
+		entry.Release()
+	}
+}
+
+func TestEntryClone(t *testing.T) {
+	entry := getLogEntry()
+	entry.Package = "clone"
+	entry.Message = "Original"
+	entry.Fields["key"] = "value"
+
+	clone := entry.Clone()
+	entry.Release()
+
+	if clone.Package != "clone" || clone.Message != "Original" || clone.Fields["key"] != "value" {
+		t.Errorf("Clone lost data after release: %+v", clone)
+	}
+
+	// Clones are not pooled, so releasing them does nothing
+	clone.Retain()
+	clone.Release()
+	clone.Release()
+}
+
+func TestEntryReleaseTwicePanics(t *testing.T) {
+	entry := getLogEntry()
+	entry.Release()
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), "Release") {
+			t.Errorf("Expected panic releasing twice, got %v", r)
+		}
+	}()
+	entry.Release()
+}
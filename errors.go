@@ -0,0 +1,202 @@
+package log4
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ErrChannelFull is reported to the error handler when an entry is dropped
+// because the log channel stayed full. Match it with errors.As, or with
+// errors.Is against any *ErrChannelFull.
+type ErrChannelFull struct {
+	Pkg     string // Package of the dropped entry
+	Message string // Message of the dropped entry
+	Dropped uint64 // Entries dropped so far, including this one
+	Err     error  // Context error if the caller's context ended while waiting
+}
+
+func (e *ErrChannelFull) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("log channel full until context done (%v), dropping message of package %s: %s", e.Err, e.Pkg, e.Message)
+	}
+	return fmt.Sprintf("log channel full, dropping message of package %s: %s", e.Pkg, e.Message)
+}
+
+// Unwrap returns the context error, if any
+func (e *ErrChannelFull) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is also an *ErrChannelFull
+func (e *ErrChannelFull) Is(target error) bool {
+	_, ok := target.(*ErrChannelFull)
+	return ok
+}
+
+// ErrRotation is reported to the error handler when a log file cannot be
+// rotated
+type ErrRotation struct {
+	Pkg  string // Package of the file, "tenant/package" for tenant files
+	Path string // Path of the active file
+	Err  error
+}
+
+func (e *ErrRotation) Error() string {
+	return fmt.Sprintf("failed to rotate log file %s for package %s: %v", e.Path, e.Pkg, e.Err)
+}
+
+// Unwrap returns the underlying file system error
+func (e *ErrRotation) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is also an *ErrRotation
+func (e *ErrRotation) Is(target error) bool {
+	_, ok := target.(*ErrRotation)
+	return ok
+}
+
+// ErrSinkWrite is reported to the error handler when a sink fails to write an
+// entry
+type ErrSinkWrite struct {
+	Sink Sink
+	Pkg  string // Package of the entry
+	Err  error
+}
+
+func (e *ErrSinkWrite) Error() string {
+	return fmt.Sprintf("sink %T write failed for package %s: %v", e.Sink, e.Pkg, e.Err)
+}
+
+// Unwrap returns the error of the sink
+func (e *ErrSinkWrite) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is also an *ErrSinkWrite
+func (e *ErrSinkWrite) Is(target error) bool {
+	_, ok := target.(*ErrSinkWrite)
+	return ok
+}
+
+// ErrManifestMismatch is reported by VerifyManifest for a rotated file that
+// differs from its manifest entry, or for a manifest whose hash chain is
+// broken
+type ErrManifestMismatch struct {
+	Path   string // Rotated file, or the manifest
+	Reason string // How it differs
+}
+
+func (e *ErrManifestMismatch) Error() string {
+	if filepath.Base(e.Path) == ManifestFile {
+		return fmt.Sprintf("manifest %s was altered: %s", e.Path, e.Reason)
+	}
+	return fmt.Sprintf("log file %s does not match the manifest: %s", e.Path, e.Reason)
+}
+
+// Is reports whether target is also an *ErrManifestMismatch
+func (e *ErrManifestMismatch) Is(target error) bool {
+	_, ok := target.(*ErrManifestMismatch)
+	return ok
+}
+
+// ErrSecretField is reported to the error handler, once per package and
+// field, when Config.SecretFields finds a field whose name looks like a secret
+// but whose value is not wrapped in Secret
+type ErrSecretField struct {
+	Pkg    string // Package of the entry
+	Field  string // Name of the field
+	Masked bool   // Whether the value was written as SecretMask
+}
+
+func (e *ErrSecretField) Error() string {
+	if e.Masked {
+		return fmt.Sprintf("field %s of package %s looks like a secret and was masked; wrap it in log4.Secret", e.Field, e.Pkg)
+	}
+	return fmt.Sprintf("field %s of package %s looks like a secret; wrap it in log4.Secret", e.Field, e.Pkg)
+}
+
+// Is reports whether target is also an *ErrSecretField
+func (e *ErrSecretField) Is(target error) bool {
+	_, ok := target.(*ErrSecretField)
+	return ok
+}
+
+// ErrSchemaViolation is reported to the error handler when the fields of an
+// entry do not match its FieldSchema, once for each field and problem
+type ErrSchemaViolation struct {
+	Pkg     string // Package of the entry
+	Schema  string // Key of the schema in Config.FieldSchemas
+	Field   string // Name of the field
+	Problem string // e.g. "is missing" or "is string, not int"
+}
+
+func (e *ErrSchemaViolation) Error() string {
+	return fmt.Sprintf("field %s of package %s %s (schema %s)", e.Field, e.Pkg, e.Problem, e.Schema)
+}
+
+// Is reports whether target is also an *ErrSchemaViolation
+func (e *ErrSchemaViolation) Is(target error) bool {
+	_, ok := target.(*ErrSchemaViolation)
+	return ok
+}
+
+// ErrDropped is returned by MustLog and the Try methods when their entry was
+// dropped instead of written. Match it with errors.As, or with errors.Is against any *ErrDropped.
+type ErrDropped struct {
+	Pkg     string     // Package of the dropped entry
+	Message string     // Message of the dropped entry
+	Reason  DropReason // Why it was dropped
+	Err     error      // Context error with DropContextDone
+}
+
+func (e *ErrDropped) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("message of package %s dropped (%s: %v): %s", e.Pkg, e.Reason, e.Err, e.Message)
+	}
+	return fmt.Sprintf("message of package %s dropped (%s): %s", e.Pkg, e.Reason, e.Message)
+}
+
+// Unwrap returns the context error, if any
+func (e *ErrDropped) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is also an *ErrDropped
+func (e *ErrDropped) Is(target error) bool {
+	_, ok := target.(*ErrDropped)
+	return ok
+}
+
+// ErrWorkerPanic is reported to the error handler when the logging goroutine
+// recovers from a panic, typically of a Formatter or Sink
+type ErrWorkerPanic struct {
+	Pkg     string        // Package of the entry being written, if any
+	Value   interface{}   // Value passed to panic
+	Stack   []byte        // Stack trace of the panic
+	Breaker time.Duration // How long entries are dropped if this panic opened the breaker
+}
+
+func (e *ErrWorkerPanic) Error() string {
+	msg := fmt.Sprintf("logging goroutine recovered from panic: %v", e.Value)
+	if e.Pkg != "" {
+		msg = fmt.Sprintf("logging goroutine recovered from panic writing package %s: %v", e.Pkg, e.Value)
+	}
+	if e.Breaker > 0 {
+		msg += fmt.Sprintf("; dropping entries for %s after repeated panics", e.Breaker)
+	}
+	return msg
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *ErrWorkerPanic) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Is reports whether target is also an *ErrWorkerPanic
+func (e *ErrWorkerPanic) Is(target error) bool {
+	_, ok := target.(*ErrWorkerPanic)
+	return ok
+}
@@ -0,0 +1,123 @@
+package log4
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTypedErrors(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var errs []error
+	var mu sync.Mutex
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.BufferSize = 1
+	config.MaxFiles = 1
+	config.Sinks = []Sink{failingSink{}}
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	for i := 0; i < 10; i++ {
+		logger.Info("api", "Flood")
+	}
+	for i := 0; i < 100 && !fileExists(filepath.Join(tempDir, "api.log")); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	// A directory in the way of api.log.1 makes rotation fail
+	os.MkdirAll(filepath.Join(tempDir, "api.log.1", "blocker"), 0755)
+	logger.Rotate()
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var full *ErrChannelFull
+	var rotation *ErrRotation
+	var sinkWrite *ErrSinkWrite
+	for _, err := range errs {
+		switch {
+		case errors.As(err, &full):
+			if full.Pkg != "api" || full.Dropped == 0 || full.Message != "Flood" {
+				t.Errorf("Unexpected channel full error %+v", full)
+			}
+		case errors.As(err, &rotation):
+			if rotation.Pkg != "api" || rotation.Path != filepath.Join(tempDir, "api.log") || rotation.Err == nil {
+				t.Errorf("Unexpected rotation error %+v", rotation)
+			}
+		case errors.As(err, &sinkWrite):
+			if _, ok := sinkWrite.Sink.(failingSink); !ok || sinkWrite.Pkg != "api" {
+				t.Errorf("Unexpected sink write error %+v", sinkWrite)
+			}
+			if !errors.Is(err, &ErrSinkWrite{}) || errors.Is(err, &ErrRotation{}) {
+				t.Error("errors.Is should match on the error kind")
+			}
+		}
+	}
+	if full == nil || rotation == nil || sinkWrite == nil {
+		t.Errorf("Expected all three error kinds, got %v", errs)
+	}
+}
+
+func TestChannelFullUnwrap(t *testing.T) {
+	err := error(&ErrChannelFull{Pkg: "api", Message: "Flood", Dropped: 3, Err: os.ErrDeadlineExceeded})
+	if !errors.Is(err, os.ErrDeadlineExceeded) || !errors.Is(err, &ErrChannelFull{}) {
+		t.Errorf("Expected %v to match its context error and kind", err)
+	}
+}
+
+func TestErrorOverflow(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var handled int
+	overflow, err := os.Create(filepath.Join(tempDir, "errors.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer overflow.Close()
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.ErrorBufferSize = 2
+	config.ErrorOverflow = overflow
+	config.ErrorHandler = func(err error) {
+		if handled == 0 {
+			entered <- struct{}{}
+			<-release
+		}
+		handled++
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.handleError(errors.New("first"))
+	<-entered // The handler holds the first error, the queue is empty
+	for i := 0; i < 5; i++ {
+		logger.handleError(errors.New("queued or overflowed"))
+	}
+	stats := logger.Stats()
+	close(release)
+	logger.Close()
+
+	if stats.Errors != 6 || stats.ErrorOverflow != 3 {
+		t.Errorf("Expected 6 errors, 3 of them overflowed, got %+v", stats)
+	}
+	if handled != 3 {
+		t.Errorf("Expected the handler to receive 3 errors, got %d", handled)
+	}
+	if lines := countLines(readFile(t, overflow.Name())); lines != 3 {
+		t.Errorf("Expected 3 errors in the overflow file, got %d", lines)
+	}
+}
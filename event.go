@@ -0,0 +1,51 @@
+package log4
+
+import "time"
+
+// FieldEvent is the field holding the type of an entry logged by Emit
+const FieldEvent = "event"
+
+// Event is an entry with a fixed set of fields, such as the HTTP requests and
+// database queries of package events, so that every service logs the same
+// occurrence under the same field names. Emit logs it.
+type Event interface {
+	// EventType names the kind of event, e.g. "http.request", and is
+	// written as FieldEvent
+	EventType() string
+	Level() LogLevel
+	Message() string
+	// AddFields sets the fields of the event in fields
+	AddFields(fields map[string]interface{})
+}
+
+// Emit logs event for pkg with its level, message and fields, and its type
+// as FieldEvent
+func (cl *ChannelLogger) Emit(pkg string, event Event) {
+	entry := getLogEntry()
+	entry.Package = pkg
+	fillEvent(entry, event)
+	cl.logEntry(entry)
+}
+
+// Emit logs event for this package with its tenant and bound fields; see
+// ChannelLogger.Emit. The fields of the event take precedence.
+func (pl *PackageLogger) Emit(event Event) {
+	entry := getLogEntry()
+	entry.Tenant = pl.tenant
+	entry.Package = pl.pkg
+	for k, v := range pl.fields {
+		entry.Fields[k] = v
+	}
+	pl.addLabels(entry)
+	fillEvent(entry, event)
+	pl.logger.logEntry(entry)
+}
+
+// fillEvent sets the level, message, time and fields of entry from event
+func fillEvent(entry *LogEntry, event Event) {
+	entry.Level = event.Level()
+	entry.Message = event.Message()
+	entry.Timestamp = time.Now()
+	event.AddFields(entry.Fields)
+	entry.Fields[FieldEvent] = event.EventType()
+}
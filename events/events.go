@@ -0,0 +1,323 @@
+// Package events defines typed log4 events for common occurrences, so that
+// every service logs an HTTP request, a database query, a cache operation or
+// a job run under the same field names instead of inventing its own.
+//
+// Example usage:
+//
+//	start := time.Now()
+//	res, err := db.ExecContext(ctx, query, id)
+//	n, _ := res.RowsAffected()
+//	dbLogger.Emit(events.Query("postgresql", query, n, time.Since(start), err))
+//	// INFO: DELETE | db_operation=DELETE, db_rows=1, db_statement=DELETE FROM sessions WHERE id = $1, db_system=postgresql, duration_ms=1.204, event=db.query
+//
+// Events set log4.FieldEvent to their type and report failures at error
+// level with the error as log4.FieldError. Durations are written as
+// log4.FieldDuration. HTTPRequest uses the fields of log4.CLFFormatter, so
+// that requests can be written as access logs.
+package events
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MhunterDev/log4"
+)
+
+// Event types, the values of log4.FieldEvent
+const (
+	TypeHTTPRequest = "http.request"
+	TypeDBQuery     = "db.query"
+	TypeCacheOp     = "cache.op"
+	TypeJobRun      = "job.run"
+)
+
+// Fields of the events beyond those defined by log4
+const (
+	FieldDBSystem    = "db_system"    // e.g. "postgresql"
+	FieldDBOperation = "db_operation" // First keyword of the statement, e.g. "SELECT"
+	FieldDBTable     = "db_table"
+	FieldDBStatement = "db_statement"
+	FieldDBRows      = "db_rows" // Rows returned or affected
+
+	FieldCache      = "cache"    // Name of the cache, e.g. "sessions"
+	FieldCacheOp    = "cache_op" // CacheGet, CacheSet or CacheDelete
+	FieldCacheKey   = "cache_key"
+	FieldCacheHit   = "cache_hit" // Whether a get found the key
+	FieldJob        = "job"       // Name of the job, e.g. "nightly-report"
+	FieldJobID      = "job_id"
+	FieldJobAttempt = "job_attempt" // Attempt of the run, from 1
+	FieldJobStatus  = "job_status"  // JobSucceeded or JobFailed
+)
+
+// Operations of CacheOp
+const (
+	CacheGet    = "get"
+	CacheSet    = "set"
+	CacheDelete = "delete"
+)
+
+// Statuses of JobRun
+const (
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// durationMillis converts d to the value of log4.FieldDuration
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// HTTPRequest is a request served, logged at error level for 5xx responses
+type HTTPRequest struct {
+	Method     string
+	Path       string // Request URI, e.g. "/index.html?page=2"
+	Proto      string
+	Status     int
+	Bytes      int64 // Response body size
+	Duration   time.Duration
+	RemoteAddr string
+	User       string
+	Referer    string
+	UserAgent  string
+}
+
+// Request returns the HTTPRequest of r answered with status and a body of
+// bytes after d
+func Request(r *http.Request, status int, bytes int64, d time.Duration) HTTPRequest {
+	e := HTTPRequest{
+		Method:     r.Method,
+		Path:       r.RequestURI,
+		Proto:      r.Proto,
+		Status:     status,
+		Bytes:      bytes,
+		Duration:   d,
+		RemoteAddr: r.RemoteAddr,
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+	}
+	if e.Path == "" && r.URL != nil {
+		e.Path = r.URL.RequestURI() // Client requests have no RequestURI
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		e.User = user
+	}
+	return e
+}
+
+// EventType returns TypeHTTPRequest
+func (e HTTPRequest) EventType() string { return TypeHTTPRequest }
+
+// Level returns ERROR for server errors and INFO otherwise
+func (e HTTPRequest) Level() log4.LogLevel {
+	if e.Status >= 500 {
+		return log4.ERROR
+	}
+	return log4.INFO
+}
+
+// Message returns the method, path and status, e.g. "GET /users 200"
+func (e HTTPRequest) Message() string {
+	return e.Method + " " + e.Path + " " + strconv.Itoa(e.Status)
+}
+
+// AddFields sets the fields of the request; empty strings are left out
+func (e HTTPRequest) AddFields(fields map[string]interface{}) {
+	fields[log4.FieldMethod] = e.Method
+	fields[log4.FieldPath] = e.Path
+	fields[log4.FieldStatus] = e.Status
+	fields[log4.FieldBytes] = e.Bytes
+	fields[log4.FieldDuration] = durationMillis(e.Duration)
+	setString(fields, log4.FieldProto, e.Proto)
+	setString(fields, log4.FieldRemoteAddr, e.RemoteAddr)
+	setString(fields, log4.FieldUser, e.User)
+	setString(fields, log4.FieldReferer, e.Referer)
+	setString(fields, log4.FieldUserAgent, e.UserAgent)
+}
+
+// DBQuery is a database statement executed
+type DBQuery struct {
+	System    string // e.g. "postgresql"
+	Operation string // e.g. "SELECT"
+	Table     string
+	Statement string
+	Rows      int64
+	Duration  time.Duration
+	Err       error
+}
+
+// Query returns the DBQuery of statement run on system, with the operation
+// taken from its first keyword
+func Query(system, statement string, rows int64, d time.Duration, err error) DBQuery {
+	op, _, _ := strings.Cut(strings.TrimSpace(statement), " ")
+	return DBQuery{
+		System:    system,
+		Operation: strings.ToUpper(op),
+		Statement: statement,
+		Rows:      rows,
+		Duration:  d,
+		Err:       err,
+	}
+}
+
+// EventType returns TypeDBQuery
+func (e DBQuery) EventType() string { return TypeDBQuery }
+
+// Level returns ERROR if the query failed and INFO otherwise
+func (e DBQuery) Level() log4.LogLevel { return levelOf(e.Err) }
+
+// Message returns the operation and the table, e.g. "SELECT users", with
+// "failed" appended if the query failed
+func (e DBQuery) Message() string {
+	msg := strings.TrimSpace(e.Operation + " " + e.Table)
+	if msg == "" {
+		msg = "query"
+	}
+	return withFailure(msg, e.Err)
+}
+
+// AddFields sets the fields of the query; empty strings are left out
+func (e DBQuery) AddFields(fields map[string]interface{}) {
+	setString(fields, FieldDBSystem, e.System)
+	setString(fields, FieldDBOperation, e.Operation)
+	setString(fields, FieldDBTable, e.Table)
+	setString(fields, FieldDBStatement, e.Statement)
+	fields[FieldDBRows] = e.Rows
+	fields[log4.FieldDuration] = durationMillis(e.Duration)
+	setError(fields, e.Err)
+}
+
+// CacheOp is an operation on a cache, logged at debug level unless it failed
+type CacheOp struct {
+	Cache     string
+	Operation string // CacheGet, CacheSet or CacheDelete
+	Key       string
+	Hit       bool // For CacheGet
+	Duration  time.Duration
+	Err       error
+}
+
+// Get returns the CacheOp of looking key up in cache
+func Get(cache, key string, hit bool, d time.Duration, err error) CacheOp {
+	return CacheOp{Cache: cache, Operation: CacheGet, Key: key, Hit: hit, Duration: d, Err: err}
+}
+
+// Set returns the CacheOp of storing key in cache
+func Set(cache, key string, d time.Duration, err error) CacheOp {
+	return CacheOp{Cache: cache, Operation: CacheSet, Key: key, Duration: d, Err: err}
+}
+
+// Delete returns the CacheOp of removing key from cache
+func Delete(cache, key string, d time.Duration, err error) CacheOp {
+	return CacheOp{Cache: cache, Operation: CacheDelete, Key: key, Duration: d, Err: err}
+}
+
+// EventType returns TypeCacheOp
+func (e CacheOp) EventType() string { return TypeCacheOp }
+
+// Level returns ERROR if the operation failed and DEBUG otherwise
+func (e CacheOp) Level() log4.LogLevel {
+	if e.Err != nil {
+		return log4.ERROR
+	}
+	return log4.DEBUG
+}
+
+// Message returns the cache and the operation, e.g. "sessions get hit"
+func (e CacheOp) Message() string {
+	msg := e.Cache + " " + e.Operation
+	if e.Operation == CacheGet && e.Err == nil {
+		if e.Hit {
+			msg += " hit"
+		} else {
+			msg += " miss"
+		}
+	}
+	return withFailure(msg, e.Err)
+}
+
+// AddFields sets the fields of the operation; FieldCacheHit is only set for
+// gets
+func (e CacheOp) AddFields(fields map[string]interface{}) {
+	setString(fields, FieldCache, e.Cache)
+	fields[FieldCacheOp] = e.Operation
+	setString(fields, FieldCacheKey, e.Key)
+	if e.Operation == CacheGet {
+		fields[FieldCacheHit] = e.Hit
+	}
+	fields[log4.FieldDuration] = durationMillis(e.Duration)
+	setError(fields, e.Err)
+}
+
+// JobRun is a run of a background job that has ended
+type JobRun struct {
+	Job      string
+	ID       string
+	Attempt  int // From 1, 0 if unknown
+	Duration time.Duration
+	Err      error
+}
+
+// Run returns the JobRun of job, which failed if err is not nil
+func Run(job, id string, attempt int, d time.Duration, err error) JobRun {
+	return JobRun{Job: job, ID: id, Attempt: attempt, Duration: d, Err: err}
+}
+
+// EventType returns TypeJobRun
+func (e JobRun) EventType() string { return TypeJobRun }
+
+// Level returns ERROR if the run failed and INFO otherwise
+func (e JobRun) Level() log4.LogLevel { return levelOf(e.Err) }
+
+// Message returns the job and its status, e.g. "nightly-report succeeded"
+func (e JobRun) Message() string {
+	return e.Job + " " + e.Status()
+}
+
+// Status returns JobFailed if the run failed and JobSucceeded otherwise
+func (e JobRun) Status() string {
+	if e.Err != nil {
+		return JobFailed
+	}
+	return JobSucceeded
+}
+
+// AddFields sets the fields of the run; FieldJobID and FieldJobAttempt are left
+// out when unknown
+func (e JobRun) AddFields(fields map[string]interface{}) {
+	fields[FieldJob] = e.Job
+	setString(fields, FieldJobID, e.ID)
+	if e.Attempt > 0 {
+		fields[FieldJobAttempt] = e.Attempt
+	}
+	fields[FieldJobStatus] = e.Status()
+	fields[log4.FieldDuration] = durationMillis(e.Duration)
+	setError(fields, e.Err)
+}
+
+func levelOf(err error) log4.LogLevel {
+	if err != nil {
+		return log4.ERROR
+	}
+	return log4.INFO
+}
+
+func withFailure(msg string, err error) string {
+	if err != nil {
+		return msg + " failed"
+	}
+	return msg
+}
+
+func setString(fields map[string]interface{}, key, value string) {
+	if value != "" {
+		fields[key] = value
+	}
+}
+
+func setError(fields map[string]interface{}, err error) {
+	if err != nil {
+		fields[log4.FieldError] = err
+	}
+}
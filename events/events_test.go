@@ -0,0 +1,76 @@
+package events
+
+import (
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/MhunterDev/log4"
+	"github.com/MhunterDev/log4/logtest"
+)
+
+func TestEmit(t *testing.T) {
+	logger := logtest.New(t, log4.WithLevel(log4.DEBUG))
+	pl := logger.Package("api").With(map[string]interface{}{"service": "checkout"})
+
+	req := httptest.NewRequest("GET", "/users?page=2", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	pl.Emit(Request(req, 503, 12, 1500*time.Microsecond))
+	pl.Emit(Query("postgresql", " select * from users", 3, time.Millisecond, nil))
+	pl.Emit(Get("sessions", "abc", false, 0, nil))
+	pl.Emit(Run("nightly-report", "42", 2, time.Second, errors.New("timeout")))
+
+	for _, tc := range []struct {
+		level   log4.LogLevel
+		message string
+		fields  map[string]interface{}
+	}{
+		{log4.ERROR, "GET /users?page=2 503", map[string]interface{}{
+			log4.FieldEvent: TypeHTTPRequest, log4.FieldStatus: 503, log4.FieldBytes: int64(12),
+			log4.FieldDuration: 1.5, log4.FieldUserAgent: "curl/8.0", log4.FieldProto: "HTTP/1.1",
+			"service": "checkout",
+		}},
+		{log4.INFO, "SELECT", map[string]interface{}{
+			log4.FieldEvent: TypeDBQuery, FieldDBOperation: "SELECT", FieldDBRows: int64(3), FieldDBSystem: "postgresql",
+		}},
+		{log4.DEBUG, "sessions get miss", map[string]interface{}{
+			log4.FieldEvent: TypeCacheOp, FieldCacheHit: false, FieldCacheKey: "abc",
+		}},
+		{log4.ERROR, "nightly-report failed", map[string]interface{}{
+			log4.FieldEvent: TypeJobRun, FieldJobAttempt: 2, FieldJobStatus: JobFailed, log4.FieldDuration: 1000.0,
+		}},
+	} {
+		entry := logtest.ExpectLog(t, logger, tc.level, "^"+regexp.QuoteMeta(tc.message)+"$")
+		if entry == nil {
+			continue
+		}
+		for k, want := range tc.fields {
+			if got := entry.Fields[k]; got != want {
+				t.Errorf("%s: expected %s=%v, got %v", tc.message, k, want, got)
+			}
+		}
+	}
+}
+
+func TestEventFields(t *testing.T) {
+	fields := map[string]interface{}{}
+	Set("sessions", "abc", time.Millisecond, nil).AddFields(fields)
+	if _, ok := fields[FieldCacheHit]; ok {
+		t.Error("Expected no cache_hit on a set")
+	}
+	if _, ok := fields[log4.FieldError]; ok {
+		t.Error("Expected no error on a successful set")
+	}
+
+	err := errors.New("connection refused")
+	fields = map[string]interface{}{}
+	Query("mysql", "INSERT INTO orders VALUES (?)", 0, 0, err).AddFields(fields)
+	if fields[log4.FieldError] != err {
+		t.Errorf("Expected the error as a field, got %v", fields[log4.FieldError])
+	}
+	if got := (DBQuery{Operation: "UPDATE", Table: "orders", Err: err}).Message(); got != "UPDATE orders failed" {
+		t.Errorf("Unexpected message %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals closes logger when one of sigs is received, os.Interrupt and
+// SIGTERM if none are given, so that the entries still queued are written
+// before the process is terminated. The signal is then raised again without
+// the handler, terminating the process as it would have been without
+// HandleSignals; where signals cannot be raised the process exits with
+// status 1. Applications that handle the signals themselves should close the
+// logger on shutdown instead. The returned function stops signal handling.
+func HandleSignals(logger *ChannelLogger, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	quit := make(chan struct{})
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+
+	go func() {
+		select {
+		case sig := <-ch:
+			logger.logNotice(fmt.Sprintf("closing on %s", sig), map[string]interface{}{"signal": sig.String()})
+			logger.Close()
+			closeExitLoggers()
+			stop()
+			raise(sig)
+		case <-quit:
+		case <-logger.done:
+		}
+	}()
+	return stop
+}
+
+// raise sends sig to the process, exiting with status 1 if it cannot
+func raise(sig os.Signal) {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		exit(1)
+	}
+}
+
+// exitLoggers holds the open loggers created with Config.CloseOnExit
+var exitLoggers = struct {
+	sync.Mutex
+	m map[*ChannelLogger]struct{}
+}{m: make(map[*ChannelLogger]struct{})}
+
+// registerExit adds cl to the loggers closed by Exit
+func (cl *ChannelLogger) registerExit() {
+	exitLoggers.Lock()
+	exitLoggers.m[cl] = struct{}{}
+	exitLoggers.Unlock()
+}
+
+// unregisterExit removes cl from the loggers closed by Exit
+func (cl *ChannelLogger) unregisterExit() {
+	exitLoggers.Lock()
+	delete(exitLoggers.m, cl)
+	exitLoggers.Unlock()
+}
+
+// closeExitLoggers closes every logger created with Config.CloseOnExit that
+// is still open
+func closeExitLoggers() {
+	exitLoggers.Lock()
+	open := make([]*ChannelLogger, 0, len(exitLoggers.m))
+	for cl := range exitLoggers.m {
+		open = append(open, cl)
+	}
+	exitLoggers.Unlock()
+
+	var wg sync.WaitGroup
+	for _, cl := range open {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cl.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+// Exit closes the loggers created with Config.CloseOnExit, writing the
+// entries they still hold, and exits with the given status. Call it in place
+// of os.Exit, which does not run deferred calls such as logger.Close:
+//
+//	if err := run(); err != nil {
+//		log4.Exit(1)
+//	}
+func Exit(code int) {
+	closeExitLoggers()
+	exit(code)
+}
@@ -0,0 +1,49 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestExit(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	code := -1
+	savedExit := exit
+	exit = func(c int) { code = c }
+	defer func() { exit = savedExit }()
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.CloseOnExit = true
+	closed := NewChannelLoggerWithConfig(config)
+	closed.stdout = io.Discard
+	closed.Close()
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	unregistered := NewChannelLogger(10, tempDir)
+	defer unregistered.Close()
+
+	logger.Info("app", "Before exit")
+	Exit(3)
+
+	if code != 3 {
+		t.Errorf("Expected exit status 3, got %d", code)
+	}
+	if !logger.closed.Load() {
+		t.Error("Exit should close loggers with CloseOnExit")
+	}
+	if unregistered.closed.Load() {
+		t.Error("Exit should not close other loggers")
+	}
+	if lines := countLines(readFile(t, filepath.Join(tempDir, "app.log"))); lines != 1 {
+		t.Errorf("Expected the queued entry written, got %d lines", lines)
+	}
+	exitLoggers.Lock()
+	defer exitLoggers.Unlock()
+	if len(exitLoggers.m) != 0 {
+		t.Errorf("Expected closed loggers unregistered, %d remain", len(exitLoggers.m))
+	}
+}
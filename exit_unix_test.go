@@ -0,0 +1,62 @@
+//go:build unix
+
+package log4
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// Receives the signal raised again once the logger is closed, which
+	// would otherwise terminate the test
+	received := make(chan os.Signal, 2)
+	signal.Notify(received, syscall.SIGUSR1)
+	defer signal.Stop(received)
+
+	logger := NewChannelLogger(1000, tempDir)
+	logger.stdout = io.Discard
+	stop := HandleSignals(logger, syscall.SIGUSR1)
+	defer stop()
+
+	config := DefaultConfig()
+	config.LogDir = filepath.Join(tempDir, "other")
+	config.CloseOnExit = true
+	other := NewChannelLoggerWithConfig(config)
+	other.stdout = io.Discard
+
+	for i := 0; i < 500; i++ {
+		logger.Info("app", "Queued before the signal")
+	}
+	other.Info("app", "Other logger")
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected the signal to be raised again after closing, got %d signals", i)
+		}
+	}
+	if !logger.closed.Load() || !other.closed.Load() {
+		t.Fatal("Expected the loggers to be closed")
+	}
+	if lines := countLines(readFile(t, filepath.Join(tempDir, "app.log"))); lines != 500 {
+		t.Errorf("Expected every queued entry written, got %d lines", lines)
+	}
+	if notice := readFile(t, filepath.Join(tempDir, InternalPackage+".log")); !strings.Contains(notice, "closing on user defined signal 1") {
+		t.Errorf("Expected the signal to be logged, got %q", notice)
+	}
+	if lines := countLines(readFile(t, filepath.Join(tempDir, "other", "app.log"))); lines != 1 {
+		t.Errorf("Expected the CloseOnExit logger written, got %d lines", lines)
+	}
+}
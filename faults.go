@@ -0,0 +1,15 @@
+package log4
+
+import "io"
+
+// faultInjector injects failures into the files of a logger, so that tests
+// can exercise error handling, rotation and shutdown under load. Only the
+// chaos tests set one, through cl.do, and only the logging goroutine
+// consults it.
+type faultInjector interface {
+	// wrap returns the writer the entries of a file key are written to
+	wrap(key string, w io.Writer) io.Writer
+	// rotate reports whether to rotate the file of a key before the next
+	// write, whatever its size
+	rotate(key string) bool
+}
@@ -0,0 +1,191 @@
+package log4
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldKind is the kind of value a field of a FieldSchema holds
+type FieldKind int
+
+const (
+	KindAny      FieldKind = iota // Any value
+	KindString                    // string
+	KindInt                       // Any integer type
+	KindNumber                    // Any integer or floating-point type
+	KindBool                      // bool
+	KindTime                      // time.Time
+	KindDuration                  // time.Duration
+)
+
+// String returns the name of the kind, e.g. "int"
+func (k FieldKind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindInt:
+		return "int"
+	case KindNumber:
+		return "number"
+	case KindBool:
+		return "bool"
+	case KindTime:
+		return "time"
+	case KindDuration:
+		return "duration"
+	}
+	return "any"
+}
+
+// FieldSchema describes the fields of the entries of a package or an event
+// type (see Config.FieldSchemas), so that a change in the fields a service
+// logs is noticed before it breaks the pipelines analysing them
+type FieldSchema struct {
+	Fields   map[string]FieldKind // Kinds of the known fields
+	Required []string             // Fields every entry must have
+	Strict   bool                 // Fields not listed in Fields are violations
+}
+
+// SchemaOf returns the FieldSchema of a struct, or pointer to one, whose
+// exported fields are the fields of the entries. Field names are taken from
+// the json tag, fields tagged "-" are skipped, and fields without omitempty
+// are required:
+//
+//	type Payment struct {
+//		OrderID string        `json:"order_id"`
+//		Amount  float64       `json:"amount"`
+//		Retry   int           `json:"retry,omitempty"`
+//		Took    time.Duration `json:"took,omitempty"`
+//	}
+//	config.FieldSchemas = map[string]*log4.FieldSchema{"payments": log4.SchemaOf(Payment{})}
+//
+// It panics if v is not a struct.
+func SchemaOf(v interface{}) *FieldSchema {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("log4: SchemaOf needs a struct, got %T", v))
+	}
+
+	s := &FieldSchema{Fields: make(map[string]FieldKind)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Fields[name] = kindOf(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// kindOf returns the FieldKind of values of t
+func kindOf(t reflect.Type) FieldKind {
+	switch t {
+	case timeType:
+		return KindTime
+	case durationType:
+		return KindDuration
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return KindString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindInt
+	case reflect.Float32, reflect.Float64:
+		return KindNumber
+	case reflect.Bool:
+		return KindBool
+	}
+	return KindAny
+}
+
+// matches reports whether v is of kind k. LogValuers, other than secrets,
+// are not resolved for this and match any kind.
+func (k FieldKind) matches(v interface{}) bool {
+	if s, ok := v.(SecretValue); ok {
+		v = s.Reveal()
+	}
+	switch v.(type) {
+	case LogValuer, slog.LogValuer:
+		return true
+	}
+	if k == KindAny {
+		return true
+	}
+	if v == nil {
+		return false
+	}
+	kind := kindOf(reflect.TypeOf(v))
+	return kind == k || kind == KindInt && k == KindNumber
+}
+
+// schemaFor returns the FieldSchema of an entry and its key in FieldSchemas:
+// the schema of its event type if it was logged by Emit, or else of its
+// package
+func (c *Config) schemaFor(entry *LogEntry) (string, *FieldSchema) {
+	if event, ok := entry.Fields[FieldEvent].(string); ok {
+		if s := c.FieldSchemas[event]; s != nil {
+			return event, s
+		}
+	}
+	return entry.Package, c.FieldSchemas[entry.Package]
+}
+
+// checkSchema reports the fields of an entry about to be written that do
+// not match Config.FieldSchemas. Each field of each schema is reported once
+// per problem; Stats counts every entry in violation. Only called by the
+// logging goroutine, which owns cl.violated.
+func (cl *ChannelLogger) checkSchema(entry *LogEntry) {
+	key, s := cl.config.schemaFor(entry)
+	if s == nil {
+		return
+	}
+
+	bad := false
+	report := func(field, problem string) {
+		bad = true
+		id := [3]string{key, field, problem}
+		if !cl.violated[id] {
+			cl.violated[id] = true
+			cl.handleError(&ErrSchemaViolation{Pkg: entry.Package, Schema: key, Field: field, Problem: problem})
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := entry.Fields[name]; !ok {
+			report(name, "is missing")
+		}
+	}
+	for name, v := range entry.Fields {
+		kind, known := s.Fields[name]
+		switch {
+		case !known && s.Strict && name != FieldEvent:
+			report(name, "is not in the schema")
+		case known && !kind.matches(v):
+			report(name, fmt.Sprintf("is %T, not %s", v, kind))
+		}
+	}
+	if bad {
+		cl.invalid.Add(1)
+	}
+}
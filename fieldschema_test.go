@@ -0,0 +1,82 @@
+package log4
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type paymentFields struct {
+	OrderID string        `json:"order_id"`
+	Amount  float64       `json:"amount"`
+	Retry   int           `json:"retry,omitempty"`
+	Took    time.Duration `json:"took,omitempty"`
+	Ignored string        `json:"-"`
+	note    string
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(&paymentFields{})
+	want := map[string]FieldKind{"order_id": KindString, "amount": KindNumber, "retry": KindInt, "took": KindDuration}
+	if len(s.Fields) != len(want) {
+		t.Errorf("Expected fields %v, got %v", want, s.Fields)
+	}
+	for name, kind := range want {
+		if s.Fields[name] != kind {
+			t.Errorf("Expected %s to be %s, got %s", name, kind, s.Fields[name])
+		}
+	}
+	if len(s.Required) != 2 || s.Required[0] != "order_id" || s.Required[1] != "amount" {
+		t.Errorf("Unexpected required fields %v", s.Required)
+	}
+}
+
+func TestFieldSchemas(t *testing.T) {
+	var mu sync.Mutex
+	var problems []string
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.FieldSchemas = map[string]*FieldSchema{
+		"payments": SchemaOf(paymentFields{}),
+		"audit":    {Fields: map[string]FieldKind{"user": KindString}, Strict: true},
+	}
+	config.ErrorHandler = func(err error) {
+		var v *ErrSchemaViolation
+		if errors.As(err, &v) {
+			mu.Lock()
+			problems = append(problems, v.Schema+" "+v.Field+" "+v.Problem)
+			mu.Unlock()
+		}
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	payments := logger.Package("payments")
+	payments.InfoWithFields("Charged", map[string]interface{}{"order_id": "A1", "amount": 12, "took": time.Second})
+	payments.InfoWithFields("Charged", map[string]interface{}{"order_id": 7, "amount": 3.5, "extra": true})
+	payments.InfoWithFields("Charged", map[string]interface{}{"order_id": 8, "amount": 3.5}) // Reported once
+	payments.InfoWithFields("Refunded", map[string]interface{}{"order_id": "A1"})
+	logger.Package("audit").InfoWithFields("Login", map[string]interface{}{"user": Secret("bob"), "ip": "10.0.0.1"})
+	logger.Info("other", "No schema")
+	logger.Close()
+
+	sort.Strings(problems)
+	want := []string{
+		"audit ip is not in the schema",
+		"payments amount is missing",
+		"payments order_id is int, not string",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Expected violations %q, got %q", want, problems)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], problems[i])
+		}
+	}
+	if n := logger.Stats().Violations; n != 4 {
+		t.Errorf("Expected 4 entries in violation, got %d", n)
+	}
+}
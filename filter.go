@@ -0,0 +1,433 @@
+package log4
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter reports whether an entry should be passed on, e.g. to a sink
+type Filter func(entry *LogEntry) bool
+
+// AllOf matches entries matching every filter
+func AllOf(filters ...Filter) Filter {
+	return func(entry *LogEntry) bool {
+		for _, f := range filters {
+			if !f(entry) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf matches entries matching at least one filter
+func AnyOf(filters ...Filter) Filter {
+	return func(entry *LogEntry) bool {
+		for _, f := range filters {
+			if f(entry) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches entries not matching f
+func Not(f Filter) Filter {
+	return func(entry *LogEntry) bool {
+		return !f(entry)
+	}
+}
+
+// ParseFilter compiles a filter expression such as
+//
+//	level>=ERROR && package=~"^db" && fields.status>=500
+//
+// Comparisons take the form name op value, where name is level, package,
+// tenant, message, fields.<key> or labels.<key>, and op is one of ==, !=, <,
+// <=, >, >=, =~ (regular expression match) or !~. Levels compare by severity
+// and accept anything ParseLogLevelStrict does; fields and labels compare
+// numerically when both sides are numbers and as text otherwise. A bare
+// fields.<key> or labels.<key> matches entries having that field or label.
+// Comparisons combine with &&, ||, ! and parentheses. Values containing
+// spaces or operators are quoted with "..." or `...`. Comparisons on a
+// missing field or label never match.
+func ParseFilter(expr string) (Filter, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf(ErrInvalidFilter, expr, err)
+	}
+	p := &filterParser{toks: toks}
+	f, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(ErrInvalidFilter, expr, err)
+	}
+	return f, nil
+}
+
+// MustParseFilter is like ParseFilter but panics if the expression is invalid
+func MustParseFilter(expr string) Filter {
+	f, err := ParseFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Token kinds of the filter language
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type filterToken struct {
+	kind int
+	text string
+}
+
+// filterOps lists the operators, longest first so that "<=" wins over "<"
+var filterOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+// lexFilter splits a filter expression into tokens
+func lexFilter(expr string) ([]filterToken, error) {
+	var toks []filterToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, filterToken{tokLParen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, filterToken{tokRParen, ")"})
+			i++
+		case c == '"' || c == '`':
+			end := i + 1
+			for end < len(expr) && expr[end] != c {
+				if c == '"' && expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d", i)
+			}
+			toks = append(toks, filterToken{tokString, s})
+			i = end + 1
+		case strings.IndexByte("=!<>&|", c) >= 0:
+			op := ""
+			for _, o := range filterOps {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unknown operator at offset %d", i)
+			}
+			toks = append(toks, filterToken{tokOp, op})
+			i += len(op)
+		default:
+			end := i
+			for end < len(expr) && strings.IndexByte(" \t\n\r()\"`=!<>&|", expr[end]) < 0 {
+				end++
+			}
+			toks = append(toks, filterToken{tokWord, expr[i:end]})
+			i = end
+		}
+	}
+	return toks, nil
+}
+
+// filterParser is a recursive descent parser over the tokens of a filter
+type filterParser struct {
+	toks []filterToken
+	pos  int
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return filterToken{tokEOF, "end of expression"}
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+// parseOr parses and-expressions separated by ||
+func (p *filterParser) parseOr() (Filter, error) {
+	f, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []Filter{f}
+	for p.peek() == (filterToken{tokOp, "||"}) {
+		p.next()
+		if f, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return AnyOf(filters...), nil
+}
+
+// parseAnd parses unary expressions separated by &&
+func (p *filterParser) parseAnd() (Filter, error) {
+	f, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := []Filter{f}
+	for p.peek() == (filterToken{tokOp, "&&"}) {
+		p.next()
+		if f, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return AllOf(filters...), nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a comparison
+func (p *filterParser) parseUnary() (Filter, error) {
+	switch t := p.next(); {
+	case t == filterToken{tokOp, "!"}:
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(f), nil
+	case t.kind == tokLParen:
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("missing )")
+		}
+		return f, nil
+	case t.kind == tokWord:
+		return p.parseComparison(t.text)
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+// parseComparison parses the operator and value following name
+func (p *filterParser) parseComparison(name string) (Filter, error) {
+	op := p.peek()
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		if key, ok := strings.CutPrefix(name, "fields."); ok && key != "" {
+			return func(entry *LogEntry) bool {
+				_, ok := entry.Fields[key]
+				return ok
+			}, nil
+		}
+		if key, ok := strings.CutPrefix(name, "labels."); ok && key != "" {
+			return func(entry *LogEntry) bool {
+				_, ok := entry.Labels[key]
+				return ok
+			}, nil
+		}
+		return nil, fmt.Errorf("expected an operator after %q", name)
+	}
+	p.next()
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, fmt.Errorf("expected a value after %s %s", name, op.text)
+	}
+	return compileComparison(name, op.text, value.text)
+}
+
+// compileComparison builds the filter of a single comparison
+func compileComparison(name, op, value string) (Filter, error) {
+	get, err := filterOperand(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		want := op == "=~"
+		return func(entry *LogEntry) bool {
+			v, ok := get(entry)
+			return ok && re.MatchString(v) == want
+		}, nil
+	}
+
+	switch name {
+	case "level":
+		level, err := ParseLogLevelStrict(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(entry *LogEntry) bool {
+			return compareResult(op, cmp.Compare(entry.Level, level))
+		}, nil
+	case "package", "tenant", "message":
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op, name)
+		}
+		return func(entry *LogEntry) bool {
+			v, _ := get(entry)
+			return compareResult(op, strings.Compare(v, value))
+		}, nil
+	}
+
+	n, numErr := strconv.ParseFloat(value, 64)
+	return func(entry *LogEntry) bool {
+		v, ok := get(entry)
+		if !ok {
+			return false
+		}
+		if numErr == nil {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return compareResult(op, cmp.Compare(f, n))
+			}
+		}
+		if op != "==" && op != "!=" {
+			return false
+		}
+		return compareResult(op, strings.Compare(v, value))
+	}, nil
+}
+
+// filterOperand returns an accessor for the named part of an entry, reporting
+// false for missing fields
+func filterOperand(name string) (func(entry *LogEntry) (string, bool), error) {
+	switch name {
+	case "level":
+		return func(entry *LogEntry) (string, bool) { return entry.Level.String(), true }, nil
+	case "package":
+		return func(entry *LogEntry) (string, bool) { return entry.Package, true }, nil
+	case "tenant":
+		return func(entry *LogEntry) (string, bool) { return entry.Tenant, true }, nil
+	case "message":
+		return func(entry *LogEntry) (string, bool) { return entry.Message, true }, nil
+	}
+	if key, ok := strings.CutPrefix(name, "fields."); ok && key != "" {
+		return func(entry *LogEntry) (string, bool) {
+			v, ok := entry.Fields[key]
+			if !ok {
+				return "", false
+			}
+			return fmt.Sprintf("%v", v), true
+		}, nil
+	}
+	if key, ok := strings.CutPrefix(name, "labels."); ok && key != "" {
+		return func(entry *LogEntry) (string, bool) {
+			v, ok := entry.Labels[key]
+			return v, ok
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown name %q", name)
+}
+
+// compareResult applies a comparison operator to the result of a three-way
+// comparison
+func compareResult(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// FilteredSink passes only the entries matching a filter on to another sink
+type FilteredSink struct {
+	sink   Sink
+	filter Filter
+}
+
+// FilterSink wraps sink so that it only receives entries matching filter:
+//
+//	log4.FilterSink(shipper, log4.MustParseFilter("level>=ERROR"))
+func FilterSink(sink Sink, filter Filter) *FilteredSink {
+	return &FilteredSink{sink: sink, filter: filter}
+}
+
+// Write forwards the entry if it matches the filter
+func (s *FilteredSink) Write(entry *LogEntry, line []byte) error {
+	if !s.filter(entry) {
+		return nil
+	}
+	return s.sink.Write(entry, line)
+}
+
+// Flush flushes the wrapped sink if it buffers output
+func (s *FilteredSink) Flush() error {
+	if f, ok := s.sink.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the wrapped sink
+func (s *FilteredSink) Close() error {
+	return s.sink.Close()
+}
+
+// LengthPrefixed reports whether the wrapped sink is a LengthPrefixer asking
+// for length-prefixed entries
+func (s *FilteredSink) LengthPrefixed() bool {
+	p, ok := s.sink.(LengthPrefixer)
+	return ok && p.LengthPrefixed()
+}
+
+// textRecords reports whether the wrapped sink takes entries without the
+// record separator
+func (s *FilteredSink) textRecords() bool {
+	r, ok := s.sink.(recordSink)
+	return ok && r.textRecords()
+}
+
+// LevelSink wraps sink so that it only receives entries at or above level,
+// e.g. to forward INFO and above over the network while files keep DEBUG
+func LevelSink(sink Sink, level LogLevel) *FilteredSink {
+	return FilterSink(sink, func(entry *LogEntry) bool {
+		return entry.Level >= level
+	})
+}
@@ -0,0 +1,69 @@
+package log4
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	entry := &LogEntry{Package: "dbpool", Level: ERROR, Message: "Query failed",
+		Fields: map[string]interface{}{"status": 503, "route": "/api/users"}}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`level>=ERROR`, true},
+		{`level < warning`, false},
+		{`level==2`, true},
+		{`package=~"db.*" && fields.status>=500`, true},
+		{`package == dbpool && fields.status < 500`, false},
+		{`level>=ERROR && package=~"^db" && fields.status>=500`, true},
+		{`package!="dbpool" || message =~ "(?i)failed"`, true},
+		{`!(level==ERROR)`, false},
+		{`fields.route == "/api/users"`, true},
+		{"fields.route =~ `^/api/\\w+$`", true},
+		{`fields.route !~ "^/api"`, false},
+		{`fields.missing != 1`, false},
+		{`fields.status && !fields.user`, true},
+		{`tenant == ""`, true},
+	}
+	for _, test := range tests {
+		f, err := ParseFilter(test.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%s) failed: %v", test.expr, err)
+			continue
+		}
+		if got := f(entry); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expr, got, test.want)
+		}
+	}
+
+	for _, expr := range []string{"", "level", "level >= LOUD", "package > db", `message == "open`,
+		"(level==ERROR", "level==ERROR)", "colour==red", "package =~ (", "level = ERROR", "&& level==INFO"} {
+		if _, err := ParseFilter(expr); err == nil || !strings.Contains(err.Error(), "invalid filter") {
+			t.Errorf("ParseFilter(%q) should fail, got %v", expr, err)
+		}
+	}
+}
+
+func TestFilterSink(t *testing.T) {
+	var sb strings.Builder
+	sink := FilterSink(NewWriterSink(&sb, nil), MustParseFilter(`level>=ERROR || fields.audit`))
+
+	for _, entry := range []*LogEntry{
+		{Package: "api", Level: INFO, Message: "Started"},
+		{Package: "api", Level: ERROR, Message: "Crashed"},
+		{Package: "api", Level: DEBUG, Message: "Login", Fields: map[string]interface{}{"audit": true}},
+	} {
+		if err := sink.Write(entry, []byte(entry.Message+"\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if sb.String() != "Crashed\nLogin\n" {
+		t.Errorf("Unexpected filtered output %q", sb.String())
+	}
+}
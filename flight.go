@@ -0,0 +1,71 @@
+package log4
+
+import (
+	"sync"
+	"time"
+)
+
+// flightRecorder keeps the recently filtered entries of each package, per
+// tenant, so they can be written retroactively when that package of the same
+// tenant logs an error
+type flightRecorder struct {
+	mu     sync.Mutex
+	size   int
+	window time.Duration
+	rings  map[string]*entryRing // By fileKey
+}
+
+func newFlightRecorder(size int, window time.Duration) *flightRecorder {
+	return &flightRecorder{
+		size:   size,
+		window: window,
+		rings:  make(map[string]*entryRing),
+	}
+}
+
+// record copies a filtered entry into the ring of its tenant and package
+func (fr *flightRecorder) record(entry *LogEntry) {
+	key := fileKey(entry.Tenant, entry.Package)
+	fr.mu.Lock()
+	ring, ok := fr.rings[key]
+	if !ok {
+		ring = newEntryRing(fr.size)
+		fr.rings[key] = ring
+	}
+	fr.mu.Unlock()
+
+	ring.add(entry)
+}
+
+// release empties the ring of a tenant's package and returns its entries
+// within the window before now as pooled entries ready to be queued
+func (fr *flightRecorder) release(tenant, pkg string, now time.Time) []*LogEntry {
+	fr.mu.Lock()
+	ring, ok := fr.rings[fileKey(tenant, pkg)]
+	fr.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	var out []*LogEntry
+	for _, buffered := range ring.drain() {
+		if fr.window > 0 && now.Sub(buffered.Timestamp) > fr.window {
+			continue
+		}
+		entry := getLogEntry()
+		entry.Package = buffered.Package
+		entry.Tenant = buffered.Tenant
+		entry.Level = buffered.Level
+		entry.Message = buffered.Message
+		entry.Timestamp = buffered.Timestamp
+		for k, v := range buffered.Fields {
+			entry.Fields[k] = v
+		}
+		for k, v := range buffered.Labels {
+			entry.SetLabel(k, v)
+		}
+		entry.Fields["flight_recorder"] = true
+		out = append(out, entry)
+	}
+	return out
+}
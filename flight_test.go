@@ -0,0 +1,104 @@
+package log4
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	config.FlightRecorderEntries = 2
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Debug("db", "evicted")
+	logger.Debug("db", "opening connection")
+	logger.Debug("api", "other package")
+	logger.Debug("db", "sending query")
+	logger.Error("db", "query failed")
+	logger.Error("db", "second failure")
+	time.Sleep(100 * time.Millisecond)
+
+	content := readFile(t, filepath.Join(tempDir, "db.log"))
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 2 replayed and 2 error lines, got %q", content)
+	}
+	if !strings.Contains(lines[0], "DEBUG: opening connection | flight_recorder=true") ||
+		!strings.Contains(lines[1], "DEBUG: sending query") ||
+		!strings.Contains(lines[2], "ERROR: query failed") ||
+		!strings.Contains(lines[3], "ERROR: second failure") {
+		t.Errorf("Unexpected flight recorder output:\n%s", content)
+	}
+	if fileExists(filepath.Join(tempDir, "api.log")) {
+		t.Error("Other packages should not be flushed")
+	}
+}
+
+func TestFlightRecorderTenants(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	config.FlightRecorderEntries = 4
+	config.RecentEntries = 10
+	config.RecentBelowMinLevel = true
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+
+	acme := logger.Tenant("acme").Package("db")
+	globex := logger.Tenant("globex").Package("db")
+	acme.Debug("acme context")
+	globex.Debug("globex context")
+	logger.Debug("db", "shared context")
+	acme.Error("acme failure")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "acme", "db.log"))
+	if !strings.Contains(content, "DEBUG: acme context") || !strings.Contains(content, "ERROR: acme failure") {
+		t.Errorf("Expected the context of acme in its own file, got:\n%s", content)
+	}
+	if strings.Contains(content, "globex") || strings.Contains(content, "shared") {
+		t.Errorf("Context of other tenants leaked into acme's file:\n%s", content)
+	}
+	if fileExists(filepath.Join(tempDir, "db.log")) || fileExists(filepath.Join(tempDir, "globex", "db.log")) {
+		t.Error("Only the ring of the failing tenant should be released")
+	}
+
+	recent := logger.Recent()
+	if len(recent) != 4 || recent[0].Tenant != "acme" || recent[1].Tenant != "globex" || recent[2].Tenant != "" {
+		t.Errorf("Expected recent entries to keep their tenant, got %+v", recent)
+	}
+}
+
+func TestFlightRecorderWindow(t *testing.T) {
+	fr := newFlightRecorder(10, time.Minute)
+	now := time.Now()
+
+	for _, age := range []time.Duration{2 * time.Minute, 30 * time.Second} {
+		entry := getLogEntry()
+		entry.Package = "db"
+		entry.Message = age.String()
+		entry.Timestamp = now.Add(-age)
+		fr.record(entry)
+		putLogEntry(entry)
+	}
+
+	released := fr.release("", "db", now)
+	if len(released) != 1 || released[0].Message != "30s" {
+		t.Errorf("Expected only the entry inside the window, got %d entries", len(released))
+	}
+	if again := fr.release("", "db", now); len(again) != 0 {
+		t.Error("Release should empty the buffer")
+	}
+}
@@ -0,0 +1,375 @@
+package log4
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// DefaultFluentAddress is the default fluentd / fluent-bit forward input
+const DefaultFluentAddress = "localhost:24224"
+
+// FluentOptions configures a FluentSink
+type FluentOptions struct {
+	Address   string // host:port of the forward input (default: DefaultFluentAddress)
+	TagPrefix string // Prepended to the package name to form the tag, e.g. "app."
+
+	// SharedKey enables the forward protocol handshake. Username and Password
+	// additionally enable user authentication when the server requires it.
+	SharedKey string
+	Username  string
+	Password  string
+	Hostname  string // Client hostname sent in the handshake (default: os.Hostname)
+
+	// RequireAck waits for the server to acknowledge every chunk, so chunks
+	// lost with a broken connection are resent
+	RequireAck bool
+
+	// Compression sends the entries of every message compressed, in
+	// CompressedPackedForward mode with the codec's name as the "compressed"
+	// option. Fluentd and fluent-bit only accept GzipCodec. (default: none)
+	Compression Codec
+
+	BatchOptions
+
+	ErrorHandler func(error) // Delivery errors (default: printed to stderr)
+}
+
+// FluentSink forwards entries to fluentd or fluent-bit using the Fluent
+// Forward protocol (MessagePack over TCP). Every entry is tagged with
+// TagPrefix plus its package name and carries level, message and its fields
+// as the record. Entries are sent in batches from a background goroutine.
+type FluentSink struct {
+	opts    FluentOptions
+	batcher *batcher[fluentEntry]
+	onError func(error)
+	sinkNetwork
+
+	// Only used by the export goroutine
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// fluentEntry is an encoded [time, record] pair waiting for export
+type fluentEntry struct {
+	tag   string
+	data  []byte
+	entry *LogEntry       // Copy kept for the dead-letter file, if one is set
+	ctx   context.Context // Context the entry was logged with, if any
+}
+
+// errFluentAuth marks handshake failures that retrying will not fix
+var errFluentAuth = errors.New("fluent handshake rejected")
+
+// NewFluentSink creates a forward protocol sink. The connection is opened
+// lazily by the first export and re-established after failures.
+func NewFluentSink(opts FluentOptions) *FluentSink {
+	if opts.Address == "" {
+		opts.Address = DefaultFluentAddress
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	opts.BatchOptions = opts.BatchOptions.withDefaults()
+
+	s := &FluentSink{opts: opts}
+	s.onError = sinkErrorHandler(opts.ErrorHandler)
+	s.batcher = newBatcher(opts.BatchOptions, s.export, s.onError, func(e fluentEntry) context.Context { return e.ctx })
+	return s
+}
+
+// Write encodes the entry and queues it for forwarding
+func (s *FluentSink) Write(entry *LogEntry, line []byte) error {
+	e := fluentEntry{
+		tag:  s.opts.TagPrefix + entry.Package,
+		data: encodeFluentEntry(entry),
+	}
+	if s.opts.DeadLetter != nil {
+		e.entry = entry.Clone()
+	}
+	e.ctx = entry.Context
+	s.batcher.add(e)
+	return nil
+}
+
+// Close forwards the queued entries and closes the connection
+func (s *FluentSink) Close() error {
+	s.batcher.close()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full or
+// they could not be delivered
+func (s *FluentSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// encodeFluentEntry encodes the [time, record] pair of an entry
+func encodeFluentEntry(entry *LogEntry) []byte {
+	b := appendMsgpackArrayHeader(nil, 2)
+	b = appendMsgpackEventTime(b, entry.Timestamp)
+
+	labels := entry.unshadowedLabels()
+	b = appendMsgpackMapHeader(b, 2+len(entry.Fields)+len(labels))
+	b = appendMsgpackString(b, "level")
+	b = appendMsgpackString(b, entry.Level.String())
+	b = appendMsgpackString(b, "message")
+	b = appendMsgpackString(b, entry.Message)
+	for _, k := range sortedFieldKeys(entry.Fields) {
+		name := k
+		if k == "level" || k == "message" {
+			name = "fields." + k
+		}
+		b = appendMsgpackString(b, name)
+		b = appendMsgpackValue(b, entry.Fields[k])
+	}
+	for _, k := range labels {
+		name := k
+		if k == "level" || k == "message" {
+			name = "labels." + k
+		}
+		b = appendMsgpackString(b, name)
+		b = appendMsgpackString(b, entry.Labels[k])
+	}
+	return b
+}
+
+// export sends a batch as one Forward mode message per tag. A tag that cannot
+// be delivered does not stop the others.
+func (s *FluentSink) export(ctx context.Context, batch []fluentEntry) error {
+	var tags []string
+	byTag := make(map[string][]fluentEntry)
+	for _, e := range batch {
+		if _, ok := byTag[e.tag]; !ok {
+			tags = append(tags, e.tag)
+		}
+		byTag[e.tag] = append(byTag[e.tag], e)
+	}
+
+	var errs []error
+	failed := 0
+	for _, tag := range tags {
+		entries := byTag[tag]
+		var packed []byte
+		var err error
+		if s.opts.Compression != nil || s.opts.Seal != nil {
+			for _, e := range entries {
+				packed = append(packed, e.data...)
+			}
+		}
+		if s.opts.Compression != nil {
+			packed, err = compressPayload(s.opts.Compression, packed)
+		}
+		if err == nil {
+			err = retryExport(ctx, s.opts.MaxRetries, s.opts.RetryBackoff, func() error {
+				// Every attempt is sealed anew, so receivers do not take it for a replay
+				var sealed *SealedBatch
+				if s.opts.Seal != nil {
+					var err error
+					if sealed, err = s.opts.Seal.seal(packed); err != nil {
+						return err
+					}
+				}
+				return s.send(tag, entries, packed, sealed)
+			})
+		}
+		if err == nil {
+			continue
+		}
+
+		failed += len(entries)
+		errs = append(errs, fmt.Errorf("fluent forward of %d entries for tag %s failed: %w", len(entries), tag, err))
+		if s.opts.DeadLetter != nil {
+			undelivered := make([]*LogEntry, len(entries))
+			for i, e := range entries {
+				undelivered[i] = e.entry
+			}
+			s.opts.DeadLetter.record(undelivered, "fluent", err, s.onError)
+		}
+	}
+	if len(errs) > 0 {
+		return &partialFailure{failed: failed, err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+// send writes one Forward mode message, connecting first if needed. Entries
+// packed by export, compressed or sealed, are sent in PackedForward mode,
+// with the compression and seal added to the options.
+func (s *FluentSink) send(tag string, entries []fluentEntry, packed []byte, sealed *SealedBatch) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	msg := appendMsgpackArrayHeader(nil, 3)
+	msg = appendMsgpackString(msg, tag)
+	if sealed != nil {
+		msg = appendMsgpackBinary(msg, sealed.Payload)
+	} else if packed != nil {
+		msg = appendMsgpackBinary(msg, packed)
+	} else {
+		msg = appendMsgpackArrayHeader(msg, len(entries))
+		for _, e := range entries {
+			msg = append(msg, e.data...)
+		}
+	}
+
+	options := 1
+	if s.opts.RequireAck {
+		options++
+	}
+	if s.opts.Compression != nil {
+		options++
+	}
+	if sealed != nil {
+		options += sealed.msgpackSealOptions()
+	}
+	msg = appendMsgpackMapHeader(msg, options)
+	var chunk string
+	if s.opts.RequireAck {
+		var id [16]byte
+		rand.Read(id[:])
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		msg = appendMsgpackString(msg, "chunk")
+		msg = appendMsgpackString(msg, chunk)
+	}
+	msg = appendMsgpackString(msg, "size")
+	msg = appendMsgpackUint(msg, uint64(len(entries)))
+	if s.opts.Compression != nil {
+		msg = appendMsgpackString(msg, "compressed")
+		msg = appendMsgpackString(msg, s.opts.Compression.Name())
+	}
+	if sealed != nil {
+		msg = sealed.appendMsgpackSeal(msg)
+	}
+
+	s.conn.SetDeadline(time.Now().Add(s.opts.Timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		s.disconnect()
+		return retryable(err)
+	}
+
+	if s.opts.RequireAck {
+		resp, err := readMsgpack(s.reader)
+		if err != nil {
+			s.disconnect()
+			return retryable(fmt.Errorf("waiting for ack: %w", err))
+		}
+		if m, ok := resp.(map[string]interface{}); !ok || m["ack"] != chunk {
+			s.disconnect()
+			return retryable(fmt.Errorf("unexpected ack %v", resp))
+		}
+	}
+	return nil
+}
+
+func (s *FluentSink) connect() error {
+	conn, err := s.dial(s.opts.BatchOptions, s.opts.Address, s.opts.Timeout)
+	if err != nil {
+		return retryable(err)
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	if s.opts.SharedKey != "" {
+		conn.SetDeadline(time.Now().Add(s.opts.Timeout))
+		if err := s.handshake(); err != nil {
+			s.disconnect()
+			if errors.Is(err, errFluentAuth) {
+				return err
+			}
+			return retryable(err)
+		}
+	}
+	return nil
+}
+
+func (s *FluentSink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.reader = nil, nil
+	}
+}
+
+// handshake performs the shared key (and optional user) authentication of
+// the forward protocol: HELO from the server, PING from the client, PONG
+func (s *FluentSink) handshake() error {
+	helo, err := readMsgpack(s.reader)
+	if err != nil {
+		return fmt.Errorf("reading HELO: %w", err)
+	}
+	msg, ok := helo.([]interface{})
+	if !ok || len(msg) < 2 || msgpackText(msg[0]) != "HELO" {
+		return fmt.Errorf("unexpected handshake message %v", helo)
+	}
+	heloOpts, _ := msg[1].(map[string]interface{})
+	nonce := msgpackText(heloOpts["nonce"])
+	authSalt := msgpackText(heloOpts["auth"])
+
+	var saltBytes [16]byte
+	rand.Read(saltBytes[:])
+	salt := hex.EncodeToString(saltBytes[:])
+
+	passwordDigest := ""
+	if authSalt != "" {
+		passwordDigest = sha512Hex(authSalt, s.opts.Username, s.opts.Password)
+	}
+
+	ping := appendMsgpackArrayHeader(nil, 6)
+	ping = appendMsgpackString(ping, "PING")
+	ping = appendMsgpackString(ping, s.opts.Hostname)
+	ping = appendMsgpackString(ping, salt)
+	ping = appendMsgpackString(ping, sha512Hex(salt, s.opts.Hostname, nonce, s.opts.SharedKey))
+	ping = appendMsgpackString(ping, s.opts.Username)
+	ping = appendMsgpackString(ping, passwordDigest)
+	if _, err := s.conn.Write(ping); err != nil {
+		return fmt.Errorf("sending PING: %w", err)
+	}
+
+	pong, err := readMsgpack(s.reader)
+	if err != nil {
+		return fmt.Errorf("reading PONG: %w", err)
+	}
+	msg, ok = pong.([]interface{})
+	if !ok || len(msg) < 5 || msgpackText(msg[0]) != "PONG" {
+		return fmt.Errorf("unexpected handshake message %v", pong)
+	}
+	if accepted, _ := msg[1].(bool); !accepted {
+		return fmt.Errorf("%w: %s", errFluentAuth, msgpackText(msg[2]))
+	}
+	if msgpackText(msg[4]) != sha512Hex(salt, msgpackText(msg[3]), nonce, s.opts.SharedKey) {
+		return fmt.Errorf("%w: server shared key mismatch", errFluentAuth)
+	}
+	return nil
+}
+
+// msgpackText returns a decoded string or binary value as a string
+func msgpackText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func sha512Hex(parts ...string) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,175 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeFluentd accepts one connection, optionally authenticates it and
+// returns the received Forward mode messages
+func fakeFluentd(t *testing.T, sharedKey string) (string, <-chan []interface{}) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan []interface{}, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		if sharedKey != "" {
+			helo := appendMsgpackArrayHeader(nil, 2)
+			helo = appendMsgpackString(helo, "HELO")
+			helo = appendMsgpackMapHeader(helo, 3)
+			helo = appendMsgpackString(helo, "nonce")
+			helo = appendMsgpackBinary(helo, []byte("nonce123"))
+			helo = appendMsgpackString(helo, "auth")
+			helo = appendMsgpackString(helo, "")
+			helo = appendMsgpackString(helo, "keepalive")
+			helo = appendMsgpackBool(helo, true)
+			conn.Write(helo)
+
+			v, err := readMsgpack(r)
+			ping, ok := v.([]interface{})
+			if err != nil || !ok || len(ping) != 6 {
+				t.Errorf("Invalid PING %v: %v", v, err)
+				return
+			}
+			hostname, salt := ping[1].(string), ping[2].(string)
+			valid := ping[3] == sha512Hex(salt, hostname, "nonce123", sharedKey)
+
+			pong := appendMsgpackArrayHeader(nil, 5)
+			pong = appendMsgpackString(pong, "PONG")
+			pong = appendMsgpackBool(pong, valid)
+			pong = appendMsgpackString(pong, "shared key mismatch")
+			pong = appendMsgpackString(pong, "fluentd")
+			pong = appendMsgpackString(pong, sha512Hex(salt, "fluentd", "nonce123", sharedKey))
+			conn.Write(pong)
+			if !valid {
+				return
+			}
+		}
+
+		for {
+			v, err := readMsgpack(r)
+			if err != nil {
+				return
+			}
+			msg, _ := v.([]interface{})
+			if len(msg) == 3 {
+				if opts, ok := msg[2].(map[string]interface{}); ok && opts["chunk"] != nil {
+					ack := appendMsgpackMapHeader(nil, 1)
+					ack = appendMsgpackString(ack, "ack")
+					ack = appendMsgpackString(ack, opts["chunk"].(string))
+					conn.Write(ack)
+				}
+			}
+			messages <- msg
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestFluentSink(t *testing.T) {
+	addr, messages := fakeFluentd(t, "secret")
+
+	var exportErr error
+	sink := NewFluentSink(FluentOptions{
+		Address:      addr,
+		TagPrefix:    "app.",
+		SharedKey:    "secret",
+		RequireAck:   true,
+		ErrorHandler: func(err error) { exportErr = err },
+	})
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	sink.Write(&LogEntry{Package: "orders", Level: INFO, Message: "created", Timestamp: ts,
+		Fields: map[string]interface{}{"id": 42, "message": "shadowed"}}, nil)
+	sink.Write(&LogEntry{Package: "orders", Level: ERROR, Message: "failed", Timestamp: ts}, nil)
+	sink.Close()
+
+	if exportErr != nil {
+		t.Fatalf("Forwarding failed: %v", exportErr)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg[0] != "app.orders" {
+			t.Errorf("Expected tag app.orders, got %v", msg[0])
+		}
+		entries := msg[1].([]interface{})
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 entries, got %d", len(entries))
+		}
+		first := entries[0].([]interface{})
+		if eventTime, ok := first[0].([]byte); !ok || len(eventTime) != 8 {
+			t.Errorf("Expected EventTime extension, got %v", first[0])
+		}
+		record := first[1].(map[string]interface{})
+		if record["level"] != "INFO" || record["message"] != "created" ||
+			record["id"] != int64(42) || record["fields.message"] != "shadowed" {
+			t.Errorf("Unexpected record %v", record)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fluentd received nothing")
+	}
+}
+
+func TestFluentSinkCompression(t *testing.T) {
+	addr, messages := fakeFluentd(t, "")
+
+	sink := NewFluentSink(FluentOptions{Address: addr, Compression: GzipCodec{}})
+	sink.Write(&LogEntry{Package: "orders", Level: INFO, Message: "created", Timestamp: time.Now()}, nil)
+	sink.Write(&LogEntry{Package: "orders", Level: INFO, Message: "paid", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	select {
+	case msg := <-messages:
+		if opts := msg[2].(map[string]interface{}); opts["compressed"] != "gzip" || opts["size"] != int64(2) {
+			t.Errorf("Unexpected options %v", opts)
+		}
+		zr, err := GzipCodec{}.NewReader(bytes.NewReader(msg[1].([]byte)))
+		if err != nil {
+			t.Fatalf("Expected gzip entries: %v", err)
+		}
+		r := bufio.NewReader(zr)
+		var got []string
+		for {
+			v, err := readMsgpack(r)
+			if err != nil {
+				break
+			}
+			got = append(got, v.([]interface{})[1].(map[string]interface{})["message"].(string))
+		}
+		if len(got) != 2 || got[0] != "created" || got[1] != "paid" {
+			t.Errorf("Unexpected entries %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fluentd received nothing")
+	}
+}
+
+func TestFluentSinkAuthFailure(t *testing.T) {
+	addr, _ := fakeFluentd(t, "secret")
+
+	var exportErr error
+	sink := NewFluentSink(FluentOptions{
+		Address:      addr,
+		SharedKey:    "wrong",
+		ErrorHandler: func(err error) { exportErr = err },
+	})
+	sink.Write(&LogEntry{Package: "auth", Message: "rejected", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	if exportErr == nil || sink.Dropped() != 1 {
+		t.Errorf("Expected an authentication error and one drop, got %v and %d", exportErr, sink.Dropped())
+	}
+}
@@ -0,0 +1,266 @@
+package log4
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Formatter renders a log entry. Format appends the rendered entry to dst,
+// without a trailing newline, and returns the extended buffer. Formatters may
+// be called from several goroutines and must be safe for concurrent use.
+type Formatter interface {
+	Format(dst []byte, entry *LogEntry) []byte
+}
+
+// DefaultContinuationPrefix marks the continuation lines of folded messages
+// unless TextFormatter.ContinuationPrefix is set
+const DefaultContinuationPrefix = "    | "
+
+// TextFormatter renders entries in the default human readable layout:
+//
+//	[2006-01-02 15:04:05] INFO: message | key=value, other=value
+//
+// With FoldLines, the first line of a multi-line message, such as a stack
+// trace, stays on the header line with the fields, and the others follow
+// indented and marked with ContinuationPrefix:
+//
+//	[2006-01-02 15:04:05] ERROR: panic: boom | request_id=42
+//	    | goroutine 1 [running]:
+//	    | main.main()
+//
+// Grepping for a timestamp or a field still finds the header; Tail and Replay
+// deliver continuation lines as entries of their own.
+//
+// LevelNames and TimeNames replace the English level, month and weekday
+// names, e.g. for tooling expecting German labels.
+//
+// LevelStyle selects how the level is set off from the message, and
+// PadLevels pads it to the width of the longest level name so that messages
+// line up in columns:
+//
+//	[2006-01-02 15:04:05] [INFO]  message
+//	[2006-01-02 15:04:05] [ERROR] message
+type TextFormatter struct {
+	TimestampFormat    string
+	FoldLines          bool
+	ContinuationPrefix string // default: DefaultContinuationPrefix
+	LevelNames         map[LogLevel]string
+	TimeNames          *TimeNames
+	LevelStyle         LevelStyle
+	PadLevels          bool
+	Encoding           FieldEncoding // How field values of common types are written
+}
+
+// LevelStyle is the delimiter style of levels in the text layout
+type LevelStyle int
+
+const (
+	LevelColon   LevelStyle = iota // "INFO: message" (default)
+	LevelBracket                   // "[INFO] message"
+	LevelPlain                     // "INFO message"
+)
+
+// appendLevel appends a level name in the style, followed by as many spaces
+// as the name is shorter than width
+func (s LevelStyle) appendLevel(dst []byte, name string, width int) []byte {
+	switch s {
+	case LevelBracket:
+		dst = append(dst, '[')
+		dst = append(dst, name...)
+		dst = append(dst, ']')
+	case LevelPlain:
+		dst = append(dst, name...)
+	default:
+		dst = append(dst, name...)
+		dst = append(dst, ':')
+	}
+	for pad := width - utf8.RuneCountInString(name); pad > 0; pad-- {
+		dst = append(dst, ' ')
+	}
+	return dst
+}
+
+// levelWidth returns the width of the longest level name in names
+func levelWidth(names map[LogLevel]string) int {
+	width := 0
+	for _, level := range []LogLevel{DEBUG, INFO, ERROR} {
+		width = max(width, utf8.RuneCountInString(levelName(names, level)))
+	}
+	return width
+}
+
+// Format appends the text layout of entry to dst
+func (f *TextFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	width := 0
+	if f.PadLevels {
+		width = levelWidth(f.LevelNames)
+	}
+	if !f.FoldLines || !strings.ContainsAny(entry.Message, "\r\n") {
+		return appendLogMessage(dst, entry, f, width)
+	}
+
+	lines := strings.Split(strings.TrimRight(entry.Message, "\r\n"), "\n")
+	header := *entry
+	header.Message = strings.TrimSuffix(lines[0], "\r")
+	dst = appendLogMessage(dst, &header, f, width)
+
+	prefix := f.ContinuationPrefix
+	if prefix == "" {
+		prefix = DefaultContinuationPrefix
+	}
+	for _, line := range lines[1:] {
+		dst = append(dst, '\n')
+		dst = append(dst, prefix...)
+		dst = append(dst, strings.TrimSuffix(line, "\r")...)
+	}
+	return dst
+}
+
+// JSONFormatter renders each entry as a single JSON object:
+//
+//	{"time":"2006-01-02T15:04:05.999999999Z07:00","level":"INFO","package":"app","msg":"message","key":"value"}
+//
+// The key names default to DefaultKeyTime, DefaultKeyLevel, DefaultKeyMessage
+// and DefaultKeyPackage and can be renamed to match an existing index mapping.
+// Fields are written in key order after the reserved keys; a field whose name
+// collides with a reserved key is written as "fields.<name>". Entries of a
+// tenant also carry the tenant under DefaultKeyTenant. LevelNames and
+// TimeNames localize the level and timestamp as for TextFormatter.
+type JSONFormatter struct {
+	TimestampFormat string // Layout for the time key (default: time.RFC3339Nano)
+	KeyTime         string
+	KeyLevel        string
+	KeyMessage      string
+	KeyPackage      string
+	LevelNames      map[LogLevel]string
+	TimeNames       *TimeNames
+	Encoding        FieldEncoding // How field values of common types are written
+}
+
+// Format appends the JSON encoding of entry to dst
+func (f *JSONFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	keyTime := keyOrDefault(f.KeyTime, DefaultKeyTime)
+	keyLevel := keyOrDefault(f.KeyLevel, DefaultKeyLevel)
+	keyMessage := keyOrDefault(f.KeyMessage, DefaultKeyMessage)
+	keyPackage := keyOrDefault(f.KeyPackage, DefaultKeyPackage)
+
+	layout := f.TimestampFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+
+	dst = append(dst, '{')
+	dst = appendJSONString(dst, keyTime)
+	dst = append(dst, ':')
+	dst = appendJSONString(dst, f.TimeNames.formatTime(entry.Timestamp, layout))
+	dst = append(dst, ',')
+	dst = appendJSONString(dst, keyLevel)
+	dst = append(dst, ':')
+	dst = appendJSONString(dst, levelName(f.LevelNames, entry.Level))
+	if entry.Tenant != "" {
+		dst = append(dst, ',')
+		dst = appendJSONString(dst, DefaultKeyTenant)
+		dst = append(dst, ':')
+		dst = appendJSONString(dst, entry.Tenant)
+	}
+	if entry.Package != "" {
+		dst = append(dst, ',')
+		dst = appendJSONString(dst, keyPackage)
+		dst = append(dst, ':')
+		dst = appendJSONString(dst, entry.Package)
+	}
+	dst = append(dst, ',')
+	dst = appendJSONString(dst, keyMessage)
+	dst = append(dst, ':')
+	dst = appendJSONString(dst, entry.Message)
+
+	dst = f.Encoding.appendJSONFields(dst, entry.Fields, func(k string) string {
+		if k == keyTime || k == keyLevel || k == keyMessage || k == keyPackage || (k == DefaultKeyTenant && entry.Tenant != "") {
+			return "fields." + k
+		}
+		return k
+	})
+	return append(dst, '}')
+}
+
+func keyOrDefault(key, def string) string {
+	if key == "" {
+		return def
+	}
+	return key
+}
+
+// appendJSONValue appends v as JSON. Errors are written as their message and
+// values that cannot be marshaled fall back to their %v representation.
+func appendJSONValue(dst []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return appendJSONString(dst, v)
+	case bool:
+		return strconv.AppendBool(dst, v)
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(dst, v, 10)
+	case error:
+		return appendJSONString(dst, v.Error())
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(dst, fmt.Sprintf("%v", v))
+	}
+	return append(dst, b...)
+}
+
+// appendJSONString appends s as a JSON string literal, replacing invalid UTF-8
+// the way encoding/json does
+func appendJSONString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
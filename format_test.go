@@ -0,0 +1,185 @@
+package log4
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	entry := &LogEntry{
+		Package:   "api",
+		Level:     ERROR,
+		Message:   "request \"failed\"\n",
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Fields: map[string]interface{}{
+			"status": 502,
+			"err":    errors.New("upstream timeout"),
+			"level":  "collides",
+		},
+	}
+
+	line := (&JSONFormatter{}).Format(nil, entry)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, line)
+	}
+	expected := map[string]interface{}{
+		"time":         "2024-05-01T12:00:00Z",
+		"level":        "ERROR",
+		"package":      "api",
+		"msg":          "request \"failed\"\n",
+		"status":       float64(502),
+		"err":          "upstream timeout",
+		"fields.level": "collides",
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("Key %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	// The output parses back into the same entry
+	parsed, err := ParseLine(string(line), DefaultConfig().TimestampFormat)
+	if err != nil {
+		t.Fatalf("ParseLine failed: %v", err)
+	}
+	if parsed.Level != ERROR || parsed.Package != "api" || !parsed.Timestamp.Equal(entry.Timestamp) {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+}
+
+func TestJSONFieldKeys(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.JSON = true
+	config.FieldKeyTime = "@timestamp"
+	config.FieldKeyLevel = "severity"
+	config.FieldKeyMsg = "message"
+	config.FieldKeyPackage = "logger"
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.Info("jsonkeys", "Renamed keys")
+	logger.Close()
+
+	content := strings.TrimSpace(readFile(t, filepath.Join(tempDir, "jsonkeys.log")))
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("Log line is not valid JSON: %v\n%s", err, content)
+	}
+	for _, key := range []string{"@timestamp", "severity", "message", "logger"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("Missing key %s in %s", key, content)
+		}
+	}
+	if got["severity"] != "INFO" || got["logger"] != "jsonkeys" {
+		t.Errorf("Unexpected values in %s", content)
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"plain", "tab\there", "ctrl\x01", "quote\"back\\slash", "héllo", "bad\xffutf8"} {
+		expected, _ := json.Marshal(s)
+		var decoded, want string
+		if err := json.Unmarshal(appendJSONString(nil, s), &decoded); err != nil {
+			t.Errorf("appendJSONString(%q) produced invalid JSON: %v", s, err)
+			continue
+		}
+		json.Unmarshal(expected, &want)
+		if decoded != want {
+			t.Errorf("appendJSONString(%q) decodes to %q, want %q", s, decoded, want)
+		}
+	}
+}
+
+func TestTextFormatterFoldLines(t *testing.T) {
+	entry := &LogEntry{
+		Level:     ERROR,
+		Message:   "panic: boom\r\ngoroutine 1 [running]:\nmain.main()\n",
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Fields:    map[string]interface{}{"request_id": 42},
+	}
+
+	got := string((&TextFormatter{TimestampFormat: time.DateTime, FoldLines: true}).Format(nil, entry))
+	want := "[2024-05-01 12:00:00] ERROR: panic: boom | request_id=42\n" +
+		"    | goroutine 1 [running]:\n" +
+		"    | main.main()"
+	if got != want {
+		t.Errorf("Folded output:\n%s\nwant:\n%s", got, want)
+	}
+
+	header, err := ParseLine(strings.SplitN(got, "\n", 2)[0], time.DateTime)
+	if err != nil || header.Message != "panic: boom" || header.Fields["request_id"] != "42" {
+		t.Errorf("Expected the header line to parse, got %+v, %v", header, err)
+	}
+
+	single := &LogEntry{Level: INFO, Message: "one line", Timestamp: entry.Timestamp}
+	f := &TextFormatter{TimestampFormat: time.DateTime, FoldLines: true, ContinuationPrefix: "\t> "}
+	if got := string(f.Format(nil, single)); got != "[2024-05-01 12:00:00] INFO: one line" {
+		t.Errorf("Single-line message changed: %q", got)
+	}
+	if got := string(f.Format(nil, &LogEntry{Level: INFO, Message: "a\nb", Timestamp: entry.Timestamp})); !strings.HasSuffix(got, "a\n\t> b") {
+		t.Errorf("Expected the custom prefix, got %q", got)
+	}
+}
+
+func TestTextFormatterLevelStyle(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		style LevelStyle
+		pad   bool
+		level LogLevel
+		want  string
+	}{
+		{LevelColon, false, INFO, "[2024-05-01 12:00:00] INFO: ready"},
+		{LevelColon, true, INFO, "[2024-05-01 12:00:00] INFO:  ready"},
+		{LevelColon, true, ERROR, "[2024-05-01 12:00:00] ERROR: ready"},
+		{LevelBracket, false, INFO, "[2024-05-01 12:00:00] [INFO] ready"},
+		{LevelBracket, true, INFO, "[2024-05-01 12:00:00] [INFO]  ready"},
+		{LevelPlain, true, INFO, "[2024-05-01 12:00:00] INFO  ready"},
+		{LevelPlain, true, DEBUG, "[2024-05-01 12:00:00] DEBUG ready"},
+	}
+	for _, tt := range tests {
+		f := &TextFormatter{TimestampFormat: time.DateTime, LevelStyle: tt.style, PadLevels: tt.pad}
+		got := string(f.Format(nil, &LogEntry{Level: tt.level, Message: "ready", Timestamp: ts}))
+		if got != tt.want {
+			t.Errorf("style %d, pad %v: got %q, want %q", tt.style, tt.pad, got, tt.want)
+			continue
+		}
+		entry, err := ParseLine(got, time.DateTime)
+		if err != nil || entry.Level != tt.level || entry.Message != "ready" {
+			t.Errorf("Failed to parse %q back: %+v, %v", got, entry, err)
+		}
+	}
+
+	f := &TextFormatter{TimestampFormat: time.DateTime, PadLevels: true, LevelNames: map[LogLevel]string{INFO: "INFORMATION"}}
+	if got := string(f.Format(nil, &LogEntry{Level: ERROR, Message: "x", Timestamp: ts})); got != "[2024-05-01 12:00:00] ERROR:       x" {
+		t.Errorf("Expected padding to the longest localized name, got %q", got)
+	}
+}
+
+func TestTextFormatterAllocs(t *testing.T) {
+	f := &TextFormatter{TimestampFormat: time.DateTime, PadLevels: true}
+	entry := &LogEntry{
+		Level:     INFO,
+		Message:   "request served",
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Fields:    map[string]interface{}{"path": "/orders", "ratio": 0.5},
+	}
+	buf := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = f.Format(buf[:0], entry)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected Format to write into the buffer without allocating, got %v allocations", allocs)
+	}
+}
@@ -0,0 +1,123 @@
+package log4
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is the file system holding the log files (see Config.FS). Its methods
+// behave like the functions of package os of the same name, returning errors
+// that errors.Is matches against fs.ErrNotExist and the like, so that a test
+// can fail any of them or keep the files in memory.
+type FS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+
+	// SameFile reports whether a and b, returned by Stat of this FS or of
+	// its Files, describe the same file
+	SameFile(a, b os.FileInfo) bool
+}
+
+// File is a file opened by an FS; *os.File implements it
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+}
+
+// OSFS is the FS of the operating system, used unless Config.FS is set
+type OSFS struct{}
+
+// OpenFile calls os.OpenFile
+func (OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err // Not a File holding a nil *os.File
+	}
+	return f, nil
+}
+
+// Stat calls os.Stat
+func (OSFS) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+// Lstat calls os.Lstat
+func (OSFS) Lstat(name string) (os.FileInfo, error) { return os.Lstat(name) }
+
+// ReadDir calls os.ReadDir
+func (OSFS) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+
+// MkdirAll calls os.MkdirAll
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+// Rename calls os.Rename
+func (OSFS) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+// Remove calls os.Remove
+func (OSFS) Remove(name string) error { return os.Remove(name) }
+
+// RemoveAll calls os.RemoveAll
+func (OSFS) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+// SameFile calls os.SameFile
+func (OSFS) SameFile(a, b os.FileInfo) bool { return os.SameFile(a, b) }
+
+// fileSystem returns the configured FS
+func (c *Config) fileSystem() FS {
+	if c.FS == nil {
+		return OSFS{}
+	}
+	return c.FS
+}
+
+// walkDir is filepath.WalkDir over fsys
+func walkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDirEntry(fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil // Skip the directory, not its siblings
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Second call, to report the error of ReadDir
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkDirEntry(fsys, filepath.Join(path, e.Name()), e, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package log4
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// faultyFS fails the operations of OSFS whose error is set
+type faultyFS struct {
+	OSFS
+	mu        sync.Mutex
+	openErr   error
+	writeErr  error
+	renameErr error
+}
+
+func (f *faultyFS) fault(err *error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *err
+}
+
+func (f *faultyFS) set(err *error, v error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*err = v
+}
+
+func (f *faultyFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if err := f.fault(&f.openErr); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	file, err := f.OSFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{File: file, fs: f}, nil
+}
+
+func (f *faultyFS) Rename(oldpath, newpath string) error {
+	if err := f.fault(&f.renameErr); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return f.OSFS.Rename(oldpath, newpath)
+}
+
+type faultyFile struct {
+	File
+	fs *faultyFS
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if err := f.fs.fault(&f.fs.writeErr); err != nil {
+		return 0, &fs.PathError{Op: "write", Path: f.Name(), Err: err}
+	}
+	return f.File.Write(p)
+}
+
+func TestFSFaults(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	var errs []error
+	fsys := &faultyFS{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.FS = fsys
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	flush := func() []error {
+		t.Helper()
+		logger.Flush()
+		logger.do(func() {}) // Errors raised by the worker are queued by now
+		mu.Lock()
+		defer mu.Unlock()
+		got := errs
+		errs = nil
+		return got
+	}
+	waitErr := func(target error) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			for _, err := range flush() {
+				if errors.Is(err, target) {
+					return
+				}
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Expected an error matching %v", target)
+	}
+
+	// Permission denied: entries of the package are discarded
+	fsys.set(&fsys.openErr, fs.ErrPermission)
+	logger.Info("denied", "Not written")
+	waitErr(fs.ErrPermission)
+	fsys.set(&fsys.openErr, nil)
+
+	// Disk full
+	if err := logger.TryInfo("app", "Written"); err != nil {
+		t.Fatal(err)
+	}
+	fsys.set(&fsys.writeErr, syscall.ENOSPC)
+	logger.Info("app", "No space")
+	waitErr(syscall.ENOSPC)
+	fsys.set(&fsys.writeErr, nil)
+
+	// Rename failure while rotating
+	fsys.set(&fsys.renameErr, syscall.EACCES)
+	logger.Rotate()
+	waitErr(&ErrRotation{})
+	fsys.set(&fsys.renameErr, nil)
+	logger.Info("app", "After")
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "app.log")); countLines(content) != 2 {
+		t.Errorf("Expected the entries written around the faults, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "app.log.1")); err == nil {
+		t.Error("Expected the failed rename to leave the file in place")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "denied.log")); err == nil {
+		t.Error("Expected no file for the denied package")
+	}
+}
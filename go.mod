@@ -1,3 +1,5 @@
 module github.com/MhunterDev/log4
 
 go 1.24.3
+
+require github.com/klauspost/compress v1.18.0
@@ -0,0 +1,116 @@
+package log4
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// HeaderPrefix starts the header line written with Config.FileHeader
+const HeaderPrefix = "#log4 "
+
+// HeaderVersion is the version of the header format written by this package
+const HeaderVersion = 1
+
+// Format names reported in FileHeader.Format
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatCLF      = "clf"      // CLFFormatter
+	FormatCombined = "combined" // CLFFormatter with Combined
+	FormatW3C      = "w3c"      // W3CFormatter
+	FormatCEF      = "cef"      // CEFFormatter
+	FormatLEEF     = "leef"     // LEEFFormatter
+	FormatCustom   = "custom"   // A Formatter of the application
+)
+
+// FileHeader describes the layout of a log file. With Config.FileHeader it is
+// written as the first line of every new file, as HeaderPrefix followed by
+// its JSON encoding:
+//
+//	#log4 {"log4":1,"format":"text","timestamp_format":"2006-01-02 15:04:05","host":"web-1","started":"2024-05-01T12:00:00Z"}
+type FileHeader struct {
+	Version         int               `json:"log4"`                       // HeaderVersion of the writer
+	Format          string            `json:"format"`                     // One of the Format constants or a SchemaPreset
+	TimestampFormat string            `json:"timestamp_format,omitempty"` // Layout of the timestamps
+	Keys            map[string]string `json:"keys,omitempty"`             // JSON keys, by DefaultKeyTime etc.
+	Host            string            `json:"host"`
+	Started         time.Time         `json:"started"` // When the file was created
+}
+
+// ParseHeader parses a header line written with Config.FileHeader
+func ParseHeader(line string) (*FileHeader, error) {
+	data, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), HeaderPrefix)
+	if !ok {
+		return nil, fmt.Errorf("not a header line: %q", line)
+	}
+	var h FileHeader
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		return nil, fmt.Errorf("invalid header line: %w", err)
+	}
+	return &h, nil
+}
+
+// IsHeaderLine reports whether line is a header written with
+// Config.FileHeader, which parsers of entries should skip
+func IsHeaderLine(line string) bool {
+	return strings.HasPrefix(line, HeaderPrefix)
+}
+
+// fileHeader describes the files written with formatter
+func fileHeader(formatter Formatter) FileHeader {
+	h := FileHeader{Version: HeaderVersion, Format: FormatCustom}
+	h.Host, _ = os.Hostname()
+
+	switch f := formatter.(type) {
+	case *TextFormatter:
+		h.Format = FormatText
+		h.TimestampFormat = f.TimestampFormat
+	case *JSONFormatter:
+		h.Format = FormatJSON
+		h.TimestampFormat = f.TimestampFormat
+		if h.TimestampFormat == "" {
+			h.TimestampFormat = time.RFC3339Nano
+		}
+		h.Keys = map[string]string{
+			DefaultKeyTime:    keyOrDefault(f.KeyTime, DefaultKeyTime),
+			DefaultKeyLevel:   keyOrDefault(f.KeyLevel, DefaultKeyLevel),
+			DefaultKeyMessage: keyOrDefault(f.KeyMessage, DefaultKeyMessage),
+			DefaultKeyPackage: keyOrDefault(f.KeyPackage, DefaultKeyPackage),
+		}
+	case *ECSFormatter:
+		h.Format = SchemaECS
+	case *DatadogFormatter:
+		h.Format = SchemaDatadog
+	case *GCPFormatter:
+		h.Format = SchemaGCP
+	case *CLFFormatter:
+		h.Format = FormatCLF
+		h.TimestampFormat = CLFTimestampFormat
+		if f.Combined {
+			h.Format = FormatCombined
+		}
+	case *W3CFormatter:
+		h.Format = FormatW3C
+	case *CEFFormatter:
+		h.Format = FormatCEF
+	case *LEEFFormatter:
+		h.Format = FormatLEEF
+		h.TimestampFormat = LEEFTimestampFormat
+	}
+	return h
+}
+
+// headerLine returns the header line of the file of a file key created at
+// started
+func (cl *ChannelLogger) headerLine(key string, started time.Time) string {
+	h := cl.header
+	if s := cl.settingsFor(key); s != nil {
+		h = s.header
+	}
+	h.Started = started
+	data, _ := json.Marshal(h)
+	return HeaderPrefix + string(data) + cl.config.recordSeparator()
+}
@@ -0,0 +1,61 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileHeader(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.FileHeader = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("app", "first")
+	logger.Flush()
+	if err := logger.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	logger.Info("app", "second")
+	logger.Close()
+
+	for _, name := range []string{"app.log", "app.log.1"} {
+		lines := strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, name))), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected a header and an entry in %s, got %q", name, lines)
+		}
+		if !IsHeaderLine(lines[0]) {
+			t.Fatalf("Expected %s to start with a header, got %q", name, lines[0])
+		}
+		h, err := ParseHeader(lines[0])
+		if err != nil {
+			t.Fatalf("ParseHeader failed: %v", err)
+		}
+		if h.Version != HeaderVersion || h.Format != FormatText || h.TimestampFormat != config.TimestampFormat || h.Started.IsZero() {
+			t.Errorf("Unexpected header %+v", h)
+		}
+		if _, err := ParseLine(lines[1], config.TimestampFormat); err != nil {
+			t.Errorf("Failed to parse entry after header: %v", err)
+		}
+	}
+
+	if _, err := ParseHeader("[2024-05-01 12:00:00] INFO: hello"); err == nil {
+		t.Error("Expected ParseHeader to reject an entry")
+	}
+}
+
+func TestFileHeaderJSONKeys(t *testing.T) {
+	h := fileHeader(&JSONFormatter{KeyMessage: "message"})
+	if h.Format != FormatJSON || h.Keys[DefaultKeyMessage] != "message" || h.Keys[DefaultKeyTime] != DefaultKeyTime {
+		t.Errorf("Unexpected header %+v", h)
+	}
+	if h := fileHeader(&ECSFormatter{}); h.Format != SchemaECS {
+		t.Errorf("Expected format %q, got %q", SchemaECS, h.Format)
+	}
+}
@@ -0,0 +1,17 @@
+package log4
+
+import "runtime/pprof"
+
+// addPprofLabels copies the profiler labels of the entry's context into its
+// fields without replacing fields that are already set.
+//
+// Go only exposes the labels of a goroutine through the context passed to
+// pprof.Do, so entries logged without that context carry no labels.
+func addPprofLabels(entry *LogEntry) {
+	pprof.ForLabels(entry.Context, func(key, value string) bool {
+		if _, exists := entry.Fields[key]; !exists {
+			entry.Fields[key] = value
+		}
+		return true
+	})
+}
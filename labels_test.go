@@ -0,0 +1,48 @@
+package log4
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+func TestPprofLabels(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.PprofLabels = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	pprof.Do(context.Background(), pprof.Labels("worker", "ingest", "pkg", "label"), func(ctx context.Context) {
+		logger.LogWithContext(ctx, "jobs", "INFO", "Batch done")
+		logger.Package("jobs").log(ctx, INFO, "Explicit", map[string]interface{}{"worker": "manual"})
+	})
+	logger.LogWithContext(context.Background(), "jobs", "INFO", "Unlabeled")
+	logger.Close()
+
+	if len(sink.entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(sink.entries))
+	}
+	if f := sink.entries[0].Fields; f["worker"] != "ingest" || f["pkg"] != "label" {
+		t.Errorf("Expected pprof labels in fields, got %v", f)
+	}
+	if f := sink.entries[1].Fields; f["worker"] != "manual" {
+		t.Errorf("Explicit field should win over label, got %v", f)
+	}
+	if f := sink.entries[2].Fields; len(f) != 0 {
+		t.Errorf("Unlabeled entry should have no fields, got %v", f)
+	}
+
+	content := readFile(t, filepath.Join(tempDir, "jobs.log"))
+	if !strings.Contains(content, "worker=ingest") {
+		t.Errorf("Expected label in log file:\n%s", content)
+	}
+}
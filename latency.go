@@ -0,0 +1,102 @@
+package log4
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the buckets of Stats.Latency
+var LatencyBuckets = []time.Duration{
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// LatencyHistogram counts entries by the time from being queued to being
+// written to every output, the delay between an entry's timestamp and its
+// appearance in the files
+type LatencyHistogram struct {
+	Buckets []LatencyBucket `json:"buckets"`
+	Count   uint64          `json:"count"`  // Entries observed, including those slower than the last bucket
+	Sum     time.Duration   `json:"sum_ns"` // Total latency of the entries observed
+}
+
+// LatencyBucket counts the entries written within Le, cumulatively as in a
+// Prometheus histogram
+type LatencyBucket struct {
+	Le    time.Duration `json:"le_ns"`
+	Count uint64        `json:"count"`
+}
+
+// Quantile returns the upper bound of the bucket holding the q-quantile,
+// e.g. 0.99, or -1 if it is beyond the last bucket or nothing was observed
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return -1
+	}
+	rank := max(uint64(math.Ceil(q*float64(h.Count))), 1)
+	for _, b := range h.Buckets {
+		if b.Count >= rank {
+			return b.Le
+		}
+	}
+	return -1
+}
+
+// latencies accumulates the histogram of Config.LatencyHistogram. It
+// is written by the logging goroutine and read by Stats.
+type latencies struct {
+	buckets []atomic.Uint64 // One per LatencyBuckets, not cumulative
+	count   atomic.Uint64
+	sum     atomic.Int64
+}
+
+func newLatencies() *latencies {
+	return &latencies{buckets: make([]atomic.Uint64, len(LatencyBuckets))}
+}
+
+// observe records the latency of an entry queued at queued
+func (r *latencies) observe(queued time.Time) {
+	if queued.IsZero() {
+		return // Written without passing the queue
+	}
+	d := time.Since(queued)
+	for i, le := range LatencyBuckets {
+		if d <= le {
+			r.buckets[i].Add(1)
+			break
+		}
+	}
+	r.count.Add(1)
+	r.sum.Add(int64(d))
+}
+
+// snapshot returns the histogram observed so far
+func (r *latencies) snapshot() *LatencyHistogram {
+	h := &LatencyHistogram{
+		Buckets: make([]LatencyBucket, len(LatencyBuckets)),
+		Count:   r.count.Load(),
+		Sum:     time.Duration(r.sum.Load()),
+	}
+	var cumulative uint64
+	for i, le := range LatencyBuckets {
+		cumulative += r.buckets[i].Load()
+		h.Buckets[i] = LatencyBucket{Le: le, Count: cumulative}
+	}
+	return h
+}
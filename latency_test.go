@@ -0,0 +1,70 @@
+package log4
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.LatencyHistogram = true
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	for i := 0; i < 10; i++ {
+		logger.Info("app", "entry")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	h := logger.Stats().Latency
+	if h == nil || h.Count != 10 || h.Sum <= 0 {
+		t.Fatalf("Expected 10 observations, got %+v", h)
+	}
+	if len(h.Buckets) != len(LatencyBuckets) || h.Buckets[len(h.Buckets)-1].Count != 10 {
+		t.Errorf("Expected cumulative buckets, got %+v", h.Buckets)
+	}
+	if q := h.Quantile(0.5); q <= 0 || q > time.Second {
+		t.Errorf("Unexpected median %v", q)
+	}
+
+	body := adminRequest(t, logger.AdminHandler(), "GET", "/metrics").Body.String()
+	for _, want := range []string{
+		"log4_written_total 10\n",
+		"# TYPE log4_write_latency_seconds histogram\n",
+		`log4_write_latency_seconds_bucket{le="1e-05"} `,
+		`log4_write_latency_seconds_bucket{le="+Inf"} 10` + "\n",
+		"log4_write_latency_seconds_count 10\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, body)
+		}
+	}
+
+	plain := NewChannelLogger(10, tempDir)
+	defer plain.Close()
+	if plain.Stats().Latency != nil {
+		t.Error("Expected no histogram unless LatencyHistogram is set")
+	}
+}
+
+func TestLatencyQuantile(t *testing.T) {
+	h := &LatencyHistogram{
+		Buckets: []LatencyBucket{{time.Millisecond, 90}, {10 * time.Millisecond, 99}, {time.Second, 99}},
+		Count:   100,
+	}
+	if q := h.Quantile(0.9); q != time.Millisecond {
+		t.Errorf("p90 = %v", q)
+	}
+	if q := h.Quantile(0.99); q != 10*time.Millisecond {
+		t.Errorf("p99 = %v", q)
+	}
+	if q := h.Quantile(1); q != -1 {
+		t.Errorf("Expected -1 beyond the last bucket, got %v", q)
+	}
+}
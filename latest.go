@@ -0,0 +1,50 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LatestSuffix is appended to the package name of the links maintained with
+// Config.LatestLinks, e.g. "app.latest.log". Package names are sanitized to
+// letters, digits, '_' and '-', so no package file can take a link's name.
+const LatestSuffix = ".latest"
+
+// latestLinkName returns the path of the latest link of an active file
+func latestLinkName(fileName string) string {
+	dir, name := filepath.Split(fileName)
+	if idx := strings.Index(name, ".log"); idx > 0 {
+		return dir + name[:idx] + LatestSuffix + name[idx:]
+	}
+	return fileName + LatestSuffix
+}
+
+// IsLatestLink reports whether path names a link maintained with
+// Config.LatestLinks, which tools listing log files should skip
+func IsLatestLink(path string) bool {
+	return strings.HasSuffix(PackageFromFileName(path), LatestSuffix)
+}
+
+// updateLatestLink points the latest link of a package at its active file.
+// The link is replaced atomically; where symbolic links cannot be created,
+// as on Windows without the privilege, a hard link is used instead, which is
+// replaced again whenever the file is reopened after a rotation.
+func (cl *ChannelLogger) updateLatestLink(fileName string) {
+	link := latestLinkName(fileName)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+
+	err := os.Symlink(filepath.Base(fileName), tmp)
+	if err != nil {
+		err = os.Link(fileName, tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, link)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		cl.handleError(fmt.Errorf(ErrLatestLink, link, err))
+	}
+}
@@ -0,0 +1,74 @@
+package log4
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLatestLinks(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.LatestLinks = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("app", "before rotation")
+	logger.Flush()
+	if err := logger.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	logger.Info("app", "after rotation")
+	logger.Close()
+
+	link := filepath.Join(tempDir, "app.latest.log")
+	if target, err := os.Readlink(link); err == nil && target != "app.log" {
+		t.Errorf("Expected the link to point at app.log, got %q", target)
+	}
+	content, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatalf("Failed to read latest link: %v", err)
+	}
+	if !strings.Contains(string(content), "after rotation") || strings.Contains(string(content), "before rotation") {
+		t.Errorf("Expected the link to show the active file, got %q", content)
+	}
+
+	if !IsLatestLink(link) || IsLatestLink(filepath.Join(tempDir, "app.log")) {
+		t.Error("IsLatestLink misclassified a file")
+	}
+	if got := latestLinkName("logs/app.log.gz"); got != "logs/app.latest.log.gz" {
+		t.Errorf("latestLinkName() = %q", got)
+	}
+}
+
+func TestLatestLinksPackageNamedLikeLink(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.LatestLinks = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("app-latest", "own file")
+	logger.Info("app", "linked file")
+	logger.Info("app.latest", "sanitized name")
+	logger.Close()
+
+	own := filepath.Join(tempDir, "app-latest.log")
+	if content := readFile(t, own); !strings.Contains(content, "own file") || strings.Contains(content, "linked file") {
+		t.Errorf("Expected the package file to keep its entries, got %q", content)
+	}
+	if IsLatestLink(own) || IsLatestLink(filepath.Join(tempDir, "app_latest.log")) {
+		t.Error("IsLatestLink mistook a package file for a link")
+	}
+	if content := readFile(t, filepath.Join(tempDir, "app.latest.log")); !strings.Contains(content, "linked file") {
+		t.Errorf("Expected the link to show app.log, got %q", content)
+	}
+}
@@ -0,0 +1,46 @@
+package log4
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// logStartup logs the startup entry of Config.Lifecycle
+func (cl *ChannelLogger) logStartup() {
+	host, _ := os.Hostname()
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+
+	fields := map[string]interface{}{
+		"version":     version,
+		"go_version":  runtime.Version(),
+		"pid":         os.Getpid(),
+		"host":        host,
+		"log_dir":     cl.config.LogDir,
+		"min_level":   cl.config.MinLevel.String(),
+		"buffer_size": cl.config.BufferSize,
+		"max_size":    cl.config.MaxFileSize,
+		"max_files":   cl.config.MaxFiles,
+		"sinks":       len(cl.sinks),
+	}
+	if cl.config.Compression != nil {
+		fields["compression"] = cl.config.Compression.Extension()
+	}
+	cl.logNotice("logger started", fields)
+}
+
+// logShutdown logs the shutdown entry of Config.Lifecycle
+func (cl *ChannelLogger) logShutdown() {
+	cl.logNotice("logger stopping", map[string]interface{}{
+		"pid":       os.Getpid(),
+		"uptime_ms": durationMillis(time.Since(cl.started)),
+		"written":   cl.written.Load(),
+		"queued":    len(cl.logChan),
+		"dropped":   cl.dropped.Load(),
+		"errors":    cl.errCount.Load(),
+	})
+}
@@ -0,0 +1,77 @@
+package log4
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLifecycleEntries(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Lifecycle = true
+	config.DisableConsole = true // The startup entry is written before stdout could be replaced
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "working")
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, InternalPackage+".log"))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a startup and a shutdown entry, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	start, err := ParseLine(lines[0], config.TimestampFormat)
+	if err != nil || start.Message != "logger started" {
+		t.Fatalf("Unexpected startup entry %q: %v", lines[0], err)
+	}
+	if start.Fields["pid"] != strconv.Itoa(os.Getpid()) || start.Fields["log_dir"] != tempDir || start.Fields["min_level"] != "DEBUG" {
+		t.Errorf("Unexpected startup fields %v", start.Fields)
+	}
+
+	stop, err := ParseLine(lines[1], config.TimestampFormat)
+	if err != nil || stop.Message != "logger stopping" {
+		t.Fatalf("Unexpected shutdown entry %q: %v", lines[1], err)
+	}
+	for _, key := range []string{"uptime_ms", "written", "queued", "dropped"} {
+		if _, ok := stop.Fields[key]; !ok {
+			t.Errorf("Expected %s in shutdown fields %v", key, stop.Fields)
+		}
+	}
+}
+
+func TestLifecycleConcurrentClose(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Lifecycle = true
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			logger.Close()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, InternalPackage+".log"))
+	if n := strings.Count(content, "logger stopping"); n != 1 {
+		t.Errorf("Expected a single shutdown entry, got %d:\n%s", n, content)
+	}
+}
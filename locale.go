@@ -0,0 +1,93 @@
+package log4
+
+import (
+	"strings"
+	"time"
+)
+
+// TimeNames holds the month and weekday names written for the January, Jan,
+// Monday and Mon elements of a timestamp layout, for locales other than
+// English. Names left empty are written in English.
+type TimeNames struct {
+	Months      [12]string // January first
+	ShortMonths [12]string
+	Days        [7]string // Sunday first, as time.Weekday
+	ShortDays   [7]string
+}
+
+// GermanTimeNames holds the German month and weekday names
+var GermanTimeNames = &TimeNames{
+	Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	ShortMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+	Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	ShortDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+}
+
+// formatTime formats t like t.Format(layout), writing the names of n. A nil
+// TimeNames formats in English.
+func (n *TimeNames) formatTime(t time.Time, layout string) string {
+	if n == nil || !strings.ContainsAny(layout, "JM") {
+		return t.Format(layout)
+	}
+
+	var sb strings.Builder
+	for layout != "" {
+		name, rest, ok := n.nextName(t, layout)
+		if !ok {
+			// Format the plain part up to the next name element in one go
+			i := 1
+			for i < len(layout) {
+				if _, _, ok := n.nextName(t, layout[i:]); ok {
+					break
+				}
+				i++
+			}
+			sb.WriteString(t.Format(layout[:i]))
+			layout = layout[i:]
+			continue
+		}
+		sb.WriteString(name)
+		layout = rest
+	}
+	return sb.String()
+}
+
+// appendTime appends t formatted like formatTime
+func (n *TimeNames) appendTime(dst []byte, t time.Time, layout string) []byte {
+	if n == nil || !strings.ContainsAny(layout, "JM") {
+		return t.AppendFormat(dst, layout)
+	}
+	return append(dst, n.formatTime(t, layout)...)
+}
+
+// nextName returns the localized name if layout starts with a name element,
+// and the layout following it
+func (n *TimeNames) nextName(t time.Time, layout string) (name, rest string, ok bool) {
+	for _, e := range []struct {
+		elem  string
+		names []string
+		i     int
+	}{
+		// Long forms first, so that "January" is not taken for "Jan"
+		{"January", n.Months[:], int(t.Month()) - 1},
+		{"Jan", n.ShortMonths[:], int(t.Month()) - 1},
+		{"Monday", n.Days[:], int(t.Weekday())},
+		{"Mon", n.ShortDays[:], int(t.Weekday())},
+	} {
+		if rest, ok := strings.CutPrefix(layout, e.elem); ok {
+			if e.names[e.i] == "" {
+				return t.Format(e.elem), rest, true
+			}
+			return e.names[e.i], rest, true
+		}
+	}
+	return "", layout, false
+}
+
+// levelName returns the name of level in names, or its English name
+func levelName(names map[LogLevel]string, level LogLevel) string {
+	if name, ok := names[level]; ok {
+		return name
+	}
+	return level.String()
+}
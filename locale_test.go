@@ -0,0 +1,57 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeNames(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC) // A Tuesday
+
+	tests := []struct {
+		names  *TimeNames
+		layout string
+		want   string
+	}{
+		{GermanTimeNames, "Monday, 02. January 2006 15:04", "Dienstag, 05. März 2024 14:30"},
+		{GermanTimeNames, "Mon 02 Jan 2006 MST", "Di 05 Mär 2024 UTC"},
+		{GermanTimeNames, time.DateTime, "2024-03-05 14:30:00"},
+		{&TimeNames{Months: [12]string{2: "marzo"}}, "Jan January Mon", "Mar marzo Tue"},
+		{nil, "Mon Jan 2", "Tue Mar 5"},
+	}
+	for _, tt := range tests {
+		if got := tt.names.formatTime(ts, tt.layout); got != tt.want {
+			t.Errorf("formatTime(%q) = %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizedOutput(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.TimestampFormat = "02. Jan 2006"
+	config.LevelNames = map[LogLevel]string{ERROR: "FEHLER", INFO: "INFO"}
+	config.TimeNames = GermanTimeNames
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Error("app", "Verbindung verloren")
+	logger.Debug("app", "Details")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "app.log"))
+	if !strings.Contains(content, "FEHLER: Verbindung verloren") || !strings.Contains(content, "DEBUG: Details") {
+		t.Errorf("Expected localized level names, got:\n%s", content)
+	}
+
+	json := string((&JSONFormatter{LevelNames: config.LevelNames}).Format(nil, &LogEntry{Level: ERROR, Timestamp: time.Now()}))
+	if !strings.Contains(json, `"level":"FEHLER"`) {
+		t.Errorf("Expected the JSON level to be localized, got %s", json)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package log4
+
+import "os"
+
+// lockFile does nothing on platforms without flock; AppendOnly still refuses
+// to rotate and reopens replaced files
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// lockPath does nothing on platforms without flock, so processes sharing
+// files may rotate them concurrently
+func lockPath(path string, mode os.FileMode) (unlock func(), err error) {
+	return func() {}, nil
+}
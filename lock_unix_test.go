@@ -0,0 +1,48 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package log4
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAppendOnlyLock(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// Another writer holds the lock
+	other, err := os.OpenFile(filepath.Join(tempDir, "audit.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer other.Close()
+	if err := lockFile(other); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	var locked atomic.Int32
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.AppendOnly = true
+	config.ErrorHandler = func(err error) {
+		if strings.Contains(err.Error(), "locked by another process") {
+			locked.Add(1)
+		}
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.Info("audit", "Clobber")
+	logger.Close()
+
+	if locked.Load() != 1 {
+		t.Error("Expected the lock conflict to be reported")
+	}
+	if content := readFile(t, filepath.Join(tempDir, "audit.log")); content != "" {
+		t.Errorf("Locked file should not be written, got %q", content)
+	}
+}
@@ -22,12 +22,16 @@ package log4
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -43,6 +47,13 @@ const (
 	MaxPackageNameLen  = 100
 	DefaultMaxFileSize = 100 * 1024 * 1024 // 100MB
 	DefaultMaxFiles    = 5
+
+	// DefaultErrorBufferSize is the number of errors queued for
+	// Config.ErrorHandler unless ErrorBufferSize is set
+	DefaultErrorBufferSize = 10
+
+	// InternalPackage receives notices about the logger itself
+	InternalPackage = "_log4"
 )
 
 // Error message templates for consistency
@@ -53,6 +64,29 @@ const (
 	ErrInvalidBufferSize = "buffer size must be positive, got %d"
 	ErrEmptyTimestamp    = "timestamp format cannot be empty"
 	ErrInvalidPackage    = "package name cannot be empty or contain invalid characters"
+	ErrLoggerClosed      = "logger is closed"
+	ErrEntryReleased     = "log4: LogEntry used after its last Release"
+	ErrDuplicateFieldKey = "field key %q is used for more than one JSON field"
+	ErrUnknownSchema     = "unknown schema preset %q"
+	ErrInvalidTenant     = "tenant name cannot be empty"
+	ErrTenantQuota       = "tenant %s exceeded its quota of %d bytes, dropping entries"
+	ErrUnknownLevel      = "unknown log level %q"
+	ErrInvalidFilter     = "invalid filter %q: %w"
+	ErrInvalidRoute      = "invalid route pattern %q: %w"
+	ErrLockLogFile       = "file is locked by another process: %w"
+	ErrAppendOnly        = "rotation is disabled in append-only mode"
+	ErrLogFileReplaced   = "log file %s was %s by another process, reopening"
+	ErrSharedFiles       = "SharedFiles cannot be combined with %s"
+	ErrInvalidQueue      = "invalid queue pattern %q: %w"
+	ErrPackageCollision  = "package %s differs from %s only in case and shares %s on case-insensitive file systems"
+	ErrLatestLink        = "failed to update latest link %s: %w"
+	ErrWriteHeader       = "failed to write header of %s: %w"
+	ErrManifest          = "failed to record %s in the manifest: %w"
+	ErrRepairRotation    = "failed to repair rotated file %s: %w"
+	ErrWriteLogFile      = "failed to write log file for package %s: %w"
+	ErrPackageConfig     = "invalid configuration of package %s: %w"
+	ErrExpirePartition   = "failed to remove expired partitions in %s: %w"
+	ErrUnconfirmed       = "entry of package %s was queued but not yet written when the context ended: %w"
 )
 
 type LogLevel int
@@ -76,14 +110,43 @@ func (l LogLevel) String() string {
 	}
 }
 
+// MarshalText encodes the level as its name
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level as accepted by ParseLogLevelStrict
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	level, err := ParseLogLevelStrict(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
 // LogEntry represents a structured log entry
 type LogEntry struct {
+	Tenant    string // Set for entries logged through a TenantLogger
 	Package   string
 	Level     LogLevel
 	Message   string
 	Fields    map[string]interface{}
 	Context   context.Context
 	Timestamp time.Time
+
+	// Labels identify the stream an entry belongs to, such as the service or
+	// the region, and take few distinct values, unlike Fields; backends such
+	// as Loki index them (see PackageLogger.WithLabel). Nil if there are none.
+	Labels map[string]string
+
+	refs   int32     // Pool references; 0 if not pooled, -1 once returned (see Retain)
+	queued time.Time // When the entry was queued, if Config.MaxEntryAge is set
+
+	// Receives the outcome of the entry for MustLog and the Try methods,
+	// which wait for it; must marks entries of MustLog, which skip filtering
+	ack  chan error
+	must bool
 }
 
 // Config holds configuration options for the logger
@@ -97,6 +160,328 @@ type Config struct {
 	MaxFileSize     int64
 	MaxFiles        int
 	ErrorHandler    func(error) // Optional error callback
+
+	// ErrorBufferSize is the number of errors queued for ErrorHandler
+	// (default: DefaultErrorBufferSize). Errors raised while it is full are
+	// written to ErrorOverflow instead and counted in Stats.ErrorOverflow.
+	ErrorBufferSize int
+	// ErrorOverflow receives the errors that do not fit in the queue of
+	// ErrorHandler, one line each (default: os.Stderr), e.g. a file opened
+	// for appending
+	ErrorOverflow io.Writer
+
+	// Compression compresses the per-package log files inline. Files get the
+	// codec's extension appended (e.g. "app.log.gz") and MaxFileSize counts
+	// bytes before compression.
+	Compression Codec
+	FrameSize   int // Uncompressed bytes per compressed frame (default: DefaultFrameSize)
+
+	// FS holds the log files (default: OSFS), e.g. a faulty or in-memory
+	// file system in tests. Custom RotationNamers rename the operating
+	// system's files themselves, and LatestLinks are only kept with OSFS.
+	FS FS
+
+	// Sinks receive every written entry in addition to the console and files
+	Sinks []Sink
+
+	// TLS secures the connections of the network sinks among Sinks, also
+	// behind FilterSink, that have no BatchOptions.TLS of their own, e.g. the
+	// client certificate of mutual TLS shared by all log endpoints. Dialer
+	// likewise opens their connections, e.g. through a proxy (see
+	// ProxyDialer). HTTP sinks given their own Client keep its settings.
+	TLS    *tls.Config
+	Dialer Dialer
+
+	// MaxEntryAge drops entries that waited in the queue for longer than this
+	// when the pipeline is backed up, rather than writing them long after the
+	// fact (default: 0, entries never expire). They are dropped with
+	// DropStale and counted in Stats.Stale.
+	MaxEntryAge time.Duration
+
+	// RecentEntries keeps the last N logged entries in memory for crash dumps
+	// and the admin handler (default: 0, disabled). With RecentBelowMinLevel
+	// entries filtered out by the minimum level are retained as well, so debug
+	// context is available in a dump even when debug logging is off.
+	RecentEntries       int
+	RecentBelowMinLevel bool
+
+	// FlightRecorderEntries buffers up to N entries per package that were
+	// filtered out by the minimum level (default: 0, disabled). When an error
+	// is logged for a package, its buffered entries from the preceding
+	// FlightRecorderWindow (default: unlimited) are written before the error,
+	// marked with the field flight_recorder=true.
+	FlightRecorderEntries int
+	FlightRecorderWindow  time.Duration
+
+	// Formatter renders entries (default: TextFormatter using TimestampFormat)
+	Formatter Formatter
+
+	// RecordSeparator ends every entry written to the console, the files and
+	// the sinks (default: DefaultRecordSeparator), e.g. "\r\n" or "\x00".
+	// Tail, Replay and the reader package read files written with the default
+	// only.
+	// EscapeNewlines writes line breaks within an entry, such as those of
+	// stack traces, as \n and \r so that every entry stays on one line.
+	// LengthPrefixSinks passes each entry to the sinks implementing
+	// LengthPrefixer, such as WriterSink, as a 4-byte big-endian length
+	// followed by the entry, without separator, for binary protocols.
+	RecordSeparator   string
+	EscapeNewlines    bool
+	LengthPrefixSinks bool
+
+	// LevelNames and TimeNames localize the level names and the month and
+	// weekday names of timestamps written by the default formatters, e.g.
+	// {ERROR: "FEHLER"} and GermanTimeNames. ParseLine, and with it Tail and
+	// Replay, only understand English names.
+	LevelNames map[LogLevel]string
+	TimeNames  *TimeNames
+
+	// SanitizeMessages escapes control characters, including line breaks and
+	// the ESC of ANSI escape sequences, in messages and string field values
+	// before they are recorded anywhere, so that user input cannot forge log
+	// lines or corrupt terminals. Tabs are kept.
+	SanitizeMessages bool
+
+	// Normalize rewrites the names and numeric values of fields into a
+	// uniform shape before entries are recorded anywhere, e.g. snake_case
+	// names and flattened maps (default: nil, fields are left alone)
+	Normalize *FieldNormalizer
+
+	// LevelStyle and PadLevels set how the default text layout delimits and
+	// pads level names, e.g. "[INFO]  message" with LevelBracket (see
+	// TextFormatter)
+	LevelStyle LevelStyle
+	PadLevels  bool
+
+	// FoldLines indents the continuation lines of multi-line messages under
+	// their header line in the default text layout (see TextFormatter).
+	// EscapeNewlines takes precedence.
+	FoldLines bool
+
+	// JSON writes entries as JSON objects when no Formatter is set. The
+	// FieldKey options rename the reserved keys (default: DefaultKeyTime,
+	// DefaultKeyLevel, DefaultKeyMessage and DefaultKeyPackage).
+	JSON            bool
+	FieldKeyTime    string
+	FieldKeyLevel   string
+	FieldKeyMsg     string
+	FieldKeyPackage string
+
+	// SchemaPreset selects a built-in formatter whose output follows a
+	// platform's schema when no Formatter is set: SchemaECS, SchemaDatadog or SchemaGCP
+	SchemaPreset string
+
+	// SecretFields makes the logger report, and optionally mask, fields whose
+	// names look like secrets but whose values are not wrapped in Secret
+	// (default: SecretFieldsAllow)
+	SecretFields SecretFieldPolicy
+
+	// FieldSchemas are checked against the fields of entries as they are
+	// written, keyed by event type (see Emit) or by package, the event type
+	// taking precedence. Violations are reported as *ErrSchemaViolation and
+	// counted in Stats; the entries are written regardless.
+	FieldSchemas map[string]*FieldSchema
+
+	// LogOnCancelledContext writes entries logged with a context that has
+	// already been cancelled or has expired, adding the reason as FieldContextError,
+	// so that the cancellation itself can be logged. When false such entries
+	// are dropped with DropCancelled. DefaultConfig sets it to true.
+	LogOnCancelledContext bool
+
+	// FieldEncoding sets how the built-in formatters write field values such
+	// as times, durations, errors and byte slices (see FieldEncoding)
+	FieldEncoding FieldEncoding
+
+	// OnDrop is called synchronously for every entry that is discarded instead
+	// of written, e.g. to count, sample or reroute dropped entries. The entry
+	// is reused after the call returns unless it is retained (see Retain).
+	OnDrop func(entry *LogEntry, reason DropReason)
+
+	// DropSummaries writes an entry to the file of each package that lost
+	// entries to overflow, sampling, throttling or MaxEntryAge at this
+	// interval, such as "dropped 523 entries between ... and ...", so that
+	// readers of the file know it is incomplete (default: 0, disabled)
+	DropSummaries time.Duration
+
+	// DeadLetter receives entries whose Write failed on one of the Sinks
+	DeadLetter *DeadLetter
+
+	// PprofLabels adds the profiler labels carried by an entry's context, as
+	// set by pprof.Do or pprof.WithLabels, to its fields. Fields set
+	// explicitly take precedence.
+	PprofLabels bool
+
+	// FlushOnContextDone flushes the files and sinks once the context of an
+	// entry logged with one is done, e.g. when a request ends, so that its
+	// entries reach disk promptly even with large compressed frames or sink
+	// batches. Entries still queued at that moment are written first.
+	FlushOnContextDone bool
+
+	// IDGenerator produces the correlation IDs attached by EnsureRequestID
+	// (default: DefaultIDGenerator, UUIDv7)
+	IDGenerator IDGenerator
+
+	// DisableConsole stops entries from being copied to stdout. ConsoleColor
+	// colors console lines by level; files and sinks are never colored.
+	DisableConsole bool
+	ConsoleColor   bool
+
+	// ConsoleWriter receives the console copy of entries instead of
+	// os.Stdout, e.g. a GUI's log view (default: os.Stdout); see
+	// SetConsoleWriter to change it while logging
+	ConsoleWriter io.Writer
+
+	// ConsoleLevel and FileLevel are the lowest levels copied to the console
+	// and written to the package files, applied after the package and global
+	// levels (default: DEBUG, everything that passes them). Sinks get their
+	// own threshold with LevelSink.
+	ConsoleLevel LogLevel
+	FileLevel    LogLevel
+
+	// Caller adds the file and line that logged each entry as FieldCaller
+	Caller bool
+
+	// GoroutineID adds the ID of the goroutine that logged each entry as
+	// FieldGoroutine. Go does not expose goroutine IDs, so they are parsed from
+	// a stack trace at every call; IDs are reused and only suited to telling
+	// concurrent goroutines apart. Prefer PackageLogger.Worker where IDs can
+	// be assigned.
+	GoroutineID bool
+
+	// Elapsed adds the time since ElapsedSince (default: when the logger was
+	// created) to each entry as FieldElapsed, e.g. "+12.345s", to compare
+	// startup sequences across machines regardless of their clocks. Use
+	// ProcessStart() to measure from the start of the process.
+	Elapsed      bool
+	ElapsedSince time.Time
+
+	// Sampling limits how often identical entries are written (default: nil,
+	// every entry is written)
+	Sampling *Sampling
+
+	// LatencyHistogram records how long each entry takes from being queued
+	// to being written, reported by Stats as Latency and by the admin
+	// handler's /metrics, e.g. to size BufferSize from data
+	LatencyHistogram bool
+
+	// Throttle drops or samples entries while writes are slow (default: nil,
+	// never throttled)
+	Throttle *Throttle
+
+	// Lifecycle logs an entry to InternalPackage when the logger starts, with
+	// the version of the main module, pid, host and a summary of the
+	// configuration, and when it is closed, with the uptime and the number of
+	// entries written, still queued and dropped until then
+	Lifecycle bool
+
+	// MaxWorkerPanics is how many panics of Formatters and Sinks the logging
+	// goroutine recovers from within WorkerPanicWindow before it stops
+	// calling them, dropping entries with DropPanic for the next
+	// WorkerPanicWindow (default: DefaultMaxWorkerPanics; negative: never).
+	// Every panic is reported to ErrorHandler as an *ErrWorkerPanic.
+	MaxWorkerPanics int
+
+	// CloseOnExit has Exit, Fatal and HandleSignals close the logger before
+	// the process exits, so that the entries still queued are written
+	CloseOnExit bool
+
+	// RuntimeMetrics logs process metrics to RuntimePackage at this interval
+	// while the logger is open (default: 0, disabled); see ReportRuntime
+	RuntimeMetrics time.Duration
+
+	// CounterInterval is how often the counts of PackageLogger.Count are
+	// logged (default: DefaultCounterInterval)
+	CounterInterval time.Duration
+
+	// Tenants holds the limits of individual tenants (see Tenant); tenants
+	// not listed use DefaultTenant
+	Tenants       map[string]TenantConfig
+	DefaultTenant TenantConfig
+
+	// AppendOnly protects the package files for write-once storage and
+	// against other processes: files are locked with flock where available,
+	// never rotated (Rotate reports an *ErrRotation), and reopened when
+	// another process renames, removes or truncates them.
+	AppendOnly bool
+
+	// SharedFiles lets several processes, such as pre-fork workers, write the
+	// same package files. Each entry is written with a single append, and
+	// rotation is coordinated through a lock file next to the log file
+	// ("app.lock"): whichever process reaches MaxFileSize first rotates, the
+	// others reopen the new file within a second. Cannot be combined with
+	// Compression or AppendOnly.
+	SharedFiles bool
+
+	// DryRun formats, filters and routes entries as usual but writes no
+	// files: LogDir is neither created nor written, while the console and
+	// sinks still receive every entry. Useful for benchmarking and for
+	// running the same code where disk writes are not allowed.
+	DryRun bool
+
+	// LatestLinks maintains a "<pkg>.latest.log" symbolic link next to each
+	// package file pointing at the active file, updated whenever a file is
+	// opened, so that tools need not rediscover the current file name
+	LatestLinks bool
+
+	// RotateOnStart rotates the file of a package written by a previous run
+	// when this process first writes to it, so that each file holds one run
+	// of the process. The previous file counts towards MaxFiles like any
+	// other rotated file. Ignored with AppendOnly; cannot be combined with
+	// SharedFiles.
+	RotateOnStart bool
+
+	// RotationNamer names rotated files (default: NumericNamer, "app.log.1"
+	// and up); TimestampNamer names them after the time of rotation instead
+	RotationNamer RotationNamer
+
+	// Manifest appends the SHA-256 and size of every rotated file to
+	// ManifestFile in LogDir, so that archived files can be checked with
+	// VerifyManifest. Rotated files are read in full when they are rotated.
+	Manifest bool
+
+	// FileHeader writes a FileHeader line, starting with HeaderPrefix, at the
+	// top of every new file: the format, timestamp layout and JSON keys,
+	// hostname and creation time, so that tools can detect the format of
+	// files found on disk. ParseHeader reads it back; Replay and the reader
+	// package skip it.
+	FileHeader bool
+
+	// Routes send the files of matching packages to other directories; the
+	// first matching route applies, other packages are written to LogDir
+	Routes []Route
+
+	// Packages overrides the level, timestamp format, rotation size and
+	// formatter of individual packages, keyed by package name
+	Packages map[string]PackageConfig
+
+	// DirLayout writes files into time-partitioned subdirectories of LogDir,
+	// of tenant directories and of route directories, such as
+	// "logs/2024/05/01/app.log" with DailyLayout. Files move to the next
+	// partition, whose directory is created then, at its first write after
+	// the boundary; rotation by MaxFileSize continues within a partition.
+	DirLayout DirLayout
+
+	// PartitionRetention removes partitions that started longer ago than
+	// this whenever a new partition begins (default: 0, kept)
+	PartitionRetention time.Duration
+
+	// PriorityBufferSize gives ERROR entries a queue of this size that is
+	// written before the others, so that a queue full of DEBUG and INFO
+	// entries does not cause errors to be dropped (default: 0, errors share
+	// the queue of their package). Errors only fall back to that queue while
+	// the priority queue is full.
+	PriorityBufferSize int
+
+	// MaxBufferSize lets the shared queue grow beyond BufferSize up to this
+	// many entries while bursts keep it full, instead of waiting and
+	// dropping; the room added shrinks again as occupancy falls (default: 0,
+	// the queue holds BufferSize entries)
+	MaxBufferSize int
+
+	// Queues give the packages matching their patterns queues of their own,
+	// with independent capacity and drop accounting; the first matching
+	// queue applies, other packages share the queue of BufferSize
+	Queues []Queue
 }
 
 // Validate checks if the configuration is valid
@@ -119,9 +504,89 @@ func (c *Config) Validate() error {
 	if c.DirMode == 0 {
 		c.DirMode = DefaultDirMode
 	}
+	if c.FrameSize <= 0 {
+		c.FrameSize = DefaultFrameSize
+	}
+	if c.ErrorBufferSize <= 0 {
+		c.ErrorBufferSize = DefaultErrorBufferSize
+	}
+	if c.SchemaPreset != "" {
+		if _, ok := formatterForPreset(c.SchemaPreset, c.FieldEncoding); !ok {
+			return fmt.Errorf(ErrUnknownSchema, c.SchemaPreset)
+		}
+	}
+	if c.SharedFiles && c.Compression != nil {
+		return fmt.Errorf(ErrSharedFiles, "Compression")
+	}
+	if c.SharedFiles && c.AppendOnly {
+		return fmt.Errorf(ErrSharedFiles, "AppendOnly")
+	}
+	if c.SharedFiles && c.RotateOnStart {
+		return fmt.Errorf(ErrSharedFiles, "RotateOnStart")
+	}
+	for _, r := range c.Routes {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return fmt.Errorf(ErrInvalidRoute, r.Pattern, err)
+		}
+	}
+	for _, q := range c.Queues {
+		if _, err := path.Match(q.Pattern, ""); err != nil {
+			return fmt.Errorf(ErrInvalidQueue, q.Pattern, err)
+		}
+	}
+	for pkg, p := range c.Packages {
+		if p.Level == "" {
+			continue
+		}
+		if _, err := ParseLogLevelStrict(p.Level); err != nil {
+			return fmt.Errorf(ErrPackageConfig, pkg, err)
+		}
+	}
+	return c.validateFieldKeys()
+}
+
+// validateFieldKeys rejects JSON key names that would collide
+func (c *Config) validateFieldKeys() error {
+	seen := make(map[string]bool, 4)
+	for _, key := range []string{
+		keyOrDefault(c.FieldKeyTime, DefaultKeyTime),
+		keyOrDefault(c.FieldKeyLevel, DefaultKeyLevel),
+		keyOrDefault(c.FieldKeyMsg, DefaultKeyMessage),
+		keyOrDefault(c.FieldKeyPackage, DefaultKeyPackage),
+	} {
+		if seen[key] {
+			return fmt.Errorf(ErrDuplicateFieldKey, key)
+		}
+		seen[key] = true
+	}
 	return nil
 }
 
+// DevelopmentConfig returns a configuration for local development: every
+// level, human-readable text, colored console output and caller information
+func DevelopmentConfig() *Config {
+	config := DefaultConfig()
+	config.MinLevel = DEBUG
+	config.ConsoleColor = true
+	config.Caller = true
+	return config
+}
+
+// ProductionConfig returns a configuration for production: INFO and above,
+// JSON lines, sampling of repeated entries and no copy on stdout
+func ProductionConfig() *Config {
+	config := DefaultConfig()
+	config.MinLevel = INFO
+	config.JSON = true
+	config.DisableConsole = true
+	config.Sampling = &Sampling{
+		Initial:    DefaultSamplingInitial,
+		Thereafter: DefaultSamplingThereafter,
+		Tick:       DefaultSamplingTick,
+	}
+	return config
+}
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
@@ -133,6 +598,9 @@ func DefaultConfig() *Config {
 		DirMode:         DefaultDirMode,
 		MaxFileSize:     DefaultMaxFileSize,
 		MaxFiles:        DefaultMaxFiles,
+		FrameSize:       DefaultFrameSize,
+
+		LogOnCancelledContext: true,
 	}
 }
 
@@ -145,38 +613,117 @@ var logEntryPool = sync.Pool{
 	},
 }
 
+// maxPooledBuffer is the capacity beyond which formatting buffers are not
+// returned to bufferPool, so that a rare huge entry does not stay in memory
+const maxPooledBuffer = 64 << 10
+
+// Pool for the buffers entries are formatted into, reused across entries
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 512)
+		return &buf
+	},
+}
+
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+func putBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBuffer {
+		return
+	}
+	*buf = (*buf)[:0]
+	bufferPool.Put(buf)
+}
+
 func getLogEntry() *LogEntry {
-	return logEntryPool.Get().(*LogEntry)
+	entry := logEntryPool.Get().(*LogEntry)
+	entry.refs = 1
+	return entry
 }
 
 func putLogEntry(entry *LogEntry) {
 	// Reset the entry
+	entry.Tenant = ""
 	entry.Package = ""
 	entry.Level = DEBUG
 	entry.Message = ""
 	entry.Context = nil
 	entry.Timestamp = time.Time{}
+	entry.queued = time.Time{}
+	entry.ack = nil
+	entry.must = false
 	// Clear the map but keep the allocated memory
 	for k := range entry.Fields {
 		delete(entry.Fields, k)
 	}
+	clear(entry.Labels)
+	atomic.StoreInt32(&entry.refs, -1)
 	logEntryPool.Put(entry)
 }
 
 // ChannelLogger is the main logger implementation
 type ChannelLogger struct {
 	logChan   chan *LogEntry
-	done      chan struct{}
-	wg        sync.WaitGroup
-	loggers   map[string]*log.Logger // per-package loggers
-	files     map[string]*os.File    // per-package files
-	fileSizes map[string]int64       // track file sizes for rotation
+	done      chan struct{}           // Closed once the worker has drained the queue
+	wg        sync.WaitGroup          // Error handling goroutine
+	workerWg  sync.WaitGroup          // Logging goroutine
+	sendMu    sync.RWMutex            // Held shared by producers, exclusively to close logChan
+	writers   map[string]io.Writer    // per-file outputs, the file or its compressed stream, keyed by fileKey
+	files     map[string]File         // per-file handles, keyed by fileKey
+	fileSizes map[string]int64        // track file sizes for rotation
+	frames    map[string]*frameWriter // per-file compressed streams
+	tenants   map[string]*tenantUsage // disk usage of tenants written so far
+	packages  map[string]struct{}     // packages written to files so far, outside tenants
+	checks    map[string]fileCheck    // last check for replaced files
+	folded    map[string]string       // lower-cased file names -> file key, to detect case collisions
+	violated  map[[3]string]bool      // schema, field and problem reported by FieldSchemas, logging goroutine only
+	labelled  map[string]interface{}  // fields and labels of the entry being formatted, logging goroutine only
+	expired   map[string]string       // base directory -> DirLayout partition current at its last expiry
+	sinks     []Sink
 	stdout    io.Writer
 	config    *Config
 	mu        sync.RWMutex
-	minLevel  atomic.Int32 // Thread-safe minimum level
-	closed    atomic.Bool  // Prevent operations after close
-	errorChan chan error   // For async error reporting
+	minLevel  atomic.Int32                        // Thread-safe minimum level
+	closed    atomic.Bool                         // Prevent operations after close
+	closing   atomic.Bool                         // Set by the first Close
+	pkgLevels atomic.Pointer[map[string]LogLevel] // Per-package overrides, copy-on-write
+	tenantLvl atomic.Pointer[map[string]LogLevel] // Per-tenant overrides, copy-on-write
+	levelsMu  sync.Mutex                          // Serializes writers of pkgLevels and tenantLvl
+	recent    *entryRing                          // Last logged entries, nil if disabled
+	flight    *flightRecorder                     // Per-package filtered entries, nil if disabled
+	written   atomic.Uint64
+	dropped   atomic.Uint64
+	stale     atomic.Uint64 // Entries dropped by MaxEntryAge
+	invalid   atomic.Uint64 // Entries not matching their FieldSchema
+	errCount  atomic.Uint64
+	errOver   atomic.Uint64 // Errors that did not fit in errorChan
+	errMu     sync.Mutex    // Serializes writes to Config.ErrorOverflow
+	started   time.Time
+	epoch     time.Time   // Origin of FieldElapsed
+	errorChan chan error  // For async error reporting
+	control   chan func() // Work that must run on the logging goroutine
+	formatter Formatter
+	header    FileHeader    // Written to new files if Config.FileHeader is set
+	sampler   *sampler      // nil unless Config.Sampling is set
+	throttle  *throttler    // nil unless Config.Throttle is set
+	latency   *latencies    // nil unless Config.LatencyHistogram is set
+	counters  counters      // Counts of PackageLogger.Count since the last report
+	dropSums  dropSummaries // Drops per package since the last summary
+	faults    faultInjector // nil outside of chaos tests
+	breaker   breaker       // Panics of the logging goroutine
+
+	priority   chan *LogEntry  // ERROR entries, nil unless PriorityBufferSize is set
+	queues     []*packageQueue // Config.Queues, fed into logChan by forwarders
+	queueCache sync.Map        // Package name -> *packageQueue, nil for logChan
+	queueWg    sync.WaitGroup  // Forwarding goroutines
+	spill      *spillBuffer    // Growth of logChan up to MaxBufferSize, nil if fixed
+	ctxFlushes sync.Map        // Done channel -> stop func of its FlushOnContextDone flush
+	secrets    sync.Map        // Package and field reported by SecretFields -> struct{}
+
+	// Config.Packages, keyed by the file name of the package
+	pkgConfig map[string]*packageSettings
 }
 
 // packageNameRegex for sanitizing package names
@@ -196,9 +743,24 @@ func sanitizePackageName(pkg string) string {
 		sanitized = sanitized[:MaxPackageNameLen]
 	}
 
+	// Windows reserves device names regardless of extension, so "con.log"
+	// cannot be created; dots, spaces and drive colons are already replaced
+	if windowsReserved[strings.ToUpper(sanitized)] {
+		sanitized += "_"
+	}
+
 	return sanitized
 }
 
+// windowsReserved lists the device names Windows reserves in every directory
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
 // NewChannelLogger creates a new logger with basic configuration
 func NewChannelLogger(bufferSize int, logDir string) *ChannelLogger {
 	config := DefaultConfig()
@@ -219,28 +781,123 @@ func NewChannelLoggerWithConfig(config *Config) *ChannelLogger {
 
 	cl := &ChannelLogger{
 		logChan:   make(chan *LogEntry, config.BufferSize),
+		spill:     newSpillBuffer(config),
+		priority:  priorityChan(config.PriorityBufferSize),
 		done:      make(chan struct{}),
-		loggers:   make(map[string]*log.Logger),
-		files:     make(map[string]*os.File),
+		writers:   make(map[string]io.Writer),
+		files:     make(map[string]File),
 		fileSizes: make(map[string]int64),
+		expired:   make(map[string]string),
+		frames:    make(map[string]*frameWriter),
+		tenants:   make(map[string]*tenantUsage),
+		packages:  make(map[string]struct{}),
+		checks:    make(map[string]fileCheck),
+		folded:    make(map[string]string),
+		violated:  make(map[[3]string]bool),
+		labelled:  make(map[string]interface{}),
+		pkgConfig: make(map[string]*packageSettings),
+		sinks:     config.Sinks,
 		stdout:    os.Stdout,
 		config:    config,
-		errorChan: make(chan error, 10), // Small buffer for errors
+		errorChan: make(chan error, config.ErrorBufferSize),
+		control:   make(chan func()),
+		started:   time.Now(),
+		formatter: config.Formatter,
+	}
+
+	if config.ConsoleWriter != nil {
+		cl.stdout = config.ConsoleWriter
+	}
+	if config.TLS != nil || config.Dialer != nil {
+		for _, sink := range config.Sinks {
+			if n, ok := sink.(networkInheritor); ok {
+				n.inheritNetwork(config)
+			}
+		}
+	}
+	cl.epoch = cl.started
+	if !config.ElapsedSince.IsZero() {
+		cl.epoch = config.ElapsedSince
+	}
+	if cl.formatter == nil && config.SchemaPreset != "" {
+		cl.formatter, _ = formatterForPreset(config.SchemaPreset, config.FieldEncoding)
+	}
+	if cl.formatter == nil && config.JSON {
+		cl.formatter = &JSONFormatter{
+			KeyTime:    config.FieldKeyTime,
+			KeyLevel:   config.FieldKeyLevel,
+			KeyMessage: config.FieldKeyMsg,
+			KeyPackage: config.FieldKeyPackage,
+			LevelNames: config.LevelNames,
+			TimeNames:  config.TimeNames,
+			Encoding:   config.FieldEncoding,
+		}
+	}
+	if cl.formatter == nil {
+		cl.formatter = &TextFormatter{
+			TimestampFormat: config.TimestampFormat,
+			FoldLines:       config.FoldLines,
+			LevelNames:      config.LevelNames,
+			TimeNames:       config.TimeNames,
+			LevelStyle:      config.LevelStyle,
+			PadLevels:       config.PadLevels,
+			Encoding:        config.FieldEncoding,
+		}
+	}
+	if config.FileHeader {
+		cl.header = fileHeader(cl.formatter)
+	}
+	cl.applyPackageConfigs()
+	if config.Sampling != nil {
+		cl.sampler = newSampler(*config.Sampling)
+	}
+	if config.Throttle != nil {
+		cl.throttle = newThrottler(*config.Throttle)
+	}
+	if config.LatencyHistogram {
+		cl.latency = newLatencies()
+	}
+	if config.RecentEntries > 0 {
+		cl.recent = newEntryRing(config.RecentEntries)
+	}
+	if config.FlightRecorderEntries > 0 {
+		cl.flight = newFlightRecorder(config.FlightRecorderEntries, config.FlightRecorderWindow)
 	}
 
 	// Set initial minimum level atomically
 	cl.minLevel.Store(int32(config.MinLevel))
 
 	// Create log directory if specified
-	if config.LogDir != "" {
-		if err := os.MkdirAll(config.LogDir, config.DirMode); err != nil {
+	if config.LogDir != "" && !config.DryRun {
+		if err := config.fileSystem().MkdirAll(config.LogDir, config.DirMode); err != nil {
 			cl.handleError(fmt.Errorf(ErrCreateLogDir, config.LogDir, err))
 		}
 	}
 
+	// Repair rotations interrupted by a crash before files are opened
+	repairs := cl.repairRotations()
+
 	// Start the logging goroutine
-	cl.wg.Add(1)
+	cl.workerWg.Add(1)
 	go cl.run()
+	cl.startQueues()
+	if cl.spill != nil {
+		cl.startSpill()
+	}
+	cl.logRepairs(repairs)
+
+	if config.Lifecycle {
+		cl.logStartup()
+	}
+	if config.CloseOnExit {
+		cl.registerExit()
+	}
+	if config.RuntimeMetrics > 0 {
+		cl.ReportRuntime(config.RuntimeMetrics)
+	}
+	for _, s := range cl.markedSamplers() {
+		cl.reportSampled(s)
+	}
 
 	// Start error handling goroutine if error handler is provided
 	if config.ErrorHandler != nil {
@@ -253,12 +910,19 @@ func NewChannelLoggerWithConfig(config *Config) *ChannelLogger {
 
 // handleError sends an error to the error channel or prints to stderr
 func (cl *ChannelLogger) handleError(err error) {
+	cl.errCount.Add(1)
 	if cl.config.ErrorHandler != nil {
 		select {
 		case cl.errorChan <- err:
 		default:
-			// Error channel is full, fall back to stderr
-			fmt.Fprintf(os.Stderr, "Logger error (channel full): %v\n", err)
+			cl.errOver.Add(1)
+			w := cl.config.ErrorOverflow
+			if w == nil {
+				w = os.Stderr
+			}
+			cl.errMu.Lock()
+			fmt.Fprintf(w, "%s Logger error (channel full): %v\n", time.Now().Format(cl.config.TimestampFormat), err)
+			cl.errMu.Unlock()
 		}
 	} else {
 		fmt.Fprintf(os.Stderr, "Logger error: %v\n", err)
@@ -288,222 +952,823 @@ func (cl *ChannelLogger) handleErrors() {
 }
 
 // shouldRotate checks if a log file should be rotated
-func (cl *ChannelLogger) shouldRotate(pkg string) bool {
-	size, exists := cl.fileSizes[pkg]
-	return exists && size >= cl.config.MaxFileSize
+func (cl *ChannelLogger) shouldRotate(key string) bool {
+	if cl.config.AppendOnly {
+		return false
+	}
+	size, exists := cl.fileSizes[key]
+	if exists && cl.faults != nil && cl.faults.rotate(key) {
+		return true
+	}
+	return exists && size >= cl.maxFileSize(key)
 }
 
-// rotateFile performs log file rotation
-func (cl *ChannelLogger) rotateFile(pkg string) error {
-	baseName := fmt.Sprintf("%s.log", sanitizePackageName(pkg))
-	if cl.config.LogDir != "" {
-		baseName = filepath.Join(cl.config.LogDir, baseName)
+// rotatesOnStart reports whether the file of a key was not opened by this
+// process yet and holds entries of a previous run that RotateOnStart moves
+// aside
+func (cl *ChannelLogger) rotatesOnStart(key string) bool {
+	if !cl.config.RotateOnStart || cl.config.AppendOnly {
+		return false
+	}
+	if _, opened := cl.fileSizes[key]; opened {
+		return false
 	}
+	info, err := cl.config.fileSystem().Stat(cl.logFileName(key))
+	return err == nil && info.Size() > 0
+}
 
-	// Close current file
-	if f, exists := cl.files[pkg]; exists {
-		f.Close()
-		delete(cl.files, pkg)
-		delete(cl.loggers, pkg)
+// fileKey identifies the log file of a package: its sanitized name, prefixed
+// with the sanitized tenant name and a slash for tenant entries
+func fileKey(tenant, pkg string) string {
+	if tenant == "" {
+		return sanitizePackageName(pkg)
 	}
+	return sanitizePackageName(tenant) + "/" + sanitizePackageName(pkg)
+}
 
-	// Rotate existing files
-	for i := cl.config.MaxFiles - 1; i > 0; i-- {
-		oldName := fmt.Sprintf("%s.%d", baseName, i)
-		newName := fmt.Sprintf("%s.%d", baseName, i+1)
-		if i == cl.config.MaxFiles-1 {
-			os.Remove(newName) // Remove oldest file
+// logFileName returns the path of the active log file for a file key, in
+// LogDir, the directory of the package's route or of its tenant, and the
+// current partition of DirLayout
+func (cl *ChannelLogger) logFileName(key string) string {
+	fileName := key[strings.IndexByte(key, '/')+1:] + ".log"
+	if cl.config.Compression != nil {
+		fileName += cl.config.Compression.Extension()
+	}
+	dir := cl.logBaseDir(key)
+	if cl.config.DirLayout != nil {
+		dir = filepath.Join(dir, cl.config.DirLayout.Partition(time.Now()))
+	}
+	if dir != "" {
+		fileName = filepath.Join(dir, fileName)
+	}
+	return fileName
+}
+
+// logBaseDir returns the directory holding the files, or the partitions, of
+// a file key
+func (cl *ChannelLogger) logBaseDir(key string) string {
+	if tenant, _, ok := strings.Cut(key, "/"); ok {
+		return filepath.Join(cl.config.LogDir, tenant)
+	}
+	if r := cl.routeFor(key); r != nil {
+		return cl.routeDir(r)
+	}
+	return cl.config.LogDir
+}
+
+// rotateFile performs log file rotation
+func (cl *ChannelLogger) rotateFile(key string) error {
+	baseName := cl.logFileName(key)
+	if cl.config.AppendOnly {
+		return &ErrRotation{Pkg: key, Path: baseName, Err: errors.New(ErrAppendOnly)}
+	}
+	if cl.config.SharedFiles {
+		unlock, err := lockPath(strings.TrimSuffix(baseName, ".log")+".lock", cl.config.FileMode)
+		if err != nil {
+			return &ErrRotation{Pkg: key, Path: baseName, Err: err}
+		}
+		defer unlock()
+
+		// Another process may have rotated the file while we waited
+		if f, ok := cl.files[key]; ok {
+			if _, change := fileMoved(cl.config.fileSystem(), f); change != "" {
+				cl.closeFile(key)
+				return nil
+			}
 		}
-		os.Rename(oldName, newName)
+	}
+	tenant, _, isTenant := strings.Cut(key, "/")
+	maxFiles := cl.config.MaxFiles
+	if isTenant {
+		if n := cl.tenantConfig(tenant).MaxFiles; n > 0 {
+			maxFiles = n
+		}
+	} else if r := cl.routeFor(key); r != nil && r.MaxFiles > 0 {
+		maxFiles = r.MaxFiles
 	}
 
-	// Move current file to .1
-	if _, err := os.Stat(baseName); err == nil {
-		os.Rename(baseName, fmt.Sprintf("%s.1", baseName))
+	cl.closeFile(key)
+
+	// Move the current file aside, then remove the oldest beyond maxFiles
+	var rotateErr error
+	fsys := cl.config.fileSystem()
+	if _, err := fsys.Stat(baseName); err == nil {
+		rotated, err := cl.config.rotate(baseName, time.Now())
+		if err != nil {
+			rotateErr = err
+		} else if cl.config.Manifest {
+			cl.recordManifest(rotated)
+		}
+	}
+	rotated, err := cl.config.rotated(baseName)
+	if err != nil && rotateErr == nil {
+		rotateErr = err
+	}
+	for i := maxFiles; i < len(rotated); i++ {
+		if err := fsys.Remove(rotated[i]); err != nil && rotateErr == nil {
+			rotateErr = err
+		}
 	}
 
 	// Reset file size tracking
-	cl.fileSizes[pkg] = 0
+	cl.fileSizes[key] = 0
+
+	if isTenant {
+		cl.enforceRetention(tenant)
+	}
 
+	if rotateErr != nil {
+		return &ErrRotation{Pkg: key, Path: baseName, Err: rotateErr}
+	}
 	return nil
 }
 
-// getLogger gets or creates a logger for the specified package
-func (cl *ChannelLogger) getLogger(pkg string) *log.Logger {
-	if cl.closed.Load() {
-		// If closed, return a stdout-only logger
-		return log.New(cl.stdout, "", 0)
+// closeFile finishes the open compressed frame of a file key and closes its
+// file; cl.mu must be held
+func (cl *ChannelLogger) closeFile(key string) {
+	if fw, exists := cl.frames[key]; exists {
+		if err := fw.Close(); err != nil {
+			cl.handleError(fmt.Errorf("failed to flush compressed log for package %s: %w", key, err))
+		}
+		delete(cl.frames, key)
+	}
+
+	if f, exists := cl.files[key]; exists {
+		f.Close()
+		delete(cl.files, key)
+		delete(cl.writers, key)
+		delete(cl.checks, key)
+	}
+}
+
+// getWriter returns the output of the specified file key, opening the file
+// and rotating it first if necessary. It is only called by the logging
+// goroutine, which owns the files; if the file cannot be opened, entries are
+// discarded until it is rotated or reopened.
+func (cl *ChannelLogger) getWriter(key string) io.Writer {
+	if cl.config.DryRun {
+		return io.Discard
 	}
 
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
 
-	logger, ok := cl.loggers[pkg]
-	if ok && !cl.shouldRotate(pkg) {
-		return logger
+	w, ok := cl.writers[key]
+	if ok && cl.config.DirLayout != nil && cl.partitionChanged(key) {
+		cl.closeFile(key)
+		delete(cl.fileSizes, key) // Not written before, see ensureLogDir
+		ok = false
+	}
+	if ok {
+		// Files are rotated by other processes sharing them and by tools
+		// like logrotate as a matter of course; only AppendOnly files are
+		// expected to stay put
+		if change := cl.fileReplaced(key); change != "" {
+			if cl.config.AppendOnly {
+				cl.handleError(fmt.Errorf(ErrLogFileReplaced, cl.files[key].Name(), change))
+			}
+			cl.closeFile(key)
+			ok = false
+		}
+	}
+	rotate := cl.shouldRotate(key)
+	if ok && !rotate {
+		return w
 	}
 
-	// Handle rotation if needed
-	if cl.shouldRotate(pkg) {
-		if err := cl.rotateFile(pkg); err != nil {
-			cl.handleError(fmt.Errorf("failed to rotate log file for package %s: %w", pkg, err))
+	// Handle rotation if needed; files written by a previous run are rotated
+	// before this process first writes them if RotateOnStart is set
+	if rotate || cl.rotatesOnStart(key) {
+		if err := cl.rotateFile(key); err != nil {
+			cl.handleError(err)
 		}
 	}
 
-	sanitizedPkg := sanitizePackageName(pkg)
-	fileName := fmt.Sprintf("%s.log", sanitizedPkg)
-	if cl.config.LogDir != "" {
-		fileName = filepath.Join(cl.config.LogDir, fileName)
+	if cl.config.DirLayout != nil {
+		cl.expirePartitions(cl.logBaseDir(key))
 	}
+	fileName := cl.logFileName(key)
+
+	// Packages differing only in case share a file on case-insensitive file
+	// systems (Windows, macOS by default)
+	folded := strings.ToLower(fileName)
+	if other, seen := cl.folded[folded]; !seen {
+		cl.folded[folded] = key
+	} else if other != key {
+		cl.handleError(fmt.Errorf(ErrPackageCollision, key, other, fileName))
+	}
+
+	w = io.Discard
 
-	var writers []io.Writer
-	writers = append(writers, cl.stdout)
+	// Tenant and routed files live outside LogDir, which may also have been
+	// removed since it was created
+	cl.ensureLogDir(key, fileName)
 
-	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, cl.config.FileMode)
+	f, err := cl.config.fileSystem().OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, cl.config.FileMode)
+	if osFile, ok := f.(*os.File); ok && cl.config.AppendOnly {
+		if err = lockFile(osFile); err != nil {
+			f.Close()
+			err = fmt.Errorf(ErrLockLogFile, err)
+		}
+	}
 	if err != nil {
 		cl.handleError(fmt.Errorf(ErrOpenLogFile, fileName, err))
 	} else {
-		cl.files[pkg] = f
-		writers = append(writers, f)
+		cl.files[key] = f
+		if _, osFS := cl.config.fileSystem().(OSFS); osFS && cl.config.LatestLinks {
+			cl.updateLatestLink(fileName)
+		}
+		if cl.config.Compression != nil {
+			fw := newFrameWriter(f, cl.config.Compression, cl.config.FrameSize)
+			cl.frames[key] = fw
+			w = fw
+		} else {
+			w = f
+		}
 
 		// Get current file size
 		if stat, err := f.Stat(); err == nil {
-			cl.fileSizes[pkg] = stat.Size()
+			cl.fileSizes[key] = stat.Size()
+		}
+		if cl.fileSizes[key] == 0 {
+			var header string
+			now := time.Now()
+			if cl.config.FileHeader {
+				header = cl.headerLine(key, now)
+			}
+			if hf, ok := cl.formatterFor(key).(HeaderFormatter); ok {
+				for _, line := range hf.HeaderLines(now) {
+					header += line + cl.config.recordSeparator()
+				}
+			}
+			if header != "" {
+				if _, err := io.WriteString(w, header); err != nil {
+					cl.handleError(fmt.Errorf(ErrWriteHeader, fileName, err))
+				}
+				cl.fileSizes[key] = int64(len(header))
+			}
 		}
 	}
 
-	logger = log.New(io.MultiWriter(writers...), "", 0)
-	cl.loggers[pkg] = logger
-	return logger
+	if cl.faults != nil {
+		w = cl.faults.wrap(key, w)
+	}
+	cl.writers[key] = w
+	return w
 }
 
-// formatLogMessage formats a log message with efficient string building
-func formatLogMessage(entry *LogEntry, timestampFormat string) string {
-	var sb strings.Builder
-
-	// Pre-allocate reasonable capacity
-	sb.Grow(len(timestampFormat) + len(entry.Level.String()) + len(entry.Message) + 20)
-
-	sb.WriteString("[")
-	sb.WriteString(entry.Timestamp.Format(timestampFormat))
-	sb.WriteString("] ")
-	sb.WriteString(entry.Level.String())
-	sb.WriteString(": ")
-	sb.WriteString(entry.Message)
+// appendLogMessage appends the text layout of entry to dst, with the
+// timestamp, level and field values written as configured by f and the level
+// padded to width
+func appendLogMessage(dst []byte, entry *LogEntry, f *TextFormatter, width int) []byte {
+	dst = append(dst, '[')
+	dst = f.TimeNames.appendTime(dst, entry.Timestamp, f.TimestampFormat)
+	dst = append(dst, "] "...)
+	dst = f.LevelStyle.appendLevel(dst, levelName(f.LevelNames, entry.Level), width)
+	dst = append(dst, ' ')
+	dst = append(dst, entry.Message...)
 
 	// Add structured fields if present
 	if len(entry.Fields) > 0 {
-		sb.WriteString(" | ")
+		dst = append(dst, " | "...)
 		first := true
 		for k, v := range entry.Fields {
 			if !first {
-				sb.WriteString(", ")
+				dst = append(dst, ", "...)
+			}
+			v = resolveValue(v)
+			dst = append(dst, k...)
+			dst = append(dst, '=')
+			dst = f.Encoding.appendText(dst, v)
+			if t := errorType(v); t != "" {
+				dst = append(dst, ", "...)
+				dst = append(dst, k...)
+				dst = append(dst, "_type="...)
+				dst = append(dst, t...)
 			}
-			sb.WriteString(k)
-			sb.WriteString("=")
-			sb.WriteString(fmt.Sprintf("%v", v))
 			first = false
 		}
 	}
-
-	return sb.String()
+	return dst
 }
 
 // run processes log entries in a background goroutine
+// until the channel is closed by Close, then releases all outputs
 func (cl *ChannelLogger) run() {
-	defer cl.wg.Done()
+	defer cl.workerWg.Done()
 
+	// A panic of a Formatter, Sink or control function unwinds work, which
+	// then starts over with the entries still queued
+	for !cl.work() {
+	}
+}
+
+// work writes queued entries until the queue is closed and drained, returning
+// false if it recovered from a panic instead
+func (cl *ChannelLogger) work() (drained bool) {
+	defer cl.recoverWorker()
+
+	priority := cl.priority // nil, blocking forever, unless PriorityBufferSize is set
 	for {
+		// Entries of the priority lane are written before the others
 		select {
-		case entry := <-cl.logChan:
-			// Check if context is cancelled
-			if entry.Context != nil && entry.Context.Err() != nil {
-				putLogEntry(entry)
+		case entry, ok := <-priority:
+			if ok {
+				cl.writeEntry(entry)
 				continue
 			}
+			priority = nil
+		default:
+		}
 
-			// Format and log the message (level check already done in logEntry)
-			formatted := formatLogMessage(entry, cl.config.TimestampFormat)
-			logger := cl.getLogger(entry.Package)
+		select {
+		case entry, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			cl.writeEntry(entry)
+			cl.flushIfIdle()
+
+		case entry, ok := <-cl.logChan:
+			if !ok {
+				// Every admitted entry has been written; the priority lane
+				// is closed first, so what is left in it is final
+				if priority != nil {
+					for entry := range priority {
+						cl.writeEntry(entry)
+					}
+				}
+				cl.flush()
+				cl.closeOutputs()
+				return true
+			}
+			cl.writeEntry(entry)
+			cl.flushIfIdle()
 
-			// Track bytes written for rotation
-			messageSize := int64(len(formatted) + 1) // +1 for newline
-			cl.mu.Lock()
-			cl.fileSizes[entry.Package] += messageSize
-			cl.mu.Unlock()
+		case fn := <-cl.control:
+			fn()
+		}
+	}
+}
 
-			logger.Println(formatted)
+// flushQueued writes the entries in the shared queue and the priority lane,
+// without waiting for more, then flushes the outputs
+func (cl *ChannelLogger) flushQueued() {
+	for n := len(cl.priority); n > 0; n-- {
+		entry, ok := <-cl.priority
+		if !ok {
+			break
+		}
+		cl.writeEntry(entry)
+	}
+	for n := len(cl.logChan); n > 0; n-- {
+		entry, ok := <-cl.logChan
+		if !ok {
+			break
+		}
+		cl.writeEntry(entry)
+	}
+	cl.flush()
+}
 
-			// Return entry to pool
-			putLogEntry(entry)
+// flushIfIdle flushes compressed frames and sinks once the queue is
+// idle so that readers tailing the output see every entry written
+func (cl *ChannelLogger) flushIfIdle() {
+	if len(cl.logChan) == 0 && len(cl.priority) == 0 {
+		cl.flush()
+	}
+}
 
-		case <-cl.done:
-			// Process remaining entries
-			for len(cl.logChan) > 0 {
-				entry := <-cl.logChan
-				if entry.Context != nil && entry.Context.Err() != nil {
-					putLogEntry(entry)
-					continue
+// closeOutputs closes sinks, unless they keep panicking, and package files
+func (cl *ChannelLogger) closeOutputs() {
+	for _, sink := range cl.sinks {
+		if cl.breaker.tripped() {
+			break
+		}
+		if err := sink.Close(); err != nil {
+			cl.handleError(fmt.Errorf("failed to close sink: %w", err))
+		}
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for pkg, f := range cl.files {
+		if err := f.Close(); err != nil {
+			cl.handleError(fmt.Errorf(ErrCloseLogFile, pkg, err))
+		}
+		delete(cl.files, pkg)
+		delete(cl.writers, pkg)
+	}
+}
+
+// writeEntry formats an entry, writes it to the console, the package file and
+// all sinks, and returns it to the pool
+func (cl *ChannelLogger) writeEntry(entry *LogEntry) {
+	if cl.breaker.tripped() {
+		cl.drop(entry, DropPanic)
+		return
+	}
+	// The caller of MustLog is still waiting for its entry, however old
+	if cl.config.MaxEntryAge > 0 && !entry.must && time.Since(entry.queued) > cl.config.MaxEntryAge {
+		cl.stale.Add(1)
+		cl.drop(entry, DropStale)
+		return
+	}
+	if entry.Tenant != "" && cl.overQuota(entry.Tenant) {
+		cl.drop(entry, DropQuota)
+		return
+	}
+	defer entry.Release()
+	defer cl.recoverEntry(entry)
+
+	if cl.config.FieldSchemas != nil {
+		cl.checkSchema(entry)
+	}
+
+	// Format and log the message (level check already done in logEntry)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	key := fileKey(entry.Tenant, entry.Package)
+	record := cl.formatterFor(key).Format((*buf)[:0], withLabelFields(entry, cl.labelled))
+	if cl.config.EscapeNewlines {
+		record = escapeNewlines(record)
+	}
+	line := append(record, cl.config.recordSeparator()...)
+	record = line[:len(record)]
+	*buf = line // Keep the grown buffer for the next entry
+
+	if cl.throttle != nil {
+		defer cl.observeWrite(time.Now())
+	}
+	if cl.latency != nil {
+		defer cl.latency.observe(entry.queued)
+	}
+
+	if !cl.config.DisableConsole && entry.Level >= cl.config.ConsoleLevel {
+		cl.writeConsole(entry.Level, line)
+	}
+	var errs []error
+	if entry.Level >= cl.config.FileLevel || entry.must {
+		w := cl.getWriter(key)
+
+		// Track bytes written for rotation and tenant quotas
+		messageSize := int64(len(line))
+		cl.mu.Lock()
+		cl.fileSizes[key] += messageSize
+		if entry.Tenant != "" {
+			cl.usageFor(entry.Tenant).bytes += messageSize
+		} else {
+			cl.packages[entry.Package] = struct{}{}
+		}
+		cl.mu.Unlock()
+
+		// A single write, so that processes sharing the file do not interleave
+		if _, err := w.Write(line); err != nil {
+			// Some systems fail writes to removed files; open a new one
+			if errors.Is(err, fs.ErrNotExist) {
+				cl.mu.Lock()
+				cl.closeFile(key)
+				cl.mu.Unlock()
+				_, err = cl.getWriter(key).Write(line)
+			}
+			if err != nil {
+				err = fmt.Errorf(ErrWriteLogFile, key, err)
+				cl.handleError(err)
+				errs = append(errs, err)
+			}
+		}
+	}
+	cl.written.Add(1)
+
+	if len(cl.sinks) > 0 {
+		var prefixed []byte
+		for _, sink := range cl.sinks {
+			sinkLine := line
+			if r, ok := sink.(recordSink); ok && r.textRecords() {
+				sinkLine = record
+			} else if p, ok := sink.(LengthPrefixer); ok && cl.config.LengthPrefixSinks && p.LengthPrefixed() {
+				if prefixed == nil {
+					buf := getBuffer()
+					defer putBuffer(buf)
+					prefixed = appendLengthPrefixed((*buf)[:0], record)
+					*buf = prefixed
 				}
-				formatted := formatLogMessage(entry, cl.config.TimestampFormat)
-				cl.getLogger(entry.Package).Println(formatted)
-				putLogEntry(entry)
+				sinkLine = prefixed
+			}
+			if err := sink.Write(entry, sinkLine); err != nil {
+				sinkErr := &ErrSinkWrite{Sink: sink, Pkg: entry.Package, Err: err}
+				cl.handleError(sinkErr)
+				cl.config.DeadLetter.record([]*LogEntry{entry}, fmt.Sprintf("%T", sink), err, cl.handleError)
+				errs = append(errs, sinkErr)
+			}
+		}
+	}
+	if entry.ack != nil {
+		entry.ack <- errors.Join(errs...)
+	}
+}
+
+// writeConsole writes a formatted entry and its separator to the console,
+// colored by level if ConsoleColor is set
+func (cl *ChannelLogger) writeConsole(level LogLevel, line []byte) {
+	if cl.config.ConsoleColor {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		record := line[:len(line)-len(cl.config.recordSeparator())]
+		colored := append(append((*buf)[:0], levelColor(level)...), record...)
+		colored = append(append(colored, colorReset...), line[len(record):]...)
+		*buf = colored
+		line = colored
+	}
+	cl.stdout.Write(line)
+}
+
+// flush pushes open compressed frames to their files and flushes buffering
+// sinks, unless they keep panicking
+func (cl *ChannelLogger) flush() {
+	cl.mu.Lock()
+	for pkg, fw := range cl.frames {
+		if err := fw.Flush(); err != nil {
+			cl.handleError(fmt.Errorf("failed to flush compressed log for package %s: %w", pkg, err))
+		}
+	}
+	cl.mu.Unlock()
+
+	if cl.breaker.tripped() {
+		return
+	}
+	for _, sink := range cl.sinks {
+		if f, ok := sink.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				cl.handleError(fmt.Errorf("sink flush failed: %w", err))
 			}
-			return
 		}
 	}
 }
 
-// ParseLogLevel converts a string to a LogLevel
+// UnknownLevelFallback is the level ParseLogLevel returns for strings it does
+// not recognize. Set it to ERROR during initialization to keep entries logged
+// with a mistyped level from being filtered out.
+var UnknownLevelFallback = INFO
+
+// levelAliases maps alternative level names to the closest LogLevel; there is
+// no WARN level, so warnings are logged as INFO
+var levelAliases = map[string]LogLevel{
+	"TRACE":    DEBUG,
+	"DBG":      DEBUG,
+	"INF":      INFO,
+	"NOTICE":   INFO,
+	"WARN":     INFO,
+	"WARNING":  INFO,
+	"ERR":      ERROR,
+	"CRIT":     ERROR,
+	"CRITICAL": ERROR,
+	"FATAL":    ERROR,
+}
+
+// ParseLogLevel converts a string to a LogLevel, returning
+// UnknownLevelFallback for strings ParseLogLevelStrict rejects
 func ParseLogLevel(level string) LogLevel {
-	switch strings.ToUpper(level) {
-	case "DEBUG":
-		return DEBUG
-	case "INFO":
-		return INFO
-	case "ERROR":
-		return ERROR
-	default:
-		return INFO // default fallback
+	parsed, err := ParseLogLevelStrict(level)
+	if err != nil {
+		return UnknownLevelFallback
 	}
+	return parsed
+}
+
+// ParseLogLevelStrict converts a level name, an alias such as WARNING, ERR or
+// TRACE, or a numeric level ("0" for DEBUG to "2" for ERROR) to a LogLevel.
+// Names are case-insensitive and surrounding spaces are ignored.
+func ParseLogLevelStrict(level string) (LogLevel, error) {
+	name := strings.ToUpper(strings.TrimSpace(level))
+	if parsed, ok := lookupLevel(name); ok {
+		return parsed, nil
+	}
+	if parsed, ok := levelAliases[name]; ok {
+		return parsed, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= int(DEBUG) && n <= int(ERROR) {
+		return LogLevel(n), nil
+	}
+	return INFO, fmt.Errorf(ErrUnknownLevel, level)
 }
 
 // logEntry sends a log entry to the processing channel
 func (cl *ChannelLogger) logEntry(entry *LogEntry) {
+	// Close cannot close the channel while an entry is being admitted
+	cl.sendMu.RLock()
+	defer cl.sendMu.RUnlock()
+
 	if cl.closed.Load() {
-		putLogEntry(entry)
+		cl.drop(entry, DropClosed)
 		return
 	}
 
+	if cl.config.SanitizeMessages {
+		sanitizeEntry(entry)
+	}
+	if cl.config.PprofLabels && entry.Context != nil {
+		addPprofLabels(entry)
+	}
+	if entry.Context != nil {
+		addRequestID(entry)
+		if cl.config.FlushOnContextDone {
+			cl.flushOnDone(entry.Context)
+		}
+	}
+	if cl.config.Normalize != nil {
+		cl.config.Normalize.normalizeEntry(entry)
+	}
+	// Before the entry can reach the recent entries or the flight recorder
+	if cl.config.SecretFields != SecretFieldsAllow {
+		cl.checkSecrets(entry)
+	}
+
 	// Check minimum level before sending to channel to avoid unnecessary work
-	if entry.Level < LogLevel(cl.minLevel.Load()) {
-		putLogEntry(entry)
+	if entry.Level < cl.levelForEntry(entry) {
+		if cl.recent != nil && cl.config.RecentBelowMinLevel {
+			cl.recent.add(entry)
+		}
+		if cl.flight != nil {
+			cl.flight.record(entry)
+		}
+		if entry.ack != nil {
+			entry.ack <- nil // Filtered out, which is not a failure
+		}
+		entry.Release()
+		return
+	}
+
+	if cl.checkCancelled(entry) {
+		cl.drop(entry, DropCancelled)
+		return
+	}
+	if cl.sampler != nil && !cl.sampler.allow(entry) {
+		cl.drop(entry, DropSampled)
+		return
+	}
+	if cl.throttle != nil && cl.throttle.active.Load() && !cl.throttle.allow(entry) {
+		cl.drop(entry, DropThrottled)
+		return
+	}
+	cl.addAutoFields(entry)
+
+	if cl.recent != nil {
+		cl.recent.add(entry)
+	}
+
+	// An error releases the debug context buffered for its package first
+	if cl.flight != nil && entry.Level >= ERROR {
+		for _, buffered := range cl.flight.release(entry.Tenant, entry.Package, entry.Timestamp) {
+			cl.enqueue(buffered)
+		}
+	}
+
+	cl.enqueue(entry)
+}
+
+// addAutoFields adds the fields Config asks to be added to every entry
+func (cl *ChannelLogger) addAutoFields(entry *LogEntry) {
+	if cl.config.Caller {
+		entry.Fields[FieldCaller] = callerOutsidePackage()
+	}
+	if cl.config.GoroutineID {
+		entry.Fields[FieldGoroutine] = goroutineID()
+	}
+	if cl.config.Elapsed {
+		entry.Fields[FieldElapsed] = formatElapsed(entry.Timestamp.Sub(cl.epoch))
+	}
+}
+
+// logNotice logs an INFO entry to InternalPackage that bypasses level
+// filtering, so that changes to the logger are recorded at any level
+func (cl *ChannelLogger) logNotice(message string, fields map[string]interface{}) {
+	entry := getLogEntry()
+	entry.Package = InternalPackage
+	entry.Level = INFO
+	entry.Message = message
+	entry.Timestamp = time.Now()
+	for k, v := range fields {
+		entry.Fields[k] = v
+	}
+
+	cl.sendMu.RLock()
+	defer cl.sendMu.RUnlock()
+	if cl.closed.Load() {
+		cl.drop(entry, DropClosed)
+		return
+	}
+	cl.enqueue(entry)
+}
+
+// enqueue sends an entry that passed filtering to the processing channel, or
+// to the queue of its package if it has one
+func (cl *ChannelLogger) enqueue(entry *LogEntry) {
+	if cl.config.MaxEntryAge > 0 || cl.latency != nil {
+		entry.queued = time.Now()
+	}
+	if cl.priority != nil && entry.Level >= ERROR {
+		select {
+		case cl.priority <- entry:
+			return
+		default:
+			// Lane full, fall back to the regular queues
+		}
+	}
+	ch := cl.logChan
+	q := cl.queueFor(entry)
+	if q != nil {
+		ch = q.ch
+	}
+
+	// While entries are spilled, later ones join them to stay in order
+	if q == nil && cl.spill != nil && cl.spill.len() > 0 && cl.spill.push(entry) {
 		return
 	}
 
 	select {
-	case cl.logChan <- entry:
-		// Successfully queued
+	case ch <- entry:
+		q.admit()
 	default:
+		if q == nil && cl.spill != nil && cl.spill.push(entry) {
+			return
+		}
+
+		// A context deadline decides how long the caller is willing to wait
+		if entry.Context != nil {
+			if _, ok := entry.Context.Deadline(); ok {
+				cl.enqueueUntilDone(ch, q, entry)
+				return
+			}
+		}
+
 		// Channel is immediately full, use a brief timeout for larger buffers
-		if cl.config.BufferSize > 10 {
+		if cap(ch) > 10 {
 			// For larger buffers, give a brief chance to queue
 			select {
-			case cl.logChan <- entry:
-				// Successfully queued after brief wait
+			case ch <- entry:
+				q.admit()
 			case <-time.After(5 * time.Millisecond):
 				// Channel remained full, drop the message
-				cl.handleError(fmt.Errorf("log channel full, dropping message: %s", entry.Message))
-				putLogEntry(entry)
+				cl.dropQueued(q, entry, DropOverflow)
 			}
 		} else {
 			// For small buffers, drop immediately to properly test overflow behavior
-			cl.handleError(fmt.Errorf("log channel full, dropping message: %s", entry.Message))
-			putLogEntry(entry)
+			cl.dropQueued(q, entry, DropOverflow)
 		}
 	}
 }
 
+// enqueueUntilDone blocks until the entry is queued or its context is done
+func (cl *ChannelLogger) enqueueUntilDone(ch chan *LogEntry, q *packageQueue, entry *LogEntry) {
+	select {
+	case ch <- entry:
+		q.admit()
+	case <-entry.Context.Done():
+		cl.dropQueued(q, entry, DropContextDone)
+	}
+}
+
+// dropQueued drops an entry that did not fit in its queue, counting it
+// against the package queue q unless it is nil
+func (cl *ChannelLogger) dropQueued(q *packageQueue, entry *LogEntry, reason DropReason) {
+	if q != nil {
+		q.dropped.Add(1)
+	}
+	cl.drop(entry, reason)
+}
+
+// drop discards an entry that will not be written, reporting it to OnDrop,
+// to a waiting MustLog or Try call as an *ErrDropped and, if the channel was
+// full, to the error handler as an *ErrChannelFull
+func (cl *ChannelLogger) drop(entry *LogEntry, reason DropReason) {
+	var dropped uint64
+	if reason != DropClosed {
+		dropped = cl.dropped.Add(1)
+	}
+	if cl.config.OnDrop != nil {
+		cl.config.OnDrop(entry, reason)
+	}
+	if cl.config.DropSummaries > 0 {
+		cl.summarizeDrop(entry, reason)
+	}
+	if entry.ack != nil {
+		err := &ErrDropped{Pkg: entry.Package, Message: entry.Message, Reason: reason}
+		if reason == DropContextDone {
+			err.Err = entry.Context.Err()
+		}
+		entry.ack <- err
+	}
+	switch reason {
+	case DropOverflow:
+		cl.handleError(&ErrChannelFull{Pkg: entry.Package, Message: entry.Message, Dropped: dropped})
+	case DropContextDone:
+		cl.handleError(&ErrChannelFull{Pkg: entry.Package, Message: entry.Message, Dropped: dropped, Err: entry.Context.Err()})
+	}
+	entry.Release()
+}
+
 // Log logs a message with string level
 func (cl *ChannelLogger) Log(pkg, level, message string) {
 	cl.LogLevel(pkg, ParseLogLevel(level), message)
@@ -519,12 +1784,31 @@ func (cl *ChannelLogger) LogLevel(pkg string, level LogLevel, message string) {
 	cl.logEntry(entry)
 }
 
-// LogWithContext logs a context-aware message
-func (cl *ChannelLogger) LogWithContext(ctx context.Context, pkg, level, message string) {
-	if ctx.Err() != nil {
-		return // Context cancelled/expired
+// checkCancelled reports whether an entry is dropped because its context is
+// already done as it is logged and Config.LogOnCancelledContext is false;
+// otherwise it adds the reason as FieldContextError. A context that ends
+// while the entry is queued does not change it.
+func (cl *ChannelLogger) checkCancelled(entry *LogEntry) bool {
+	if entry.Context == nil || entry.Context.Err() == nil {
+		return false
+	}
+	if !cl.config.LogOnCancelledContext && !entry.must {
+		return true
 	}
+	entry.Fields[FieldContextError] = context.Cause(entry.Context).Error()
+	return false
+}
 
+// FieldContextError is the field holding why the context of an entry ended,
+// added when Config.LogOnCancelledContext writes it
+const FieldContextError = "context_error"
+
+// LogWithContext logs a context-aware message. If the queue is full and ctx
+// has a deadline, the call waits for room until the deadline instead of the
+// default short timeout. The context is passed on to sinks in LogEntry.Context.
+// Once ctx is done the message is still logged, with the reason as
+// FieldContextError, unless Config.LogOnCancelledContext is false.
+func (cl *ChannelLogger) LogWithContext(ctx context.Context, pkg, level, message string) {
 	entry := getLogEntry()
 	entry.Package = pkg
 	entry.Level = ParseLogLevel(level)
@@ -565,6 +1849,50 @@ func (cl *ChannelLogger) Debug(pkg, message string) {
 	cl.LogLevel(pkg, DEBUG, message)
 }
 
+// do runs fn on the logging goroutine and waits for it to complete, so that
+// fn can safely touch files and sinks owned by the worker
+func (cl *ChannelLogger) do(fn func()) error {
+	if cl.closed.Load() {
+		return fmt.Errorf(ErrLoggerClosed)
+	}
+
+	done := make(chan struct{})
+	select {
+	case cl.control <- func() { defer close(done); fn() }:
+	case <-cl.done:
+		return fmt.Errorf(ErrLoggerClosed)
+	}
+	<-done
+	return nil
+}
+
+// SetConsoleWriter redirects the console copy of entries to w, or discards
+// it if w is nil. The writer is swapped on the logging goroutine, so no entry
+// is written to both or half to each; entries still queued when it is called
+// may be written to either.
+func (cl *ChannelLogger) SetConsoleWriter(w io.Writer) error {
+	if w == nil {
+		w = io.Discard
+	}
+	return cl.do(func() {
+		cl.stdout = w
+	})
+}
+
+// Rotate rotates the log files of all packages written so far, regardless of
+// their size. New files are opened on the next write.
+func (cl *ChannelLogger) Rotate() error {
+	return cl.do(func() {
+		cl.mu.Lock()
+		defer cl.mu.Unlock()
+		for pkg := range cl.files {
+			if err := cl.rotateFile(pkg); err != nil {
+				cl.handleError(err)
+			}
+		}
+	})
+}
+
 // SetMinLevel changes the minimum log level at runtime (thread-safe)
 func (cl *ChannelLogger) SetMinLevel(level LogLevel) {
 	cl.minLevel.Store(int32(level))
@@ -575,27 +1903,145 @@ func (cl *ChannelLogger) GetMinLevel() LogLevel {
 	return LogLevel(cl.minLevel.Load())
 }
 
-// Close gracefully shuts down the logger
-func (cl *ChannelLogger) Close() {
-	if !cl.closed.CompareAndSwap(false, true) {
-		return // Already closed
-	}
+// SetPackageLevel overrides the minimum level for a package and its
+// descendants without an override of their own, separated by dots or slashes:
+// a level set on "app.db" applies to "app.db.sql" and "app.db/pool"
+// (thread-safe)
+func (cl *ChannelLogger) SetPackageLevel(pkg string, level LogLevel) {
+	cl.updateLevels(&cl.pkgLevels, func(levels map[string]LogLevel) {
+		levels[pkg] = level
+	})
+}
 
-	close(cl.done) // Signal shutdown
-	cl.wg.Wait()   // Wait for goroutines to finish
+// ClearPackageLevel removes a package override so the global level applies again
+func (cl *ChannelLogger) ClearPackageLevel(pkg string) {
+	cl.updateLevels(&cl.pkgLevels, func(levels map[string]LogLevel) {
+		delete(levels, pkg)
+	})
+}
+
+// PackageLevels returns a copy of the per-package level overrides
+func (cl *ChannelLogger) PackageLevels() map[string]LogLevel {
+	return copyLevels(&cl.pkgLevels)
+}
 
-	// Close all file handles
+// EffectiveLevel returns the minimum level applying to a package, inherited
+// from its nearest overridden ancestor or the global level (thread-safe)
+func (cl *ChannelLogger) EffectiveLevel(pkg string) LogLevel {
+	return cl.levelFor(pkg)
+}
+
+// EffectiveLevels returns the effective level of every overridden package and
+// every package written to a file so far
+func (cl *ChannelLogger) EffectiveLevels() map[string]LogLevel {
+	levels := cl.PackageLevels()
 	cl.mu.Lock()
-	for pkg, f := range cl.files {
-		if err := f.Close(); err != nil {
-			cl.handleError(fmt.Errorf(ErrCloseLogFile, pkg, err))
-		}
+	for pkg := range cl.packages {
+		levels[pkg] = DEBUG
 	}
 	cl.mu.Unlock()
+	for pkg := range levels {
+		levels[pkg] = cl.levelFor(pkg)
+	}
+	return levels
+}
 
-	// Close channels
+// copyLevels returns a copy of the overrides published in p
+func copyLevels(p *atomic.Pointer[map[string]LogLevel]) map[string]LogLevel {
+	levels := make(map[string]LogLevel)
+	if current := p.Load(); current != nil {
+		for name, level := range *current {
+			levels[name] = level
+		}
+	}
+	return levels
+}
+
+// Enabled reports whether entries of a package at level are written, taking
+// package overrides into account (thread-safe)
+func (cl *ChannelLogger) Enabled(pkg string, level LogLevel) bool {
+	return level >= cl.levelFor(pkg)
+}
+
+// keeps reports whether an entry would be written or retained below the
+// level by the flight recorder or the recent entries
+func (cl *ChannelLogger) keeps(tenant, pkg string, level LogLevel) bool {
+	return level >= cl.levelForTenant(tenant, pkg) || cl.flight != nil ||
+		(cl.recent != nil && cl.config.RecentBelowMinLevel)
+}
+
+// updateLevels applies fn to a copy of the overrides in p and publishes it
+func (cl *ChannelLogger) updateLevels(p *atomic.Pointer[map[string]LogLevel], fn func(map[string]LogLevel)) {
+	cl.levelsMu.Lock()
+	defer cl.levelsMu.Unlock()
+	levels := copyLevels(p)
+	fn(levels)
+	p.Store(&levels)
+}
+
+// levelFor returns the effective minimum level of a package: the override of
+// the package or of its nearest ancestor, otherwise the global level
+func (cl *ChannelLogger) levelFor(pkg string) LogLevel {
+	if levels := cl.pkgLevels.Load(); levels != nil && len(*levels) > 0 {
+		for name := pkg; ; {
+			if level, ok := (*levels)[name]; ok {
+				return level
+			}
+			idx := strings.LastIndexAny(name, "./")
+			if idx < 0 {
+				break
+			}
+			name = name[:idx]
+		}
+	}
+	return LogLevel(cl.minLevel.Load())
+}
+
+// Flush writes the entries queued so far, including those in Config.Queues
+// and beyond BufferSize, then flushes compressed frames and buffering sinks
+func (cl *ChannelLogger) Flush() error {
+	cl.waitForwarded()
+	return cl.do(cl.flushQueued)
+}
+
+// Close gracefully shuts down the logger. Counts of PackageLogger.Count and
+// sampling markers not yet reported are logged, and every entry accepted
+// before Close is written before it returns; entries logged afterwards are
+// dropped with DropClosed.
+func (cl *ChannelLogger) Close() {
+	if !cl.closing.CompareAndSwap(false, true) {
+		return // Already closed or closing
+	}
+	// Entries written on the way out, while the logger still accepts them
+	cl.flushCounters() // Counts of the interval in progress
+	if cl.config.DropSummaries > 0 {
+		cl.flushDrops()
+	}
+	for _, s := range cl.markedSamplers() {
+		cl.flushSampled(s)
+	}
+	if cl.config.Lifecycle {
+		cl.logShutdown()
+	}
+	cl.closed.Store(true)
+	if cl.config.CloseOnExit {
+		defer cl.unregisterExit()
+	}
+
+	// Producers that saw the logger open finish queueing before the channel
+	// closes; later producers see it closed and drop their entries
+	cl.sendMu.Lock()
+	cl.closeQueues()
+	if cl.priority != nil {
+		close(cl.priority)
+	}
 	close(cl.logChan)
-	close(cl.errorChan)
+	cl.sendMu.Unlock()
+	cl.stopContextFlushes() // No producer registers more
+
+	cl.workerWg.Wait() // Worker drains every admitted entry and closes outputs
+	close(cl.done)     // Signal shutdown to the remaining goroutines
+	cl.wg.Wait()       // Error handler reports everything raised while draining
 }
 
 // Package creates a new PackageLogger for the specified package
@@ -613,57 +2059,163 @@ func (cl *ChannelLogger) Package(pkg string) *PackageLogger {
 // PackageLogger provides a package-scoped logger interface
 type PackageLogger struct {
 	logger *ChannelLogger
+	tenant string // Empty unless created through a TenantLogger
 	pkg    string
+	fields map[string]interface{} // Bound by With, never modified once set
+	labels map[string]string      // Bound by WithLabel, never modified once set
+}
+
+// log builds an entry for this package and logs it
+func (pl *PackageLogger) log(ctx context.Context, level LogLevel, message string, fields map[string]interface{}) {
+	entry := getLogEntry()
+	entry.Tenant = pl.tenant
+	entry.Package = pl.pkg
+	entry.Level = level
+	entry.Message = message
+	entry.Context = ctx
+	entry.Timestamp = time.Now()
+	for k, v := range pl.fields {
+		entry.Fields[k] = v
+	}
+	pl.addLabels(entry)
+	for k, v := range fields {
+		entry.Fields[k] = v
+	}
+	pl.logger.logEntry(entry)
+}
+
+// Sub creates a PackageLogger for the child package "parent/name". It keeps
+// the tenant and bound fields of pl, and inherits the level of pl's package
+// unless the child has an override of its own.
+func (pl *PackageLogger) Sub(name string) *PackageLogger {
+	if name == "" {
+		panic(fmt.Errorf(ErrInvalidPackage))
+	}
+	sub := *pl
+	sub.pkg = pl.pkg + "/" + name
+	return &sub
+}
+
+// With returns a PackageLogger adding fields to every entry it logs, in
+// addition to those bound to pl. Fields passed to a logging call take
+// precedence.
+func (pl *PackageLogger) With(fields map[string]interface{}) *PackageLogger {
+	merged := make(map[string]interface{}, len(pl.fields)+len(fields))
+	for k, v := range pl.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	with := *pl
+	with.fields = merged
+	return &with
 }
 
 // Info logs an info-level message for this package
 func (pl *PackageLogger) Info(message string) {
-	pl.logger.Info(pl.pkg, message)
+	pl.log(nil, INFO, message, nil)
 }
 
 // Error logs an error-level message for this package
 func (pl *PackageLogger) Error(message string) {
-	pl.logger.Error(pl.pkg, message)
+	pl.log(nil, ERROR, message, nil)
 }
 
 // Debug logs a debug-level message for this package
 func (pl *PackageLogger) Debug(message string) {
-	pl.logger.Debug(pl.pkg, message)
+	pl.log(nil, DEBUG, message, nil)
+}
+
+// Fatal logs an error-level message for this package, dumps the retained
+// entries and exits; see ChannelLogger.Fatal
+func (pl *PackageLogger) Fatal(message string) {
+	pl.log(nil, ERROR, message, nil)
+	pl.logger.exitFatal()
 }
 
-// InfoF logs a formatted info-level message for this package
+// InfoF logs a formatted info-level message for this package. The message
+// is only formatted if the entry will be kept.
 func (pl *PackageLogger) InfoF(format string, args ...interface{}) {
-	pl.logger.Info(pl.pkg, fmt.Sprintf(format, args...))
+	if pl.logger.keeps(pl.tenant, pl.pkg, INFO) {
+		pl.log(nil, INFO, fmt.Sprintf(format, args...), nil)
+	}
 }
 
 // ErrorF logs a formatted error-level message for this package
 func (pl *PackageLogger) ErrorF(format string, args ...interface{}) {
-	pl.logger.Error(pl.pkg, fmt.Sprintf(format, args...))
+	if pl.logger.keeps(pl.tenant, pl.pkg, ERROR) {
+		pl.log(nil, ERROR, fmt.Sprintf(format, args...), nil)
+	}
 }
 
 // DebugF logs a formatted debug-level message for this package
 func (pl *PackageLogger) DebugF(format string, args ...interface{}) {
-	pl.logger.Debug(pl.pkg, fmt.Sprintf(format, args...))
+	if pl.logger.keeps(pl.tenant, pl.pkg, DEBUG) {
+		pl.log(nil, DEBUG, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// InfoFn logs the message returned by fn at info level. fn is only called if
+// the entry will be kept, so expensive messages cost nothing when filtered.
+func (pl *PackageLogger) InfoFn(fn func() string) {
+	if pl.logger.keeps(pl.tenant, pl.pkg, INFO) {
+		pl.log(nil, INFO, fn(), nil)
+	}
+}
+
+// ErrorFn logs the message returned by fn at error level; see InfoFn
+func (pl *PackageLogger) ErrorFn(fn func() string) {
+	if pl.logger.keeps(pl.tenant, pl.pkg, ERROR) {
+		pl.log(nil, ERROR, fn(), nil)
+	}
+}
+
+// DebugFn logs the message returned by fn at debug level; see InfoFn
+func (pl *PackageLogger) DebugFn(fn func() string) {
+	if pl.logger.keeps(pl.tenant, pl.pkg, DEBUG) {
+		pl.log(nil, DEBUG, fn(), nil)
+	}
+}
+
+// Enabled reports whether entries of this package at level are written
+func (pl *PackageLogger) Enabled(level LogLevel) bool {
+	return level >= pl.logger.levelForTenant(pl.tenant, pl.pkg)
+}
+
+// DebugEnabled reports whether debug entries of this package are written
+func (pl *PackageLogger) DebugEnabled() bool {
+	return pl.Enabled(DEBUG)
+}
+
+// InfoEnabled reports whether info entries of this package are written
+func (pl *PackageLogger) InfoEnabled() bool {
+	return pl.Enabled(INFO)
+}
+
+// FatalF logs a formatted error-level message for this package and exits
+func (pl *PackageLogger) FatalF(format string, args ...interface{}) {
+	pl.Fatal(fmt.Sprintf(format, args...))
 }
 
 // InfoWithFields logs an info message with structured fields
 func (pl *PackageLogger) InfoWithFields(message string, fields map[string]interface{}) {
-	pl.logger.LogWithFields(pl.pkg, INFO, message, fields)
+	pl.log(nil, INFO, message, fields)
 }
 
 // ErrorWithFields logs an error message with structured fields
 func (pl *PackageLogger) ErrorWithFields(message string, fields map[string]interface{}) {
-	pl.logger.LogWithFields(pl.pkg, ERROR, message, fields)
+	pl.log(nil, ERROR, message, fields)
 }
 
 // DebugWithFields logs a debug message with structured fields
 func (pl *PackageLogger) DebugWithFields(message string, fields map[string]interface{}) {
-	pl.logger.LogWithFields(pl.pkg, DEBUG, message, fields)
+	pl.log(nil, DEBUG, message, fields)
 }
 
 // LogWithContext logs a context-aware message for this package
 func (pl *PackageLogger) LogWithContext(ctx context.Context, level, message string) {
-	pl.logger.LogWithContext(ctx, pl.pkg, level, message)
+	pl.log(ctx, ParseLogLevel(level), message, nil)
 }
 
 // GetPackageName returns the package name this logger is associated with
@@ -671,6 +2223,11 @@ func (pl *PackageLogger) GetPackageName() string {
 	return pl.pkg
 }
 
+// GetTenantName returns the tenant of this logger, or "" if it has none
+func (pl *PackageLogger) GetTenantName() string {
+	return pl.tenant
+}
+
 // Logger interface for compatibility
 type Logger interface {
 	Info(pkg, message string)
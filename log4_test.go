@@ -2,11 +2,16 @@ package log4
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -78,6 +83,11 @@ func TestParseLogLevel(t *testing.T) {
 		{"error", ERROR},
 		{"INVALID", INFO}, // default fallback
 		{"", INFO},        // default fallback
+		{" warning ", INFO},
+		{"Trace", DEBUG},
+		{"err", ERROR},
+		{"2", ERROR},
+		{"3", INFO}, // default fallback
 	}
 
 	for _, test := range tests {
@@ -85,6 +95,54 @@ func TestParseLogLevel(t *testing.T) {
 			t.Errorf("ParseLogLevel(%s) = %v, want %v", test.input, got, test.expected)
 		}
 	}
+
+	UnknownLevelFallback = ERROR
+	defer func() { UnknownLevelFallback = INFO }()
+	if got := ParseLogLevel("verbose"); got != ERROR {
+		t.Errorf("Expected the configured fallback, got %v", got)
+	}
+}
+
+func TestParseLogLevelStrict(t *testing.T) {
+	for _, input := range []string{"debug", "TRACE", "0", "Info", "WARN", "1", "error", "FATAL", "2"} {
+		if _, err := ParseLogLevelStrict(input); err != nil {
+			t.Errorf("ParseLogLevelStrict(%q) failed: %v", input, err)
+		}
+	}
+	for _, input := range []string{"", "INVALID", "-1", "3", "1.0", "0x1"} {
+		if level, err := ParseLogLevelStrict(input); err == nil {
+			t.Errorf("ParseLogLevelStrict(%q) = %v, want error", input, level)
+		}
+	}
+
+	var level LogLevel
+	if err := level.UnmarshalText([]byte("warning")); err != nil || level != INFO {
+		t.Errorf("UnmarshalText should accept aliases, got %v, %v", level, err)
+	}
+}
+
+func FuzzParseLogLevel(f *testing.F) {
+	for _, seed := range []string{"DEBUG", "info", " Error ", "WARNING", "trace", "0", "2", "-1", "", "\xff"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		level, err := ParseLogLevelStrict(input)
+		if err != nil {
+			if got := ParseLogLevel(input); got != UnknownLevelFallback {
+				t.Errorf("ParseLogLevel(%q) = %v, want fallback", input, got)
+			}
+			return
+		}
+		if level < DEBUG || level > ERROR {
+			t.Fatalf("ParseLogLevelStrict(%q) = %d, out of range", input, level)
+		}
+		if again, err := ParseLogLevelStrict(level.String()); err != nil || again != level {
+			t.Errorf("Level %v does not round-trip: %v, %v", level, again, err)
+		}
+		if got := ParseLogLevel(input); got != level {
+			t.Errorf("ParseLogLevel(%q) = %v, strict parser returned %v", input, got, level)
+		}
+	})
 }
 
 // Test Config validation
@@ -136,6 +194,13 @@ func TestConfigValidation(t *testing.T) {
 			t.Errorf("DirMode not auto-fixed: got %o, want %o", config.DirMode, DefaultDirMode)
 		}
 	})
+	t.Run("Duplicate field keys", func(t *testing.T) {
+		config := DefaultConfig()
+		config.FieldKeyLevel = "msg"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected error for duplicate JSON field keys")
+		}
+	})
 }
 
 // Test package name sanitization
@@ -151,6 +216,14 @@ func TestSanitizePackageName(t *testing.T) {
 		{"package with spaces", "package_with_spaces"},
 		{"package@#$%", "package____"},
 		{strings.Repeat("a", 150), strings.Repeat("a", MaxPackageNameLen)},
+		{"CON", "CON_"},
+		{"nul", "nul_"},
+		{"Com1", "Com1_"},
+		{"lpt9", "lpt9_"},
+		{"console", "console"},
+		{"com10", "com10"},
+		{`C:\logs`, "C__logs"},
+		{"trailing. ", "trailing__"},
 	}
 
 	for _, test := range tests {
@@ -319,12 +392,96 @@ func TestContextLogging(t *testing.T) {
 
 		logFile := filepath.Join(tempDir, "test.log")
 		content := readFile(t, logFile)
-		if strings.Contains(content, "Cancelled context message") {
-			t.Error("Cancelled context message should not be logged")
+		if !strings.Contains(content, "Cancelled context message") || !strings.Contains(content, "context_error=context canceled") {
+			t.Errorf("Expected the cancelled context message with its reason, got %q", content)
+		}
+	})
+
+	t.Run("Cause", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(errors.New("client went away"))
+
+		logger.Package("cause").LogWithContext(ctx, "ERROR", "Request abandoned")
+		logger.Flush()
+
+		content := readFile(t, filepath.Join(tempDir, "cause.log"))
+		if !strings.Contains(content, "context_error=client went away") {
+			t.Errorf("Expected the cause of the cancellation, got %q", content)
 		}
 	})
 }
 
+func TestSkipCancelledContext(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.LogOnCancelledContext = false
+	logger := NewChannelLoggerWithConfig(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logger.LogWithContext(ctx, "test", "INFO", "Before")
+	logger.Flush()
+	cancel()
+	logger.LogWithContext(ctx, "test", "INFO", "Cancelled")
+	logger.Package("test").LogWithContext(ctx, "INFO", "Cancelled")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "test.log"))
+	if countLines(content) != 1 || strings.Contains(content, "Cancelled") {
+		t.Errorf("Expected only the entry logged before cancellation, got %q", content)
+	}
+}
+
+func TestCancelledContextDropReason(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	reasons := make(map[DropReason]int)
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.LogOnCancelledContext = false
+	config.Sinks = []Sink{sink}
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		mu.Lock()
+		reasons[reason]++
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logger.Info("test", "stalls the worker")
+	logger.LogWithContext(ctx, "test", "INFO", "Cancelled while queued")
+	cancel()
+	logger.LogWithContext(ctx, "test", "INFO", "Cancelled")
+	logger.Package("test").LogWithContext(ctx, "INFO", "Cancelled")
+	close(sink.release)
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if reasons[DropCancelled] != 2 || len(reasons) != 1 {
+		t.Errorf("Expected 2 entries dropped with DropCancelled, got %v", reasons)
+	}
+	if stats := logger.Stats(); stats.Dropped != 2 {
+		t.Errorf("Expected the drops in Stats, got %d", stats.Dropped)
+	}
+	if DropCancelled.String() != "cancelled" {
+		t.Errorf("Unexpected name %q", DropCancelled.String())
+	}
+
+	// Logged before the context ended, so written as it was
+	content := readFile(t, filepath.Join(tempDir, "test.log"))
+	if !strings.Contains(content, "Cancelled while queued") || strings.Contains(content, FieldContextError) {
+		t.Errorf("Expected the entry queued before cancel to be written untouched, got %q", content)
+	}
+}
+
 // Test minimum level changes
 func TestMinLevelChanges(t *testing.T) {
 	tempDir := createTempDir(t)
@@ -423,7 +580,7 @@ func TestPackageLoggerMethods(t *testing.T) {
 	content := readFile(t, logFile)
 	expectedMessages := []string{
 		"Info message",
-		"Error message", 
+		"Error message",
 		"Debug message",
 		"Formatted info: 42",
 		"Formatted error: test",
@@ -476,13 +633,48 @@ func TestLogRotation(t *testing.T) {
 	}
 }
 
+func TestManualRotate(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+
+	logger.Info("manual", "First file")
+	time.Sleep(50 * time.Millisecond)
+
+	if err := logger.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	logger.Info("manual", "Second file")
+	time.Sleep(50 * time.Millisecond)
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "manual.log.1")); !strings.Contains(content, "First file") {
+		t.Errorf("Rotated file should contain the first message, got %q", content)
+	}
+	if content := readFile(t, filepath.Join(tempDir, "manual.log")); strings.Contains(content, "First file") {
+		t.Errorf("Active file should only contain new messages, got %q", content)
+	}
+
+	if err := logger.Rotate(); err == nil {
+		t.Error("Expected error rotating a closed logger")
+	}
+}
+
 // Test error handling
 func TestErrorHandling(t *testing.T) {
 	var capturedErrors []error
 	var mu sync.Mutex
 
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// A regular file as parent makes the directory impossible to create, even as root
+	notDir := filepath.Join(tempDir, "file")
+	os.WriteFile(notDir, nil, 0644)
+
 	config := DefaultConfig()
-	config.LogDir = "/invalid/path/that/does/not/exist"
+	config.LogDir = filepath.Join(notDir, "logs")
 	config.ErrorHandler = func(err error) {
 		mu.Lock()
 		capturedErrors = append(capturedErrors, err)
@@ -606,6 +798,41 @@ func TestMemoryPool(t *testing.T) {
 	putLogEntry(entry3)
 }
 
+func TestBufferPool(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &bufferSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+
+	// A short entry after a long one must not carry bytes of the buffer's
+	// previous use
+	logger.Info("app", strings.Repeat("x", 2000))
+	logger.Info("app", "short")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "app.log"))
+	if content != sink.buf.String() {
+		t.Errorf("Expected the sink to receive the file's lines, got %q", sink.buf.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "INFO: short") {
+		t.Errorf("Unexpected lines %q", lines)
+	}
+
+	huge := make([]byte, 0, maxPooledBuffer+1)
+	putBuffer(&huge)
+	for i := 0; i < 10; i++ {
+		if buf := getBuffer(); cap(*buf) > maxPooledBuffer {
+			t.Fatal("Expected buffers beyond maxPooledBuffer not to be pooled")
+		}
+	}
+}
+
 // Benchmark tests
 func BenchmarkChannelLogger(b *testing.B) {
 	tempDir := createTempDir(&testing.T{})
@@ -624,6 +851,67 @@ func BenchmarkChannelLogger(b *testing.B) {
 	})
 }
 
+func BenchmarkWriteEntry(b *testing.B) {
+	tempDir := createTempDir(&testing.T{})
+	defer cleanupTempDir(&testing.T{}, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entry := getLogEntry()
+		entry.Package = "benchmark"
+		entry.Level = INFO
+		entry.Message = "Benchmark message"
+		entry.Timestamp = time.Now()
+		logger.writeEntry(entry)
+	}
+}
+
+// BenchmarkFileWrite compares writing a formatted record straight to the
+// file, as the logging goroutine does, with writing it through the
+// log.Logger and MultiWriter it used to go through. Both run on the logging
+// goroutine, which owns the files, and write the same record.
+func BenchmarkFileWrite(b *testing.B) {
+	tempDir := createTempDir(&testing.T{})
+	defer cleanupTempDir(&testing.T{}, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	entry := &LogEntry{Package: "benchmark", Level: INFO, Message: "Benchmark message", Timestamp: time.Now()}
+	record := append(logger.formatterFor("benchmark").Format(nil, entry), '\n')
+
+	for _, bm := range []struct {
+		name   string
+		writer func(w io.Writer) io.Writer
+	}{
+		{"Direct", func(w io.Writer) io.Writer { return w }},
+		{"LogLogger", func(w io.Writer) io.Writer { return log.New(io.MultiWriter(w), "", 0).Writer() }},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(record)))
+			logger.do(func() {
+				w := bm.writer(logger.getWriter("benchmark"))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					w.Write(record)
+				}
+				b.StopTimer()
+			})
+		})
+	}
+}
+
 func BenchmarkStructuredLogging(b *testing.B) {
 	tempDir := createTempDir(&testing.T{})
 	defer cleanupTempDir(&testing.T{}, tempDir)
@@ -645,4 +933,380 @@ func BenchmarkStructuredLogging(b *testing.B) {
 			i++
 		}
 	})
-}
\ No newline at end of file
+}
+
+// blockingSink stalls the logging goroutine until release is closed
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(entry *LogEntry, line []byte) error {
+	<-s.release
+	return nil
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestFlushWritesQueued(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.BufferSize = 10
+	config.MaxBufferSize = 100
+	config.Queues = []Queue{{Pattern: "audit", BufferSize: 100}}
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Info("app", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 50; i++ {
+		logger.Info("app", "Queued before Flush") // Beyond BufferSize, so spilled
+		logger.Info("audit", "Queued before Flush")
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond) // Flush is waiting by then
+		close(sink.release)
+	}()
+	if err := logger.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	for _, pkg := range []string{"app", "audit"} {
+		if n := strings.Count(readFile(t, filepath.Join(tempDir, pkg+".log")), "Queued before Flush"); n != 50 {
+			t.Errorf("Expected the entries of %s queued before Flush written, got %d", pkg, n)
+		}
+	}
+}
+
+func TestContextDeadlineEnqueue(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.BufferSize = 1
+	config.Sinks = []Sink{sink}
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Info("ctx", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	logger.Info("ctx", "fills the queue")
+
+	t.Run("Deadline expires", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		logger.LogWithContext(ctx, "ctx", "INFO", "gives up")
+		if waited := time.Since(start); waited < 40*time.Millisecond {
+			t.Errorf("Expected to wait for the deadline, waited %v", waited)
+		}
+		if logger.Stats().Dropped != 1 {
+			t.Errorf("Expected one dropped entry, got %d", logger.Stats().Dropped)
+		}
+	})
+
+	t.Run("Queued before deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(sink.release)
+		}()
+		logger.LogWithContext(ctx, "ctx", "INFO", "waits for room")
+		time.Sleep(50 * time.Millisecond)
+
+		content := readFile(t, filepath.Join(tempDir, "ctx.log"))
+		if !strings.Contains(content, "waits for room") {
+			t.Errorf("Entry should be written once room is available, got %q", content)
+		}
+	})
+}
+
+func TestOnDrop(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	reasons := make(map[DropReason][]string)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.BufferSize = 1
+	config.Sinks = []Sink{sink}
+	config.ErrorHandler = func(error) {}
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		mu.Lock()
+		reasons[reason] = append(reasons[reason], entry.Message)
+		mu.Unlock()
+	}
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.Info("drop", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	logger.Info("drop", "fills the queue")
+	logger.Info("drop", "overflows")
+
+	close(sink.release)
+	logger.Close()
+	logger.Info("drop", "after close")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := reasons[DropOverflow]; len(got) != 1 || got[0] != "overflows" {
+		t.Errorf("Unexpected overflow drops: %v", got)
+	}
+	if got := reasons[DropClosed]; len(got) != 1 || got[0] != "after close" {
+		t.Errorf("Unexpected closed drops: %v", got)
+	}
+	if DropContextDone.String() != "context_done" {
+		t.Errorf("Unexpected reason name %q", DropContextDone)
+	}
+}
+
+// Test that every entry accepted before Close is written while producers race it
+func TestCloseDrainsQueue(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var dropped atomic.Int64
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.BufferSize = 64
+	config.ErrorHandler = func(error) {}
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		dropped.Add(1)
+	}
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	const producers = 16
+	const perProducer = 500
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < perProducer; i++ {
+				logger.Info("stress", fmt.Sprintf("producer %d message %d", p, i))
+			}
+		}(p)
+	}
+
+	close(start)
+	time.Sleep(time.Millisecond)
+	logger.Close()
+	wg.Wait()
+
+	written := countLines(readFile(t, filepath.Join(tempDir, "stress.log")))
+	if total := written + int(dropped.Load()); total != producers*perProducer {
+		t.Errorf("Expected %d entries written or dropped, got %d written and %d dropped",
+			producers*perProducer, written, dropped.Load())
+	}
+	if stats := logger.Stats(); int(stats.Written) != written {
+		t.Errorf("Stats report %d written, file has %d lines", stats.Written, written)
+	}
+}
+
+func TestLazyLogging(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	pl := logger.Package("lazy")
+
+	if pl.DebugEnabled() || !pl.InfoEnabled() || logger.Enabled("lazy", DEBUG) {
+		t.Error("Unexpected enabled levels at INFO")
+	}
+	logger.SetPackageLevel("lazy", DEBUG)
+	if !pl.DebugEnabled() || !logger.Enabled("lazy", DEBUG) || logger.Enabled("other", DEBUG) {
+		t.Error("Package override should enable DEBUG for lazy only")
+	}
+	logger.ClearPackageLevel("lazy")
+
+	calls := 0
+	expensive := func() string {
+		calls++
+		return "Expensive state"
+	}
+	pl.DebugFn(expensive)
+	pl.DebugF("State %v", stringerFunc(func() string { calls++; return "" }))
+	pl.InfoFn(expensive)
+	logger.Close()
+
+	if calls != 1 {
+		t.Errorf("Expected only the INFO message to be built, got %d calls", calls)
+	}
+	content := readFile(t, filepath.Join(tempDir, "lazy.log"))
+	if countLines(content) != 1 || !strings.Contains(content, "INFO: Expensive state") {
+		t.Errorf("Unexpected log content:\n%s", content)
+	}
+}
+
+// stringerFunc counts formatting in tests
+type stringerFunc func() string
+
+func (f stringerFunc) String() string { return f() }
+
+func TestHierarchicalLevels(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.SetPackageLevel("app.db", DEBUG)
+	logger.SetPackageLevel("app.db.cache", ERROR)
+
+	logger.Debug("app.db.sql", "Query plan")
+	logger.Info("app.db.cache", "Hidden")
+	logger.Debug("app.dbx", "Hidden")
+	logger.Debug("app", "Hidden")
+	logger.Info("web", "Started")
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "app_db_sql.log")); !strings.Contains(content, "Query plan") {
+		t.Errorf("Child package should inherit DEBUG, got %q", content)
+	}
+	for _, name := range []string{"app_db_cache.log", "app_dbx.log", "app.log"} {
+		if fileExists(filepath.Join(tempDir, name)) {
+			t.Errorf("Did not expect %s to be written", name)
+		}
+	}
+
+	want := map[string]LogLevel{"app.db": DEBUG, "app.db.cache": ERROR, "app.db.sql": DEBUG, "web": INFO}
+	if got := logger.EffectiveLevels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("EffectiveLevels() = %v, want %v", got, want)
+	}
+	if level := logger.EffectiveLevel("app.db.cache.redis"); level != ERROR {
+		t.Errorf("Expected the nearest ancestor to win, got %v", level)
+	}
+}
+
+func TestSubLogger(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.SetPackageLevel("app", ERROR)
+	logger.SetPackageLevel("app/cache", DEBUG)
+
+	app := logger.Package("app").With(map[string]interface{}{"svc": "api", "region": "eu"})
+	db := app.Sub("db")
+	db.Info("Hidden")
+	db.Error("Connection lost")
+	db.ErrorWithFields("Retrying", map[string]interface{}{"region": "us"})
+	app.Sub("cache").Debug("Cache warm")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "app_db.log"))
+	if strings.Contains(content, "Hidden") {
+		t.Errorf("Child should inherit the ERROR level of its parent, got %q", content)
+	}
+	if !strings.Contains(content, "Connection lost") || !strings.Contains(content, "svc=api") {
+		t.Errorf("Expected bound fields on the child's entries, got %q", content)
+	}
+	if !strings.Contains(content, "region=us") {
+		t.Errorf("Expected per-call fields to override bound fields, got %q", content)
+	}
+	if content := readFile(t, filepath.Join(tempDir, "app_cache.log")); !strings.Contains(content, "Cache warm") {
+		t.Errorf("Expected the child's own override to win, got %q", content)
+	}
+	if level := logger.EffectiveLevel("app/db"); level != ERROR {
+		t.Errorf("EffectiveLevel(app/db) = %v, want ERROR", level)
+	}
+}
+
+func TestMaxEntryAge(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	var stale atomic.Int64
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.MaxEntryAge = 20 * time.Millisecond
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		if reason == DropStale {
+			stale.Add(1)
+		}
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("queue", "stalls the worker")
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		logger.Info("queue", "waited too long")
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(sink.release)
+	logger.Info("queue", "fresh")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "queue.log"))
+	if strings.Contains(content, "waited too long") || !strings.Contains(content, "fresh") {
+		t.Errorf("Expected only entries within MaxEntryAge, got:\n%s", content)
+	}
+	stats := logger.Stats()
+	if stats.Stale != 3 || stale.Load() != 3 {
+		t.Errorf("Expected 3 stale entries, got %d (OnDrop %d)", stats.Stale, stale.Load())
+	}
+	if stats.MaxEntryAge != config.MaxEntryAge {
+		t.Errorf("Expected MaxEntryAge %v in stats, got %v", config.MaxEntryAge, stats.MaxEntryAge)
+	}
+}
+
+func TestPackageCaseCollision(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	var errs []error
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("api", "lower")
+	logger.Info("API", "upper")
+	logger.Info("api", "lower again")
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "package API differs from api only in case") {
+		t.Errorf("Expected one collision error, got %v", errs)
+	}
+}
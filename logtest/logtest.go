@@ -0,0 +1,205 @@
+// Package logtest helps tests assert on what code logs through log4.
+//
+// Example usage:
+//
+//	func TestCheckout(t *testing.T) {
+//		logger := logtest.New(t, log4.WithLevel(log4.DEBUG))
+//		logger.FailOnError() // Any unexpected ERROR entry fails the test
+//
+//		svc := NewService(logger.ChannelLogger)
+//		svc.Checkout(cart)
+//
+//		logtest.ExpectLog(t, logger, log4.INFO, `order \d+ created`)
+//		logtest.ExpectNoLog(t, logger, log4.ERROR, `payment`)
+//	}
+package logtest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MhunterDev/log4"
+)
+
+// DefaultTimeout is how long ExpectLog waits for a matching entry
+var DefaultTimeout = time.Second
+
+// Recorder is a log4.Sink that keeps a copy of every entry written
+type Recorder struct {
+	mu       sync.Mutex
+	entries  []*log4.LogEntry
+	expected map[*log4.LogEntry]bool // Entries matched by ExpectLog
+	changed  chan struct{}           // Closed and replaced on every write
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{expected: make(map[*log4.LogEntry]bool), changed: make(chan struct{})}
+}
+
+// Write records a copy of the entry
+func (r *Recorder) Write(entry *log4.LogEntry, line []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry.Clone())
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return nil
+}
+
+// Close does nothing
+func (r *Recorder) Close() error { return nil }
+
+// Entries returns the entries recorded so far
+func (r *Recorder) Entries() []*log4.LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*log4.LogEntry(nil), r.entries...)
+}
+
+// Reset forgets the recorded entries
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+	r.expected = make(map[*log4.LogEntry]bool)
+}
+
+// find returns the first recorded entry matching match, marking it expected,
+// and a channel closed on the next write
+func (r *Recorder) find(match func(*log4.LogEntry) bool) (*log4.LogEntry, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if match(e) {
+			r.expected[e] = true
+			return e, nil
+		}
+	}
+	return nil, r.changed
+}
+
+// Wait returns the first entry matching match, waiting up to timeout for it
+// to be written
+func (r *Recorder) Wait(timeout time.Duration, match func(*log4.LogEntry) bool) (*log4.LogEntry, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		entry, changed := r.find(match)
+		if entry != nil {
+			return entry, true
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return nil, false
+		}
+	}
+}
+
+// Logger is a log4 logger for tests whose entries are recorded
+type Logger struct {
+	*log4.ChannelLogger
+	Recorder *Recorder
+
+	t           testing.TB
+	failOnError bool
+}
+
+// New creates a logger writing to a temporary directory, without console
+// output, with opts applied. It is closed when the test ends.
+func New(t testing.TB, opts ...log4.Option) *Logger {
+	t.Helper()
+	rec := NewRecorder()
+	all := append([]log4.Option{
+		log4.WithDir(t.TempDir()),
+		func(c *log4.Config) { c.DisableConsole = true },
+	}, opts...)
+	all = append(all, log4.WithSink(rec))
+
+	l := &Logger{ChannelLogger: log4.NewLogger(all...), Recorder: rec, t: t}
+	t.Cleanup(l.cleanup)
+	return l
+}
+
+// FailOnError makes the test fail when it ends if an ERROR entry was logged
+// that no ExpectLog call matched
+func (l *Logger) FailOnError() {
+	l.failOnError = true
+}
+
+// Sync waits until every entry logged so far has been written
+func (l *Logger) Sync() {
+	for l.Stats().QueueLength > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The worker finishes the entry it is writing before running Flush
+	l.Flush()
+}
+
+func (l *Logger) cleanup() {
+	l.Close()
+	if !l.failOnError {
+		return
+	}
+
+	l.Recorder.mu.Lock()
+	defer l.Recorder.mu.Unlock()
+	for _, e := range l.Recorder.entries {
+		if e.Level >= log4.ERROR && !l.Recorder.expected[e] {
+			l.t.Errorf("unexpected ERROR entry logged: %s", describe(e))
+		}
+	}
+}
+
+// ExpectLog fails the test unless an entry with the given level and a
+// message matching the regular expression pattern is written within
+// DefaultTimeout. It returns the entry.
+func ExpectLog(t testing.TB, l *Logger, level log4.LogLevel, pattern string) *log4.LogEntry {
+	t.Helper()
+	re := regexp.MustCompile(pattern)
+	entry, ok := l.Recorder.Wait(DefaultTimeout, func(e *log4.LogEntry) bool {
+		return e.Level == level && re.MatchString(e.Message)
+	})
+	if !ok {
+		t.Fatalf("no %s entry matching %q was logged within %s; got:\n%s",
+			level, pattern, DefaultTimeout, describeAll(l.Recorder.Entries()))
+	}
+	return entry
+}
+
+// ExpectNoLog fails the test if an entry with the given level and a message
+// matching pattern has been logged. Entries still queued are waited for.
+func ExpectNoLog(t testing.TB, l *Logger, level log4.LogLevel, pattern string) {
+	t.Helper()
+	re := regexp.MustCompile(pattern)
+	l.Sync()
+	for _, e := range l.Recorder.Entries() {
+		if e.Level == level && re.MatchString(e.Message) {
+			t.Errorf("unexpected entry logged: %s", describe(e))
+		}
+	}
+}
+
+func describe(e *log4.LogEntry) string {
+	s := fmt.Sprintf("[%s] %s: %s", e.Package, e.Level, e.Message)
+	if len(e.Fields) > 0 {
+		s += fmt.Sprintf(" %v", e.Fields)
+	}
+	return s
+}
+
+func describeAll(entries []*log4.LogEntry) string {
+	if len(entries) == 0 {
+		return "  (nothing)"
+	}
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = "  " + describe(e)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,88 @@
+package logtest
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/MhunterDev/log4"
+)
+
+// fakeT records failures instead of failing the real test
+type fakeT struct {
+	*testing.T
+	failures []string
+	cleanups []func()
+}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+// run calls fn like a test body and then the registered cleanups
+func (f *fakeT) run(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestExpectLog(t *testing.T) {
+	logger := New(t)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		logger.Info("orders", "order 42 created")
+	}()
+
+	entry := ExpectLog(t, logger, log4.INFO, `order \d+ created`)
+	if entry.Package != "orders" {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	ExpectNoLog(t, logger, log4.ERROR, `order`)
+}
+
+func TestExpectLogFailures(t *testing.T) {
+	defer func(d time.Duration) { DefaultTimeout = d }(DefaultTimeout)
+	DefaultTimeout = 20 * time.Millisecond
+
+	f := &fakeT{T: t}
+	f.run(func() {
+		logger := New(f)
+		logger.Error("db", "connection lost")
+		ExpectNoLog(f, logger, log4.ERROR, `lost`)
+		ExpectLog(f, logger, log4.INFO, `reconnected`)
+		t.Error("ExpectLog should have stopped the test")
+	})
+
+	if len(f.failures) != 2 {
+		t.Fatalf("Expected 2 failures, got %q", f.failures)
+	}
+}
+
+func TestFailOnError(t *testing.T) {
+	f := &fakeT{T: t}
+	f.run(func() {
+		logger := New(f)
+		logger.FailOnError()
+		logger.Error("db", "expected failure")
+		logger.Error("db", "silent failure")
+		ExpectLog(f, logger, log4.ERROR, `expected`)
+	})
+
+	if len(f.failures) != 1 || f.failures[0] != "unexpected ERROR entry logged: [db] ERROR: silent failure" {
+		t.Errorf("Expected only the silent failure to be reported, got %q", f.failures)
+	}
+}
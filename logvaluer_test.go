@@ -0,0 +1,71 @@
+package log4
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// secret logs as a redacted value and counts its LogValue calls
+type secret struct {
+	value string
+	calls *atomic.Int32
+}
+
+func (s secret) LogValue() interface{} {
+	s.calls.Add(1)
+	return fmt.Sprintf("[REDACTED %d chars]", len(s.value))
+}
+
+// recursive returns itself from LogValue
+type recursive struct{}
+
+func (r recursive) LogValue() interface{} { return r }
+
+// panicky panics in LogValue
+type panicky struct{}
+
+func (panicky) LogValue() interface{} { panic("boom") }
+
+// slogUser implements slog.LogValuer
+type slogUser struct{ id int }
+
+func (u slogUser) LogValue() slog.Value { return slog.IntValue(u.id) }
+
+func TestLogValuer(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var calls atomic.Int32
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+
+	pl := logger.Package("auth")
+	pl.InfoWithFields("login", map[string]interface{}{
+		"password": secret{"hunter2", &calls},
+		"loop":     recursive{},
+		"bad":      panicky{},
+		"user":     slogUser{42},
+	})
+	pl.DebugWithFields("filtered", map[string]interface{}{"password": secret{"hunter2", &calls}})
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "auth.log"))
+	for _, want := range []string{"password=[REDACTED 7 chars]", "loop=!ERROR: LogValue called 100 times", "bad=!PANIC: boom", "user=42"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in %q", want, content)
+		}
+	}
+	if strings.Contains(content, "hunter2") {
+		t.Errorf("Expected the secret to be redacted, got %q", content)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected LogValue to be called once, for the entry written, got %d", n)
+	}
+}
@@ -0,0 +1,196 @@
+package log4
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ManifestFile is the name of the manifest kept in LogDir with Config.Manifest
+const ManifestFile = "manifest.sha256"
+
+// ManifestEntry is a line of the manifest, recorded when a file is rotated:
+//
+//	<sha256> <size> <rotated at, RFC 3339> <chain> <path>
+//
+// Chain links the entries into a hash chain: it is the SHA-256 of the Chain
+// of the previous entry and the other columns of this one, so editing,
+// removing or reordering lines breaks the chain from there on.
+type ManifestEntry struct {
+	SHA256  string    // Hex encoded
+	Size    int64     // Bytes on disk
+	Rotated time.Time // When the file was rotated
+	Chain   string    // Hex encoded link of the hash chain
+	Path    string    // Name it was rotated to, relative to LogDir
+}
+
+// link returns the Chain of e following an entry whose Chain is prev
+func (e ManifestEntry) link(prev string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s %d %s %s", prev, e.SHA256, e.Size, e.Rotated.Format(time.RFC3339), e.Path)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordManifest appends the checksum of a file just rotated to the
+// manifest, linked to its last entry. The file is read in full on the logging
+// goroutine.
+func (cl *ChannelLogger) recordManifest(rotated string) {
+	fsys := cl.config.fileSystem()
+	sum, size, err := hashFile(fsys, rotated)
+	if err != nil {
+		cl.handleError(fmt.Errorf(ErrManifest, rotated, err))
+		return
+	}
+	rel, err := filepath.Rel(cl.config.LogDir, rotated)
+	if err != nil {
+		rel = rotated
+	}
+	path := filepath.Join(cl.config.LogDir, ManifestFile)
+	prev, err := lastManifestChain(fsys, path)
+	if err != nil {
+		cl.handleError(fmt.Errorf(ErrManifest, rotated, err))
+		return
+	}
+
+	e := ManifestEntry{SHA256: sum, Size: size, Rotated: time.Now().Truncate(time.Second), Path: filepath.ToSlash(rel)}
+	line := fmt.Sprintf("%s %d %s %s %s\n", e.SHA256, e.Size, e.Rotated.Format(time.RFC3339), e.link(prev), e.Path)
+	f, err := fsys.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, cl.config.FileMode)
+	if err == nil {
+		_, err = io.WriteString(f, line)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		cl.handleError(fmt.Errorf(ErrManifest, rotated, err))
+	}
+}
+
+// lastManifestChain returns the Chain of the last entry of the manifest at
+// path, or "" if it has none yet
+func lastManifestChain(fsys FS, path string) (string, error) {
+	f, err := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		last = scanner.Text()
+	}
+	if parts := strings.SplitN(last, " ", 5); len(parts) == 5 {
+		return parts[3], scanner.Err()
+	}
+	return "", scanner.Err()
+}
+
+// hashFile returns the hex encoded SHA-256 and the size of a file of fsys
+func hashFile(fsys FS, path string) (string, int64, error) {
+	f, err := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// ReadManifest returns the entries of the manifest in dir, oldest first
+func ReadManifest(dir string) ([]ManifestEntry, error) {
+	f, err := os.Open(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		parts := strings.SplitN(scanner.Text(), " ", 5)
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("%s line %d: expected 5 columns", ManifestFile, line)
+		}
+		size, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid size: %w", ManifestFile, line, err)
+		}
+		rotated, err := time.Parse(time.RFC3339, parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid time: %w", ManifestFile, line, err)
+		}
+		entries = append(entries, ManifestEntry{SHA256: parts[0], Size: size, Rotated: rotated, Chain: parts[3], Path: parts[4]})
+	}
+	return entries, scanner.Err()
+}
+
+// VerifyManifest checks the hash chain of the manifest written with
+// Config.Manifest and the rotated files in dir against it. Files named by
+// NumericNamer move up as newer ones are rotated, so an entry for "app.log.1"
+// is checked against "app.log.2" once another file of app.log was rotated,
+// and so on. Files removed by retention are skipped; the first broken link of
+// the chain and every file present that does not match its entry are reported
+// as an *ErrManifestMismatch, joined with errors.Join.
+//
+// The chain shows that the manifest was not edited short of being rewritten
+// from the altered line on; compare the Chain of its last entry with one
+// kept elsewhere to rule that out.
+func VerifyManifest(dir string) error {
+	entries, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	prev := ""
+	for i, e := range entries {
+		if e.link(prev) != e.Chain {
+			errs = append(errs, &ErrManifestMismatch{Path: filepath.Join(dir, ManifestFile), Reason: fmt.Sprintf("hash chain broken at line %d", i+1)})
+			break
+		}
+		prev = e.Chain
+	}
+
+	shifts := make(map[string]int) // Later rotations of an active file
+	for i := len(entries) - 1; i >= 0; i-- {
+		path := filepath.Join(dir, filepath.FromSlash(entries[i].Path))
+		if idx := strings.LastIndexByte(path, '.'); idx > 0 {
+			if n, err := strconv.Atoi(path[idx+1:]); err == nil {
+				active := path[:idx]
+				path = active + "." + strconv.Itoa(n+shifts[active])
+				shifts[active]++
+			}
+		}
+		e := entries[i]
+
+		sum, size, err := hashFile(OSFS{}, path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			continue
+		case err != nil:
+			errs = append(errs, err)
+		case size != e.Size:
+			errs = append(errs, &ErrManifestMismatch{Path: path, Reason: fmt.Sprintf("size %d, recorded %d", size, e.Size)})
+		case sum != e.SHA256:
+			errs = append(errs, &ErrManifestMismatch{Path: path, Reason: "checksum differs"})
+		}
+	}
+	return errors.Join(errs...)
+}
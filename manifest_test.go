@@ -0,0 +1,94 @@
+package log4
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Manifest = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	for _, msg := range []string{"first", "second", "third"} {
+		logger.Info("app", msg)
+		logger.Flush()
+		if err := logger.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	logger.Close()
+
+	entries, err := ReadManifest(tempDir)
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 manifest entries, got %d", len(entries))
+	}
+	if entries[0].Path != "app.log.1" || entries[0].Size == 0 || len(entries[0].SHA256) != 64 {
+		t.Errorf("Unexpected manifest entry %+v", entries[0])
+	}
+	if err := VerifyManifest(tempDir); err != nil {
+		t.Fatalf("Expected untouched files to verify, got %v", err)
+	}
+
+	// The oldest rotated file holds the first entry
+	if err := os.WriteFile(filepath.Join(tempDir, "app.log.3"), []byte("tampered\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyManifest(tempDir)
+	var mismatch *ErrManifestMismatch
+	if !errors.As(err, &mismatch) || mismatch.Path != filepath.Join(tempDir, "app.log.3") {
+		t.Errorf("Expected a mismatch for app.log.3, got %v", err)
+	}
+}
+
+func TestManifestChain(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Manifest = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	for _, msg := range []string{"first", "second", "third"} {
+		logger.Info("app", msg)
+		logger.Flush()
+		logger.Rotate()
+	}
+	logger.Close()
+
+	entries, err := ReadManifest(tempDir)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Expected 3 manifest entries, got %d (%v)", len(entries), err)
+	}
+	if entries[1].Chain != entries[1].link(entries[0].Chain) {
+		t.Errorf("Entry 2 is not linked to entry 1: %+v", entries[1])
+	}
+	if err := VerifyManifest(tempDir); err != nil {
+		t.Fatalf("Expected the chain to verify, got %v", err)
+	}
+
+	// Dropping the record of the second rotation breaks the link of the third
+	path := filepath.Join(tempDir, ManifestFile)
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(path, []byte(lines[0]+lines[2]), 0644)
+
+	err = VerifyManifest(tempDir)
+	var mismatch *ErrManifestMismatch
+	if !errors.As(err, &mismatch) || mismatch.Path != path || !strings.Contains(mismatch.Reason, "line 2") {
+		t.Errorf("Expected the chain to break at line 2, got %v", err)
+	}
+}
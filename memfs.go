@@ -0,0 +1,381 @@
+package log4
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errDirNotEmpty is returned by MemFS.Remove for a directory with entries
+var errDirNotEmpty = errors.New("directory not empty")
+
+// MemFS is an FS holding the log files in memory, for environments without a
+// writable disk such as serverless functions. Snapshot and WriteTar return
+// what was written, e.g. to include it in a response or upload it when a job
+// ends; call Flush on the logger first. The zero value is an empty file
+// system whose roots, such as the working directory, exist.
+type MemFS struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode // keyed by cleaned path
+}
+
+// memNode is a file or directory of a MemFS. Renaming moves the node, so
+// SameFile compares nodes.
+type memNode struct {
+	dir     bool
+	mode    os.FileMode
+	modTime time.Time
+	data    []byte
+}
+
+// NewMemFS returns an empty MemFS
+func NewMemFS() *MemFS {
+	return &MemFS{}
+}
+
+// node returns the node at the cleaned path; roots, such as "." and "/",
+// always exist. m.mu is held.
+func (m *MemFS) node(name string) *memNode {
+	if m.nodes == nil {
+		m.nodes = make(map[string]*memNode)
+	}
+	n := m.nodes[name]
+	if n == nil && filepath.Dir(name) == name {
+		n = &memNode{dir: true, mode: os.ModeDir | 0755, modTime: time.Now()}
+		m.nodes[name] = n
+	}
+	return n
+}
+
+// OpenFile opens the named file with the flags of os.OpenFile
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	n := m.node(name)
+	switch {
+	case n == nil && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case n != nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case n != nil && n.dir && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	case n == nil:
+		if parent := m.node(filepath.Dir(name)); parent == nil || !parent.dir {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		n = &memNode{mode: perm & os.ModePerm, modTime: time.Now()}
+		m.nodes[name] = n
+	case flag&os.O_TRUNC != 0:
+		n.data = nil
+		n.modTime = time.Now()
+	}
+	return &memFile{fs: m, node: n, name: name, flag: flag}, nil
+}
+
+// Stat returns the info of the named file
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	n := m.node(name)
+	if n == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return n.info(name), nil
+}
+
+// Lstat is Stat, as a MemFS has no links
+func (m *MemFS) Lstat(name string) (os.FileInfo, error) {
+	return m.Stat(name)
+}
+
+// ReadDir returns the entries of the named directory sorted by name
+func (m *MemFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	n := m.node(name)
+	switch {
+	case n == nil:
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	case !n.dir:
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var entries []os.DirEntry
+	for path, child := range m.nodes {
+		if path != name && filepath.Dir(path) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(child.info(path)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// MkdirAll creates the directory path along with its missing parents
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = filepath.Clean(path)
+	if n := m.node(path); n != nil {
+		if !n.dir {
+			return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	// Parents first, the closest existing one ending the walk
+	var missing []string
+	for dir := path; m.node(dir) == nil; dir = filepath.Dir(dir) {
+		missing = append(missing, dir)
+	}
+	if parent := m.node(filepath.Dir(missing[len(missing)-1])); !parent.dir {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+	}
+	now := time.Now()
+	for i := len(missing) - 1; i >= 0; i-- {
+		m.nodes[missing[i]] = &memNode{dir: true, mode: os.ModeDir | perm&os.ModePerm, modTime: now}
+	}
+	return nil
+}
+
+// Rename moves oldpath, and everything below it if it is a directory, to
+// newpath, replacing the file at newpath
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	n := m.node(oldpath)
+	if n == nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if oldpath == newpath {
+		return nil
+	}
+	if target := m.node(newpath); target != nil && (target.dir || n.dir) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+	if parent := m.node(filepath.Dir(newpath)); parent == nil || !parent.dir {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if n.dir && below(newpath, oldpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+	}
+
+	for path, child := range m.nodes {
+		if below(path, oldpath) {
+			rel, _ := filepath.Rel(oldpath, path)
+			delete(m.nodes, path)
+			m.nodes[filepath.Join(newpath, rel)] = child
+		}
+	}
+	delete(m.nodes, oldpath)
+	m.nodes[newpath] = n
+	return nil
+}
+
+// Remove removes the named file or empty directory
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	n := m.node(name)
+	if n == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if n.dir {
+		for path := range m.nodes {
+			if path != name && filepath.Dir(path) == name {
+				return &fs.PathError{Op: "remove", Path: name, Err: errDirNotEmpty}
+			}
+		}
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+// RemoveAll removes path and everything below it; a missing path is not an
+// error
+func (m *MemFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = filepath.Clean(path)
+	for name := range m.nodes {
+		if below(name, path) {
+			delete(m.nodes, name)
+		}
+	}
+	if filepath.Dir(path) != path {
+		delete(m.nodes, path)
+	}
+	return nil
+}
+
+// SameFile reports whether a and b describe the same file of a MemFS
+func (m *MemFS) SameFile(a, b os.FileInfo) bool {
+	na, ok := a.Sys().(*memNode)
+	return ok && na == b.Sys()
+}
+
+// Snapshot returns a copy of the contents of every file, keyed by path
+func (m *MemFS) Snapshot() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make(map[string][]byte)
+	for path, n := range m.nodes {
+		if !n.dir {
+			files[path] = append([]byte(nil), n.data...)
+		}
+	}
+	return files
+}
+
+// WriteTar writes every file to w as a tar archive, sorted by path. Names
+// use forward slashes and are relative to the root for absolute paths.
+func (m *MemFS) WriteTar(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	paths := make([]string, 0, len(m.nodes))
+	for path, n := range m.nodes {
+		if !n.dir {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	tw := tar.NewWriter(w)
+	for _, path := range paths {
+		n := m.nodes[path]
+		hdr := &tar.Header{
+			Name:    strings.TrimPrefix(filepath.ToSlash(path), "/"),
+			Mode:    int64(n.mode.Perm()),
+			Size:    int64(len(n.data)),
+			ModTime: n.modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(n.data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// below reports whether the cleaned path name is inside the directory dir
+func below(name, dir string) bool {
+	for p := filepath.Dir(name); p != name; name, p = p, filepath.Dir(p) {
+		if p == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// info returns the current info of n at path; m.mu is held
+func (n *memNode) info(path string) os.FileInfo {
+	return memFileInfo{name: filepath.Base(path), size: int64(len(n.data)), mode: n.mode, modTime: n.modTime, node: n}
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	node    *memNode
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.node.dir }
+func (i memFileInfo) Sys() any           { return i.node }
+
+// memFile is a file opened by a MemFS. It keeps writing to its node after
+// the path is renamed or removed, like an open file of the operating system.
+type memFile struct {
+	fs     *MemFS
+	node   *memNode
+	name   string
+	flag   int
+	offset int64
+	closed bool
+}
+
+func (f *memFile) Name() string { return f.name }
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	switch {
+	case f.closed:
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	case f.node.dir || f.flag&os.O_WRONLY != 0:
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	case f.offset >= int64(len(f.node.data)):
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	switch {
+	case f.closed:
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrClosed}
+	case f.flag&(os.O_WRONLY|os.O_RDWR) == 0:
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset += int64(len(p))
+	f.node.modTime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrClosed}
+	}
+	return f.node.info(f.name), nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
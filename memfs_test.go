@@ -0,0 +1,105 @@
+package log4
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemFS(t *testing.T) {
+	mem := NewMemFS()
+	logger := NewLogger(WithDir("/logs"), WithFS(mem), WithConsoleWriter(io.Discard), WithRotation(1<<20, 3))
+
+	logger.Info("app", "Before rotation")
+	logger.Flush()
+	if err := logger.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	logger.Info("app", "After rotation")
+	logger.Info("db", "Connected")
+	logger.Close()
+
+	files := mem.Snapshot()
+	for name, want := range map[string]string{
+		"/logs/app.log":   "After rotation",
+		"/logs/app.log.1": "Before rotation",
+		"/logs/db.log":    "Connected",
+	} {
+		if got := string(files[filepath.FromSlash(name)]); !strings.Contains(got, want) || countLines(got) != 1 {
+			t.Errorf("Expected %s to hold %q, got %q", name, want, got)
+		}
+	}
+	if len(files) != 3 {
+		t.Errorf("Expected 3 files, got %d", len(files))
+	}
+
+	var buf bytes.Buffer
+	if err := mem.WriteTar(&buf); err != nil {
+		t.Fatalf("WriteTar failed: %v", err)
+	}
+	tr := tar.NewReader(&buf)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Reading the archive failed: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if got := strings.Join(names, ","); got != "logs/app.log,logs/app.log.1,logs/db.log" {
+		t.Errorf("Unexpected archive entries %s", got)
+	}
+}
+
+func TestMemFSOperations(t *testing.T) {
+	var mem MemFS
+
+	if _, err := mem.OpenFile("a/b.log", os.O_CREATE|os.O_WRONLY, 0644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing directory to fail, got %v", err)
+	}
+	if err := mem.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := mem.OpenFile("a/b/c.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, "one\n")
+	open, _ := f.Stat()
+
+	// Renamed files keep their identity and take writes
+	if err := mem.Rename("a", "z"); err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, "two\n")
+	f.Close()
+	onDisk, err := mem.Stat("z/b/c.log")
+	if err != nil || !mem.SameFile(open, onDisk) || onDisk.Size() != 8 {
+		t.Errorf("Expected the renamed file, got %v, %v", onDisk, err)
+	}
+	if _, err := mem.Stat("a/b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the old path to be gone, got %v", err)
+	}
+
+	entries, err := mem.ReadDir(".")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "z" || !entries[0].IsDir() {
+		t.Errorf("Unexpected entries %v, %v", entries, err)
+	}
+	if err := mem.Remove("z/b"); err == nil {
+		t.Error("Expected removing a directory with files to fail")
+	}
+	if err := mem.RemoveAll("z"); err != nil {
+		t.Fatal(err)
+	}
+	if files := mem.Snapshot(); len(files) != 0 {
+		t.Errorf("Expected no files, got %v", files)
+	}
+}
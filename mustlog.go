@@ -0,0 +1,116 @@
+package log4
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MustLog logs an entry and waits until it has been written, for audit
+// trails where an entry must never be lost and the caller needs to know it
+// was not. The entry skips level filtering, sampling, throttling and
+// MaxEntryAge, and is written to its package file whatever FileLevel is. If
+// the queue is full, MustLog waits for room until ctx is done.
+//
+// It returns nil once the file and every sink have written the entry, the
+// write errors joined otherwise, an *ErrDropped if the entry was dropped
+// (after Close, over a tenant quota, or when ctx ended while waiting for room
+// in a full queue), or an error wrapping the context's cause if ctx ended
+// after the entry was queued; such an entry is still written.
+func (cl *ChannelLogger) MustLog(ctx context.Context, pkg string, level LogLevel, message string, fields map[string]interface{}) error {
+	entry := getLogEntry()
+	entry.Package = pkg
+	entry.Level = level
+	entry.Message = message
+	entry.Timestamp = time.Now()
+	for k, v := range fields {
+		entry.Fields[k] = v
+	}
+	return cl.mustLog(ctx, entry)
+}
+
+// MustLog logs an entry for this package, with its tenant and bound fields,
+// and waits until it has been written; see ChannelLogger.MustLog
+func (pl *PackageLogger) MustLog(ctx context.Context, level LogLevel, message string, fields map[string]interface{}) error {
+	entry := getLogEntry()
+	entry.Tenant = pl.tenant
+	entry.Package = pl.pkg
+	entry.Level = level
+	entry.Message = message
+	entry.Timestamp = time.Now()
+	for k, v := range pl.fields {
+		entry.Fields[k] = v
+	}
+	pl.addLabels(entry)
+	for k, v := range fields {
+		entry.Fields[k] = v
+	}
+	return pl.logger.mustLog(ctx, entry)
+}
+
+// mustLog queues an entry for MustLog and waits for its outcome
+func (cl *ChannelLogger) mustLog(ctx context.Context, entry *LogEntry) error {
+	pkg := entry.Package
+	ack := make(chan error, 1) // Never blocks the logging goroutine
+	entry.ack = ack
+	entry.must = true
+	entry.Context = ctx
+	if !cl.mustEnqueue(entry) {
+		return <-ack
+	}
+	select {
+	case err := <-ack:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf(ErrUnconfirmed, pkg, context.Cause(ctx))
+	}
+}
+
+// mustEnqueue waits until the entry is queued, reporting whether it was;
+// otherwise it was dropped and its ack holds the error
+func (cl *ChannelLogger) mustEnqueue(entry *LogEntry) bool {
+	cl.sendMu.RLock()
+	defer cl.sendMu.RUnlock()
+
+	if cl.closed.Load() {
+		cl.drop(entry, DropClosed)
+		return false
+	}
+	if cl.config.SanitizeMessages {
+		sanitizeEntry(entry)
+	}
+	addRequestID(entry)
+	if cl.config.Normalize != nil {
+		cl.config.Normalize.normalizeEntry(entry)
+	}
+	if cl.config.SecretFields != SecretFieldsAllow {
+		cl.checkSecrets(entry)
+	}
+	cl.checkCancelled(entry) // Never drops MustLog entries
+	cl.addAutoFields(entry)
+	if cl.recent != nil {
+		cl.recent.add(entry)
+	}
+
+	entry.queued = time.Now()
+	ch := cl.logChan
+	q := cl.queueFor(entry)
+	if q != nil {
+		ch = q.ch
+	}
+	// Room in the queue wins over a context that is already done
+	select {
+	case ch <- entry:
+		q.admit()
+		return true
+	default:
+	}
+	select {
+	case ch <- entry:
+		q.admit()
+		return true
+	case <-entry.Context.Done():
+		cl.dropQueued(q, entry, DropContextDone)
+		return false
+	}
+}
@@ -0,0 +1,108 @@
+package log4
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMustLog(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.MinLevel = ERROR
+	config.FileLevel = ERROR
+	logger := NewChannelLoggerWithConfig(config)
+
+	err := logger.MustLog(context.Background(), "audit", DEBUG, "Record deleted", map[string]interface{}{"id": 42})
+	if err != nil {
+		t.Fatalf("MustLog failed: %v", err)
+	}
+	// Written once MustLog returns, without a Flush
+	content := readFile(t, filepath.Join(tempDir, "audit.log"))
+	if !strings.Contains(content, "Record deleted") || !strings.Contains(content, "id=42") {
+		t.Errorf("Expected the entry below MinLevel and FileLevel to be written, got %q", content)
+	}
+
+	pl := logger.Package("audit").With(map[string]interface{}{"user": "alice"})
+	if err := pl.MustLog(context.Background(), INFO, "Record restored", nil); err != nil {
+		t.Fatalf("PackageLogger.MustLog failed: %v", err)
+	}
+	if content := readFile(t, filepath.Join(tempDir, "audit.log")); !strings.Contains(content, "Record restored | user=alice") {
+		t.Errorf("Expected the bound fields, got %q", content)
+	}
+
+	logger.Close()
+	err = logger.MustLog(context.Background(), "audit", INFO, "Too late", nil)
+	var dropped *ErrDropped
+	if !errors.As(err, &dropped) || dropped.Reason != DropClosed {
+		t.Errorf("Expected an *ErrDropped with DropClosed after Close, got %v", err)
+	}
+}
+
+func TestMustLogSinkError(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.Sinks = []Sink{failingSink{}}
+	config.ErrorHandler = func(error) {}
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	err := logger.MustLog(context.Background(), "audit", INFO, "Shipped", nil)
+	var sinkErr *ErrSinkWrite
+	if !errors.As(err, &sinkErr) || sinkErr.Pkg != "audit" {
+		t.Errorf("Expected the sink's *ErrSinkWrite, got %v", err)
+	}
+}
+
+func TestMustLogDeadline(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.BufferSize = 1
+	config.Sinks = []Sink{sink}
+	config.ErrorHandler = func(error) {}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("audit", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+
+	// Queued, but not written before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := logger.MustLog(ctx, "audit", INFO, "queued", nil)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, &ErrDropped{}) {
+		t.Errorf("Expected an unconfirmed entry, got %v", err)
+	}
+
+	// The queue is full; the deadline passes while waiting for room
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = logger.MustLog(ctx, "audit", INFO, "dropped", nil)
+	var dropped *ErrDropped
+	if !errors.As(err, &dropped) || dropped.Reason != DropContextDone || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected an *ErrDropped with DropContextDone, got %v", err)
+	}
+
+	close(sink.release)
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "audit.log"))
+	if !strings.Contains(content, "queued") || strings.Contains(content, "dropped") {
+		t.Errorf("Expected the unconfirmed entry to be written and the dropped one not, got %q", content)
+	}
+}
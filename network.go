@@ -0,0 +1,104 @@
+package log4
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Dialer opens the connections of the network sinks, e.g. a *net.Dialer
+// bound to a source address, or one returned by ProxyDialer. Dialers of
+// golang.org/x/net/proxy implementing proxy.ContextDialer satisfy it too.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// sinkNetwork holds the network settings a sink inherits from the logger it
+// is added to, used where its BatchOptions leave them unset
+type sinkNetwork struct {
+	inherited atomic.Pointer[networkConfig]
+}
+
+// networkConfig are the network settings of a Config
+type networkConfig struct {
+	tls    *tls.Config
+	dialer Dialer
+}
+
+// inheritNetwork is called by NewChannelLoggerWithConfig
+func (n *sinkNetwork) inheritNetwork(c *Config) {
+	n.inherited.Store(&networkConfig{tls: c.TLS, dialer: c.Dialer})
+}
+
+// tlsConfig returns the tls.Config of a sink configured with opts, or nil
+// if it does not use TLS
+func (n *sinkNetwork) tlsConfig(opts BatchOptions) *tls.Config {
+	c := opts.TLS
+	if inherited := n.inherited.Load(); c == nil && inherited != nil {
+		c = inherited.tls
+	}
+	if c == nil || opts.TLSServerName == "" {
+		return c
+	}
+	c = c.Clone()
+	c.ServerName = opts.TLSServerName
+	return c
+}
+
+// dialer returns the Dialer of a sink configured with opts, or nil if
+// neither opts nor the logger set one
+func (n *sinkNetwork) dialer(opts BatchOptions) Dialer {
+	if opts.Dialer != nil {
+		return opts.Dialer
+	}
+	if inherited := n.inherited.Load(); inherited != nil {
+		return inherited.dialer
+	}
+	return nil
+}
+
+// dial connects to address over TCP with the sink's Dialer, or a net.Dialer,
+// and its TLS, if any, giving up after timeout
+func (n *sinkNetwork) dial(opts BatchOptions, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dialer := n.dialer(opts)
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := n.tlsConfig(opts)
+	if tlsConfig == nil {
+		return conn, nil
+	}
+
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// networkInheritor is implemented by sinks using the network settings of
+// Config, or wrapping one that does
+type networkInheritor interface {
+	inheritNetwork(c *Config)
+}
+
+// inheritNetwork passes the settings of c on to the wrapped sink
+func (s *FilteredSink) inheritNetwork(c *Config) {
+	if n, ok := s.sink.(networkInheritor); ok {
+		n.inheritNetwork(c)
+	}
+}
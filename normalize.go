@@ -0,0 +1,205 @@
+package log4
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeyCase is how a FieldNormalizer rewrites field names
+type KeyCase int
+
+const (
+	KeysAsIs  KeyCase = iota // Leave names alone
+	KeysLower                // "UserID" becomes "userid"
+	KeysSnake                // "UserID" and "user-id" become "user_id"
+)
+
+// NumberMode is how a FieldNormalizer converts numeric field values
+type NumberMode int
+
+const (
+	NumbersAsIs  NumberMode = iota // Leave numbers alone
+	NumbersWide                    // Integers become int64, or uint64 beyond its range, and floats float64
+	NumbersFloat                   // Every number becomes float64
+)
+
+// FieldNormalizer rewrites the fields of entries into a uniform shape (see
+// Config.Normalize), so that entries of services written by different teams
+// can share the indexes of an aggregation backend
+type FieldNormalizer struct {
+	Keys    KeyCase    // How names are rewritten
+	Flatten bool       // Replace map fields by a field per key, e.g. "http.status"
+	Numbers NumberMode // How numbers are converted
+
+	// Separator joins the names of flattened maps and their keys (default: ".")
+	Separator string
+}
+
+// normalizeEntry rewrites the fields of an entry with n. A field whose name
+// is already normalized wins over others rewritten to the same name, and
+// among those the first in sorted order wins.
+func (n *FieldNormalizer) normalizeEntry(entry *LogEntry) {
+	if len(entry.Fields) == 0 {
+		return
+	}
+
+	var pending []string
+	for k, v := range entry.Fields {
+		if _, nested := v.(map[string]interface{}); (nested && n.Flatten) || n.key(k) != k {
+			pending = append(pending, k)
+			continue
+		}
+		entry.Fields[k] = n.number(v)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	sort.Strings(pending)
+	moved := make(map[string]interface{}, len(pending))
+	for _, k := range pending {
+		moved[k] = entry.Fields[k]
+		delete(entry.Fields, k)
+	}
+	for _, k := range pending {
+		n.add(entry.Fields, n.key(k), moved[k])
+	}
+}
+
+// add sets the normalized field key to v, or to the fields of v if it is a
+// map to flatten, unless the field is set already
+func (n *FieldNormalizer) add(fields map[string]interface{}, key string, v interface{}) {
+	if m, ok := v.(map[string]interface{}); ok && n.Flatten {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sep := n.Separator
+		if sep == "" {
+			sep = "."
+		}
+		for _, k := range keys {
+			n.add(fields, key+sep+n.key(k), m[k])
+		}
+		return
+	}
+	if _, taken := fields[key]; !taken {
+		fields[key] = n.number(v)
+	}
+}
+
+// key returns the normalized name of a field
+func (n *FieldNormalizer) key(k string) string {
+	switch n.Keys {
+	case KeysLower:
+		return strings.ToLower(k)
+	case KeysSnake:
+		return snakeCase(k)
+	}
+	return k
+}
+
+// number returns v converted by n.Numbers if it is a number
+func (n *FieldNormalizer) number(v interface{}) interface{} {
+	if n.Numbers == NumbersAsIs {
+		return v
+	}
+
+	var i int64
+	switch v := v.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint:
+		return n.unsigned(uint64(v))
+	case uint8:
+		i = int64(v)
+	case uint16:
+		i = int64(v)
+	case uint32:
+		i = int64(v)
+	case uint64:
+		return n.unsigned(v)
+	case float32:
+		return float64(v)
+	default:
+		return v // float64 included
+	}
+	if n.Numbers == NumbersFloat {
+		return float64(i)
+	}
+	return i
+}
+
+func (n *FieldNormalizer) unsigned(u uint64) interface{} {
+	switch {
+	case n.Numbers == NumbersFloat:
+		return float64(u)
+	case u > math.MaxInt64:
+		return u
+	}
+	return int64(u)
+}
+
+// snakeCase returns k in lower case with words separated by underscores:
+// "userID", "UserId", "user-id" and "User ID" all become "user_id", and
+// "HTTPStatus" becomes "http_status". Dots are kept.
+func snakeCase(k string) string {
+	if isSnakeCase(k) {
+		return k
+	}
+
+	var b strings.Builder
+	b.Grow(len(k) + 4)
+	runes := []rune(k)
+	underscore := func() {
+		if s := b.String(); s != "" && s[len(s)-1] != '_' && s[len(s)-1] != '.' {
+			b.WriteByte('_')
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+					underscore()
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.':
+			b.WriteRune(r)
+		default:
+			underscore()
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
+
+// isSnakeCase reports whether snakeCase would return k unchanged
+func isSnakeCase(k string) bool {
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		switch {
+		case c >= utf8.RuneSelf:
+			return false
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.':
+		case c == '_' && i > 0 && i < len(k)-1 && k[i-1] != '_' && k[i-1] != '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package log4
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"user_id":      "user_id",
+		"userID":       "user_id",
+		"UserId":       "user_id",
+		"user-id":      "user_id",
+		"User ID":      "user_id",
+		"HTTPStatus":   "http_status",
+		"http.Status":  "http.status",
+		"retry2Count":  "retry2_count",
+		"__private__":  "private",
+		"already.ok_1": "already.ok_1",
+	} {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	n := &FieldNormalizer{Keys: KeysSnake, Flatten: true, Numbers: NumbersWide}
+	entry := &LogEntry{Fields: map[string]interface{}{
+		"userID":   int32(7),
+		"user_id":  "kept", // Already normalized, wins over userID
+		"latency":  float32(1.5),
+		"timeout":  time.Second, // Not a plain number
+		"big":      uint64(math.MaxUint64),
+		"Request":  map[string]interface{}{"Method": "GET", "headers": map[string]interface{}{"ContentLength": uint8(3)}},
+		"plain_ok": "x",
+	}}
+	n.normalizeEntry(entry)
+
+	want := map[string]interface{}{
+		"user_id":                        "kept",
+		"latency":                        float64(1.5),
+		"timeout":                        time.Second,
+		"big":                            uint64(math.MaxUint64),
+		"request.method":                 "GET",
+		"request.headers.content_length": int64(3),
+		"plain_ok":                       "x",
+	}
+	if len(entry.Fields) != len(want) {
+		t.Errorf("Expected fields %v, got %v", want, entry.Fields)
+	}
+	for k, v := range want {
+		if entry.Fields[k] != v {
+			t.Errorf("Expected %s=%v (%T), got %v (%T)", k, v, v, entry.Fields[k], entry.Fields[k])
+		}
+	}
+
+	n = &FieldNormalizer{Keys: KeysLower, Numbers: NumbersFloat}
+	nested := map[string]interface{}{"a": 1}
+	entry = &LogEntry{Fields: map[string]interface{}{"Count": 3, "Nested": nested}}
+	n.normalizeEntry(entry)
+	if entry.Fields["count"] != float64(3) || entry.Fields["nested"] == nil || len(entry.Fields) != 2 {
+		t.Errorf("Unexpected fields %v", entry.Fields)
+	}
+}
+
+func TestNormalizeConfig(t *testing.T) {
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.Sinks = []Sink{sink}
+	config.Normalize = &FieldNormalizer{Keys: KeysSnake, Flatten: true}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.Package("api").With(map[string]interface{}{"serviceName": "checkout"}).
+		InfoWithFields("Done", map[string]interface{}{"HTTP": map[string]interface{}{"StatusCode": 200}})
+	logger.Close()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(sink.entries))
+	}
+	if f := sink.entries[0].Fields; f["service_name"] != "checkout" || f["http.status_code"] != 200 {
+		t.Errorf("Unexpected fields %v", f)
+	}
+}
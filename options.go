@@ -0,0 +1,110 @@
+package log4
+
+import "io"
+
+// Option configures a logger created with NewLogger
+type Option func(*Config)
+
+// NewLogger creates a logger from DefaultConfig with opts applied in order.
+// Like NewChannelLoggerWithConfig, it panics if the result is invalid.
+func NewLogger(opts ...Option) *ChannelLogger {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+	return NewChannelLoggerWithConfig(config)
+}
+
+// WithConfig starts from a copy of base instead of DefaultConfig; use it as
+// the first option, e.g. WithConfig(ProductionConfig())
+func WithConfig(base *Config) Option {
+	return func(c *Config) {
+		*c = *base
+		c.Sinks = append([]Sink(nil), base.Sinks...)
+	}
+}
+
+// WithDir sets the log directory
+func WithDir(dir string) Option {
+	return func(c *Config) {
+		c.LogDir = dir
+	}
+}
+
+// WithLevel sets the minimum level
+func WithLevel(level LogLevel) Option {
+	return func(c *Config) {
+		c.MinLevel = level
+	}
+}
+
+// WithJSON writes entries as JSON lines
+func WithJSON() Option {
+	return func(c *Config) {
+		c.JSON = true
+	}
+}
+
+// WithFormatter sets the formatter
+func WithFormatter(f Formatter) Option {
+	return func(c *Config) {
+		c.Formatter = f
+	}
+}
+
+// WithRotation rotates files at size bytes and keeps files rotated files
+func WithRotation(size int64, files int) Option {
+	return func(c *Config) {
+		c.MaxFileSize = size
+		c.MaxFiles = files
+	}
+}
+
+// WithCompression compresses log files inline with codec
+func WithCompression(codec Codec) Option {
+	return func(c *Config) {
+		c.Compression = codec
+	}
+}
+
+// WithSink adds sinks; it may be given more than once
+func WithSink(sinks ...Sink) Option {
+	return func(c *Config) {
+		c.Sinks = append(c.Sinks, sinks...)
+	}
+}
+
+// WithBufferSize sets the size of the entry queue
+func WithBufferSize(size int) Option {
+	return func(c *Config) {
+		c.BufferSize = size
+	}
+}
+
+// WithErrorHandler sets the callback for internal errors
+func WithErrorHandler(fn func(error)) Option {
+	return func(c *Config) {
+		c.ErrorHandler = fn
+	}
+}
+
+// WithDryRun processes entries without writing files
+func WithDryRun() Option {
+	return func(c *Config) {
+		c.DryRun = true
+	}
+}
+
+// WithConsoleWriter copies entries to w instead of stdout
+func WithConsoleWriter(w io.Writer) Option {
+	return func(c *Config) {
+		c.ConsoleWriter = w
+	}
+}
+
+// WithFS keeps the log files in fsys, e.g. a MemFS
+func WithFS(fsys FS) Option {
+	return func(c *Config) {
+		c.FS = fsys
+	}
+}
@@ -0,0 +1,51 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	first, second := &retainingSink{}, &retainingSink{}
+	base := ProductionConfig()
+	logger := NewLogger(
+		WithConfig(base),
+		WithDir(tempDir),
+		WithLevel(DEBUG),
+		WithRotation(1024, 2),
+		WithSink(first),
+		WithSink(second),
+	)
+	logger.stdout = io.Discard
+
+	if logger.config.MaxFileSize != 1024 || logger.config.MaxFiles != 2 || !logger.config.JSON {
+		t.Errorf("Options not applied: %+v", logger.config)
+	}
+	if len(base.Sinks) != 0 {
+		t.Error("WithSink should not modify the base config")
+	}
+
+	logger.Debug("opts", "Configured")
+	logger.Close()
+
+	if len(first.entries) != 1 || len(second.entries) != 1 {
+		t.Errorf("Expected both sinks to receive the entry, got %d and %d", len(first.entries), len(second.entries))
+	}
+	if content := readFile(t, filepath.Join(tempDir, "opts.log")); !strings.Contains(content, `"msg":"Configured"`) {
+		t.Errorf("Expected a JSON entry, got %q", content)
+	}
+}
+
+func TestNewLoggerInvalidOption(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected an invalid buffer size to panic")
+		}
+	}()
+	NewLogger(WithBufferSize(0))
+}
@@ -0,0 +1,491 @@
+package log4
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLP transport protocols
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// Defaults for OTLPOptions
+const (
+	DefaultOTLPHTTPEndpoint = "http://localhost:4318/v1/logs"
+	DefaultOTLPGRPCEndpoint = "localhost:4317"
+)
+
+// otlpGRPCPath is the gRPC method exporting logs
+const otlpGRPCPath = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+// OTLPOptions configures an OTLPSink
+type OTLPOptions struct {
+	// Protocol is OTLPProtocolHTTP (default) or OTLPProtocolGRPC
+	Protocol string
+
+	// Endpoint is the full URL for HTTP (default: DefaultOTLPHTTPEndpoint) and
+	// host:port or a URL for gRPC (default: DefaultOTLPGRPCEndpoint)
+	Endpoint string
+
+	// Insecure uses cleartext HTTP/2 for gRPC endpoints given as host:port
+	Insecure bool
+
+	Headers  map[string]string      // Extra request headers, e.g. authentication
+	Resource map[string]interface{} // Resource attributes such as service.name
+
+	// Compression compresses the body of every export, announced with the
+	// codec's name as Content-Encoding, or grpc-encoding for gRPC. The
+	// Collector accepts GzipCodec, ZstdCodec and SnappyCodec. (default: none)
+	Compression Codec
+
+	// Transport is cloned for the client created when Client is nil, e.g. to
+	// tune connection pooling or set a Proxy function. The sink's TLS is
+	// used if it has no TLSClientConfig, and its Dialer, if any, replaces
+	// DialContext and Proxy.
+	Transport *http.Transport
+
+	BatchOptions
+
+	Severities   SeverityMap  // Level mapping (default: DefaultSeverities)
+	Client       *http.Client // Optional client; one is created for the protocol, TLS and Dialer if nil
+	ErrorHandler func(error)  // Export errors (default: printed to stderr)
+}
+
+// OTLPSink exports entries to an OpenTelemetry Collector using the OTLP logs
+// protocol over HTTP or gRPC. Entries are encoded when written and exported
+// in batches from a background goroutine, so a slow collector never blocks
+// the logger. Each package is reported as its own instrumentation scope and
+// entry fields become log record attributes.
+type OTLPSink struct {
+	opts     OTLPOptions
+	url      string
+	resource []byte // encoded Resource message
+
+	batcher *batcher[otlpRecord]
+	onError func(error)
+
+	sinkNetwork
+	client *http.Client // Only used by the export goroutine
+}
+
+// otlpRecord is an encoded LogRecord waiting for export
+type otlpRecord struct {
+	scope string
+	data  []byte
+	entry *LogEntry       // Copy kept for the dead-letter file, if one is set
+	ctx   context.Context // Context the entry was logged with, if any
+}
+
+// NewOTLPSink creates an OTLP sink and starts its exporter
+func NewOTLPSink(opts OTLPOptions) (*OTLPSink, error) {
+	if opts.Protocol == "" {
+		opts.Protocol = OTLPProtocolHTTP
+	}
+	opts.BatchOptions = opts.BatchOptions.withDefaults()
+
+	s := &OTLPSink{opts: opts}
+
+	switch opts.Protocol {
+	case OTLPProtocolHTTP:
+		s.url = opts.Endpoint
+		if s.url == "" {
+			s.url = DefaultOTLPHTTPEndpoint
+		}
+	case OTLPProtocolGRPC:
+		endpoint := opts.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultOTLPGRPCEndpoint
+		}
+		if !strings.Contains(endpoint, "://") {
+			scheme := "https://"
+			if opts.Insecure {
+				scheme = "http://"
+			}
+			endpoint = scheme + endpoint
+		}
+		s.url = strings.TrimSuffix(endpoint, "/") + otlpGRPCPath
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", opts.Protocol)
+	}
+
+	s.resource = encodeOTLPResource(opts.Resource)
+	s.onError = sinkErrorHandler(opts.ErrorHandler)
+	s.batcher = newBatcher(opts.BatchOptions, s.export, s.onError, func(r otlpRecord) context.Context { return r.ctx })
+	return s, nil
+}
+
+// Write encodes the entry and queues it for export
+func (s *OTLPSink) Write(entry *LogEntry, line []byte) error {
+	record := otlpRecord{
+		scope: entry.Package,
+		data:  encodeOTLPLogRecord(entry, s.opts.Severities.Lookup(entry.Level)),
+	}
+	if s.opts.DeadLetter != nil {
+		record.entry = entry.Clone()
+	}
+	record.ctx = entry.Context
+	s.batcher.add(record)
+	return nil
+}
+
+// Close exports the queued records, waiting at most ShutdownTimeout
+func (s *OTLPSink) Close() error {
+	s.batcher.close()
+	return nil
+}
+
+// Dropped returns the number of records dropped because the queue was full or
+// their export failed permanently
+func (s *OTLPSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// export sends one batch, retrying transient failures with backoff
+func (s *OTLPSink) export(ctx context.Context, batch []otlpRecord) error {
+	body := encodeOTLPRequest(s.resource, batch)
+	var err error
+	if s.opts.Compression != nil {
+		body, err = compressPayload(s.opts.Compression, body)
+	}
+	if err == nil {
+		err = retryExport(ctx, s.opts.MaxRetries, s.opts.RetryBackoff, func() error {
+			// Every attempt is sealed anew, so receivers do not take it for a replay
+			var sealed *SealedBatch
+			if s.opts.Seal != nil {
+				var err error
+				if sealed, err = s.opts.Seal.seal(body); err != nil {
+					return err
+				}
+			}
+			return s.send(ctx, body, sealed)
+		})
+	}
+	if err != nil {
+		if s.opts.DeadLetter != nil {
+			entries := make([]*LogEntry, len(batch))
+			for i, r := range batch {
+				entries[i] = r.entry
+			}
+			s.opts.DeadLetter.record(entries, "otlp", err, s.onError)
+		}
+		return fmt.Errorf("OTLP export of %d records failed: %w", len(batch), err)
+	}
+	return nil
+}
+
+// send performs a single export request, of the sealed body if sealed is set
+func (s *OTLPSink) send(ctx context.Context, body []byte, sealed *SealedBatch) error {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	if sealed != nil {
+		body = sealed.Payload
+	}
+	if s.opts.Protocol == OTLPProtocolGRPC {
+		// Length-prefixed gRPC message, flagged if compressed
+		framed := make([]byte, 5, 5+len(body))
+		if s.opts.Compression != nil {
+			framed[0] = 1
+		}
+		binary.BigEndian.PutUint32(framed[1:], uint32(len(body)))
+		body = append(framed, body...)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if s.opts.Protocol == OTLPProtocolGRPC {
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+		if s.opts.Compression != nil {
+			req.Header.Set("Grpc-Encoding", s.opts.Compression.Name())
+		}
+	} else {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		if s.opts.Compression != nil {
+			req.Header.Set("Content-Encoding", otlpContentEncoding(s.opts.Compression))
+		}
+	}
+	for k, v := range s.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	if sealed != nil {
+		sealed.setHeaders(req.Header)
+	}
+
+	if s.client == nil {
+		s.client = s.newClient()
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Network errors are transient
+		return retryable(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // Trailers are only available after the body
+
+	if s.opts.Protocol == OTLPProtocolGRPC {
+		return grpcStatusError(resp)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &exportError{
+			msg:        "collector returned " + resp.Status,
+			retryable:  true,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return &exportError{msg: "collector returned " + resp.Status}
+}
+
+// newClient returns opts.Client, or else a client for the protocol using
+// the sink's Transport, TLS and Dialer. It is created by the first export,
+// once the sink had the chance to inherit the settings of its logger.
+func (s *OTLPSink) newClient() *http.Client {
+	if s.opts.Client != nil {
+		return s.opts.Client
+	}
+
+	var transport *http.Transport
+	switch {
+	case s.opts.Transport != nil:
+		transport = s.opts.Transport.Clone()
+	case s.opts.Protocol == OTLPProtocolGRPC:
+		transport = &http.Transport{}
+	default:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if s.opts.Transport == nil || s.opts.Transport.TLSClientConfig == nil {
+		// DefaultTransport has a TLSClientConfig of its own once used
+		transport.TLSClientConfig = s.tlsConfig(s.opts.BatchOptions)
+	}
+	if dialer := s.dialer(s.opts.BatchOptions); dialer != nil {
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil // The dialer reaches the proxy, if any
+	}
+
+	if s.opts.Protocol == OTLPProtocolGRPC {
+		protocols := new(http.Protocols)
+		if strings.HasPrefix(s.url, "http://") {
+			protocols.SetUnencryptedHTTP2(true)
+		} else {
+			protocols.SetHTTP2(true)
+		}
+		transport.Protocols = protocols
+		transport.ForceAttemptHTTP2 = true // Even with a custom TLSClientConfig
+	}
+	return &http.Client{Transport: transport}
+}
+
+// otlpContentEncoding returns the Content-Encoding of a codec's output. The
+// Collector reserves "snappy" for the block format and reads the framing
+// format written by SnappyCodec as "x-snappy-framed".
+func otlpContentEncoding(c Codec) string {
+	if _, ok := c.(SnappyCodec); ok {
+		return "x-snappy-framed"
+	}
+	return c.Name()
+}
+
+// grpcStatusError interprets the grpc-status of a response
+func grpcStatusError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return &exportError{msg: "collector returned " + resp.Status, retryable: resp.StatusCode >= 500}
+	}
+
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		// Trailers-only responses carry the status in the headers
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return &exportError{msg: fmt.Sprintf("invalid grpc-status %q", status)}
+	}
+	if code == 0 {
+		return nil
+	}
+
+	// Retryable codes per the OTLP specification
+	retry := false
+	switch code {
+	case 1, 4, 8, 10, 11, 14, 15: // CANCELLED, DEADLINE_EXCEEDED, RESOURCE_EXHAUSTED, ABORTED, OUT_OF_RANGE, UNAVAILABLE, DATA_LOSS
+		retry = true
+	}
+	return &exportError{msg: fmt.Sprintf("grpc status %d: %s", code, message), retryable: retry}
+}
+
+// parseRetryAfter accepts the delay-seconds form of Retry-After
+func parseRetryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// Protobuf encoding of the OTLP logs messages. Only the fields written by this
+// package are encoded; see opentelemetry/proto/logs/v1/logs.proto.
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendProtoTag(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendProtoString(b []byte, field int, v string) []byte {
+	b = appendProtoTag(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+// encodeOTLPRequest builds an ExportLogsServiceRequest with one ResourceLogs
+// and a ScopeLogs per package
+func encodeOTLPRequest(resource []byte, batch []otlpRecord) []byte {
+	var scopes []string
+	byScope := make(map[string][][]byte)
+	for _, r := range batch {
+		if _, ok := byScope[r.scope]; !ok {
+			scopes = append(scopes, r.scope)
+		}
+		byScope[r.scope] = append(byScope[r.scope], r.data)
+	}
+
+	var resourceLogs []byte
+	resourceLogs = appendProtoBytes(resourceLogs, 1, resource)
+	for _, scope := range scopes {
+		var scopeLogs, scopeMsg []byte
+		scopeMsg = appendProtoString(scopeMsg, 1, scope) // InstrumentationScope.name
+		scopeLogs = appendProtoBytes(scopeLogs, 1, scopeMsg)
+		for _, record := range byScope[scope] {
+			scopeLogs = appendProtoBytes(scopeLogs, 2, record)
+		}
+		resourceLogs = appendProtoBytes(resourceLogs, 2, scopeLogs)
+	}
+
+	return appendProtoBytes(nil, 1, resourceLogs)
+}
+
+// encodeOTLPResource encodes a Resource message
+func encodeOTLPResource(attrs map[string]interface{}) []byte {
+	var b []byte
+	for _, k := range sortedFieldKeys(attrs) {
+		b = appendProtoBytes(b, 1, encodeOTLPKeyValue(k, attrs[k]))
+	}
+	return b
+}
+
+// encodeOTLPLogRecord encodes a LogRecord message
+func encodeOTLPLogRecord(entry *LogEntry, sev Severity) []byte {
+	b := make([]byte, 0, 64+len(entry.Message))
+	b = appendProtoFixed64(b, 1, uint64(entry.Timestamp.UnixNano()))
+	b = appendProtoVarint(b, 2, uint64(sev.OTLP))
+	b = appendProtoString(b, 3, sev.Text)
+	b = appendProtoBytes(b, 5, encodeOTLPAnyValue(entry.Message))
+	for _, k := range sortedFieldKeys(entry.Fields) {
+		b = appendProtoBytes(b, 6, encodeOTLPKeyValue(k, entry.Fields[k]))
+	}
+	for _, k := range entry.unshadowedLabels() {
+		b = appendProtoBytes(b, 6, encodeOTLPKeyValue(k, entry.Labels[k]))
+	}
+	b = appendProtoFixed64(b, 11, uint64(time.Now().UnixNano())) // observed_time_unix_nano
+	return b
+}
+
+// encodeOTLPKeyValue encodes a KeyValue message
+func encodeOTLPKeyValue(key string, value interface{}) []byte {
+	b := appendProtoString(nil, 1, key)
+	return appendProtoBytes(b, 2, encodeOTLPAnyValue(value))
+}
+
+// encodeOTLPAnyValue encodes an AnyValue message. Values without an OTLP
+// representation are sent as their %v string.
+func encodeOTLPAnyValue(v interface{}) []byte {
+	switch v := resolveValue(v).(type) {
+	case string:
+		return appendProtoString(nil, 1, v)
+	case bool:
+		var n uint64
+		if v {
+			n = 1
+		}
+		return appendProtoVarint(nil, 2, n)
+	case int:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case int8:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case int16:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case int32:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case int64:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case uint8:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case uint16:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case uint32:
+		return appendProtoVarint(nil, 3, uint64(v))
+	case float32:
+		return appendProtoFixed64(nil, 4, math.Float64bits(float64(v)))
+	case float64:
+		return appendProtoFixed64(nil, 4, math.Float64bits(v))
+	case []byte:
+		return appendProtoBytes(nil, 7, v)
+	case time.Time:
+		return appendProtoString(nil, 1, v.Format(time.RFC3339Nano))
+	case error:
+		return appendProtoString(nil, 1, v.Error())
+	case fmt.Stringer:
+		return appendProtoString(nil, 1, v.String())
+	case map[string]interface{}:
+		var list []byte
+		for _, k := range sortedFieldKeys(v) {
+			list = appendProtoBytes(list, 1, encodeOTLPKeyValue(k, v[k]))
+		}
+		return appendProtoBytes(nil, 6, list)
+	case []interface{}:
+		var list []byte
+		for _, item := range v {
+			list = appendProtoBytes(list, 1, encodeOTLPAnyValue(item))
+		}
+		return appendProtoBytes(nil, 5, list)
+	}
+	return appendProtoString(nil, 1, fmt.Sprintf("%v", v))
+}
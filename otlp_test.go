@@ -0,0 +1,299 @@
+package log4
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// protoMessage indexes the length-delimited and varint fields of a protobuf
+// message by field number
+type protoMessage struct {
+	bytes   map[int][][]byte
+	varints map[int][]uint64
+}
+
+func parseProto(t *testing.T, b []byte) protoMessage {
+	m := protoMessage{bytes: make(map[int][][]byte), varints: make(map[int][]uint64)}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("Invalid protobuf tag")
+		}
+		b = b[n:]
+		field, wire := int(tag>>3), int(tag&7)
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(b)
+			m.varints[field] = append(m.varints[field], v)
+			b = b[n:]
+		case 1:
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			b = b[n:]
+			m.bytes[field] = append(m.bytes[field], b[:l])
+			b = b[l:]
+		default:
+			t.Fatalf("Unexpected wire type %d", wire)
+		}
+	}
+	return m
+}
+
+// otlpBodies extracts the package and body of every record in a request
+func otlpBodies(t *testing.T, body []byte) map[string][]string {
+	out := make(map[string][]string)
+	req := parseProto(t, body)
+	for _, rl := range req.bytes[1] {
+		for _, sl := range parseProto(t, rl).bytes[2] {
+			scopeLogs := parseProto(t, sl)
+			scope := string(parseProto(t, scopeLogs.bytes[1][0]).bytes[1][0])
+			for _, record := range scopeLogs.bytes[2] {
+				anyValue := parseProto(t, parseProto(t, record).bytes[5][0])
+				out[scope] = append(out[scope], string(anyValue.bytes[1][0]))
+			}
+		}
+	}
+	return out
+}
+
+func TestOTLPSinkHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]byte
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first export is rejected with a retryable status
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("Authorization") != "token" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPOptions{
+		Endpoint:     server.URL + "/v1/logs",
+		Headers:      map[string]string{"Authorization": "token"},
+		Resource:     map[string]interface{}{"service.name": "checkout"},
+		BatchOptions: BatchOptions{BatchSize: 2, RetryBackoff: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPSink failed: %v", err)
+	}
+
+	for i, pkg := range []string{"api", "db", "api"} {
+		entry := &LogEntry{Package: pkg, Level: INFO, Message: pkg + string(rune('0'+i)), Timestamp: time.Now()}
+		sink.Write(entry, nil)
+	}
+	sink.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(requests))
+	}
+	got := otlpBodies(t, requests[0])
+	for k, v := range otlpBodies(t, requests[1]) {
+		got[k] = append(got[k], v...)
+	}
+	if len(got["api"]) != 2 || got["api"][0] != "api0" || len(got["db"]) != 1 {
+		t.Errorf("Unexpected exported records %v", got)
+	}
+	if sink.Dropped() != 0 {
+		t.Errorf("Expected no drops, got %d", sink.Dropped())
+	}
+}
+
+func TestOTLPSinkCompression(t *testing.T) {
+	var mu sync.Mutex
+	var records []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "zstd" {
+			t.Errorf("Expected a zstd body, got headers %v", r.Header)
+		}
+		zr, err := ZstdCodec{}.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("NewReader failed: %v", err)
+			return
+		}
+		defer zr.Close()
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Errorf("Failed to decompress the body: %v", err)
+			return
+		}
+		mu.Lock()
+		records = append(records, otlpBodies(t, body)["api"]...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink, _ := NewOTLPSink(OTLPOptions{Endpoint: server.URL, Compression: ZstdCodec{}})
+	sink.Write(&LogEntry{Package: "api", Level: INFO, Message: "compressed", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 1 || records[0] != "compressed" {
+		t.Errorf("Expected the compressed record, got %v", records)
+	}
+	if otlpContentEncoding(SnappyCodec{}) != "x-snappy-framed" || otlpContentEncoding(GzipCodec{}) != "gzip" {
+		t.Error("Unexpected Content-Encoding of the codecs")
+	}
+}
+
+func TestOTLPSinkDropCancelled(t *testing.T) {
+	var mu sync.Mutex
+	var records []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		records = append(records, otlpBodies(t, body)["api"]...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink, _ := NewOTLPSink(OTLPOptions{
+		Endpoint:     server.URL,
+		BatchOptions: BatchOptions{BatchTimeout: time.Hour, DropCancelled: true},
+	})
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logger.LogWithContext(ctx, "api", "INFO", "abandoned")
+	logger.LogWithContext(context.Background(), "api", "INFO", "kept")
+	logger.Info("api", "no context")
+	logger.Flush()
+	cancel() // Before the batch is exported on Close
+	logger.LogWithContext(ctx, "api", "INFO", "already cancelled")
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 2 || records[0] != "kept" || records[1] != "no context" {
+		t.Errorf("Expected only entries without a cancelled context, got %v", records)
+	}
+	if sink.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", sink.Dropped())
+	}
+}
+
+func TestOTLPSinkGRPC(t *testing.T) {
+	received := make(chan map[string][]string, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpGRPCPath || r.ProtoMajor != 2 {
+			t.Errorf("Unexpected request %s %s", r.Proto, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			t.Errorf("Invalid gRPC framing")
+			return
+		}
+		received <- otlpBodies(t, body[5:])
+
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	var exportErr error
+	sink, err := NewOTLPSink(OTLPOptions{
+		Protocol:     OTLPProtocolGRPC,
+		Endpoint:     server.Listener.Addr().String(),
+		Insecure:     true,
+		ErrorHandler: func(err error) { exportErr = err },
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPSink failed: %v", err)
+	}
+	sink.Write(&LogEntry{Package: "grpc", Level: ERROR, Message: "over grpc", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	if exportErr != nil {
+		t.Fatalf("Export failed: %v", exportErr)
+	}
+	select {
+	case got := <-received:
+		if len(got["grpc"]) != 1 || got["grpc"][0] != "over grpc" {
+			t.Errorf("Unexpected exported records %v", got)
+		}
+	default:
+		t.Fatal("Collector received nothing")
+	}
+}
+
+func TestOTLPSinkPermanentFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var errs atomic.Int32
+	sink, _ := NewOTLPSink(OTLPOptions{
+		Endpoint:     server.URL,
+		ErrorHandler: func(error) { errs.Add(1) },
+	})
+	sink.Write(&LogEntry{Package: "bad", Message: "rejected", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	if errs.Load() != 1 || sink.Dropped() != 1 {
+		t.Errorf("Expected one error and drop, got %d errors and %d drops", errs.Load(), sink.Dropped())
+	}
+}
+
+func TestSinkCloseDuringRetries(t *testing.T) {
+	// A collector that is down: the port was just released
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	retrying := BatchOptions{BatchTimeout: 10 * time.Millisecond, RetryBackoff: time.Minute}
+	otlp, err := NewOTLPSink(OTLPOptions{Endpoint: "http://" + addr, BatchOptions: retrying, ErrorHandler: func(error) {}})
+	if err != nil {
+		t.Fatalf("NewOTLPSink failed: %v", err)
+	}
+	otlp.batcher.shutdown = 100 * time.Millisecond
+	fluent := NewFluentSink(FluentOptions{Address: addr, BatchOptions: retrying, ErrorHandler: func(error) {}})
+	fluent.batcher.shutdown = 100 * time.Millisecond
+
+	for name, sink := range map[string]Sink{"otlp": otlp, "fluent": fluent} {
+		sink.Write(&LogEntry{Package: "api", Message: "lost", Timestamp: time.Now()}, nil)
+		time.Sleep(50 * time.Millisecond) // The first attempt failed and waits to retry
+
+		start := time.Now()
+		sink.Close()
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: Close took %v while the export was retrying", name, elapsed)
+		}
+	}
+	if otlp.Dropped() != 1 || fluent.Dropped() != 1 {
+		t.Errorf("Expected the entries to be dropped, got %d and %d", otlp.Dropped(), fluent.Dropped())
+	}
+}
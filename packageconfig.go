@@ -0,0 +1,83 @@
+package log4
+
+import "strings"
+
+// PackageConfig overrides settings of Config for one package, so that log
+// classes as different as access logs and debug traces can share a logger
+// (see Config.Packages)
+type PackageConfig struct {
+	// Level is the minimum level of the package, e.g. "DEBUG", set as by
+	// SetPackageLevel when the logger is created, so that it also applies to
+	// child packages and can be changed at runtime (default: inherited)
+	Level string
+	// TimestampFormat replaces Config.TimestampFormat in the layout of
+	// TextFormatter or JSONFormatter; other formatters ignore it
+	TimestampFormat string
+	// MaxFileSize rotates the package's files at this size (default:
+	// Config.MaxFileSize)
+	MaxFileSize int64
+	// Formatter renders the package's entries for every output (default:
+	// that of Config)
+	Formatter Formatter
+}
+
+// packageSettings is a PackageConfig as applied by the logger
+type packageSettings struct {
+	formatter   Formatter
+	header      FileHeader // Written to new files if Config.FileHeader is set
+	maxFileSize int64
+}
+
+// applyPackageConfigs sets up Config.Packages once the default formatter is
+// known
+func (cl *ChannelLogger) applyPackageConfigs() {
+	for pkg, p := range cl.config.Packages {
+		if p.Level != "" {
+			level, _ := ParseLogLevelStrict(p.Level)
+			cl.SetPackageLevel(pkg, level)
+		}
+
+		s := &packageSettings{formatter: p.Formatter, maxFileSize: p.MaxFileSize}
+		if s.formatter == nil && p.TimestampFormat != "" {
+			switch f := cl.formatter.(type) {
+			case *TextFormatter:
+				custom := *f
+				custom.TimestampFormat = p.TimestampFormat
+				s.formatter = &custom
+			case *JSONFormatter:
+				custom := *f
+				custom.TimestampFormat = p.TimestampFormat
+				s.formatter = &custom
+			}
+		}
+		if s.formatter == nil {
+			s.formatter = cl.formatter
+		}
+		if cl.config.FileHeader {
+			s.header = fileHeader(s.formatter)
+		}
+		cl.pkgConfig[sanitizePackageName(pkg)] = s
+	}
+}
+
+// settingsFor returns the settings of the package of a file key, or nil
+// if Config.Packages has none
+func (cl *ChannelLogger) settingsFor(key string) *packageSettings {
+	return cl.pkgConfig[key[strings.IndexByte(key, '/')+1:]]
+}
+
+// formatterFor returns the formatter of the package of a file key
+func (cl *ChannelLogger) formatterFor(key string) Formatter {
+	if s := cl.settingsFor(key); s != nil {
+		return s.formatter
+	}
+	return cl.formatter
+}
+
+// maxFileSize returns the size at which the files of a file key are rotated
+func (cl *ChannelLogger) maxFileSize(key string) int64 {
+	if s := cl.settingsFor(key); s != nil && s.maxFileSize > 0 {
+		return s.maxFileSize
+	}
+	return cl.config.MaxFileSize
+}
@@ -0,0 +1,83 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clfFormatter writes entries in a layout like the Apache Common Log Format
+type clfFormatter struct{}
+
+func (clfFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	return fmt.Appendf(dst, "%v - - [%s] %q %v %v",
+		entry.Fields["host"], entry.Timestamp.Format("02/Jan/2006:15:04:05 -0700"),
+		entry.Message, entry.Fields["status"], entry.Fields["bytes"])
+}
+
+func TestPackageConfig(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.MinLevel = INFO
+	config.Packages = map[string]PackageConfig{
+		"access": {Formatter: clfFormatter{}},
+		"db":     {Level: "DEBUG", TimestampFormat: time.RFC3339},
+		"big":    {MaxFileSize: 64},
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.LogWithFields("access", INFO, "GET /index.html HTTP/1.1", map[string]interface{}{
+		"host": "127.0.0.1", "status": 200, "bytes": 2326,
+	})
+	logger.Debug("db", "Query planned")
+	logger.Debug("db.pool", "Connection reused")
+	logger.Debug("app", "Filtered")
+	logger.Info("app", "Default layout")
+	for i := 0; i < 3; i++ {
+		logger.Info("big", "An entry of more than half of MaxFileSize")
+	}
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "access.log"))
+	if !strings.HasPrefix(content, `127.0.0.1 - - [`) || !strings.HasSuffix(content, `] "GET /index.html HTTP/1.1" 200 2326`+"\n") {
+		t.Errorf("Expected the package's formatter, got %q", content)
+	}
+
+	content = readFile(t, filepath.Join(tempDir, "db.log"))
+	if !strings.Contains(content, "Query planned") || !strings.Contains(content, time.Now().Format("2006-01-02T")) {
+		t.Errorf("Expected DEBUG entries with the package's timestamp format, got %q", content)
+	}
+	if content := readFile(t, filepath.Join(tempDir, "db_pool.log")); !strings.Contains(content, "Connection reused") {
+		t.Errorf("Expected the level to apply to child packages, got %q", content)
+	}
+	if logger.EffectiveLevel("db") != DEBUG {
+		t.Errorf("Expected the level to be visible as a package override, got %v", logger.EffectiveLevel("db"))
+	}
+
+	content = readFile(t, filepath.Join(tempDir, "app.log"))
+	if countLines(content) != 1 || !strings.Contains(content, "["+time.Now().Format("2006-01-02 ")) {
+		t.Errorf("Expected other packages to keep the default level and layout, got %q", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "big.log.1")); err != nil {
+		t.Errorf("Expected the package to rotate at its own MaxFileSize: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "app.log.1")); err == nil {
+		t.Error("Expected other packages to keep the default MaxFileSize")
+	}
+}
+
+func TestPackageConfigInvalidLevel(t *testing.T) {
+	config := DefaultConfig()
+	config.Packages = map[string]PackageConfig{"db": {Level: "VERBOSE"}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "package db") {
+		t.Errorf("Expected an error naming the package, got %v", err)
+	}
+}
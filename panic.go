@@ -0,0 +1,97 @@
+package log4
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the panic breaker of the logging goroutine
+const (
+	// DefaultMaxWorkerPanics is used unless Config.MaxWorkerPanics is set
+	DefaultMaxWorkerPanics = 5
+	// WorkerPanicWindow is the period over which panics are counted, and for
+	// which entries are dropped once the breaker opens
+	WorkerPanicWindow = time.Minute
+)
+
+// breaker stops the logging goroutine from calling Formatters and Sinks for
+// WorkerPanicWindow after Config.MaxWorkerPanics panics within the window.
+// Only the logging goroutine updates it.
+type breaker struct {
+	panics []time.Time // Panics within the last WorkerPanicWindow
+	until  time.Time   // When the open breaker closes again
+	open   atomic.Bool // Also read by Stats
+	count  atomic.Uint64
+}
+
+// trip records a panic, returning true if it opened the breaker
+func (b *breaker) trip(max int, now time.Time) bool {
+	b.count.Add(1)
+	if max < 0 {
+		return false
+	}
+	if max == 0 {
+		max = DefaultMaxWorkerPanics
+	}
+
+	recent := b.panics[:0]
+	for _, t := range b.panics {
+		if now.Sub(t) < WorkerPanicWindow {
+			recent = append(recent, t)
+		}
+	}
+	b.panics = append(recent, now)
+	if len(b.panics) < max {
+		return false
+	}
+	b.panics = b.panics[:0]
+	b.until = now.Add(WorkerPanicWindow)
+	b.open.Store(true)
+	return true
+}
+
+// tripped reports whether the breaker is open, closing it once
+// WorkerPanicWindow has passed
+func (b *breaker) tripped() bool {
+	if !b.open.Load() {
+		return false
+	}
+	if time.Now().Before(b.until) {
+		return true
+	}
+	b.open.Store(false)
+	return false
+}
+
+// recoverEntry turns a panic while writing entry into an *ErrWorkerPanic,
+// drops the entry with DropPanic so that a waiting caller is released, and
+// panics again for the logging goroutine to recover. It must be deferred
+// directly.
+func (cl *ChannelLogger) recoverEntry(entry *LogEntry) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err := &ErrWorkerPanic{Pkg: entry.Package, Value: r, Stack: debug.Stack()}
+	entry.Retain() // Released by drop, the caller still holds its reference
+	cl.drop(entry, DropPanic)
+	panic(err)
+}
+
+// recoverWorker reports a panic of the logging goroutine, opening the breaker
+// after repeated panics. It must be deferred directly.
+func (cl *ChannelLogger) recoverWorker() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err, ok := r.(*ErrWorkerPanic)
+	if !ok {
+		err = &ErrWorkerPanic{Value: r, Stack: debug.Stack()}
+	}
+	if cl.breaker.trip(cl.config.MaxWorkerPanics, time.Now()) {
+		err.Breaker = WorkerPanicWindow
+	}
+	cl.handleError(err)
+}
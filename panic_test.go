@@ -0,0 +1,124 @@
+package log4
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// panickingFormatter panics on entries whose message contains "boom"
+type panickingFormatter struct{}
+
+func (panickingFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	if strings.Contains(entry.Message, "boom") {
+		panic(errors.New("formatter exploded"))
+	}
+	return append(dst, entry.Message...)
+}
+
+// panicRecorder collects the errors and drops of a logger
+type panicRecorder struct {
+	mu     sync.Mutex
+	errs   []*ErrWorkerPanic
+	drops  []string
+	config *Config
+}
+
+func newPanicRecorder(t *testing.T, dir string) *panicRecorder {
+	r := &panicRecorder{config: DefaultConfig()}
+	r.config.LogDir = dir
+	r.config.DisableConsole = true
+	r.config.Formatter = panickingFormatter{}
+	r.config.ErrorHandler = func(err error) {
+		var panicErr *ErrWorkerPanic
+		if !errors.As(err, &panicErr) {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		r.mu.Lock()
+		r.errs = append(r.errs, panicErr)
+		r.mu.Unlock()
+	}
+	r.config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		r.mu.Lock()
+		r.drops = append(r.drops, entry.Message+" "+reason.String())
+		r.mu.Unlock()
+	}
+	return r
+}
+
+func TestWorkerPanic(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	r := newPanicRecorder(t, tempDir)
+	logger := NewChannelLoggerWithConfig(r.config)
+
+	logger.Info("app", "before")
+	err := logger.TryInfo("app", "boom")
+	var dropped *ErrDropped
+	if !errors.As(err, &dropped) || dropped.Reason != DropPanic {
+		t.Errorf("Expected the entry dropped with DropPanic, got %v", err)
+	}
+	if err := logger.do(func() { panic("control") }); err != nil {
+		t.Errorf("Expected a panicking control function to return, got %v", err)
+	}
+	logger.Info("app", "after")
+	stats := logger.Stats()
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "app.log")); content != "before\nafter\n" {
+		t.Errorf("Expected the worker to keep writing, got %q", content)
+	}
+	if stats.Panics != 2 || stats.BreakerOpen {
+		t.Errorf("Expected 2 panics with the breaker closed, got %+v", stats)
+	}
+	if len(r.errs) != 2 {
+		t.Fatalf("Expected 2 panics reported, got %v", r.errs)
+	}
+	if r.errs[0].Pkg != "app" || r.errs[0].Unwrap() == nil || len(r.errs[0].Stack) == 0 {
+		t.Errorf("Expected the package, error and stack of the panic, got %+v", r.errs[0])
+	}
+	if r.errs[1].Value != "control" || r.errs[1].Pkg != "" {
+		t.Errorf("Expected the panic of the control function, got %+v", r.errs[1])
+	}
+	if len(r.drops) != 1 || r.drops[0] != "boom panic" {
+		t.Errorf("Expected the panicking entry dropped, got %v", r.drops)
+	}
+}
+
+func TestWorkerPanicBreaker(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	r := newPanicRecorder(t, tempDir)
+	r.config.MaxWorkerPanics = 3
+	logger := NewChannelLoggerWithConfig(r.config)
+
+	for i := 0; i < 3; i++ {
+		logger.TryInfo("app", "boom")
+	}
+	logger.Info("app", "while open")
+	logger.Flush()
+	if stats := logger.Stats(); !stats.BreakerOpen || stats.Panics != 3 {
+		t.Errorf("Expected the breaker open after 3 panics, got %+v", stats)
+	}
+
+	// Writing resumes once WorkerPanicWindow has passed
+	logger.do(func() { logger.breaker.until = time.Now() })
+	logger.Info("app", "closed again")
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "app.log")); content != "closed again\n" {
+		t.Errorf("Expected only the entry logged after the breaker closed, got %q", content)
+	}
+	if len(r.errs) != 3 || r.errs[1].Breaker != 0 || r.errs[2].Breaker != WorkerPanicWindow {
+		t.Errorf("Expected the third panic to open the breaker, got %v", r.errs)
+	}
+	if len(r.drops) != 4 || r.drops[3] != "while open panic" {
+		t.Errorf("Expected entries dropped while the breaker is open, got %v", r.drops)
+	}
+}
@@ -0,0 +1,194 @@
+package log4
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Default key names used when entries are encoded as JSON
+const (
+	DefaultKeyTime    = "time"
+	DefaultKeyLevel   = "level"
+	DefaultKeyMessage = "msg"
+	DefaultKeyPackage = "package"
+	DefaultKeyTenant  = "tenant"
+)
+
+// ParseLine parses a single line written by this package back into a
+// LogEntry. Both the text layout ("[timestamp] LEVEL: message | k=v, ...",
+// in any LevelStyle) and JSON lines are understood. Field values parsed from the text layout are
+// returned as strings.
+//
+// The returned entry is not taken from the internal pool and may be kept by
+// the caller.
+func ParseLine(line, timestampFormat string) (*LogEntry, error) {
+	return ParseLineKeys(line, timestampFormat, nil)
+}
+
+// ParseLineKeys is ParseLine for JSON lines written with custom key names,
+// such as Config.FieldKeyMsg. keys maps DefaultKeyTime, DefaultKeyLevel,
+// DefaultKeyMessage, DefaultKeyPackage and DefaultKeyTenant to the names
+// used in the line, as recorded in FileHeader.Keys; missing names keep their
+// default.
+func ParseLineKeys(line, timestampFormat string, keys map[string]string) (*LogEntry, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLine(line, timestampFormat, keys)
+	}
+	return parseTextLine(line, timestampFormat)
+}
+
+// parseTextLine parses the default text layout produced by appendLogMessage
+func parseTextLine(line, timestampFormat string) (*LogEntry, error) {
+	if !strings.HasPrefix(line, "[") {
+		return nil, fmt.Errorf("unrecognized log line: %q", line)
+	}
+	end := strings.Index(line, "] ")
+	if end < 0 {
+		return nil, fmt.Errorf("missing timestamp terminator: %q", line)
+	}
+
+	ts, err := time.ParseInLocation(timestampFormat, line[1:end], time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp in %q: %w", line, err)
+	}
+
+	name, message, ok := cutLevel(line[end+2:])
+	if !ok {
+		return nil, fmt.Errorf("missing level in %q", line)
+	}
+	level, ok := lookupLevel(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown level %q", name)
+	}
+	// Padding of TextFormatter.PadLevels
+	for pad := levelWidth(nil) - len(name); pad > 0 && strings.HasPrefix(message, " "); pad-- {
+		message = message[1:]
+	}
+
+	entry := &LogEntry{
+		Level:     level,
+		Message:   message,
+		Timestamp: ts,
+		Fields:    make(map[string]interface{}),
+	}
+
+	// Structured fields follow the last " | " separator
+	if idx := strings.LastIndex(entry.Message, " | "); idx >= 0 {
+		if fields, ok := parseTextFields(entry.Message[idx+3:]); ok {
+			entry.Message = entry.Message[:idx]
+			entry.Fields = fields
+		}
+	}
+
+	return entry, nil
+}
+
+// cutLevel splits the level name from the message in any LevelStyle:
+// "INFO: message", "[INFO] message" or "INFO message"
+func cutLevel(s string) (name, message string, ok bool) {
+	if rest, found := strings.CutPrefix(s, "["); found {
+		if name, message, ok = strings.Cut(rest, "] "); ok {
+			return name, message, true
+		}
+	}
+	if name, message, ok = strings.Cut(s, ": "); ok && !strings.Contains(name, " ") {
+		return name, message, true
+	}
+	return strings.Cut(s, " ")
+}
+
+// parseTextFields parses "k=v, k2=v2"; ok is false if the text does not look
+// like a field list
+func parseTextFields(s string) (map[string]interface{}, bool) {
+	fields := make(map[string]interface{})
+	for _, pair := range strings.Split(s, ", ") {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, false
+		}
+		fields[k] = v
+	}
+	return fields, true
+}
+
+// parseJSONLine parses a JSON encoded entry, renaming keys back to the
+// default key names
+func parseJSONLine(line, timestampFormat string, keys map[string]string) (*LogEntry, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON log line: %w", err)
+	}
+
+	var defaults map[string]string // Written name to default name
+	for def, name := range keys {
+		if name != "" && name != def {
+			if defaults == nil {
+				defaults = make(map[string]string, len(keys))
+			}
+			defaults[name] = def
+		}
+	}
+
+	entry := &LogEntry{Fields: make(map[string]interface{})}
+	for k, v := range raw {
+		key := k
+		if def, ok := defaults[k]; ok {
+			key = def
+		} else if name := keys[k]; name != "" && name != k {
+			// A default name moved to another key is an ordinary field
+			key = ""
+		}
+		switch key {
+		case DefaultKeyTime:
+			s, _ := v.(string)
+			ts, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				ts, err = time.ParseInLocation(timestampFormat, s, time.Local)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q: %w", s, err)
+			}
+			entry.Timestamp = ts
+		case DefaultKeyLevel:
+			s, _ := v.(string)
+			level, ok := lookupLevel(strings.ToUpper(s))
+			if !ok {
+				return nil, fmt.Errorf("unknown level %q", s)
+			}
+			entry.Level = level
+		case DefaultKeyMessage:
+			entry.Message, _ = v.(string)
+		case DefaultKeyPackage:
+			entry.Package, _ = v.(string)
+		case DefaultKeyTenant:
+			entry.Tenant, _ = v.(string)
+		default:
+			entry.Fields[k] = v
+		}
+	}
+	return entry, nil
+}
+
+// lookupLevel maps an exact level name to its LogLevel
+func lookupLevel(name string) (LogLevel, bool) {
+	for _, level := range []LogLevel{DEBUG, INFO, ERROR} {
+		if level.String() == name {
+			return level, true
+		}
+	}
+	return DEBUG, false
+}
+
+// PackageFromFileName derives the package name from a log file path such as
+// "logs/app.log", "logs/app.log.3" or "logs/app.log.gz.1"
+func PackageFromFileName(path string) string {
+	name := filepath.Base(path)
+	if idx := strings.Index(name, ".log"); idx > 0 {
+		return name[:idx]
+	}
+	return name
+}
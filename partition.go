@@ -0,0 +1,104 @@
+package log4
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DirLayout partitions log files into subdirectories by time (see
+// Config.DirLayout)
+type DirLayout interface {
+	// Partition returns the subdirectory, relative to LogDir, of the files
+	// written at t, e.g. "2024/05/01"
+	Partition(t time.Time) string
+	// Time returns the start of the partition a subdirectory holds, and
+	// false if it is not a partition of this layout
+	Time(partition string) (time.Time, bool)
+}
+
+// DateLayout is a DirLayout named by a time layout whose slashes separate
+// directories, in local time
+type DateLayout string
+
+const (
+	// DailyLayout writes the files of each day to their own directory, e.g.
+	// "logs/2024/05/01/app.log"
+	DailyLayout DateLayout = "2006/01/02"
+	// HourlyLayout writes the files of each hour to their own directory,
+	// e.g. "logs/2024/05/01/13/app.log"
+	HourlyLayout DateLayout = "2006/01/02/15"
+)
+
+// Partition formats t with the layout
+func (l DateLayout) Partition(t time.Time) string {
+	return filepath.FromSlash(t.Format(string(l)))
+}
+
+// Time parses a partition with the layout
+func (l DateLayout) Time(partition string) (time.Time, bool) {
+	t, err := time.ParseInLocation(string(l), filepath.ToSlash(partition), time.Local)
+	return t, err == nil
+}
+
+// partitionChanged reports whether the open file of a key belongs to an
+// earlier partition than the one written now; cl.mu must be held
+func (cl *ChannelLogger) partitionChanged(key string) bool {
+	f, ok := cl.files[key]
+	return ok && filepath.Dir(f.Name()) != filepath.Dir(cl.logFileName(key))
+}
+
+// expirePartitions removes the partitions in the base directory of a file
+// that started longer ago than PartitionRetention, when a file is first
+// opened there in a new partition; cl.mu must be held
+func (cl *ChannelLogger) expirePartitions(base string) {
+	now := time.Now()
+	current := cl.config.DirLayout.Partition(now)
+	if last, ok := cl.expired[base]; ok && last == current {
+		return
+	}
+	cl.expired[base] = current
+	if cl.config.PartitionRetention <= 0 {
+		return
+	}
+	if err := removePartitions(cl.config.fileSystem(), cmp.Or(base, "."), cl.config.DirLayout, current, now.Add(-cl.config.PartitionRetention)); err != nil {
+		cl.handleError(fmt.Errorf(ErrExpirePartition, base, err))
+	}
+}
+
+// removePartitions removes the partitions of layout in base of fsys that
+// started before cutoff, other than current, along with the directories they
+// leave empty
+func removePartitions(fsys FS, base string, layout DirLayout, current string, cutoff time.Time) error {
+	depth := strings.Count(current, string(filepath.Separator)) + 1
+	var errs []error
+	err := walkDir(fsys, base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == base {
+			return nil
+		}
+		rel, _ := filepath.Rel(base, path)
+		t, ok := layout.Time(rel)
+		switch {
+		case ok && rel != current && t.Before(cutoff):
+			if err := fsys.RemoveAll(path); err != nil {
+				errs = append(errs, err)
+				return filepath.SkipDir
+			}
+			// Empty parents, such as the month of the last day removed
+			for dir := filepath.Dir(path); dir != base && fsys.Remove(dir) == nil; dir = filepath.Dir(dir) {
+			}
+			return filepath.SkipDir
+		case ok || strings.Count(rel, string(filepath.Separator))+1 >= depth:
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	return errors.Join(append(errs, err)...)
+}
@@ -0,0 +1,137 @@
+package log4
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// switchLayout puts every file in the partition it is set to
+type switchLayout struct {
+	name atomic.Value
+}
+
+func (l *switchLayout) Partition(t time.Time) string { return l.name.Load().(string) }
+func (l *switchLayout) Time(partition string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func TestDailyLayout(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.DirLayout = DailyLayout
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "Partitioned")
+	logger.Tenant("acme").Package("db").Info("Partitioned")
+	logger.Close()
+
+	day := filepath.FromSlash(time.Now().Format("2006/01/02"))
+	for _, path := range []string{
+		filepath.Join(tempDir, day, "app.log"),
+		filepath.Join(tempDir, "acme", day, "db.log"),
+	} {
+		if content := readFile(t, path); !strings.Contains(content, "Partitioned") {
+			t.Errorf("Expected the entry in %s, got %q", path, content)
+		}
+	}
+
+	partition, ok := DailyLayout.Time(day)
+	if y, m, d := time.Now().Date(); !ok || partition != time.Date(y, m, d, 0, 0, 0, 0, time.Local) {
+		t.Errorf("Expected %s to parse as the start of today, got %v, %v", day, partition, ok)
+	}
+	if _, ok := DailyLayout.Time("2024/05"); ok {
+		t.Error("Expected a month not to parse as a daily partition")
+	}
+}
+
+func TestPartitionBoundary(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	var errs []error
+	layout := &switchLayout{}
+	layout.name.Store("day1")
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.DirLayout = layout
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "Before")
+	logger.Flush()
+	layout.name.Store("day2")
+	logger.Info("app", "After")
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "day1", "app.log")); countLines(content) != 1 || !strings.Contains(content, "Before") {
+		t.Errorf("Expected the first partition to keep the earlier entry, got %q", content)
+	}
+	if content := readFile(t, filepath.Join(tempDir, "day2", "app.log")); countLines(content) != 1 || !strings.Contains(content, "After") {
+		t.Errorf("Expected the later entry in a new partition, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "day2", InternalPackage+".log")); err == nil {
+		t.Error("Expected no notice of a recreated directory for a new partition")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestPartitionRetention(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	for _, dir := range []string{"2020/01/01", "2020/01/02", "acme/2020/01/01", "archive/old"} {
+		path := filepath.Join(tempDir, filepath.FromSlash(dir))
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "app.log"), []byte("old\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	yesterday := filepath.FromSlash(time.Now().AddDate(0, 0, -1).Format("2006/01/02"))
+	if err := os.MkdirAll(filepath.Join(tempDir, yesterday), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.DirLayout = DailyLayout
+	config.PartitionRetention = 7 * 24 * time.Hour
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Tenant("acme").Package("db").Info("Current")
+	logger.Info("app", "Current")
+	logger.Close()
+
+	for _, dir := range []string{"2020", "acme/2020"} {
+		if _, err := os.Stat(filepath.Join(tempDir, filepath.FromSlash(dir))); !os.IsNotExist(err) {
+			t.Errorf("Expected the expired partitions in %s to be removed, got %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"archive/old", yesterday, time.Now().Format("2006/01/02")} {
+		if _, err := os.Stat(filepath.Join(tempDir, filepath.FromSlash(dir))); err != nil {
+			t.Errorf("Expected %s to be kept: %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package log4
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProxy accepts connections, lets handshake read the target address off
+// each and relays the connection to it, counting relayed connections
+func fakeProxy(t *testing.T, handshake func(conn net.Conn, r *bufio.Reader) (string, bool)) (string, *atomic.Int32) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var relayed atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				target, ok := handshake(conn, r)
+				if !ok {
+					return
+				}
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				relayed.Add(1)
+				go io.Copy(upstream, r)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String(), &relayed
+}
+
+// socks5Handshake accepts the user bob with password secret
+func socks5Handshake(conn net.Conn, r *bufio.Reader) (string, bool) {
+	greeting := make([]byte, 3)
+	if _, err := io.ReadFull(r, greeting); err != nil || greeting[2] != 0x02 {
+		conn.Write([]byte{0x05, 0xff})
+		return "", false
+	}
+	conn.Write([]byte{0x05, 0x02})
+
+	readString := func() string {
+		n, _ := r.ReadByte()
+		b := make([]byte, n)
+		io.ReadFull(r, b)
+		return string(b)
+	}
+	r.ReadByte() // Subnegotiation version
+	if readString() != "bob" || readString() != "secret" {
+		conn.Write([]byte{0x01, 0x01})
+		return "", false
+	}
+	conn.Write([]byte{0x01, 0x00})
+
+	head := make([]byte, 4)
+	io.ReadFull(r, head)
+	var host string
+	switch head[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 0x03:
+		host = readString()
+	}
+	port := make([]byte, 2)
+	io.ReadFull(r, port)
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+}
+
+func TestFluentSinkSOCKS5(t *testing.T) {
+	addr, messages := fakeFluentd(t, "")
+	proxyAddr, relayed := fakeProxy(t, socks5Handshake)
+
+	dialer, err := ProxyDialer("socks5://bob:secret@"+proxyAddr, nil)
+	if err != nil {
+		t.Fatalf("ProxyDialer failed: %v", err)
+	}
+	var exportErr error
+	sink := NewFluentSink(FluentOptions{
+		Address:      addr,
+		BatchOptions: BatchOptions{Dialer: dialer},
+		ErrorHandler: func(err error) { exportErr = err },
+	})
+	sink.Write(&LogEntry{Package: "orders", Level: INFO, Message: "created", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	if exportErr != nil {
+		t.Fatalf("Forwarding failed: %v", exportErr)
+	}
+	select {
+	case msg := <-messages:
+		if msg[0] != "orders" {
+			t.Errorf("Unexpected message %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fluentd received nothing")
+	}
+	if relayed.Load() != 1 {
+		t.Errorf("Expected 1 connection through the proxy, got %d", relayed.Load())
+	}
+
+	// Wrong credentials
+	dialer, _ = ProxyDialer("socks5://bob:wrong@"+proxyAddr, nil)
+	if _, err := dialer.DialContext(t.Context(), "tcp", addr); err == nil {
+		t.Error("Expected the proxy to reject wrong credentials")
+	}
+}
+
+func TestSOCKS5Resolution(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	targets := make(chan string, 1)
+	proxyAddr, _ := fakeProxy(t, func(conn net.Conn, r *bufio.Reader) (string, bool) {
+		target, ok := socks5Handshake(conn, r)
+		targets <- target
+		return target, ok
+	})
+
+	for scheme, want := range map[string]string{
+		"socks5":  net.JoinHostPort("127.0.0.1", port),
+		"socks5h": net.JoinHostPort("localhost", port),
+	} {
+		dialer, _ := ProxyDialer(scheme+"://bob:secret@"+proxyAddr, nil)
+		conn, err := dialer.DialContext(t.Context(), "tcp4", net.JoinHostPort("localhost", port))
+		if err != nil {
+			t.Fatalf("%s: DialContext failed: %v", scheme, err)
+		}
+		conn.Close()
+		if got := <-targets; got != want {
+			t.Errorf("%s: expected the proxy to be asked for %s, got %s", scheme, want, got)
+		}
+	}
+}
+
+func TestOTLPSinkHTTPConnect(t *testing.T) {
+	var mu sync.Mutex
+	var records int
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		records += len(otlpBodies(t, body)["api"])
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	proxyAddr, relayed := fakeProxy(t, func(conn net.Conn, r *bufio.Reader) (string, bool) {
+		req, err := http.ReadRequest(r)
+		if err != nil || req.Method != http.MethodConnect {
+			return "", false
+		}
+		if user, password, ok := parseBasicAuth(req.Header.Get("Proxy-Authorization")); !ok || user != "bob" || password != "secret" {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return "", false
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return req.Host, true
+	})
+
+	dialer, err := ProxyDialer("http://bob:secret@"+proxyAddr, nil)
+	if err != nil {
+		t.Fatalf("ProxyDialer failed: %v", err)
+	}
+	sink, _ := NewOTLPSink(OTLPOptions{Endpoint: collector.URL})
+
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.Dialer = dialer // Inherited by the sink
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.Info("api", "Through the proxy")
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if records != 1 || relayed.Load() != 1 {
+		t.Errorf("Expected 1 record through the proxy, got %d records and %d connections", records, relayed.Load())
+	}
+
+	dialer, _ = ProxyDialer("http://bob:wrong@"+proxyAddr, nil)
+	if _, err := dialer.DialContext(t.Context(), "tcp", collector.Listener.Addr().String()); err == nil {
+		t.Error("Expected the proxy to reject wrong credentials")
+	}
+}
+
+// parseBasicAuth decodes a Basic authorization header
+func parseBasicAuth(header string) (string, string, bool) {
+	req := &http.Request{Header: http.Header{"Authorization": {header}}}
+	return req.BasicAuth()
+}
+
+func TestProxyDialerScheme(t *testing.T) {
+	if _, err := ProxyDialer("ftp://proxy:21", nil); err == nil {
+		t.Error("Expected unsupported schemes to be rejected")
+	}
+}
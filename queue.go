@@ -0,0 +1,171 @@
+package log4
+
+import (
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+// Queue gives the packages matching a pattern a queue of their own, so that a
+// chatty package filling the shared queue cannot cause their entries to be
+// dropped, e.g. for audit logs
+type Queue struct {
+	// Pattern selects packages as Route.Pattern does, e.g. "audit*"
+	Pattern string
+	// BufferSize is the capacity of the queue (default: Config.BufferSize)
+	BufferSize int
+}
+
+// QueueStats describes the activity of a Queue
+type QueueStats struct {
+	Length   int    `json:"length"`   // Entries waiting to be written
+	Capacity int    `json:"capacity"` // Size of the queue
+	Dropped  uint64 `json:"dropped"`  // Entries dropped because the queue was full
+}
+
+// packageQueue is a Queue of a logger. Its entries are moved to the shared
+// queue by a forwarding goroutine, which waits for room instead of dropping,
+// so the entries of the package are only dropped when its own queue is full.
+type packageQueue struct {
+	Queue
+	ch        chan *LogEntry
+	dropped   atomic.Uint64
+	admitted  atomic.Uint64 // Entries sent to ch
+	forwarded atomic.Uint64 // Entries moved to the shared queue
+}
+
+// admit counts an entry sent to the queue, or does nothing if q is nil for
+// the shared queue
+func (q *packageQueue) admit() {
+	if q != nil {
+		q.admitted.Add(1)
+	}
+}
+
+// priorityChan creates the priority lane of Config.PriorityBufferSize, or
+// returns nil if it is disabled
+func priorityChan(size int) chan *LogEntry {
+	if size <= 0 {
+		return nil
+	}
+	return make(chan *LogEntry, size)
+}
+
+// startQueues creates the queues of Config.Queues and their forwarders
+func (cl *ChannelLogger) startQueues() {
+	for _, q := range cl.config.Queues {
+		if q.BufferSize <= 0 {
+			q.BufferSize = cl.config.BufferSize
+		}
+		pq := &packageQueue{Queue: q, ch: make(chan *LogEntry, q.BufferSize)}
+		cl.queues = append(cl.queues, pq)
+
+		cl.queueWg.Add(1)
+		go func() {
+			defer cl.queueWg.Done()
+			for entry := range pq.ch {
+				cl.logChan <- entry
+				pq.forwarded.Add(1)
+			}
+		}()
+	}
+}
+
+// queueFor returns the queue of an entry's package, or nil for the shared
+// queue. Lookups are cached per package.
+func (cl *ChannelLogger) queueFor(entry *LogEntry) *packageQueue {
+	if len(cl.queues) == 0 {
+		return nil
+	}
+	if q, ok := cl.queueCache.Load(entry.Package); ok {
+		return q.(*packageQueue)
+	}
+
+	var match *packageQueue
+	name := sanitizePackageName(entry.Package)
+	for _, q := range cl.queues {
+		if ok, _ := path.Match(routePattern(q.Pattern), name); ok {
+			match = q
+			break
+		}
+	}
+	cl.queueCache.Store(entry.Package, match)
+	return match
+}
+
+// closeQueues closes the package queues and the spill buffer and waits
+// until their entries have been moved to the shared queue. Called with sendMu
+// held exclusively.
+func (cl *ChannelLogger) closeQueues() {
+	for _, q := range cl.queues {
+		close(q.ch)
+	}
+	if cl.spill != nil {
+		close(cl.spill.stop)
+	}
+	cl.queueWg.Wait()
+}
+
+// waitForwarded waits until the package queues and the spill buffer have
+// moved the entries they held when it was called to the shared queue, or
+// until the logger is closed
+func (cl *ChannelLogger) waitForwarded() {
+	admitted := make([]uint64, len(cl.queues))
+	for i, q := range cl.queues {
+		admitted[i] = q.admitted.Load()
+	}
+	var pushed uint64
+	if cl.spill != nil {
+		pushed = cl.spill.pushed.Load()
+	}
+
+	for i := 0; i < len(cl.queues); {
+		if cl.queues[i].forwarded.Load() >= admitted[i] {
+			i++
+		} else if !cl.waitForwarder() {
+			return
+		}
+	}
+	for cl.spill != nil && cl.spill.forwarded.Load() < pushed {
+		if !cl.waitForwarder() {
+			return
+		}
+	}
+}
+
+// waitForwarder gives the forwarding goroutines time to move entries,
+// reporting false once the logger is closed
+func (cl *ChannelLogger) waitForwarder() bool {
+	select {
+	case <-cl.done:
+		return false
+	case <-time.After(time.Millisecond):
+		return true
+	}
+}
+
+// queueStats returns the stats of the package queues by pattern
+func (cl *ChannelLogger) queueStats() map[string]QueueStats {
+	if len(cl.queues) == 0 {
+		return nil
+	}
+	stats := make(map[string]QueueStats, len(cl.queues))
+	for _, q := range cl.queues {
+		stats[q.Pattern] = QueueStats{
+			Length:   len(q.ch),
+			Capacity: cap(q.ch),
+			Dropped:  q.dropped.Load(),
+		}
+	}
+	return stats
+}
+
+// priorityStats returns the stats of the priority lane, or nil if it is
+// disabled. Errors that do not fit fall back to the regular queues, so the
+// lane itself drops nothing.
+func (cl *ChannelLogger) priorityStats() *QueueStats {
+	if cl.priority == nil {
+		return nil
+	}
+	return &QueueStats{Length: len(cl.priority), Capacity: cap(cl.priority)}
+}
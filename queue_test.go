@@ -0,0 +1,112 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPackageQueues(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.BufferSize = 2
+	config.Sinks = []Sink{sink}
+	config.Queues = []Queue{{Pattern: "audit*", BufferSize: 5}}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("chatty", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		logger.Info("chatty", "noise")
+	}
+	for i := 0; i < 5; i++ {
+		logger.Info("audit.login", "user logged in")
+	}
+
+	stats := logger.Stats()
+	if stats.Dropped == 0 {
+		t.Error("Expected the shared queue to drop entries")
+	}
+	if q := stats.Queues["audit*"]; q.Dropped != 0 || q.Capacity != 5 {
+		t.Errorf("Expected the audit queue to keep every entry, got %+v", q)
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Info("audit.login", "overflow")
+	}
+	dropped := logger.Stats().Queues["audit*"].Dropped
+	if dropped == 0 {
+		t.Error("Expected the audit queue to drop entries once full")
+	}
+
+	close(sink.release)
+	logger.Close()
+
+	lines := countLines(readFile(t, filepath.Join(tempDir, "audit_login.log")))
+	if lines+int(dropped) != 15 {
+		t.Errorf("Expected 15 audit entries written or dropped, got %d written and %d dropped", lines, dropped)
+	}
+}
+
+func TestInvalidQueuePattern(t *testing.T) {
+	config := DefaultConfig()
+	config.Queues = []Queue{{Pattern: "["}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an invalid queue pattern to be rejected")
+	}
+}
+
+func TestPriorityLane(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	var dropped []LogLevel
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.BufferSize = 2
+	config.PriorityBufferSize = 5
+	config.Sinks = []Sink{sink}
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		dropped = append(dropped, entry.Level)
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("app", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		logger.Info("app", "noise")
+	}
+	for i := 0; i < 5; i++ {
+		logger.Error("app", "failure")
+	}
+	if p := logger.Stats().Priority; p == nil || p.Length != 5 || p.Capacity != 5 {
+		t.Errorf("Expected 5 errors in the priority lane, got %+v", p)
+	}
+
+	close(sink.release)
+	logger.Close()
+
+	for _, level := range dropped {
+		if level == ERROR {
+			t.Fatalf("Expected no errors to be dropped, dropped %v", dropped)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, "app.log"))), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("Expected 8 lines, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for i, line := range lines[1:6] {
+		if !strings.Contains(line, "failure") {
+			t.Errorf("Expected line %d to be an error written before queued entries, got %q", i+2, line)
+		}
+	}
+}
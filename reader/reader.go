@@ -0,0 +1,321 @@
+// Package reader scans log directories written by log4 and returns the
+// entries matching a query, effectively a structured grep over the package's
+// own text and JSON output.
+//
+// Example usage:
+//
+//	entries, err := reader.Read("./logs", reader.Query{
+//		Since:    time.Now().Add(-time.Hour),
+//		MinLevel: log4.ERROR,
+//		Packages: []string{"database"},
+//		Where:    []reader.Predicate{reader.FieldEquals("status", "500")},
+//	})
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MhunterDev/log4"
+)
+
+// Predicate reports whether an entry should be included in the results
+type Predicate func(entry *log4.LogEntry) bool
+
+// Query selects entries from a log directory. The zero value matches
+// everything.
+type Query struct {
+	Since           time.Time     // Only entries at or after Since
+	Until           time.Time     // Only entries before Until
+	MinLevel        log4.LogLevel // Only entries at or above MinLevel
+	Packages        []string      // Only these packages (default: all)
+	Where           []Predicate   // All predicates must match
+	TimestampFormat string        // Layout used by the writer (default: the file header's, or log4.DefaultConfig's)
+
+	// Keys are the JSON key names used by the writer, by log4.DefaultKeyTime
+	// etc. (default: the file header's, or the default names)
+	Keys map[string]string
+}
+
+// Match reports whether an entry satisfies the query
+func (q *Query) Match(entry *log4.LogEntry) bool {
+	if entry.Level < q.MinLevel {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !entry.Timestamp.Before(q.Until) {
+		return false
+	}
+	if !q.matchPackage(entry.Package) {
+		return false
+	}
+	for _, p := range q.Where {
+		if !p(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) matchPackage(pkg string) bool {
+	if len(q.Packages) == 0 {
+		return true
+	}
+	for _, p := range q.Packages {
+		if p == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldExists matches entries that have the given field
+func FieldExists(key string) Predicate {
+	return func(entry *log4.LogEntry) bool {
+		_, ok := entry.Fields[key]
+		return ok
+	}
+}
+
+// FieldEquals matches entries whose field formats to the given value. Values
+// read from text logs are strings, so comparison is done on the %v form.
+func FieldEquals(key string, value interface{}) Predicate {
+	want := fmt.Sprintf("%v", value)
+	return func(entry *log4.LogEntry) bool {
+		v, ok := entry.Fields[key]
+		return ok && fmt.Sprintf("%v", v) == want
+	}
+}
+
+// FieldMatches matches entries whose field matches the regular expression
+func FieldMatches(key string, re *regexp.Regexp) Predicate {
+	return func(entry *log4.LogEntry) bool {
+		v, ok := entry.Fields[key]
+		return ok && re.MatchString(fmt.Sprintf("%v", v))
+	}
+}
+
+// FieldCompare matches entries whose numeric field compares to value using
+// one of the operators <, <=, >, >=, == or !=. Non-numeric fields never match.
+func FieldCompare(key, op string, value float64) Predicate {
+	return func(entry *log4.LogEntry) bool {
+		v, ok := entry.Fields[key]
+		if !ok {
+			return false
+		}
+		n, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64)
+		if err != nil {
+			return false
+		}
+		switch op {
+		case "<":
+			return n < value
+		case "<=":
+			return n <= value
+		case ">":
+			return n > value
+		case ">=":
+			return n >= value
+		case "==":
+			return n == value
+		case "!=":
+			return n != value
+		}
+		return false
+	}
+}
+
+// MessageContains matches entries whose message contains substr
+func MessageContains(substr string) Predicate {
+	return func(entry *log4.LogEntry) bool {
+		return strings.Contains(entry.Message, substr)
+	}
+}
+
+// LogFile describes a log file found in a directory
+type LogFile struct {
+	Path     string
+	Package  string
+	Rotation int // 0 for the active file, N for the ".N" rotated file
+	Size     int64
+	ModTime  time.Time
+}
+
+// Files lists the log files in dir, grouped by package with the oldest
+// rotation first so that reading them in order yields chronological output
+func Files(dir string) ([]LogFile, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory %s: %w", dir, err)
+	}
+
+	var files []LogFile
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.Contains(de.Name(), ".log") || log4.IsLatestLink(de.Name()) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, LogFile{
+			Path:     filepath.Join(dir, de.Name()),
+			Package:  log4.PackageFromFileName(de.Name()),
+			Rotation: rotationIndex(de.Name()),
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+		})
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Package != files[j].Package {
+			return files[i].Package < files[j].Package
+		}
+		// Higher rotation numbers are older; the active file (0) comes last
+		return files[i].Rotation > files[j].Rotation
+	})
+	return files, nil
+}
+
+// rotationIndex returns N for names ending in ".N", otherwise 0
+func rotationIndex(name string) int {
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(name[idx+1:])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Scan calls fn for every entry in dir matching q, file by file in the order
+// returned by Files. Returning an error from fn stops the scan and returns
+// that error.
+func Scan(dir string, q Query, fn func(entry *log4.LogEntry) error) error {
+	files, err := Files(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if !q.matchPackage(f.Package) {
+			continue
+		}
+		// Nothing in a file last written before Since can match
+		if !q.Since.IsZero() && f.ModTime.Before(q.Since) {
+			continue
+		}
+		if err := ScanFile(f.Path, q, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanFile calls fn for every entry in a single log file matching q.
+// Compressed files are decoded using the codec registered for their
+// extension. Lines that cannot be parsed, such as stack traces, are appended
+// to the message of the preceding entry. Without q.TimestampFormat or
+// q.Keys, the layout and key names recorded in the file's header are used if
+// it has one (see log4.Config.FileHeader).
+func ScanFile(path string, q Query, fn func(entry *log4.LogEntry) error) error {
+	detect := q.TimestampFormat == ""
+	detectKeys := q.Keys == nil
+	if detect {
+		q.TimestampFormat = log4.DefaultConfig().TimestampFormat
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if codec := log4.CodecForFile(path); codec != nil {
+		cr, err := codec.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		defer cr.Close()
+		r = cr
+	}
+
+	pkg := log4.PackageFromFileName(path)
+	var pending *log4.LogEntry
+	deliver := func() error {
+		if pending == nil {
+			return nil
+		}
+		entry := pending
+		pending = nil
+		if entry.Package == "" {
+			entry.Package = pkg
+		}
+		if q.Match(entry) {
+			return fn(entry)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if log4.IsHeaderLine(scanner.Text()) {
+			if h, err := log4.ParseHeader(scanner.Text()); err == nil {
+				if detect && h.TimestampFormat != "" {
+					q.TimestampFormat = h.TimestampFormat
+				}
+				if detectKeys {
+					q.Keys = h.Keys
+				}
+			}
+			continue
+		}
+		entry, err := log4.ParseLineKeys(scanner.Text(), q.TimestampFormat, q.Keys)
+		if err != nil {
+			if pending != nil {
+				pending.Message += "\n" + scanner.Text()
+			}
+			continue
+		}
+		if err := deliver(); err != nil {
+			return err
+		}
+		pending = entry
+	}
+	// An incomplete trailing frame of a compressed file is not an error
+	if err := scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return deliver()
+}
+
+// Read returns all entries in dir matching q, ordered by timestamp
+func Read(dir string, q Query) ([]*log4.LogEntry, error) {
+	var entries []*log4.LogEntry
+	err := Scan(dir, q, func(entry *log4.LogEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
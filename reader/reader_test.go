@@ -0,0 +1,161 @@
+package reader
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MhunterDev/log4"
+)
+
+func writeFile(t *testing.T, path string, lines ...string) {
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func writeTestLogs(t *testing.T) string {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "api.log.1"),
+		"[2025-06-23 10:00:00] INFO: Request served",
+		"[2025-06-23 10:00:01] ERROR: Request failed | status=500",
+	)
+	writeFile(t, filepath.Join(dir, "api.log"),
+		"[2025-06-23 11:00:00] INFO: Request slow | status=200, duration_ms=1200",
+		"[2025-06-23 11:00:05] ERROR: Panic recovered",
+		"goroutine 1 [running]:",
+		"main.main()",
+	)
+	writeFile(t, filepath.Join(dir, "db.log"),
+		"[2025-06-23 10:30:00] DEBUG: Query executed",
+		"[2025-06-23 10:30:01] ERROR: Connection lost",
+	)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not a log")
+	return dir
+}
+
+func messages(entries []*log4.LogEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Message)
+	}
+	return out
+}
+
+func TestFiles(t *testing.T) {
+	dir := writeTestLogs(t)
+
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f.Path))
+	}
+	expected := []string{"api.log.1", "api.log", "db.log"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Files() = %v, want %v", names, expected)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := writeTestLogs(t)
+
+	t.Run("All entries in time order", func(t *testing.T) {
+		entries, err := Read(dir, Query{})
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		got := messages(entries)
+		if len(got) != 6 || got[0] != "Request served" || got[2] != "Query executed" {
+			t.Errorf("Unexpected entries: %q", got)
+		}
+	})
+
+	t.Run("Level and package", func(t *testing.T) {
+		entries, _ := Read(dir, Query{MinLevel: log4.ERROR, Packages: []string{"db"}})
+		if got := messages(entries); len(got) != 1 || got[0] != "Connection lost" {
+			t.Errorf("Unexpected entries: %q", got)
+		}
+	})
+
+	t.Run("Time range", func(t *testing.T) {
+		since := time.Date(2025, 6, 23, 10, 0, 1, 0, time.Local)
+		until := time.Date(2025, 6, 23, 11, 0, 0, 0, time.Local)
+		entries, _ := Read(dir, Query{Since: since, Until: until})
+		if got := messages(entries); len(got) != 3 {
+			t.Errorf("Expected 3 entries in range, got %q", got)
+		}
+	})
+
+	t.Run("Field predicates", func(t *testing.T) {
+		entries, _ := Read(dir, Query{Where: []Predicate{FieldCompare("duration_ms", ">", 1000)}})
+		if got := messages(entries); len(got) != 1 || got[0] != "Request slow" {
+			t.Errorf("Unexpected entries: %q", got)
+		}
+
+		entries, _ = Read(dir, Query{Where: []Predicate{FieldMatches("status", regexp.MustCompile(`^5`))}})
+		if got := messages(entries); len(got) != 1 || got[0] != "Request failed" {
+			t.Errorf("Unexpected entries: %q", got)
+		}
+	})
+
+	t.Run("Continuation lines", func(t *testing.T) {
+		entries, _ := Read(dir, Query{Where: []Predicate{MessageContains("goroutine 1")}})
+		if len(entries) != 1 || !strings.HasPrefix(entries[0].Message, "Panic recovered\n") {
+			t.Errorf("Stack trace should be folded into the preceding entry, got %q", messages(entries))
+		}
+	})
+}
+
+func TestScanCompressed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.log.gz")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	// Two frames, as written by log4's inline compression
+	for _, line := range []string{"[2025-06-23 10:00:00] INFO: one\n", "[2025-06-23 10:00:01] INFO: two\n"} {
+		zw := gzip.NewWriter(f)
+		zw.Write([]byte(line))
+		zw.Close()
+	}
+	f.Close()
+
+	entries, err := Read(dir, Query{})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got := messages(entries); len(got) != 2 || entries[0].Package != "jobs" {
+		t.Errorf("Unexpected entries: %q", got)
+	}
+}
+
+func TestScanFileHeader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	writeFile(t, path,
+		`#log4 {"log4":1,"format":"text","timestamp_format":"2006-01-02T15:04:05","host":"web-1","started":"2025-06-23T10:00:00Z"}`,
+		"[2025-06-23T10:00:00] INFO: Request served",
+	)
+
+	var got []*log4.LogEntry
+	err := ScanFile(path, Query{}, func(entry *log4.LogEntry) error {
+		got = append(got, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanFile failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Message != "Request served" || got[0].Timestamp.Hour() != 10 {
+		t.Errorf("Expected the entry parsed with the header's layout, got %v", messages(got))
+	}
+}
@@ -0,0 +1,201 @@
+package log4
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecentEntry is a logged entry retained in memory
+type RecentEntry struct {
+	Package   string    `json:"package"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Level     LogLevel  `json:"level"`
+	Timestamp time.Time `json:"time"`
+	Line      string    `json:"line"`
+}
+
+// entryRing is a fixed-size ring of the most recently logged entries. Entries
+// are copied when added and only formatted when a snapshot is taken, which
+// keeps the cost on the logging call path low.
+type entryRing struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	next    int
+	full    bool
+	added   uint64        // Entries added so far
+	wake    chan struct{} // Closed by the next add, if a follower waits
+}
+
+func newEntryRing(size int) *entryRing {
+	return &entryRing{entries: make([]LogEntry, size)}
+}
+
+// add copies entry into the ring, overwriting the oldest entry when full
+func (r *entryRing) add(entry *LogEntry) {
+	var fields map[string]interface{}
+	if len(entry.Fields) > 0 {
+		fields = make(map[string]interface{}, len(entry.Fields))
+		for k, v := range entry.Fields {
+			fields[k] = v
+		}
+	}
+
+	var labels map[string]string
+	if len(entry.Labels) > 0 {
+		labels = make(map[string]string, len(entry.Labels))
+		for k, v := range entry.Labels {
+			labels[k] = v
+		}
+	}
+
+	r.mu.Lock()
+	r.entries[r.next] = LogEntry{
+		Package:   entry.Package,
+		Tenant:    entry.Tenant,
+		Level:     entry.Level,
+		Message:   entry.Message,
+		Fields:    fields,
+		Timestamp: entry.Timestamp,
+		Labels:    labels,
+	}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.added++
+	if r.wake != nil {
+		close(r.wake)
+		r.wake = nil
+	}
+	r.mu.Unlock()
+}
+
+// copyEntries returns the retained entries, oldest first; callers hold r.mu
+func (r *entryRing) copyEntries() []LogEntry {
+	var entries []LogEntry
+	if r.full {
+		entries = append(entries, r.entries[r.next:]...)
+	}
+	return append(entries, r.entries[:r.next]...)
+}
+
+// drain returns the retained entries, oldest first, and empties the ring
+func (r *entryRing) drain() []LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := r.copyEntries()
+	for i := range r.entries {
+		r.entries[i] = LogEntry{}
+	}
+	r.next, r.full = 0, false
+	return entries
+}
+
+// snapshot returns the retained entries formatted with f, oldest first
+func (r *entryRing) snapshot(f Formatter) []RecentEntry {
+	r.mu.Lock()
+	entries := r.copyEntries()
+	r.mu.Unlock()
+	return formatRecent(f, entries)
+}
+
+// follow returns the entries added after the first pos that are still
+// retained, formatted with f, and the position to follow from next. Without
+// new entries it returns a channel closed by the next add instead.
+func (r *entryRing) follow(f Formatter, pos uint64) ([]RecentEntry, uint64, <-chan struct{}) {
+	r.mu.Lock()
+	if r.added == pos {
+		if r.wake == nil {
+			r.wake = make(chan struct{})
+		}
+		wake := r.wake
+		r.mu.Unlock()
+		return nil, pos, wake
+	}
+	entries := r.copyEntries()
+	if n := r.added - pos; n < uint64(len(entries)) {
+		entries = entries[len(entries)-int(n):]
+	}
+	pos = r.added
+	r.mu.Unlock()
+	return formatRecent(f, entries), pos, nil
+}
+
+// formatRecent formats copied entries with f
+func formatRecent(f Formatter, entries []LogEntry) []RecentEntry {
+	out := make([]RecentEntry, len(entries))
+	var buf []byte
+	scratch := make(map[string]interface{})
+	for i := range entries {
+		buf = f.Format(buf[:0], withLabelFields(&entries[i], scratch))
+		out[i] = RecentEntry{
+			Package:   entries[i].Package,
+			Tenant:    entries[i].Tenant,
+			Level:     entries[i].Level,
+			Timestamp: entries[i].Timestamp,
+			Line:      string(buf),
+		}
+	}
+	return out
+}
+
+// Recent returns the last logged entries, oldest first. It returns nil
+// unless Config.RecentEntries is set.
+func (cl *ChannelLogger) Recent() []RecentEntry {
+	if cl.recent == nil {
+		return nil
+	}
+	return cl.recent.snapshot(cl.formatter)
+}
+
+// DumpRecent writes the retained entries to w, oldest first, each line
+// prefixed with its package, and tenant if any, as "tenant/package"
+func (cl *ChannelLogger) DumpRecent(w io.Writer) error {
+	for _, e := range cl.Recent() {
+		pkg := e.Package
+		if e.Tenant != "" {
+			pkg = e.Tenant + "/" + pkg
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", pkg, e.Line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpOnPanic dumps the retained entries to w if the calling goroutine is
+// panicking, then continues the panic. It must be deferred directly:
+//
+//	defer logger.DumpOnPanic(os.Stderr)
+func (cl *ChannelLogger) DumpOnPanic(w io.Writer) {
+	if r := recover(); r != nil {
+		fmt.Fprintf(w, "panic: %v\n--- last logged entries ---\n", r)
+		cl.DumpRecent(w)
+		panic(r)
+	}
+}
+
+// exit is replaced in tests
+var exit = os.Exit
+
+// Fatal logs an error-level message, closes the logger so that every queued
+// entry is written, dumps the retained entries to stderr and exits with
+// status 1
+func (cl *ChannelLogger) Fatal(pkg, message string) {
+	cl.Error(pkg, message)
+	cl.exitFatal()
+}
+
+// exitFatal closes the logger, dumps the retained entries and exits like
+// Exit
+func (cl *ChannelLogger) exitFatal() {
+	cl.Close()
+	if cl.recent != nil {
+		fmt.Fprintln(os.Stderr, "--- last logged entries ---")
+		cl.DumpRecent(os.Stderr)
+	}
+	Exit(1)
+}
@@ -0,0 +1,116 @@
+package log4
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecentBelowMinLevel(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	config.RecentEntries = 3
+	config.RecentBelowMinLevel = true
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Info("app", "dropped from ring")
+	logger.Debug("app", "debug context")
+	logger.LogWithFields("app", DEBUG, "more context", map[string]interface{}{"id": 7})
+	logger.Error("app", "failure")
+
+	recent := logger.Recent()
+	if len(recent) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(recent))
+	}
+	if !strings.HasSuffix(recent[0].Line, "DEBUG: debug context") ||
+		!strings.HasSuffix(recent[1].Line, "more context | id=7") ||
+		recent[2].Level != ERROR {
+		t.Errorf("Unexpected ring contents: %+v", recent)
+	}
+
+	var buf bytes.Buffer
+	if err := logger.DumpRecent(&buf); err != nil {
+		t.Fatalf("DumpRecent failed: %v", err)
+	}
+	if countLines(buf.String()) != 3 || !strings.HasPrefix(buf.String(), "app [") {
+		t.Errorf("Unexpected dump: %q", buf.String())
+	}
+}
+
+func TestRecentExcludesFilteredByDefault(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	config.RecentEntries = 10
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Debug("app", "filtered")
+	logger.Info("app", "kept")
+
+	if recent := logger.Recent(); len(recent) != 1 || recent[0].Level != INFO {
+		t.Errorf("Expected only the info entry, got %+v", recent)
+	}
+}
+
+func TestDumpOnPanic(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.RecentEntries = 5
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+	logger.Info("app", "before the crash")
+
+	var buf bytes.Buffer
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Panic should continue after dumping, got %v", r)
+			}
+		}()
+		defer logger.DumpOnPanic(&buf)
+		panic("boom")
+	}()
+
+	if !strings.Contains(buf.String(), "panic: boom") || !strings.Contains(buf.String(), "before the crash") {
+		t.Errorf("Unexpected dump: %q", buf.String())
+	}
+}
+
+func TestFatal(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var code int
+	savedExit := exit
+	exit = func(c int) { code = c }
+	defer func() { exit = savedExit }()
+
+	logger := NewChannelLogger(10, tempDir)
+	logger.stdout = io.Discard
+	logger.Package("app").FatalF("cannot continue: %s", "disk full")
+	time.Sleep(10 * time.Millisecond)
+
+	if code != 1 {
+		t.Errorf("Expected exit status 1, got %d", code)
+	}
+	if !logger.closed.Load() {
+		t.Error("Fatal should close the logger")
+	}
+}
@@ -0,0 +1,45 @@
+package log4
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// DefaultRecordSeparator ends every record unless Config.RecordSeparator is
+// set
+const DefaultRecordSeparator = "\n"
+
+// recordSeparator returns the separator written after every record
+func (c *Config) recordSeparator() string {
+	if c.RecordSeparator == "" {
+		return DefaultRecordSeparator
+	}
+	return c.RecordSeparator
+}
+
+// escapeNewlines replaces the line breaks within a formatted entry with \n
+// and \r, so that multi-line messages stay on one line
+func escapeNewlines(formatted []byte) []byte {
+	if bytes.IndexAny(formatted, "\r\n") < 0 {
+		return formatted
+	}
+	escaped := make([]byte, 0, len(formatted)+8)
+	for _, c := range formatted {
+		switch c {
+		case '\n':
+			escaped = append(escaped, '\\', 'n')
+		case '\r':
+			escaped = append(escaped, '\\', 'r')
+		default:
+			escaped = append(escaped, c)
+		}
+	}
+	return escaped
+}
+
+// appendLengthPrefixed appends record preceded by its length as a 4-byte
+// big-endian integer
+func appendLengthPrefixed(dst, record []byte) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(record)))
+	return append(dst, record...)
+}
@@ -0,0 +1,130 @@
+package log4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bufferSink keeps the lines passed to Write
+type bufferSink struct {
+	buf bytes.Buffer
+}
+
+func (s *bufferSink) Write(entry *LogEntry, line []byte) error {
+	s.buf.Write(line)
+	return nil
+}
+
+func (s *bufferSink) Close() error { return nil }
+
+func TestRecordSeparator(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var console bytes.Buffer
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.RecordSeparator = "\x00"
+	config.EscapeNewlines = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = &console
+
+	logger.Error("app", "panic: boom\ngoroutine 1 [running]:\r\nmain.main()")
+	logger.Info("app", "done")
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "app.log"))
+	records := bytes.Split([]byte(content), []byte{0})
+	if len(records) != 3 || len(records[2]) != 0 {
+		t.Fatalf("Expected 2 NUL-terminated records, got %q", content)
+	}
+	if bytes.ContainsAny(records[0], "\r\n") || !bytes.Contains(records[0], []byte(`boom\ngoroutine 1 [running]:\r\nmain`)) {
+		t.Errorf("Expected escaped line breaks, got %q", records[0])
+	}
+	if console.String() != content {
+		t.Errorf("Expected the console to use the separator, got %q", console.String())
+	}
+	if size := logger.fileSizes["app"]; size != int64(len(content)) {
+		t.Errorf("Tracked size %d, file has %d bytes", size, len(content))
+	}
+}
+
+func TestLengthPrefixSinks(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var buf bytes.Buffer
+	stream := NewStreamSink(0)
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{NewWriterSink(&buf, nil), stream}
+	config.LengthPrefixSinks = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	streamed, cancel := stream.Subscribe(DEBUG, "")
+	defer cancel()
+
+	logger.Info("app", "first")
+	logger.Info("app", "multi\nline")
+	logger.Close()
+
+	// Sinks that are no LengthPrefixer keep receiving lines
+	lines := 0
+	for e := range streamed {
+		lines++
+		if !strings.HasPrefix(e.Line, "[") || strings.HasSuffix(e.Line, "\n") {
+			t.Errorf("Expected the stream to receive plain lines, got %q", e.Line)
+		}
+	}
+	if lines != 2 {
+		t.Errorf("Expected 2 streamed entries, got %d", lines)
+	}
+
+	b := buf.Bytes()
+	var records []string
+	for len(b) >= 4 {
+		n := binary.BigEndian.Uint32(b)
+		records = append(records, string(b[4:4+n]))
+		b = b[4+n:]
+	}
+	if len(records) != 2 || len(b) != 0 {
+		t.Fatalf("Expected 2 length-prefixed records, got %q", buf.String())
+	}
+	if !bytes.HasSuffix([]byte(records[1]), []byte("INFO: multi\nline")) {
+		t.Errorf("Expected the record without separator, got %q", records[1])
+	}
+	if content := readFile(t, filepath.Join(tempDir, "app.log")); countLines(content) != 3 {
+		t.Errorf("Expected files to keep newline separators, got %q", content)
+	}
+}
+
+func TestRecordSeparatorTextSinks(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	stream := NewStreamSink(10)
+	notifier := &recordingNotifier{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.RecordSeparator = "\r\n"
+	config.Sinks = []Sink{stream, LevelSink(NewAlertSink(AlertOptions{Notifiers: []Notifier{notifier}}), ERROR)}
+	logger := NewChannelLoggerWithConfig(config)
+
+	entries, cancel := stream.Subscribe(DEBUG, "")
+	defer cancel()
+	logger.Error("db", "Connection refused")
+	logger.Close()
+
+	if e := <-entries; !strings.HasSuffix(e.Line, "ERROR: Connection refused") {
+		t.Errorf("Expected the streamed line without the separator, got %q", e.Line)
+	}
+	alerts := notifier.received()
+	if len(alerts) != 1 || !strings.HasSuffix(alerts[0].Samples[0].Line, "ERROR: Connection refused") {
+		t.Errorf("Expected the alert sample without the separator, got %+v", alerts)
+	}
+}
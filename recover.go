@@ -0,0 +1,140 @@
+package log4
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rotationRepair records the renames made to repair the rotated files of one
+// active file
+type rotationRepair struct {
+	file    string
+	renamed []string
+}
+
+// repairRotations renumbers rotated files left out of sequence by a process
+// that stopped in the middle of a rotation, e.g. "app.log.1", "app.log.3"
+// and "app.log.4" after "app.log.2" was shifted, or "app.log.01" next to
+// "app.log.1". Files keep their order and are renamed to "app.log.1" and up;
+// those beyond MaxFiles are removed by the next rotation. It runs before the
+// logging goroutine starts, over LogDir, its tenant directories and the
+// directories of routes, and only for NumericNamer and a LogDir written
+// outside DryRun. Processes sharing the files may be rotating them, so
+// nothing is renamed with SharedFiles.
+func (cl *ChannelLogger) repairRotations() []rotationRepair {
+	if _, numeric := cl.config.rotationNamer().(NumericNamer); !numeric || cl.config.AppendOnly || cl.config.SharedFiles {
+		return nil
+	}
+	if cl.config.LogDir == "" || cl.config.DryRun {
+		return nil
+	}
+
+	dirs := []string{cl.config.LogDir}
+	if entries, err := cl.config.fileSystem().ReadDir(cl.config.LogDir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, filepath.Join(cl.config.LogDir, e.Name()))
+			}
+		}
+	}
+	for i := range cl.config.Routes {
+		dirs = append(dirs, cl.routeDir(&cl.config.Routes[i]))
+	}
+
+	var repairs []rotationRepair
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if dir = filepath.Clean(dir); !seen[dir] {
+			seen[dir] = true
+			repairs = append(repairs, cl.repairDir(dir)...)
+		}
+	}
+	return repairs
+}
+
+// repairDir renumbers the rotated files of every active file in dir
+func (cl *ChannelLogger) repairDir(dir string) []rotationRepair {
+	fsys := cl.config.fileSystem()
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	type rotatedFile struct {
+		name string
+		n    int
+	}
+	groups := make(map[string][]rotatedFile) // Active file name -> rotated files
+	for _, e := range entries {
+		if e.IsDir() || !isRotatedLogFile(e.Name()) {
+			continue
+		}
+		idx := strings.LastIndexByte(e.Name(), '.')
+		n, _ := strconv.Atoi(e.Name()[idx+1:])
+		groups[e.Name()[:idx]] = append(groups[e.Name()[:idx]], rotatedFile{e.Name(), n})
+	}
+
+	var repairs []rotationRepair
+	for active, files := range groups {
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].n != files[j].n {
+				return files[i].n < files[j].n
+			}
+			return files[i].name < files[j].name
+		})
+
+		inSequence := true
+		for i, f := range files {
+			if f.name != fmt.Sprintf("%s.%d", active, i+1) {
+				inSequence = false
+			}
+		}
+		if inSequence {
+			continue
+		}
+
+		// Move every file out of the way first, so that no rename replaces a
+		// file that has not been moved yet
+		repair := rotationRepair{file: filepath.Join(dir, active)}
+		temp := make([]string, 0, len(files))
+		for i, f := range files {
+			name := filepath.Join(dir, fmt.Sprintf("%s.repair%d", active, i+1))
+			if err := fsys.Rename(filepath.Join(dir, f.name), name); err != nil {
+				cl.handleError(fmt.Errorf(ErrRepairRotation, filepath.Join(dir, f.name), err))
+				break
+			}
+			temp = append(temp, name)
+		}
+		if len(temp) < len(files) {
+			for i, name := range temp {
+				fsys.Rename(name, filepath.Join(dir, files[i].name))
+			}
+			continue
+		}
+		for i, f := range files {
+			name := fmt.Sprintf("%s.%d", active, i+1)
+			if err := fsys.Rename(temp[i], filepath.Join(dir, name)); err != nil {
+				cl.handleError(fmt.Errorf(ErrRepairRotation, temp[i], err))
+				continue
+			}
+			if f.name != name {
+				repair.renamed = append(repair.renamed, f.name+" -> "+name)
+			}
+		}
+		repairs = append(repairs, repair)
+	}
+	return repairs
+}
+
+// logRepairs reports the repairs of repairRotations to InternalPackage
+func (cl *ChannelLogger) logRepairs(repairs []rotationRepair) {
+	for _, r := range repairs {
+		cl.logNotice("repaired rotated files", map[string]interface{}{
+			"file":    r.file,
+			"renamed": strings.Join(r.renamed, ", "),
+		})
+	}
+}
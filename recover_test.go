@@ -0,0 +1,52 @@
+package log4
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairRotations(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// A rotation stopped after app.log.2 was shifted to app.log.3
+	for name, content := range map[string]string{
+		"app.log":   "active\n",
+		"app.log.1": "newest\n",
+		"app.log.3": "older\n",
+		"app.log.4": "oldest\n",
+		"db.log.1":  "untouched\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	NewChannelLoggerWithConfig(config).Close()
+
+	for name, want := range map[string]string{
+		"app.log":   "active",
+		"app.log.1": "newest",
+		"app.log.2": "older",
+		"app.log.3": "oldest",
+		"db.log.1":  "untouched",
+	} {
+		if content := readFile(t, filepath.Join(tempDir, name)); !strings.Contains(content, want) {
+			t.Errorf("Expected %s to hold %q, got %q", name, want, content)
+		}
+	}
+	if fileExists(filepath.Join(tempDir, "app.log.4")) {
+		t.Error("Expected app.log.4 to be renumbered")
+	}
+
+	notice := readFile(t, filepath.Join(tempDir, InternalPackage+".log"))
+	if countLines(notice) != 1 || !strings.Contains(notice, "repaired rotated files") ||
+		!strings.Contains(notice, "app.log.3 -> app.log.2, app.log.4 -> app.log.3") {
+		t.Errorf("Expected one repair notice, got %q", notice)
+	}
+}
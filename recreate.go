@@ -0,0 +1,31 @@
+package log4
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// ensureLogDir creates the directory of a log file if it does not exist.
+// LogDir, and the directories of files written before, only go missing when
+// removed while the logger runs; their recreation is reported once, as a
+// notice in InternalPackage.
+func (cl *ChannelLogger) ensureLogDir(key, fileName string) {
+	dir := filepath.Dir(fileName)
+	fsys := cl.config.fileSystem()
+	if _, err := fsys.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err := fsys.MkdirAll(dir, cl.config.DirMode); err != nil {
+		cl.handleError(fmt.Errorf(ErrCreateLogDir, dir, err))
+		return
+	}
+	if _, written := cl.fileSizes[key]; written || dir == filepath.Clean(cl.config.LogDir) {
+		// Not from the logging goroutine, which would wait for room in a full queue
+		go cl.logNotice("log directory recreated", map[string]interface{}{
+			"dir":  dir,
+			"file": fileName,
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package log4
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogDirRemoved(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	var errs []error
+	logDir := filepath.Join(tempDir, "logs")
+	config := DefaultConfig()
+	config.LogDir = logDir
+	config.DisableConsole = true
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "Before")
+	logger.Info("db", "Before")
+	logger.Flush()
+	if err := os.RemoveAll(logDir); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+
+	// Skip the wait between checks
+	logger.mu.Lock()
+	for key := range logger.files {
+		logger.checks[key] = fileCheck{at: time.Now().Add(-fileCheckInterval)}
+	}
+	logger.mu.Unlock()
+
+	logger.Info("app", "After")
+	logger.Info("db", "After")
+	time.Sleep(50 * time.Millisecond) // Let the notice be queued
+	logger.Close()
+
+	for _, pkg := range []string{"app", "db"} {
+		content := readFile(t, filepath.Join(logDir, pkg+".log"))
+		if countLines(content) != 1 || !strings.Contains(content, "After") {
+			t.Errorf("Expected %s.log to be recreated, got %q", pkg, content)
+		}
+	}
+
+	content := readFile(t, filepath.Join(logDir, InternalPackage+".log"))
+	if strings.Count(content, "log directory recreated") != 1 || !strings.Contains(content, "dir="+logDir) {
+		t.Errorf("Expected a single notice of the recreated directory, got %q", content)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestExternalRotation(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+
+	path := filepath.Join(tempDir, "app.log")
+	logger.Info("app", "Before")
+	logger.Flush()
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	logger.mu.Lock()
+	logger.checks["app"] = fileCheck{at: time.Now().Add(-fileCheckInterval)}
+	logger.mu.Unlock()
+
+	logger.Info("app", "After")
+	logger.Close()
+
+	if content := readFile(t, path); countLines(content) != 1 || !strings.Contains(content, "After") {
+		t.Errorf("Expected the file to be reopened, got %q", content)
+	}
+	if content := readFile(t, path+".old"); countLines(content) != 1 || !strings.Contains(content, "Before") {
+		t.Errorf("Expected the moved file to keep earlier entries only, got %q", content)
+	}
+}
+
+func TestExternalTruncation(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	logger := NewChannelLoggerWithConfig(config)
+
+	path := filepath.Join(tempDir, "app.log")
+	logger.Info("app", "Before")
+	logger.Info("app", "Before")
+	logger.Flush()
+
+	// As left by the last check; logrotate's copytruncate then empties the
+	// file in place
+	logger.mu.Lock()
+	logger.checks["app"] = fileCheck{at: time.Now().Add(-fileCheckInterval), size: int64(len(readFile(t, path)))}
+	logger.mu.Unlock()
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	logger.Info("app", "After")
+	logger.Flush()
+
+	content := readFile(t, path)
+	if countLines(content) != 1 || !strings.Contains(content, "After") {
+		t.Errorf("Expected writing to continue at the start of the file, got %q", content)
+	}
+	logger.mu.Lock()
+	size := logger.fileSizes["app"]
+	logger.mu.Unlock()
+	if size != int64(len(content)) {
+		t.Errorf("Expected the size used for rotation to restart at %d, got %d", len(content), size)
+	}
+	logger.Close()
+}
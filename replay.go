@@ -0,0 +1,193 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ReplayOptions configures Replay
+type ReplayOptions struct {
+	TimestampFormat string    // Layout of text timestamps (default: DefaultConfig's)
+	Formatter       Formatter // Builds the line passed to the sink (default: text layout)
+	Package         string    // Package of entries whose line names none (default: "default")
+
+	// Codec decodes compressed input. gzip is detected without it.
+	Codec Codec
+
+	// StartAt rewrites timestamps so that the first entry is at StartAt while
+	// the spacing between entries is kept. Zero keeps the original times.
+	StartAt time.Time
+
+	// Transform is called for every entry before it is sent and may modify
+	// it. Entries for which it returns false are skipped.
+	Transform func(entry *LogEntry) bool
+}
+
+// Replay parses log lines previously written by this package, in the text or
+// JSON layout and optionally compressed, and sends them to sink. This can
+// backfill a new aggregation system or reproduce an incident in staging; use
+// LoggerSink to replay into a ChannelLogger. Lines that cannot be parsed, such
+// as stack traces, are appended to the message of the preceding entry.
+//
+// Replay returns how many entries the sink accepted and stops at the first
+// entry it rejects. Network sinks deliver asynchronously, so close them to
+// wait for the replayed entries to be sent.
+func Replay(r io.Reader, sink Sink, opts *ReplayOptions) (int, error) {
+	var o ReplayOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.TimestampFormat == "" {
+		o.TimestampFormat = DefaultConfig().TimestampFormat
+	}
+	if o.Formatter == nil {
+		o.Formatter = &TextFormatter{TimestampFormat: o.TimestampFormat}
+	}
+	if o.Package == "" {
+		o.Package = "default"
+	}
+
+	br := bufio.NewReader(r)
+	if o.Codec == nil {
+		if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+			o.Codec = GzipCodec{}
+		}
+	}
+	var in io.Reader = br
+	if o.Codec != nil {
+		cr, err := o.Codec.NewReader(br)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode %s input: %w", o.Codec.Name(), err)
+		}
+		defer cr.Close()
+		in = cr
+	}
+
+	var (
+		line    []byte
+		pending *LogEntry
+		shift   time.Duration
+		shifted bool
+		n       int
+	)
+	deliver := func() error {
+		if pending == nil {
+			return nil
+		}
+		entry := pending
+		pending = nil
+		if entry.Package == "" {
+			entry.Package = o.Package
+		}
+		if !o.StartAt.IsZero() {
+			if !shifted {
+				shift, shifted = o.StartAt.Sub(entry.Timestamp), true
+			}
+			entry.Timestamp = entry.Timestamp.Add(shift)
+		}
+		if o.Transform != nil && !o.Transform(entry) {
+			return nil
+		}
+
+		line = append(o.Formatter.Format(line[:0], entry), '\n')
+		if err := sink.Write(entry, line); err != nil {
+			return err
+		}
+		n++
+		return nil
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 || IsHeaderLine(text) {
+			continue
+		}
+		entry, err := ParseLine(text, o.TimestampFormat)
+		if err != nil {
+			if pending != nil {
+				pending.Message += "\n" + text
+			}
+			continue
+		}
+		if err := deliver(); err != nil {
+			return n, err
+		}
+		pending = entry
+	}
+	// An incomplete trailing frame of a compressed file is not an error
+	if err := scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return n, err
+	}
+	if err := deliver(); err != nil {
+		return n, err
+	}
+
+	if f, ok := sink.(Flusher); ok {
+		if err := f.Flush(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ReplayFile replays the log file at path. The codec is chosen from the file
+// extension and the package of entries defaults to the one in the file name.
+func ReplayFile(path string, sink Sink, opts *ReplayOptions) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var o ReplayOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Codec == nil {
+		o.Codec = CodecForFile(path)
+	}
+	if o.Package == "" {
+		o.Package = PackageFromFileName(path)
+	}
+	return Replay(f, sink, &o)
+}
+
+// loggerSink sends entries through a ChannelLogger
+type loggerSink struct {
+	logger *ChannelLogger
+}
+
+// LoggerSink returns a Sink that logs every entry it receives through logger,
+// keeping its package, level, timestamp and fields. The logger's levels,
+// outputs and formatter apply as for any other entry. Closing the sink does
+// not close the logger.
+func LoggerSink(logger *ChannelLogger) Sink {
+	return loggerSink{logger: logger}
+}
+
+// Write logs a copy of entry
+func (s loggerSink) Write(entry *LogEntry, line []byte) error {
+	if s.logger.closed.Load() {
+		return fmt.Errorf(ErrLoggerClosed)
+	}
+	e := getLogEntry()
+	e.Package = entry.Package
+	e.Level = entry.Level
+	e.Message = entry.Message
+	e.Context = entry.Context
+	e.Timestamp = entry.Timestamp
+	for k, v := range entry.Fields {
+		e.Fields[k] = v
+	}
+	s.logger.logEntry(e)
+	return nil
+}
+
+// Close does nothing; the logger is closed by its owner
+func (s loggerSink) Close() error { return nil }
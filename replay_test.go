@@ -0,0 +1,76 @@
+package log4
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	input := strings.Join([]string{
+		`[2024-05-01 12:00:00.000] INFO: Server started | port=8080`,
+		`[2024-05-01 12:00:05.000] ERROR: Request failed`,
+		`goroutine 1 [running]:`,
+		`{"time":"2024-05-01T12:00:10Z","level":"DEBUG","msg":"Cache miss","package":"cache"}`,
+		``,
+	}, "\n")
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(input))
+	zw.Close()
+
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.Local)
+	sink := &retainingSink{}
+	n, err := Replay(&gz, sink, &ReplayOptions{
+		Package: "api",
+		StartAt: start,
+		Transform: func(entry *LogEntry) bool {
+			return entry.Level != DEBUG
+		},
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("Replay returned %d, %v", n, err)
+	}
+
+	first, second := sink.entries[0], sink.entries[1]
+	if first.Package != "api" || first.Fields["port"] != "8080" || !first.Timestamp.Equal(start) {
+		t.Errorf("Unexpected first entry %+v", first)
+	}
+	if !second.Timestamp.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("Expected spacing to be kept, got %v", second.Timestamp)
+	}
+	if second.Message != "Request failed\ngoroutine 1 [running]:" {
+		t.Errorf("Expected continuation line to be appended, got %q", second.Message)
+	}
+}
+
+func TestReplayIntoLogger(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	input := `{"time":"2024-05-01T12:00:00Z","level":"ERROR","msg":"Payment failed","package":"orders","order":"A-1"}` + "\n"
+	n, err := Replay(strings.NewReader(input), LoggerSink(logger), nil)
+	logger.Close()
+	if err != nil || n != 1 {
+		t.Fatalf("Replay returned %d, %v", n, err)
+	}
+
+	content := readFile(t, filepath.Join(tempDir, "orders.log"))
+	if !strings.Contains(content, "ERROR: Payment failed | order=A-1") || !strings.Contains(content, "2024-05-01") {
+		t.Errorf("Unexpected replayed log:\n%s", content)
+	}
+
+	if _, err := Replay(strings.NewReader(input), LoggerSink(logger), nil); err == nil {
+		t.Error("Expected replay into a closed logger to fail")
+	}
+}
@@ -0,0 +1,146 @@
+package log4
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// FieldRequestID is the field holding the correlation ID carried by the
+// context of an entry (see ContextWithRequestID)
+const FieldRequestID = "request_id"
+
+// IDGenerator produces correlation IDs (see Config.IDGenerator)
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID calls f
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDv7Generator generates RFC 9562 version 7 UUIDs, which start with the
+// time in milliseconds and sort by creation time:
+//
+//	018f3a1c-5e2b-7c4d-9a1b-3c5d7e9f1a2b
+type UUIDv7Generator struct{}
+
+// NewID returns a new UUIDv7
+func (UUIDv7Generator) NewID() string {
+	var b [16]byte
+	putIDTime(b[:6], time.Now())
+	rand.Read(b[6:])
+	b[6] = b[6]&0x0f | 0x70 // Version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+// ULIDGenerator generates ULIDs, 26 characters of Crockford's base32 that
+// start with the time in milliseconds and sort by creation time:
+//
+//	01HXK3Q8V5Z9C2M4N6P8R0T2W4
+type ULIDGenerator struct{}
+
+// crockford is the alphabet of ULIDs, without I, L, O and U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewID returns a new ULID
+func (ULIDGenerator) NewID() string {
+	var b [16]byte
+	putIDTime(b[:6], time.Now())
+	rand.Read(b[6:])
+
+	// 26 characters of 5 bits hold 130 bits, the first 2 of which are zero
+	var out [26]byte
+	for i := range out {
+		var v byte
+		for bit := i*5 - 2; bit < i*5+3; bit++ {
+			v <<= 1
+			if bit >= 0 && b[bit/8]&(0x80>>(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out[:])
+}
+
+// putIDTime writes the Unix time of t in milliseconds to the 6 bytes of dst,
+// big-endian
+func putIDTime(dst []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(dst, ms[2:])
+}
+
+// DefaultIDGenerator is used by NewRequestID, and by loggers whose
+// Config.IDGenerator is nil
+var DefaultIDGenerator IDGenerator = UUIDv7Generator{}
+
+// NewRequestID returns a new correlation ID from DefaultIDGenerator
+func NewRequestID() string {
+	return DefaultIDGenerator.NewID()
+}
+
+// requestIDKey is the context key of the correlation ID
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying a correlation ID.
+// Entries logged with the context, or one derived from it, get the ID as
+// FieldRequestID unless they set that field themselves.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the correlation ID carried by ctx, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// EnsureRequestID returns ctx and its correlation ID, first attaching a new
+// one from Config.IDGenerator if ctx carries none, e.g. at the start of a
+// request whose caller did not send an ID
+func (cl *ChannelLogger) EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return ctx, id
+	}
+	id := cl.newID()
+	return ContextWithRequestID(ctx, id), id
+}
+
+// newID returns a new ID from Config.IDGenerator
+func (cl *ChannelLogger) newID() string {
+	if cl.config.IDGenerator != nil {
+		return cl.config.IDGenerator.NewID()
+	}
+	return DefaultIDGenerator.NewID()
+}
+
+// addRequestID copies the correlation ID of the entry's context into its
+// fields without replacing a FieldRequestID that is already set
+func addRequestID(entry *LogEntry) {
+	if _, exists := entry.Fields[FieldRequestID]; exists {
+		return
+	}
+	if id, ok := RequestIDFromContext(entry.Context); ok {
+		entry.Fields[FieldRequestID] = id
+	}
+}
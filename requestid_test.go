@@ -0,0 +1,98 @@
+package log4
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDGenerators(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulid := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	tests := []struct {
+		name    string
+		gen     IDGenerator
+		pattern *regexp.Regexp
+	}{
+		{"UUIDv7", UUIDv7Generator{}, uuid},
+		{"ULID", ULIDGenerator{}, ulid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.gen.NewID()
+			if !tt.pattern.MatchString(first) {
+				t.Fatalf("Malformed ID %q", first)
+			}
+			if again := tt.gen.NewID(); again == first {
+				t.Errorf("Expected distinct IDs, got %q twice", first)
+			}
+
+			// IDs of later milliseconds sort after earlier ones
+			time.Sleep(2 * time.Millisecond)
+			if later := tt.gen.NewID(); later <= first {
+				t.Errorf("Expected %q to sort after %q", later, first)
+			}
+		})
+	}
+
+	// The leading characters hold the time
+	before := time.Now().UnixMilli()
+	id := strings.ReplaceAll(UUIDv7Generator{}.NewID(), "-", "")
+	var ms int64
+	for _, c := range id[:12] {
+		ms = ms<<4 | int64(strings.IndexRune("0123456789abcdef", c))
+	}
+	if ms < before || ms > time.Now().UnixMilli() {
+		t.Errorf("Expected the UUIDv7 time around %d, got %d", before, ms)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.IDGenerator = IDGeneratorFunc(func() string { return "generated" })
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	ctx, id := logger.EnsureRequestID(context.Background())
+	if id != "generated" {
+		t.Fatalf("Expected the ID of Config.IDGenerator, got %q", id)
+	}
+	if again, same := logger.EnsureRequestID(ctx); again != ctx || same != id {
+		t.Errorf("Expected the ID of the context to be kept, got %q", same)
+	}
+
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger.LogWithContext(child, "api", "INFO", "Derived")
+	logger.Package("api").log(ctx, INFO, "Explicit", map[string]interface{}{FieldRequestID: "caller"})
+	logger.MustLog(ContextWithRequestID(context.Background(), "must"), "api", INFO, "Must", nil)
+	logger.LogWithContext(context.Background(), "api", "INFO", "Without")
+	logger.Close()
+
+	want := []interface{}{"generated", "caller", "must", nil}
+	if len(sink.entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(sink.entries))
+	}
+	for i, w := range want {
+		if got := sink.entries[i].Fields[FieldRequestID]; got != w {
+			t.Errorf("Entry %q: expected request ID %v, got %v", sink.entries[i].Message, w, got)
+		}
+	}
+
+	if _, ok := RequestIDFromContext(ContextWithRequestID(context.Background(), "")); ok {
+		t.Error("An empty ID should not count")
+	}
+	if id := NewRequestID(); len(id) != 36 {
+		t.Errorf("Expected a UUID from NewRequestID, got %q", id)
+	}
+}
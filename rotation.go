@@ -0,0 +1,231 @@
+package log4
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RotationNamer decides the names of rotated files (see Config.RotationNamer).
+// Paths passed to it are those of active files, such as "logs/app.log" or
+// "logs/app.log.gz" with compression.
+type RotationNamer interface {
+	// Rotate moves the active file at path aside, rotated at t, and returns
+	// the name it was moved to
+	Rotate(path string, t time.Time) (string, error)
+	// Rotated returns the existing rotated files of the active file at path,
+	// newest first; the logger removes those beyond the retained number
+	Rotated(path string) ([]string, error)
+}
+
+// NumericNamer is the default RotationNamer: the active file is renamed to
+// "app.log.1", shifting older files to "app.log.2" and so on
+type NumericNamer struct{}
+
+// Rotate shifts the rotated files up by one and renames path to path.1
+func (n NumericNamer) Rotate(path string, t time.Time) (string, error) {
+	return n.rotateIn(OSFS{}, path, t)
+}
+
+// Rotated returns path.1, path.2, ... as far as they exist, gaps included
+func (n NumericNamer) Rotated(path string) ([]string, error) {
+	return n.rotatedIn(OSFS{}, path)
+}
+
+func (NumericNamer) rotateIn(fsys FS, path string, t time.Time) (string, error) {
+	rotated, err := NumericNamer{}.rotatedIn(fsys, path)
+	if err != nil {
+		return "", err
+	}
+	// Oldest first, so that no file is overwritten; gaps are kept
+	for i := len(rotated) - 1; i >= 0; i-- {
+		n, _ := rotationSuffix(path, rotated[i])
+		if err := fsys.Rename(rotated[i], fmt.Sprintf("%s.%d", path, n+1)); err != nil {
+			return "", err
+		}
+	}
+	newName := path + ".1"
+	return newName, fsys.Rename(path, newName)
+}
+
+func (NumericNamer) rotatedIn(fsys FS, path string) ([]string, error) {
+	names, err := siblings(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	var rotated []string
+	for _, name := range names {
+		if _, ok := rotationSuffix(path, name); ok {
+			rotated = append(rotated, name)
+		}
+	}
+	sort.Slice(rotated, func(i, j int) bool {
+		a, _ := rotationSuffix(path, rotated[i])
+		b, _ := rotationSuffix(path, rotated[j])
+		return a < b
+	})
+	return rotated, nil
+}
+
+// rotationSuffix returns N if name is path.N
+func rotationSuffix(path, name string) (int, bool) {
+	suffix, ok := strings.CutPrefix(name, filepath.Clean(path)+".")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(suffix)
+	return n, err == nil && n > 0
+}
+
+// DefaultRotationLayout is the timestamp layout of TimestampNamer
+const DefaultRotationLayout = "20060102T150405"
+
+// TimestampNamer renames rotated files after the time of their rotation,
+// e.g. "app-20240501T120000.log", so that names never change once written,
+// as logrotate's dateext does. A file rotated twice within the resolution of
+// the layout gets a counter, as in "app-20240501T120000-2.log".
+type TimestampNamer struct {
+	Layout string // Layout of the timestamp (default: DefaultRotationLayout)
+}
+
+func (n TimestampNamer) layout() string {
+	if n.Layout == "" {
+		return DefaultRotationLayout
+	}
+	return n.Layout
+}
+
+// splitLogName splits the name of an active file around its ".log"
+// extension, e.g. "logs/app.log.gz" into "logs/app" and ".log.gz"
+func splitLogName(path string) (string, string) {
+	dir, name := filepath.Split(path)
+	if idx := strings.Index(name, ".log"); idx > 0 {
+		return dir + name[:idx], name[idx:]
+	}
+	return path, ""
+}
+
+// Rotate renames path to a name carrying t
+func (n TimestampNamer) Rotate(path string, t time.Time) (string, error) {
+	return n.rotateIn(OSFS{}, path, t)
+}
+
+// Rotated returns the files named after path and a timestamp in the layout,
+// newest first
+func (n TimestampNamer) Rotated(path string) ([]string, error) {
+	return n.rotatedIn(OSFS{}, path)
+}
+
+func (n TimestampNamer) rotateIn(fsys FS, path string, t time.Time) (string, error) {
+	base, ext := splitLogName(path)
+	stamp := base + "-" + t.Format(n.layout())
+	newName := stamp + ext
+	for i := 2; ; i++ {
+		if _, err := fsys.Lstat(newName); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		newName = fmt.Sprintf("%s-%d%s", stamp, i, ext)
+	}
+	return newName, fsys.Rename(path, newName)
+}
+
+func (n TimestampNamer) rotatedIn(fsys FS, path string) ([]string, error) {
+	names, err := siblings(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	base, ext := splitLogName(filepath.Clean(path))
+
+	type rotatedFile struct {
+		name  string
+		t     time.Time
+		count int
+	}
+	var files []rotatedFile
+	for _, name := range names {
+		stamp, ok := strings.CutPrefix(name, base+"-")
+		if !ok {
+			continue
+		}
+		if stamp, ok = strings.CutSuffix(stamp, ext); !ok {
+			continue
+		}
+		count := 1
+		t, err := time.ParseInLocation(n.layout(), stamp, time.Local)
+		if idx := strings.LastIndexByte(stamp, '-'); err != nil && idx > 0 {
+			if c, cerr := strconv.Atoi(stamp[idx+1:]); cerr == nil {
+				t, err = time.ParseInLocation(n.layout(), stamp[:idx], time.Local)
+				count = c
+			}
+		}
+		if err == nil {
+			files = append(files, rotatedFile{name, t, count})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].t.Equal(files[j].t) {
+			return files[i].t.After(files[j].t)
+		}
+		return files[i].count > files[j].count
+	})
+
+	rotated := make([]string, len(files))
+	for i, f := range files {
+		rotated[i] = f.name
+	}
+	return rotated, nil
+}
+
+// siblings returns the paths of the files in the directory of path
+func siblings(fsys FS, path string) ([]string, error) {
+	dir := filepath.Dir(path)
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, filepath.Join(dir, e.Name()))
+		}
+	}
+	return names, nil
+}
+
+// rotationNamer returns the configured RotationNamer
+func (c *Config) rotationNamer() RotationNamer {
+	if c.RotationNamer == nil {
+		return NumericNamer{}
+	}
+	return c.RotationNamer
+}
+
+// fsNamer is implemented by the built-in RotationNamers, which rotate the
+// files of Config.FS rather than of the operating system
+type fsNamer interface {
+	rotateIn(fsys FS, path string, t time.Time) (string, error)
+	rotatedIn(fsys FS, path string) ([]string, error)
+}
+
+// rotate moves the active file at path aside with the configured
+// RotationNamer
+func (c *Config) rotate(path string, t time.Time) (string, error) {
+	if n, ok := c.rotationNamer().(fsNamer); ok {
+		return n.rotateIn(c.fileSystem(), path, t)
+	}
+	return c.rotationNamer().Rotate(path, t)
+}
+
+// rotated returns the rotated files of the active file at path, newest
+// first, as named by the configured RotationNamer
+func (c *Config) rotated(path string) ([]string, error) {
+	if n, ok := c.rotationNamer().(fsNamer); ok {
+		return n.rotatedIn(c.fileSystem(), path)
+	}
+	return c.rotationNamer().Rotated(path)
+}
@@ -0,0 +1,140 @@
+package log4
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNumericNamerRetention(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MaxFiles = 2
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	for _, msg := range []string{"first", "second", "third", "fourth"} {
+		logger.Info("app", msg)
+		logger.Flush()
+		if err := logger.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	logger.Close()
+
+	if !strings.Contains(readFile(t, filepath.Join(tempDir, "app.log.1")), "fourth") ||
+		!strings.Contains(readFile(t, filepath.Join(tempDir, "app.log.2")), "third") {
+		t.Error("Expected the newest files to be kept in order")
+	}
+	if fileExists(filepath.Join(tempDir, "app.log.3")) {
+		t.Error("Expected files beyond MaxFiles to be removed")
+	}
+}
+
+func TestTimestampNamer(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MaxFiles = 2
+	config.RotationNamer = TimestampNamer{}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	for _, msg := range []string{"first", "second", "third"} {
+		logger.Info("app", msg)
+		logger.Flush()
+		if err := logger.Rotate(); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+	}
+	logger.Close()
+
+	rotated, err := TimestampNamer{}.Rotated(filepath.Join(tempDir, "app.log"))
+	if err != nil {
+		t.Fatalf("Rotated failed: %v", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("Expected 2 rotated files, got %v", rotated)
+	}
+	// Rotations within the same second are told apart by a counter
+	stamp := time.Now().Format(DefaultRotationLayout)[:8]
+	if name := filepath.Base(rotated[0]); !strings.HasPrefix(name, "app-"+stamp) || !strings.HasSuffix(name, ".log") {
+		t.Errorf("Unexpected rotated name %q", name)
+	}
+	if !strings.Contains(readFile(t, rotated[0]), "third") || !strings.Contains(readFile(t, rotated[1]), "second") {
+		t.Errorf("Expected the newest files first, got %v", rotated)
+	}
+}
+
+func TestTimestampNamerRotated(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	for _, name := range []string{
+		"app-20240501T120000.log.gz",
+		"app-20240502T120000.log.gz",
+		"app-20240502T120000-2.log.gz",
+		"app-latest.log.gz",
+		"app-extra.log.gz.1",
+		"app.log.gz",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rotated, err := TimestampNamer{}.Rotated(filepath.Join(tempDir, "app.log.gz"))
+	if err != nil {
+		t.Fatalf("Rotated failed: %v", err)
+	}
+	var names []string
+	for _, r := range rotated {
+		names = append(names, filepath.Base(r))
+	}
+	want := "app-20240502T120000-2.log.gz app-20240502T120000.log.gz app-20240501T120000.log.gz"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Rotated() = %s, want %s", got, want)
+	}
+}
+
+func TestRotateOnStart(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	for _, run := range []string{"run 1", "run 2", "run 3", "run 4"} {
+		config := DefaultConfig()
+		config.LogDir = tempDir
+		config.MaxFiles = 2
+		config.RotateOnStart = true
+		logger := NewChannelLoggerWithConfig(config)
+		logger.stdout = io.Discard
+		logger.Info("app", run)
+		logger.Info("app", run+" again")
+		logger.Close()
+	}
+
+	for name, want := range map[string]string{"app.log": "run 4", "app.log.1": "run 3", "app.log.2": "run 2"} {
+		content := readFile(t, filepath.Join(tempDir, name))
+		if countLines(content) != 2 || !strings.Contains(content, want) {
+			t.Errorf("Expected %s to hold %s only, got %q", name, want, content)
+		}
+	}
+	if fileExists(filepath.Join(tempDir, "app.log.3")) {
+		t.Error("Expected the previous runs to count towards MaxFiles")
+	}
+
+	config := DefaultConfig()
+	config.SharedFiles = true
+	config.RotateOnStart = true
+	if err := config.Validate(); err == nil {
+		t.Error("Expected RotateOnStart to be rejected with SharedFiles")
+	}
+}
@@ -0,0 +1,56 @@
+package log4
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Route sends the files of the packages matching a pattern to another
+// directory, e.g. to put access logs on a mount with a different retention
+type Route struct {
+	// Pattern selects packages with path.Match syntax, e.g. "access*".
+	// Characters a file name cannot contain are replaced as in the file name,
+	// so "app.db.*" matches the files of "app.db.sql" and "app.db.cache".
+	Pattern string
+	// Dir holds the files of matching packages; relative paths are joined
+	// with LogDir
+	Dir string
+	// MaxFiles is the number of rotated files kept (default: Config.MaxFiles)
+	MaxFiles int
+}
+
+// routeFor returns the first route matching a file key, or nil if there is
+// none. Tenant files always stay in their tenant's directory.
+func (cl *ChannelLogger) routeFor(key string) *Route {
+	if strings.Contains(key, "/") {
+		return nil
+	}
+	for i := range cl.config.Routes {
+		r := &cl.config.Routes[i]
+		if ok, _ := path.Match(routePattern(r.Pattern), key); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// routeDir returns the directory of a route
+func (cl *ChannelLogger) routeDir(r *Route) string {
+	if filepath.IsAbs(r.Dir) {
+		return r.Dir
+	}
+	return filepath.Join(cl.config.LogDir, r.Dir)
+}
+
+// routePattern replaces the characters sanitizePackageName would replace,
+// keeping the glob syntax of path.Match
+func routePattern(pattern string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`*?[]^!-\`, r) || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, pattern)
+}
@@ -0,0 +1,79 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+	auditDir := createTempDir(t)
+	defer cleanupTempDir(t, auditDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Routes = []Route{
+		{Pattern: "access*", Dir: "access", MaxFiles: 2},
+		{Pattern: "audit", Dir: auditDir},
+		{Pattern: "app.db.*", Dir: "db"},
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Info("access", "GET /")
+	logger.Info("access-admin", "GET /admin")
+	logger.Info("audit", "User created")
+	logger.Info("app.db.sql", "Connected")
+	logger.Info("auditor", "Not routed")
+	logger.Tenant("acme").Package("access").Info("Tenant request")
+	logger.Close()
+
+	for _, path := range []string{
+		filepath.Join(tempDir, "access", "access.log"),
+		filepath.Join(tempDir, "access", "access-admin.log"),
+		filepath.Join(auditDir, "audit.log"),
+		filepath.Join(tempDir, "db", "app_db_sql.log"),
+		filepath.Join(tempDir, "auditor.log"),
+		filepath.Join(tempDir, "acme", "access.log"),
+	} {
+		if !fileExists(path) {
+			t.Errorf("Expected %s to be written", path)
+		}
+	}
+	if fileExists(filepath.Join(tempDir, "access.log")) || fileExists(filepath.Join(tempDir, "audit.log")) {
+		t.Error("Routed packages should not be written to LogDir")
+	}
+}
+
+func TestRouteRotation(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MaxFileSize = 1
+	config.Routes = []Route{{Pattern: "access", Dir: "access", MaxFiles: 2}}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	for i := 0; i < 5; i++ {
+		logger.Info("access", "GET /")
+	}
+	logger.Close()
+
+	dir := filepath.Join(tempDir, "access")
+	if !fileExists(filepath.Join(dir, "access.log.2")) || fileExists(filepath.Join(dir, "access.log.3")) {
+		t.Error("Expected the route's MaxFiles to limit rotated files")
+	}
+}
+
+func TestInvalidRoute(t *testing.T) {
+	config := DefaultConfig()
+	config.Routes = []Route{{Pattern: "access[", Dir: "access"}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "invalid route pattern") {
+		t.Errorf("Expected an invalid route error, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package log4
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RuntimePackage is the package of the entries logged by ReportRuntime
+const RuntimePackage = "_runtime"
+
+// ReportRuntime logs process metrics to RuntimePackage every interval, as an
+// INFO entry with the fields:
+//
+//	goroutines         number of goroutines
+//	heap_alloc_bytes   bytes of allocated heap objects
+//	heap_sys_bytes     heap memory obtained from the OS
+//	heap_objects       number of allocated heap objects
+//	gc_cycles          GC cycles since the previous report
+//	gc_pause_total_ms  stop-the-world pause time of those cycles
+//	gc_pause_max_ms    longest of those pauses
+//	open_fds           open file descriptors (Linux only)
+//
+// Reporting ends when the logger is closed or the returned function is
+// called. Config.RuntimeMetrics starts a reporter with the logger.
+func (cl *ChannelLogger) ReportRuntime(interval time.Duration) (stop func()) {
+	quit := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastGC uint32
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		lastGC = ms.NumGC
+
+		for {
+			select {
+			case <-ticker.C:
+				runtime.ReadMemStats(&ms)
+				cl.LogWithFields(RuntimePackage, INFO, "runtime metrics", runtimeFields(&ms, lastGC))
+				lastGC = ms.NumGC
+			case <-quit:
+				return
+			case <-cl.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit) })
+	}
+}
+
+// runtimeFields builds the fields of a runtime report; lastGC is the GC count
+// of the previous report
+func runtimeFields(ms *runtime.MemStats, lastGC uint32) map[string]interface{} {
+	cycles := ms.NumGC - lastGC
+	// PauseNs is a ring of the most recent 256 pauses
+	counted := min(cycles, uint32(len(ms.PauseNs)))
+	var total, longest uint64
+	for i := uint32(0); i < counted; i++ {
+		pause := ms.PauseNs[(ms.NumGC-i+255)%256]
+		total += pause
+		longest = max(longest, pause)
+	}
+
+	fields := map[string]interface{}{
+		"goroutines":        runtime.NumGoroutine(),
+		"heap_alloc_bytes":  ms.HeapAlloc,
+		"heap_sys_bytes":    ms.HeapSys,
+		"heap_objects":      ms.HeapObjects,
+		"gc_cycles":         int(cycles),
+		"gc_pause_total_ms": float64(total) / 1e6,
+		"gc_pause_max_ms":   float64(longest) / 1e6,
+	}
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		fields["open_fds"] = len(fds)
+	}
+	return fields
+}
@@ -0,0 +1,48 @@
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportRuntime(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.ReportRuntime(10 * time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, RuntimePackage+".log"))
+	if countLines(content) == 0 || !strings.Contains(content, "INFO: runtime metrics") ||
+		!strings.Contains(content, "goroutines=") || !strings.Contains(content, "heap_alloc_bytes=") {
+		t.Errorf("Unexpected runtime report:\n%s", content)
+	}
+}
+
+func TestRuntimeFields(t *testing.T) {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+
+	fields := runtimeFields(&ms, ms.NumGC-2)
+	if fields["gc_cycles"] != 2 {
+		t.Errorf("Expected 2 GC cycles, got %v", fields["gc_cycles"])
+	}
+	if fields["gc_pause_max_ms"].(float64) > fields["gc_pause_total_ms"].(float64) {
+		t.Errorf("Longest pause exceeds the total: %v", fields)
+	}
+	if runtime.GOOS == "linux" && fields["open_fds"].(int) == 0 {
+		t.Error("Expected open file descriptors on Linux")
+	}
+}
@@ -0,0 +1,187 @@
+package log4
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Defaults used by ProductionConfig
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+	DefaultSamplingTick       = time.Second
+)
+
+// Fields written with Sampling.Mark
+const (
+	FieldSampled        = "sampled"         // true on entries standing for dropped ones, and on markers
+	FieldSampleRate     = "sample_rate"     // Number of entries a sampled entry stands for
+	FieldSampledMessage = "sampled_message" // Message a marker reports on
+	FieldSampledTotal   = "sampled_total"   // Entries of the message since the last marker
+	FieldSampledDropped = "sampled_dropped" // Of which were dropped
+)
+
+// Sampling caps the volume of repeated entries. Within each Tick, the first
+// Initial entries with the same package, level and message are written and
+// after that only every Thereafter-th one; the others are dropped with
+// DropSampled. Thereafter 0 drops every entry after the first Initial.
+//
+// With Mark, downstream analytics can correct their counts instead of
+// undercounting: every entry kept after the first Initial gets FieldSampled
+// and FieldSampleRate set to Thereafter, and every Tick a marker entry is
+// logged for each message that lost entries, with the level and package of
+// the message:
+//
+//	INFO: sampling dropped 891 of 1000 entries | sampled=true, sampled_dropped=891, sampled_message=cache miss, sampled_total=1000
+type Sampling struct {
+	Initial    int
+	Thereafter int
+	Tick       time.Duration // default: DefaultSamplingTick
+	Mark       bool
+}
+
+// sampler counts entries per package, level and message in the current tick
+type sampler struct {
+	cfg Sampling
+
+	mu      sync.Mutex
+	tickEnd time.Time
+	counts  map[string]int
+	marks   map[sampleKey]*sampleMark // Since the last marker, with Mark
+}
+
+// sampleKey identifies the entries a marker reports on
+type sampleKey struct {
+	tenant, pkg string
+	level       LogLevel
+	message     string
+}
+
+// sampleMark counts the entries of a message since the last marker
+type sampleMark struct {
+	total, dropped int
+}
+
+func newSampler(cfg Sampling) *sampler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = DefaultSamplingTick
+	}
+	return &sampler{cfg: cfg, counts: make(map[string]int)}
+}
+
+// allow reports whether entry is to be written
+func (s *sampler) allow(entry *LogEntry) bool {
+	key := entry.Package + "\x00" + entry.Level.String() + "\x00" + entry.Message
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := time.Now(); now.After(s.tickEnd) {
+		clear(s.counts)
+		s.tickEnd = now.Add(s.cfg.Tick)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+	keep := n <= s.cfg.Initial || s.cfg.Thereafter > 0 && (n-s.cfg.Initial)%s.cfg.Thereafter == 0
+	if s.cfg.Mark {
+		s.mark(entry, keep && n > s.cfg.Initial, keep)
+	}
+	return keep
+}
+
+// mark counts entry for the next marker and tags it if it stands for
+// dropped entries; s.mu must be held
+func (s *sampler) mark(entry *LogEntry, sampled, keep bool) {
+	if sampled && s.cfg.Thereafter > 1 {
+		entry.Fields[FieldSampled] = true
+		entry.Fields[FieldSampleRate] = s.cfg.Thereafter
+	}
+
+	key := sampleKey{entry.Tenant, entry.Package, entry.Level, entry.Message}
+	if s.marks == nil {
+		s.marks = make(map[sampleKey]*sampleMark)
+	}
+	m := s.marks[key]
+	if m == nil {
+		m = &sampleMark{}
+		s.marks[key] = m
+	}
+	m.total++
+	if !keep {
+		m.dropped++
+	}
+}
+
+// markedSamplers returns the samplers of Config.Sampling and Config.Throttle
+// that set Mark
+func (cl *ChannelLogger) markedSamplers() []*sampler {
+	var samplers []*sampler
+	if cl.sampler != nil && cl.sampler.cfg.Mark {
+		samplers = append(samplers, cl.sampler)
+	}
+	if cl.throttle != nil && cl.throttle.sampler != nil && cl.throttle.sampler.cfg.Mark {
+		samplers = append(samplers, cl.throttle.sampler)
+	}
+	return samplers
+}
+
+// reportSampled starts the goroutine logging the markers of s every tick
+// until the logger is closed
+func (cl *ChannelLogger) reportSampled(s *sampler) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.Tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cl.flushSampled(s)
+			case <-cl.done:
+				return
+			}
+		}
+	}()
+}
+
+// flushSampled logs a marker for every message of s that lost entries since
+// the last one, bypassing levels, sampling and throttling
+func (cl *ChannelLogger) flushSampled(s *sampler) {
+	s.mu.Lock()
+	marks := s.marks
+	s.marks = nil
+	s.mu.Unlock()
+
+	keys := make([]sampleKey, 0, len(marks))
+	for key, m := range marks {
+		if m.dropped > 0 {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b sampleKey) int {
+		return cmp.Or(cmp.Compare(a.tenant, b.tenant), cmp.Compare(a.pkg, b.pkg),
+			cmp.Compare(a.level, b.level), cmp.Compare(a.message, b.message))
+	})
+
+	cl.sendMu.RLock()
+	defer cl.sendMu.RUnlock()
+	for _, key := range keys {
+		m := marks[key]
+		entry := getLogEntry()
+		entry.Tenant = key.tenant
+		entry.Package = key.pkg
+		entry.Level = key.level
+		entry.Message = fmt.Sprintf("sampling dropped %d of %d entries", m.dropped, m.total)
+		entry.Timestamp = time.Now()
+		entry.Fields[FieldSampled] = true
+		entry.Fields[FieldSampledMessage] = key.message
+		entry.Fields[FieldSampledTotal] = m.total
+		entry.Fields[FieldSampledDropped] = m.dropped
+		if cl.closed.Load() {
+			entry.Release()
+			continue
+		}
+		cl.enqueue(entry)
+	}
+}
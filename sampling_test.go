@@ -0,0 +1,124 @@
+package log4
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	s := newSampler(Sampling{Initial: 3, Thereafter: 5, Tick: time.Hour})
+	entry := &LogEntry{Package: "api", Level: INFO, Message: "Request"}
+
+	allowed := 0
+	for i := 0; i < 23; i++ {
+		if s.allow(entry) {
+			allowed++
+		}
+	}
+	// Entries 1-3, then 8, 13, 18 and 23
+	if allowed != 7 {
+		t.Errorf("Expected 7 sampled entries, got %d", allowed)
+	}
+	if !s.allow(&LogEntry{Package: "api", Level: ERROR, Message: "Request"}) {
+		t.Error("A different level should be counted separately")
+	}
+
+	s.tickEnd = time.Now().Add(-time.Second)
+	if !s.allow(entry) {
+		t.Error("A new tick should reset the counts")
+	}
+}
+
+func TestProductionConfig(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var sampled int
+	config := ProductionConfig()
+	config.LogDir = tempDir
+	config.Sampling.Initial = 2
+	config.Sampling.Thereafter = 0
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		if reason == DropSampled {
+			sampled++
+		}
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	var console bytes.Buffer
+	logger.stdout = &console
+
+	logger.Debug("api", "Filtered")
+	for i := 0; i < 5; i++ {
+		logger.Info("api", "Request handled")
+	}
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "api.log"))
+	if countLines(content) != 2 || sampled != 3 {
+		t.Errorf("Expected 2 lines and 3 sampled drops, got %d and %d", countLines(content), sampled)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.SplitN(content, "\n", 2)[0]), &obj); err != nil || obj["level"] != "INFO" {
+		t.Errorf("Expected JSON lines, got %q (%v)", content, err)
+	}
+	if console.Len() != 0 {
+		t.Errorf("Production config should not write to stdout, got %q", console.String())
+	}
+}
+
+func TestSamplingMark(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.Formatter = &JSONFormatter{}
+	config.Sampling = &Sampling{Initial: 2, Thereafter: 4, Tick: time.Hour, Mark: true}
+	logger := NewChannelLoggerWithConfig(config)
+
+	for i := 0; i < 12; i++ {
+		logger.Info("api", "Request handled")
+	}
+	logger.Error("api", "Upstream down")
+	logger.Close()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, "api.log"))), "\n") {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		lines = append(lines, obj)
+	}
+
+	// Entries 1 and 2, then 6 and 10 standing for 4 each, the error and
+	// the marker written on Close
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 lines, got %d: %v", len(lines), lines)
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := lines[i][FieldSampled]; ok {
+			t.Errorf("Entry %d within Initial should not be marked: %v", i+1, lines[i])
+		}
+	}
+	for i := 2; i < 4; i++ {
+		if lines[i][FieldSampled] != true || lines[i][FieldSampleRate] != float64(4) {
+			t.Errorf("Expected entry %d to be marked with rate 4: %v", i+1, lines[i])
+		}
+	}
+	if _, ok := lines[4][FieldSampled]; ok {
+		t.Errorf("Unsampled message should not be marked: %v", lines[4])
+	}
+
+	marker := lines[5]
+	if marker["msg"] != "sampling dropped 8 of 12 entries" || marker["level"] != "INFO" ||
+		marker[FieldSampledMessage] != "Request handled" || marker[FieldSampledTotal] != float64(12) ||
+		marker[FieldSampledDropped] != float64(8) {
+		t.Errorf("Unexpected marker %v", marker)
+	}
+}
@@ -0,0 +1,74 @@
+package log4
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// sanitizeEntry escapes the control characters of the message and string
+// field values of an entry for Config.SanitizeMessages
+func sanitizeEntry(entry *LogEntry) {
+	entry.Message = sanitizeString(entry.Message)
+	for k, v := range entry.Fields {
+		switch v := v.(type) {
+		case string:
+			entry.Fields[k] = sanitizeString(v)
+		case error:
+			if s := v.Error(); needsSanitizing(s) {
+				entry.Fields[k] = sanitizeString(s)
+			}
+		}
+	}
+}
+
+// needsSanitizing reports whether s contains a character sanitizeString
+// escapes
+func needsSanitizing(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < 0x20 && c != '\t') || c == 0x7f || c >= utf8.RuneSelf {
+			return strings.IndexFunc(s[i:], isUnsafeRune) >= 0
+		}
+	}
+	return false
+}
+
+// isUnsafeRune reports whether r is a control character other than tab, or a
+// line or paragraph separator
+func isUnsafeRune(r rune) bool {
+	return (r < 0x20 && r != '\t') || (r >= 0x7f && r <= 0x9f) || r == '\u2028' || r == '\u2029'
+}
+
+// sanitizeString escapes control characters so that s cannot forge log lines
+// or drive a terminal: line breaks become \n and \r, and other control
+// characters, including the ESC starting ANSI escape sequences, \x1b or
+// \u0085 style escapes. Tabs are kept.
+func sanitizeString(s string) string {
+	if !needsSanitizing(s) {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + 8)
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case !isUnsafeRune(r):
+			sb.WriteRune(r)
+		case r < utf8.RuneSelf:
+			sb.WriteString(`\x`)
+			if r < 0x10 {
+				sb.WriteByte('0')
+			}
+			sb.WriteString(strconv.FormatInt(int64(r), 16))
+		default:
+			sb.WriteString(`\u`)
+			s := strconv.FormatInt(int64(r), 16)
+			sb.WriteString(strings.Repeat("0", 4-len(s)))
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
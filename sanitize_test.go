@@ -0,0 +1,64 @@
+package log4
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"tab\tkept", "tab\tkept"},
+		{"héllo wörld", "héllo wörld"},
+		{"user\n[2024-01-01 00:00:00] ERROR: forged", `user\n[2024-01-01 00:00:00] ERROR: forged`},
+		{"a\r\nb", `a\r\nb`},
+		{"\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`},
+		{"bell\x07 del\x7f", `bell\x07 del\x7f`},
+		{"next\u0085line\u2028sep", `next\u0085line\u2028sep`},
+	}
+	for _, tt := range tests {
+		if got := sanitizeString(tt.in); got != tt.want {
+			t.Errorf("sanitizeString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeMessages(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.SanitizeMessages = true
+	config.RecentEntries = 10
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.Package("auth").InfoWithFields("login failed for admin\n[2024-01-01 00:00:00] INFO: login ok", map[string]interface{}{
+		"user":  "\x1b]0;pwned\x07",
+		"err":   errors.New("bad\rinput"),
+		"count": 3,
+	})
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "auth.log"))
+	if countLines(content) != 1 {
+		t.Errorf("Expected a single line, got:\n%s", content)
+	}
+	if strings.ContainsAny(content[:len(content)-1], "\x1b\x07\r\n") {
+		t.Errorf("Expected control characters to be escaped, got %q", content)
+	}
+	for _, want := range []string{`admin\n[2024`, `user=\x1b]0;pwned\x07`, `err=bad\rinput`, "count=3"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in %q", want, content)
+		}
+	}
+	if recent := logger.Recent(); len(recent) != 1 || strings.ContainsAny(recent[0].Line, "\x1b\n") {
+		t.Errorf("Expected recent entries to be sanitized, got %+v", recent)
+	}
+}
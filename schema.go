@@ -0,0 +1,192 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema presets selectable through Config.SchemaPreset
+const (
+	SchemaECS     = "ecs"     // Elastic Common Schema
+	SchemaDatadog = "datadog" // Datadog reserved and standard attributes
+	SchemaGCP     = "gcp"     // Google Cloud Logging structured logs
+)
+
+// ECSVersion is the Elastic Common Schema version reported in ecs.version
+const ECSVersion = "8.11.0"
+
+// formatterForPreset returns the formatter of a schema preset writing field
+// values with enc
+func formatterForPreset(name string, enc FieldEncoding) (Formatter, bool) {
+	switch strings.ToLower(name) {
+	case SchemaECS:
+		return &ECSFormatter{Encoding: enc}, true
+	case SchemaDatadog:
+		return &DatadogFormatter{Encoding: enc}, true
+	case SchemaGCP:
+		return &GCPFormatter{ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"), Encoding: enc}, true
+	}
+	return nil, false
+}
+
+// ECSFormatter renders entries as Elastic Common Schema documents:
+//
+//	{"@timestamp":"...","log.level":"info","log.logger":"app","message":"...","ecs.version":"8.11.0","labels":{"key":"value"}}
+//
+// ECS labels are keyword fields, so field values are written as strings.
+type ECSFormatter struct {
+	Encoding FieldEncoding // How field values of common types are written
+}
+
+// Format appends the ECS encoding of entry to dst
+func (f *ECSFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	dst = append(dst, `{"@timestamp":`...)
+	dst = appendJSONString(dst, entry.Timestamp.Format(time.RFC3339Nano))
+	dst = append(dst, `,"log.level":`...)
+	dst = appendJSONString(dst, strings.ToLower(entry.Level.String()))
+	if entry.Package != "" {
+		dst = append(dst, `,"log.logger":`...)
+		dst = appendJSONString(dst, entry.Package)
+	}
+	dst = append(dst, `,"message":`...)
+	dst = appendJSONString(dst, entry.Message)
+	dst = append(dst, `,"ecs.version":`...)
+	dst = appendJSONString(dst, ECSVersion)
+
+	if len(entry.Fields) > 0 {
+		dst = append(dst, `,"labels":{`...)
+		for i, k := range sortedFieldKeys(entry.Fields) {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, k)
+			dst = append(dst, ':')
+			v := resolveValue(entry.Fields[k])
+			dst = appendJSONString(dst, f.Encoding.text(v))
+			if t := errorType(v); t != "" {
+				dst = append(dst, ',')
+				dst = appendJSONString(dst, k+"_type")
+				dst = append(dst, ':')
+				dst = appendJSONString(dst, t)
+			}
+		}
+		dst = append(dst, '}')
+	}
+	return append(dst, '}')
+}
+
+// DatadogFormatter renders entries using Datadog's reserved attributes so the
+// date, status and message are recognized without a pipeline:
+//
+//	{"date":"...","status":"info","logger.name":"app","message":"...","key":"value"}
+//
+// Fields are written as top level attributes, so fields such as service, host
+// or ddtags are picked up as Datadog attributes. A field named like one of the
+// attributes written by the formatter is written as "fields.<name>".
+type DatadogFormatter struct {
+	Encoding FieldEncoding // How field values of common types are written
+}
+
+// datadogKeys are the attributes written by DatadogFormatter itself
+var datadogKeys = map[string]bool{
+	"date": true, "status": true, "message": true, "logger.name": true,
+}
+
+// Format appends the Datadog encoding of entry to dst
+func (f *DatadogFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	dst = append(dst, `{"date":`...)
+	dst = appendJSONString(dst, entry.Timestamp.Format(time.RFC3339Nano))
+	dst = append(dst, `,"status":`...)
+	dst = appendJSONString(dst, strings.ToLower(entry.Level.String()))
+	if entry.Package != "" {
+		dst = append(dst, `,"logger.name":`...)
+		dst = appendJSONString(dst, entry.Package)
+	}
+	dst = append(dst, `,"message":`...)
+	dst = appendJSONString(dst, entry.Message)
+
+	dst = f.Encoding.appendJSONFields(dst, entry.Fields, func(k string) string {
+		if datadogKeys[k] {
+			return "fields." + k
+		}
+		return k
+	})
+	return append(dst, '}')
+}
+
+// GCPFormatter renders entries as the structured JSON that the Cloud Logging
+// agents on GKE, Cloud Run and App Engine parse from stdout:
+//
+//	{"severity":"ERROR","time":"...","message":"...","logging.googleapis.com/labels":{"package":"app"},...}
+//
+// Severity names come from Severities. The fields trace_id and span_id are
+// moved to logging.googleapis.com/trace and logging.googleapis.com/spanId so
+// entries are correlated with Cloud Trace; the trace is qualified with
+// ProjectID when it is set. Other fields stay in the JSON payload.
+type GCPFormatter struct {
+	ProjectID  string
+	Severities SeverityMap   // Level mapping (default: DefaultSeverities)
+	Encoding   FieldEncoding // How field values of common types are written
+}
+
+// gcpKeys are the keys written by GCPFormatter itself
+var gcpKeys = map[string]bool{
+	"severity": true, "time": true, "message": true,
+	"logging.googleapis.com/labels": true, "logging.googleapis.com/trace": true,
+	"logging.googleapis.com/spanId": true,
+}
+
+// Format appends the Cloud Logging encoding of entry to dst
+func (f *GCPFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	dst = append(dst, `{"severity":`...)
+	dst = appendJSONString(dst, f.Severities.Lookup(entry.Level).Text)
+	dst = append(dst, `,"time":`...)
+	dst = appendJSONString(dst, entry.Timestamp.Format(time.RFC3339Nano))
+	dst = append(dst, `,"message":`...)
+	dst = appendJSONString(dst, entry.Message)
+	if entry.Package != "" {
+		dst = append(dst, `,"logging.googleapis.com/labels":{"package":`...)
+		dst = appendJSONString(dst, entry.Package)
+		dst = append(dst, '}')
+	}
+
+	if trace, ok := entry.Fields["trace_id"]; ok {
+		id := fmt.Sprintf("%v", trace)
+		if f.ProjectID != "" && !strings.HasPrefix(id, "projects/") {
+			id = "projects/" + f.ProjectID + "/traces/" + id
+		}
+		dst = append(dst, `,"logging.googleapis.com/trace":`...)
+		dst = appendJSONString(dst, id)
+	}
+	if span, ok := entry.Fields["span_id"]; ok {
+		dst = append(dst, `,"logging.googleapis.com/spanId":`...)
+		dst = appendJSONString(dst, fmt.Sprintf("%v", span))
+	}
+
+	dst = f.Encoding.appendJSONFields(dst, entry.Fields, func(k string) string {
+		switch {
+		case k == "trace_id" || k == "span_id":
+			return "" // Written above
+		case gcpKeys[k]:
+			return "fields." + k
+		}
+		return k
+	})
+	return append(dst, '}')
+}
+
+// sortedFieldKeys returns the field names in a stable order
+func sortedFieldKeys(fields map[string]interface{}) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
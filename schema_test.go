@@ -0,0 +1,133 @@
+package log4
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func schemaTestEntry() *LogEntry {
+	return &LogEntry{
+		Package:   "billing",
+		Level:     ERROR,
+		Message:   "charge failed",
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Fields:    map[string]interface{}{"attempt": 3, "message": "card declined", "service": "payments"},
+	}
+}
+
+func decodeJSONLine(t *testing.T, line []byte) map[string]interface{} {
+	var got map[string]interface{}
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, line)
+	}
+	return got
+}
+
+func TestECSFormatter(t *testing.T) {
+	got := decodeJSONLine(t, (&ECSFormatter{}).Format(nil, schemaTestEntry()))
+
+	expected := map[string]interface{}{
+		"@timestamp":  "2024-05-01T12:00:00Z",
+		"log.level":   "error",
+		"log.logger":  "billing",
+		"message":     "charge failed",
+		"ecs.version": ECSVersion,
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("Key %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	labels, ok := got["labels"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected labels object, got %v", got["labels"])
+	}
+	if labels["attempt"] != "3" || labels["message"] != "card declined" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+}
+
+func TestDatadogFormatter(t *testing.T) {
+	got := decodeJSONLine(t, (&DatadogFormatter{}).Format(nil, schemaTestEntry()))
+
+	expected := map[string]interface{}{
+		"date":           "2024-05-01T12:00:00Z",
+		"status":         "error",
+		"logger.name":    "billing",
+		"message":        "charge failed",
+		"service":        "payments",
+		"attempt":        float64(3),
+		"fields.message": "card declined",
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("Key %s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestGCPFormatter(t *testing.T) {
+	entry := schemaTestEntry()
+	entry.Fields["trace_id"] = "4bf92f3577b34da6a3ce929d0e0e4736"
+	entry.Fields["span_id"] = "00f067aa0ba902b7"
+
+	got := decodeJSONLine(t, (&GCPFormatter{ProjectID: "shop"}).Format(nil, entry))
+
+	expected := map[string]interface{}{
+		"severity":                      "ERROR",
+		"time":                          "2024-05-01T12:00:00Z",
+		"message":                       "charge failed",
+		"logging.googleapis.com/trace":  "projects/shop/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		"logging.googleapis.com/spanId": "00f067aa0ba902b7",
+		"fields.message":                "card declined",
+		"attempt":                       float64(3),
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("Key %s = %v, want %v", k, got[k], v)
+		}
+	}
+	if labels, _ := got["logging.googleapis.com/labels"].(map[string]interface{}); labels["package"] != "billing" {
+		t.Errorf("Expected package label, got %v", got["logging.googleapis.com/labels"])
+	}
+	if _, ok := got["trace_id"]; ok {
+		t.Error("trace_id should be moved to the trace key")
+	}
+
+	// Custom severities, e.g. reporting INFO as NOTICE
+	f := &GCPFormatter{Severities: SeverityMap{INFO: {Text: "NOTICE"}}}
+	entry.Level = INFO
+	if got := decodeJSONLine(t, f.Format(nil, entry)); got["severity"] != "NOTICE" {
+		t.Errorf("Expected NOTICE severity, got %v", got["severity"])
+	}
+}
+
+func TestSchemaPreset(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.SchemaPreset = "ECS"
+
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	logger.Info("preset", "ECS message")
+	logger.Close()
+
+	content := strings.TrimSpace(readFile(t, filepath.Join(tempDir, "preset.log")))
+	if got := decodeJSONLine(t, []byte(content)); got["log.level"] != "info" {
+		t.Errorf("Expected ECS output, got %s", content)
+	}
+
+	config = DefaultConfig()
+	config.SchemaPreset = "splunk"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown schema preset")
+	}
+}
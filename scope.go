@@ -0,0 +1,100 @@
+package log4
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Fields of the entries logged through a Scope
+const (
+	FieldScope       = "scope"           // Names of the scope and its parents, e.g. "import-users/parse"
+	FieldScopeID     = "scope_id"        // ID of the scope, from Config.IDGenerator
+	FieldParentScope = "parent_scope_id" // ID of the enclosing scope
+	FieldError       = "error"           // Error a scope ended with
+)
+
+// Scope is a named block of work, such as a job or a request, logged as a
+// span of poor man's tracing: it logs an entry when it begins and one with
+// its FieldDuration when it ends, and every entry logged through it carries
+// FieldScope, FieldScopeID and FieldParentScope. Scopes nest through their
+// Context, across packages:
+//
+//	scope := pl.Scope(ctx, "import-users")
+//	defer scope.End()
+//	scope.Info("Reading file")
+//	insert := db.Scope(scope.Context(), "insert") // scope=import-users/insert
+//
+// The request ID of the context (see ContextWithRequestID) is bound to the
+// scope's entries as well.
+type Scope struct {
+	*PackageLogger // Logs with the fields of the scope
+
+	ctx   context.Context
+	name  string // As given to Scope
+	path  string // Value of FieldScope
+	start time.Time
+	ended atomic.Bool
+}
+
+// scopeKey is the context key of the innermost Scope
+type scopeKey struct{}
+
+// Scope begins a scope named name within the scope of ctx, if any, and logs
+// "<name> started" at info level
+func (pl *PackageLogger) Scope(ctx context.Context, name string) *Scope {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &Scope{name: name, path: name, start: time.Now()}
+	fields := map[string]interface{}{FieldScopeID: pl.logger.newID()}
+	if parent, ok := ctx.Value(scopeKey{}).(*Scope); ok {
+		s.path = parent.path + "/" + name
+		fields[FieldParentScope] = parent.fields[FieldScopeID]
+	}
+	fields[FieldScope] = s.path
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		fields[FieldRequestID] = requestID
+	}
+
+	s.PackageLogger = pl.With(fields)
+	s.ctx = context.WithValue(ctx, scopeKey{}, s)
+	s.log(nil, INFO, name+" started", nil)
+	return s
+}
+
+// Scope begins a scope nested in s, in the same package
+func (s *Scope) Scope(name string) *Scope {
+	return s.PackageLogger.Scope(s.ctx, name)
+}
+
+// Context returns the context of the scope, through which scopes begun with
+// it are nested in s
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+// End logs "<name> finished" at info level with the duration of the scope.
+// Only the first call to End or EndWithError logs.
+func (s *Scope) End() {
+	s.end(nil)
+}
+
+// EndWithError ends the scope like End, or if err is not nil logs
+// "<name> failed" at error level with err as FieldError
+func (s *Scope) EndWithError(err error) {
+	s.end(err)
+}
+
+func (s *Scope) end(err error) {
+	if !s.ended.CompareAndSwap(false, true) {
+		return
+	}
+	fields := map[string]interface{}{FieldDuration: durationMillis(time.Since(s.start))}
+	if err != nil {
+		fields[FieldError] = err
+		s.log(nil, ERROR, s.name+" failed", fields)
+		return
+	}
+	s.log(nil, INFO, s.name+" finished", fields)
+}
@@ -0,0 +1,77 @@
+package log4
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	ids := 0
+	config.IDGenerator = IDGeneratorFunc(func() string {
+		ids++
+		return string(rune('a' + ids - 1))
+	})
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	ctx := ContextWithRequestID(context.Background(), "req")
+	scope := logger.Package("jobs").Scope(ctx, "import-users")
+	scope.Info("Reading file")
+	parse := logger.Package("db").Scope(scope.Context(), "insert")
+	parse.EndWithError(errors.New("duplicate key"))
+	parse.End()
+	scope.End()
+	logger.Close()
+
+	want := []struct {
+		pkg    string
+		level  LogLevel
+		msg    string
+		scope  string
+		id     string
+		parent interface{}
+	}{
+		{"jobs", INFO, "import-users started", "import-users", "a", nil},
+		{"jobs", INFO, "Reading file", "import-users", "a", nil},
+		{"db", INFO, "insert started", "import-users/insert", "b", "a"},
+		{"db", ERROR, "insert failed", "import-users/insert", "b", "a"},
+		{"jobs", INFO, "import-users finished", "import-users", "a", nil},
+	}
+	if len(sink.entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(sink.entries))
+	}
+	for i, w := range want {
+		e := sink.entries[i]
+		if e.Package != w.pkg || e.Level != w.level || e.Message != w.msg {
+			t.Errorf("Entry %d: expected %s %v %q, got %s %v %q", i, w.pkg, w.level, w.msg, e.Package, e.Level, e.Message)
+		}
+		if e.Fields[FieldScope] != w.scope || e.Fields[FieldScopeID] != w.id || e.Fields[FieldParentScope] != w.parent {
+			t.Errorf("Entry %q: unexpected scope fields %v", e.Message, e.Fields)
+		}
+		if e.Fields[FieldRequestID] != "req" {
+			t.Errorf("Entry %q: expected the request ID of the context, got %v", e.Message, e.Fields[FieldRequestID])
+		}
+	}
+
+	if _, ok := sink.entries[3].Fields[FieldDuration]; !ok {
+		t.Error("Expected the duration on the failed entry")
+	}
+	if err, _ := sink.entries[3].Fields[FieldError].(error); err == nil || err.Error() != "duplicate key" {
+		t.Errorf("Expected the error of the scope, got %v", sink.entries[3].Fields[FieldError])
+	}
+	if _, ok := sink.entries[4].Fields[FieldDuration]; !ok {
+		t.Error("Expected the duration on the finished entry")
+	}
+	if _, ok := sink.entries[1].Fields[FieldDuration]; ok {
+		t.Error("Entries within the scope should not carry a duration")
+	}
+}
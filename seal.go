@@ -0,0 +1,523 @@
+package log4
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Algorithms of sealed batches
+const (
+	SealHMACSHA256 = "hmac-sha256"
+	SealEd25519    = "ed25519"
+	SealAESGCM     = "aes-gcm"
+)
+
+// HTTP headers carrying the seal of an OTLP export
+const (
+	HeaderSignature          = "X-Log4-Signature" // Base64 signature of the body
+	HeaderSignatureAlgorithm = "X-Log4-Signature-Algorithm"
+	HeaderSignatureKey       = "X-Log4-Signature-Key"
+	HeaderEncryption         = "X-Log4-Encryption" // Encryption algorithm of the body
+	HeaderEncryptionKey      = "X-Log4-Encryption-Key"
+	HeaderSealSender         = "X-Log4-Seal-Sender"   // Random ID of the sending Seal
+	HeaderSealSequence       = "X-Log4-Seal-Sequence" // Number of the batch from its sender
+	HeaderSealTime           = "X-Log4-Seal-Time"     // Unix time in nanoseconds the batch was sealed
+)
+
+// DefaultSealMaxAge is the default Seal.MaxAge
+const DefaultSealMaxAge = 5 * time.Minute
+
+// Errors returned by Seal.Open
+var (
+	ErrUnsigned     = errors.New("batch is not signed")
+	ErrBadSignature = errors.New("batch signature does not verify")
+	ErrUnencrypted  = errors.New("batch is not encrypted")
+	ErrSealExpired  = errors.New("batch was sealed outside the accepted age")
+	ErrReplayed     = errors.New("batch was already opened")
+)
+
+// KeyProvider supplies the keys of a Signer or Encrypter, so they can be
+// loaded from a secret store and rotated while the logger runs. Every sealed
+// batch names the ID of its key, and the receiving side looks it up by ID.
+type KeyProvider interface {
+	// CurrentKey returns the ID and material of the key to seal with
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the material of the key with the given ID, to open with
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider holding fixed keys by ID. Current is the ID of
+// the key to seal with; the others still open batches sealed before a
+// rotation.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey returns the key named by Current
+func (k StaticKeys) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+// Key returns the key with the given ID
+func (k StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	return key, nil
+}
+
+// Signer signs sealed batches and verifies them on the receiving side. The
+// signed data covers the ID of the signing key, so Sign is given the ID
+// returned by KeyID.
+type Signer interface {
+	Algorithm() string
+	// KeyID returns the ID of the key to sign with
+	KeyID() (string, error)
+	Sign(keyID string, data []byte) ([]byte, error)
+	Verify(keyID string, data, sig []byte) error
+}
+
+// Encrypter encrypts the payloads of sealed batches and decrypts them on the
+// receiving side
+type Encrypter interface {
+	Algorithm() string
+	Encrypt(plaintext []byte) (keyID string, ciphertext []byte, err error)
+	Decrypt(keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// HMACSigner signs with HMAC-SHA256. Both sides share the keys of Keys.
+type HMACSigner struct {
+	Keys KeyProvider
+}
+
+// Algorithm returns SealHMACSHA256
+func (s HMACSigner) Algorithm() string { return SealHMACSHA256 }
+
+// KeyID returns the ID of the current key
+func (s HMACSigner) KeyID() (string, error) {
+	id, _, err := s.Keys.CurrentKey()
+	return id, err
+}
+
+// Sign returns the HMAC of data with the key keyID
+func (s HMACSigner) Sign(keyID string, data []byte) ([]byte, error) {
+	key, err := s.Keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return hmacSHA256(key, data), nil
+}
+
+// Verify checks sig against the HMAC of data with the key keyID
+func (s HMACSigner) Verify(keyID string, data, sig []byte) error {
+	key, err := s.Keys.Key(keyID)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(sig, hmacSHA256(key, data)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func hmacSHA256(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Ed25519Signer signs with Ed25519, so the receiving side only holds public
+// keys. Keys of the sending side return private keys, either the 32-byte
+// seed or the 64-byte form; those of the receiving side return the 32-byte
+// public keys under the same IDs.
+type Ed25519Signer struct {
+	Keys KeyProvider
+}
+
+// Algorithm returns SealEd25519
+func (s Ed25519Signer) Algorithm() string { return SealEd25519 }
+
+// KeyID returns the ID of the current private key
+func (s Ed25519Signer) KeyID() (string, error) {
+	id, _, err := s.Keys.CurrentKey()
+	return id, err
+}
+
+// Sign returns the signature of data with the private key keyID
+func (s Ed25519Signer) Sign(keyID string, data []byte) ([]byte, error) {
+	key, err := s.Keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		key = ed25519.NewKeyFromSeed(key)
+	case ed25519.PrivateKeySize:
+	default:
+		return nil, fmt.Errorf("ed25519 key %q has %d bytes", keyID, len(key))
+	}
+	return ed25519.Sign(ed25519.PrivateKey(key), data), nil
+}
+
+// Verify checks sig with the public key keyID
+func (s Ed25519Signer) Verify(keyID string, data, sig []byte) error {
+	key, err := s.Keys.Key(keyID)
+	if err != nil {
+		return err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("ed25519 public key %q has %d bytes", keyID, len(key))
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// AESGCMEncrypter encrypts with AES-GCM using 16, 24 or 32-byte keys shared
+// by both sides. The random nonce precedes the ciphertext.
+type AESGCMEncrypter struct {
+	Keys KeyProvider
+}
+
+// Algorithm returns SealAESGCM
+func (e AESGCMEncrypter) Algorithm() string { return SealAESGCM }
+
+// Encrypt encrypts plaintext with the current key
+func (e AESGCMEncrypter) Encrypt(plaintext []byte) (string, []byte, error) {
+	id, key, err := e.Keys.CurrentKey()
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("key %q: %w", id, err)
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return id, aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts ciphertext with the key keyID
+func (e AESGCMEncrypter) Decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	key, err := e.Keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", keyID, err)
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal signs, and optionally encrypts, every batch a network sink sends (see
+// BatchOptions.Seal), so batches crossing untrusted networks can be verified
+// by the receiving side. Payloads are encrypted first; the signature covers
+// the encrypted payload together with the algorithms and key IDs, a random ID
+// of the sending Seal, the sequence number of the batch and the time it was
+// sealed. The receiving side configures a Seal with the same algorithms and
+// its own keys and calls Open, or passes it to SocketServerOptions. Open
+// rejects batches sealed more than MaxAge away from its clock and batches it
+// has already opened, so captured batches cannot be replayed.
+type Seal struct {
+	Signer    Signer        // Signs every batch (required)
+	Encrypter Encrypter     // Encrypts every batch (optional)
+	MaxAge    time.Duration // Clock difference Open accepts, DefaultSealMaxAge if zero
+
+	senderOnce sync.Once
+	sender     string
+	sequence   atomic.Uint64
+
+	mu     sync.Mutex
+	opened map[sealID]time.Time // Batches opened within MaxAge -> their time
+	pruned time.Time
+}
+
+// sealID identifies a batch for replay detection
+type sealID struct {
+	sender   string
+	sequence uint64
+}
+
+// SealedBatch is the payload of a batch as it is sent, with the algorithms,
+// key IDs and replay protection needed to open it
+type SealedBatch struct {
+	Payload            []byte
+	Signature          []byte
+	SignatureAlgorithm string
+	SignatureKey       string
+	Encryption         string // Empty if Payload is not encrypted
+	EncryptionKey      string
+	Sender             string    // Random ID of the sending Seal
+	Sequence           uint64    // Number of the batch from Sender
+	Time               time.Time // When the batch was sealed
+}
+
+// sealVersion starts the signed data, so its layout can change
+const sealVersion = "log4-seal-v1"
+
+// signedData returns what the signature of b covers: every field but the
+// signature, each length-prefixed so no two batches encode alike
+func (b *SealedBatch) signedData() []byte {
+	var d []byte
+	for _, f := range []string{sealVersion, b.SignatureAlgorithm, b.SignatureKey, b.Encryption, b.EncryptionKey, b.Sender} {
+		d = binary.BigEndian.AppendUint32(d, uint32(len(f)))
+		d = append(d, f...)
+	}
+	d = binary.BigEndian.AppendUint64(d, b.Sequence)
+	d = binary.BigEndian.AppendUint64(d, uint64(b.Time.UnixNano()))
+	d = binary.BigEndian.AppendUint32(d, uint32(len(b.Payload)))
+	return append(d, b.Payload...)
+}
+
+// seal encrypts and signs payload. Every call takes the next sequence number,
+// so a batch is sealed again when it is sent again.
+func (s *Seal) seal(payload []byte) (*SealedBatch, error) {
+	if s.Signer == nil {
+		return nil, errors.New("seal without a signer")
+	}
+	s.senderOnce.Do(func() {
+		var id [16]byte
+		rand.Read(id[:])
+		s.sender = hex.EncodeToString(id[:])
+	})
+	b := &SealedBatch{
+		Payload:  payload,
+		Sender:   s.sender,
+		Sequence: s.sequence.Add(1),
+		Time:     time.Now(),
+	}
+	if s.Encrypter != nil {
+		id, ciphertext, err := s.Encrypter.Encrypt(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting batch: %w", err)
+		}
+		b.Payload, b.Encryption, b.EncryptionKey = ciphertext, s.Encrypter.Algorithm(), id
+	}
+	id, err := s.Signer.KeyID()
+	if err != nil {
+		return nil, fmt.Errorf("signing batch: %w", err)
+	}
+	b.SignatureAlgorithm, b.SignatureKey = s.Signer.Algorithm(), id
+	if b.Signature, err = s.Signer.Sign(id, b.signedData()); err != nil {
+		return nil, fmt.Errorf("signing batch: %w", err)
+	}
+	return b, nil
+}
+
+// Open verifies the signature of a batch and decrypts it, returning the
+// payload the sink encoded. Batches signed or encrypted with other
+// algorithms than those of s are rejected, as are unencrypted batches if s
+// has an Encrypter, batches sealed more than MaxAge before or after now, and
+// batches already opened by s.
+func (s *Seal) Open(b *SealedBatch) ([]byte, error) {
+	if s.Signer == nil {
+		return nil, errors.New("seal without a signer")
+	}
+	if len(b.Signature) == 0 {
+		return nil, ErrUnsigned
+	}
+	if b.SignatureAlgorithm != s.Signer.Algorithm() {
+		return nil, fmt.Errorf("batch signed with %q, expected %q", b.SignatureAlgorithm, s.Signer.Algorithm())
+	}
+	if err := s.Signer.Verify(b.SignatureKey, b.signedData(), b.Signature); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.Encrypter == nil && b.Encryption != "":
+		return nil, fmt.Errorf("batch encrypted with %q, but no Encrypter is set", b.Encryption)
+	case s.Encrypter != nil && b.Encryption == "":
+		return nil, ErrUnencrypted
+	case s.Encrypter != nil && b.Encryption != s.Encrypter.Algorithm():
+		return nil, fmt.Errorf("batch encrypted with %q, expected %q", b.Encryption, s.Encrypter.Algorithm())
+	}
+	if err := s.checkReplay(b); err != nil {
+		return nil, err
+	}
+	if s.Encrypter == nil {
+		return b.Payload, nil
+	}
+	payload, err := s.Encrypter.Decrypt(b.EncryptionKey, b.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting batch: %w", err)
+	}
+	return payload, nil
+}
+
+// checkReplay rejects a verified batch sealed outside MaxAge, or opened
+// before. Batches are remembered for as long as their time is accepted.
+func (s *Seal) checkReplay(b *SealedBatch) error {
+	maxAge := s.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultSealMaxAge
+	}
+	now := time.Now()
+	if b.Time.Before(now.Add(-maxAge)) || b.Time.After(now.Add(maxAge)) {
+		return ErrSealExpired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.pruned) > maxAge {
+		for id, t := range s.opened {
+			if t.Before(now.Add(-maxAge)) {
+				delete(s.opened, id)
+			}
+		}
+		s.pruned = now
+	}
+	id := sealID{b.Sender, b.Sequence}
+	if _, ok := s.opened[id]; ok {
+		return ErrReplayed
+	}
+	if s.opened == nil {
+		s.opened = make(map[sealID]time.Time)
+	}
+	s.opened[id] = b.Time
+	return nil
+}
+
+// OpenRequest opens the body of an HTTP request sent by an OTLPSink with a
+// Seal, e.g. in a proxy in front of the collector. The gRPC length prefix,
+// if any, is kept.
+func (s *Seal) OpenRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", HeaderSignature, err)
+	}
+	b := &SealedBatch{
+		Payload:            body,
+		Signature:          sig,
+		SignatureAlgorithm: r.Header.Get(HeaderSignatureAlgorithm),
+		SignatureKey:       r.Header.Get(HeaderSignatureKey),
+		Encryption:         r.Header.Get(HeaderEncryption),
+		EncryptionKey:      r.Header.Get(HeaderEncryptionKey),
+		Sender:             r.Header.Get(HeaderSealSender),
+	}
+	if v := r.Header.Get(HeaderSealSequence); v != "" {
+		if b.Sequence, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", HeaderSealSequence, err)
+		}
+	}
+	if v := r.Header.Get(HeaderSealTime); v != "" {
+		nanos, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", HeaderSealTime, err)
+		}
+		b.Time = time.Unix(0, nanos)
+	}
+	return s.Open(b)
+}
+
+// setHeaders adds the seal of b, without its payload, to the headers of a
+// request
+func (b *SealedBatch) setHeaders(h http.Header) {
+	h.Set(HeaderSignature, base64.StdEncoding.EncodeToString(b.Signature))
+	h.Set(HeaderSignatureAlgorithm, b.SignatureAlgorithm)
+	h.Set(HeaderSignatureKey, b.SignatureKey)
+	h.Set(HeaderSealSender, b.Sender)
+	h.Set(HeaderSealSequence, strconv.FormatUint(b.Sequence, 10))
+	h.Set(HeaderSealTime, strconv.FormatInt(b.Time.UnixNano(), 10))
+	if b.Encryption != "" {
+		h.Set(HeaderEncryption, b.Encryption)
+		h.Set(HeaderEncryptionKey, b.EncryptionKey)
+	}
+}
+
+// msgpackSealOptions returns the number of map pairs appendMsgpackSeal
+// appends for b
+func (b *SealedBatch) msgpackSealOptions() int {
+	if b.Encryption != "" {
+		return 8
+	}
+	return 6
+}
+
+// appendMsgpackSeal appends the seal of b, without its payload, as map pairs
+func (b *SealedBatch) appendMsgpackSeal(m []byte) []byte {
+	m = appendMsgpackString(m, "log4_sig")
+	m = appendMsgpackBinary(m, b.Signature)
+	m = appendMsgpackString(m, "log4_sig_alg")
+	m = appendMsgpackString(m, b.SignatureAlgorithm)
+	m = appendMsgpackString(m, "log4_sig_key")
+	m = appendMsgpackString(m, b.SignatureKey)
+	m = appendMsgpackString(m, "log4_sender")
+	m = appendMsgpackString(m, b.Sender)
+	m = appendMsgpackString(m, "log4_seq")
+	m = appendMsgpackUint(m, b.Sequence)
+	m = appendMsgpackString(m, "log4_time")
+	m = appendMsgpackInt(m, b.Time.UnixNano())
+	if b.Encryption != "" {
+		m = appendMsgpackString(m, "log4_enc")
+		m = appendMsgpackString(m, b.Encryption)
+		m = appendMsgpackString(m, "log4_enc_key")
+		m = appendMsgpackString(m, b.EncryptionKey)
+	}
+	return m
+}
+
+// sealedFromMsgpack returns the batch of payload sealed as decoded from the
+// pairs appended by appendMsgpackSeal
+func sealedFromMsgpack(payload []byte, m map[string]interface{}) *SealedBatch {
+	sig, _ := m["log4_sig"].([]byte)
+	return &SealedBatch{
+		Payload:            payload,
+		Signature:          sig,
+		SignatureAlgorithm: msgpackText(m["log4_sig_alg"]),
+		SignatureKey:       msgpackText(m["log4_sig_key"]),
+		Encryption:         msgpackText(m["log4_enc"]),
+		EncryptionKey:      msgpackText(m["log4_enc_key"]),
+		Sender:             msgpackText(m["log4_sender"]),
+		Sequence:           uint64(msgpackInt(m["log4_seq"])),
+		Time:               time.Unix(0, msgpackInt(m["log4_time"])),
+	}
+}
+
+// msgpackInt returns v decoded by readMsgpack as an integer, or 0
+func msgpackInt(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case uint64:
+		return int64(v)
+	}
+	return 0
+}
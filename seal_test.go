@@ -0,0 +1,234 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSealOpen(t *testing.T) {
+	aesKeys := StaticKeys{Current: "k2", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32), "k2": bytes.Repeat([]byte{2}, 16)}}
+	seal := &Seal{
+		Signer:    HMACSigner{Keys: StaticKeys{Current: "h1", Keys: map[string][]byte{"h1": []byte("shared secret")}}},
+		Encrypter: AESGCMEncrypter{Keys: aesKeys},
+	}
+	payload := []byte("entries")
+
+	sealed, err := seal.seal(payload)
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if sealed.EncryptionKey != "k2" || sealed.SignatureKey != "h1" || bytes.Contains(sealed.Payload, payload) {
+		t.Errorf("Unexpected sealed batch %+v", sealed)
+	}
+	if opened, err := seal.Open(sealed); err != nil || string(opened) != "entries" {
+		t.Errorf("Open returned %q, %v", opened, err)
+	}
+
+	tampered := *sealed
+	tampered.Payload = append([]byte(nil), sealed.Payload...)
+	tampered.Payload[0] ^= 1
+	if _, err := seal.Open(&tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for a tampered payload, got %v", err)
+	}
+
+	plain, _ := (&Seal{Signer: seal.Signer}).seal(payload)
+	if _, err := seal.Open(plain); !errors.Is(err, ErrUnencrypted) {
+		t.Errorf("Expected ErrUnencrypted, got %v", err)
+	}
+	if _, err := seal.Open(&SealedBatch{Payload: payload}); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned, got %v", err)
+	}
+
+	// Ed25519: the receiving side only has the public key
+	private := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	sender := &Seal{Signer: Ed25519Signer{Keys: StaticKeys{Current: "e1", Keys: map[string][]byte{"e1": private.Seed()}}}}
+	receiver := &Seal{Signer: Ed25519Signer{Keys: StaticKeys{Keys: map[string][]byte{"e1": private.Public().(ed25519.PublicKey)}}}}
+	sealed, err = sender.seal(payload)
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if opened, err := receiver.Open(sealed); err != nil || string(opened) != "entries" {
+		t.Errorf("Open returned %q, %v", opened, err)
+	}
+	if _, err := seal.Open(sealed); err == nil {
+		t.Error("Expected a batch signed with another algorithm to be rejected")
+	}
+}
+
+func TestSealReplay(t *testing.T) {
+	keys := StaticKeys{Current: "k2", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32), "k2": bytes.Repeat([]byte{2}, 32)}}
+	sender := &Seal{Signer: HMACSigner{Keys: keys}, Encrypter: AESGCMEncrypter{Keys: keys}}
+	receiver := &Seal{Signer: HMACSigner{Keys: keys}, Encrypter: AESGCMEncrypter{Keys: keys}}
+
+	first, _ := sender.seal([]byte("first"))
+	second, _ := sender.seal([]byte("first"))
+	if first.Sender == "" || second.Sender != first.Sender || second.Sequence != first.Sequence+1 {
+		t.Fatalf("Expected consecutive sequence numbers, got %+v and %+v", first, second)
+	}
+
+	// The seal fields are signed along with the payload
+	for name, tamper := range map[string]func(b *SealedBatch){
+		"encryption key": func(b *SealedBatch) { b.EncryptionKey = "k1" },
+		"encryption":     func(b *SealedBatch) { b.Encryption = "" },
+		"sender":         func(b *SealedBatch) { b.Sender = "forged" },
+		"sequence":       func(b *SealedBatch) { b.Sequence++ },
+		"time":           func(b *SealedBatch) { b.Time = b.Time.Add(time.Second) },
+	} {
+		tampered := *first
+		tamper(&tampered)
+		if _, err := receiver.Open(&tampered); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("Expected ErrBadSignature for a tampered %s, got %v", name, err)
+		}
+	}
+
+	if opened, err := receiver.Open(first); err != nil || string(opened) != "first" {
+		t.Fatalf("Open returned %q, %v", opened, err)
+	}
+	if _, err := receiver.Open(first); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Expected ErrReplayed, got %v", err)
+	}
+	if _, err := receiver.Open(second); err != nil {
+		t.Errorf("Expected the next batch to open, got %v", err)
+	}
+
+	// Batches older than MaxAge are rejected even if never opened
+	strict := &Seal{Signer: receiver.Signer, Encrypter: receiver.Encrypter, MaxAge: 10 * time.Millisecond}
+	old, _ := sender.seal([]byte("old"))
+	time.Sleep(20 * time.Millisecond)
+	if _, err := strict.Open(old); !errors.Is(err, ErrSealExpired) {
+		t.Errorf("Expected ErrSealExpired, got %v", err)
+	}
+}
+
+func TestSealedSocket(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{9}, 32)}}
+	seal := &Seal{Signer: HMACSigner{Keys: keys}, Encrypter: AESGCMEncrypter{Keys: keys}}
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	daemon := NewChannelLoggerWithConfig(config)
+	defer daemon.Close()
+
+	socketPath := filepath.Join(tempDir, "log4.sock")
+	server, err := NewSocketServerWithOptions(socketPath, daemon, SocketServerOptions{Seal: seal})
+	if err != nil {
+		t.Fatalf("NewSocketServerWithOptions failed: %v", err)
+	}
+	defer server.Close()
+
+	worker := NewLogger(WithDir(t.TempDir()), WithSink(NewSocketSink(SocketOptions{
+		Path:         socketPath,
+		BatchOptions: BatchOptions{BatchTimeout: 10 * time.Millisecond, Seal: seal},
+	})), func(c *Config) { c.DisableConsole = true })
+	for i := 0; i < 10; i++ {
+		worker.Info("api", "Sealed")
+	}
+	worker.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for daemon.Stats().Written < 10 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	server.Close()
+	daemon.Close()
+
+	if n := countLines(readFile(t, filepath.Join(tempDir, "api.log"))); n != 10 {
+		t.Errorf("Expected 10 entries through the sealed socket, got %d", n)
+	}
+}
+
+func TestSealedOTLP(t *testing.T) {
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": []byte("shared secret")}}
+	seal := &Seal{Signer: HMACSigner{Keys: keys}}
+
+	var mu sync.Mutex
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := seal.OpenRequest(r)
+		if err != nil {
+			t.Errorf("OpenRequest failed: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPOptions{Endpoint: server.URL, BatchOptions: BatchOptions{Seal: seal}})
+	if err != nil {
+		t.Fatalf("NewOTLPSink failed: %v", err)
+	}
+	sink.Write(&LogEntry{Package: "api", Level: INFO, Message: "Signed", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 || otlpBodies(t, bodies[0])["api"][0] != "Signed" {
+		t.Errorf("Unexpected exports %q", bodies)
+	}
+
+	// A request replayed or altered in transit does not open
+	sealed, _ := seal.seal([]byte("body"))
+	for i, want := range []error{nil, ErrReplayed} {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(sealed.Payload))
+		sealed.setHeaders(req.Header)
+		if _, err := seal.OpenRequest(req); !errors.Is(err, want) {
+			t.Errorf("Request %d: expected %v, got %v", i, want, err)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("forged")))
+	req.Header.Set(HeaderSignature, "AAAA")
+	req.Header.Set(HeaderSignatureAlgorithm, SealHMACSHA256)
+	req.Header.Set(HeaderSignatureKey, "k1")
+	if _, err := seal.OpenRequest(req); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature, got %v", err)
+	}
+}
+
+func TestSealedFluent(t *testing.T) {
+	addr, messages := fakeFluentd(t, "")
+	keys := StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{3}, 32)}}
+	seal := &Seal{Signer: HMACSigner{Keys: keys}, Encrypter: AESGCMEncrypter{Keys: keys}}
+
+	sink := NewFluentSink(FluentOptions{Address: addr, BatchOptions: BatchOptions{Seal: seal}})
+	sink.Write(&LogEntry{Package: "orders", Level: INFO, Message: "created", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	select {
+	case msg := <-messages:
+		packed, ok := msg[1].([]byte)
+		opts, _ := msg[2].(map[string]interface{})
+		if !ok || opts["log4_enc"] != SealAESGCM {
+			t.Fatalf("Expected a sealed PackedForward message, got %v", msg)
+		}
+		payload, err := seal.Open(sealedFromMsgpack(packed, opts))
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		entry, err := readMsgpack(bufio.NewReader(bytes.NewReader(payload)))
+		if err != nil {
+			t.Fatalf("Decoding the entry failed: %v", err)
+		}
+		if record := entry.([]interface{})[1].(map[string]interface{}); record["message"] != "created" {
+			t.Errorf("Unexpected record %v", record)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fluentd received nothing")
+	}
+}
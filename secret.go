@@ -0,0 +1,98 @@
+package log4
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretMask is written in place of secret values
+const SecretMask = "*****"
+
+// SecretValue holds a value that is never written by the logger; see Secret
+type SecretValue struct {
+	value interface{}
+}
+
+// Secret wraps a field value so that every formatter and sink, fmt verbs,
+// encoding/json and log/slog write it as SecretMask:
+//
+//	logger.InfoWithFields("login", map[string]interface{}{"password": log4.Secret(pw)})
+func Secret(value interface{}) SecretValue {
+	return SecretValue{value: value}
+}
+
+// Reveal returns the wrapped value
+func (s SecretValue) Reveal() interface{} {
+	return s.value
+}
+
+// LogValue returns SecretMask
+func (s SecretValue) LogValue() interface{} {
+	return SecretMask
+}
+
+// String returns SecretMask
+func (s SecretValue) String() string {
+	return SecretMask
+}
+
+// GoString returns SecretMask, so that %#v does not print the value either
+func (s SecretValue) GoString() string {
+	return SecretMask
+}
+
+// Format writes SecretMask for every verb and flag
+func (s SecretValue) Format(f fmt.State, verb rune) {
+	f.Write([]byte(SecretMask))
+}
+
+// MarshalText returns SecretMask
+func (s SecretValue) MarshalText() ([]byte, error) {
+	return []byte(SecretMask), nil
+}
+
+// MarshalJSON returns SecretMask as a JSON string
+func (s SecretValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + SecretMask + `"`), nil
+}
+
+// SecretFieldNames are the fragments of field names that SecretFields treats
+// as secrets, matched case-insensitively anywhere in the name
+var SecretFieldNames = []string{"password", "passwd", "secret", "token", "authorization", "api_key", "apikey", "private_key", "cookie"}
+
+// SecretFieldPolicy is what the logger does with fields whose names look like
+// secrets (see SecretFieldNames) but whose values are not wrapped in Secret
+type SecretFieldPolicy int
+
+const (
+	SecretFieldsAllow SecretFieldPolicy = iota // Write them as they are (default)
+	SecretFieldsWarn                           // Write them and report each package and field once as an *ErrSecretField
+	SecretFieldsMask                           // Write them as SecretMask and report them as with SecretFieldsWarn
+)
+
+// secretFieldName reports whether a field name looks like it holds a secret
+func secretFieldName(key string) bool {
+	key = strings.ToLower(key)
+	for _, name := range SecretFieldNames {
+		if strings.Contains(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecrets applies Config.SecretFields to the fields of an entry as it is
+// logged, before it is kept by the recent entries or the flight recorder
+func (cl *ChannelLogger) checkSecrets(entry *LogEntry) {
+	for k, v := range entry.Fields {
+		if _, ok := v.(SecretValue); ok || !secretFieldName(k) {
+			continue
+		}
+		if cl.config.SecretFields == SecretFieldsMask {
+			entry.Fields[k] = SecretValue{value: v}
+		}
+		if _, reported := cl.secrets.LoadOrStore([2]string{entry.Package, k}, struct{}{}); !reported {
+			cl.handleError(&ErrSecretField{Pkg: entry.Package, Field: k, Masked: cl.config.SecretFields == SecretFieldsMask})
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package log4
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &bufferSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+
+	s := Secret("hunter2")
+	logger.Package("auth").InfoWithFields("login", map[string]interface{}{"pw": s})
+	logger.Close()
+
+	if content := readFile(t, filepath.Join(tempDir, "auth.log")); !strings.Contains(content, "pw="+SecretMask) {
+		t.Errorf("Expected the secret to be masked, got %q", content)
+	}
+	if strings.Contains(sink.buf.String(), "hunter2") {
+		t.Errorf("Expected the secret to be masked for sinks, got %q", sink.buf.String())
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"pw": s})
+	var slogOut bytes.Buffer
+	slog.New(slog.NewJSONHandler(&slogOut, nil)).Info("login", "pw", s)
+	for _, out := range []string{fmt.Sprintf("%v %+v %#v %s %q", s, s, s, s, s), string(b), slogOut.String()} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("Expected the secret to be masked, got %q", out)
+		}
+	}
+	if s.Reveal() != "hunter2" {
+		t.Errorf("Expected Reveal to return the value, got %v", s.Reveal())
+	}
+}
+
+func TestSecretFields(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	var errs []error
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.SecretFields = SecretFieldsMask
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	pl := logger.Package("auth")
+	for i := 0; i < 3; i++ {
+		pl.InfoWithFields("login", map[string]interface{}{"user": "bob", "Authorization": "Bearer abc", "password": Secret("x")})
+	}
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "auth.log"))
+	if strings.Contains(content, "Bearer") || strings.Count(content, "Authorization="+SecretMask) != 3 || !strings.Contains(content, "user=bob") {
+		t.Errorf("Expected the authorization field to be masked, got %q", content)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var secretErr *ErrSecretField
+	if len(errs) != 1 || !errors.As(errs[0], &secretErr) || secretErr.Field != "Authorization" || !secretErr.Masked {
+		t.Errorf("Expected a single report of the authorization field, got %v", errs)
+	}
+}
+
+func TestSecretFieldsMaskedInRecent(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.SecretFields = SecretFieldsMask
+	config.RecentEntries = 10
+	config.RecentBelowMinLevel = true
+	config.ErrorHandler = func(error) {}
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	pl := logger.Package("auth")
+	pl.InfoWithFields("login", map[string]interface{}{"password": "hunter2"})
+	pl.DebugWithFields("login", map[string]interface{}{"password": "hunter2"})
+
+	var dump bytes.Buffer
+	if err := logger.DumpRecent(&dump); err != nil {
+		t.Fatalf("DumpRecent failed: %v", err)
+	}
+	if strings.Contains(dump.String(), "hunter2") || strings.Count(dump.String(), "password="+SecretMask) != 2 {
+		t.Errorf("Expected the recent entries to hold the masked field, got %q", dump.String())
+	}
+}
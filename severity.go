@@ -0,0 +1,61 @@
+package log4
+
+// Severity is how a level is reported to an external system
+type Severity struct {
+	Syslog int    // RFC 5424 severity, 0 (emergency) to 7 (debug)
+	GELF   int    // GELF level, which uses the syslog numbering
+	OTLP   int32  // OpenTelemetry SeverityNumber, 1 (TRACE) to 24 (FATAL4)
+	Text   string // Severity text for systems that take a name
+	CEF    int    // CEF and LEEF severity, 0 (lowest) to 10
+}
+
+// SeverityMap maps log levels to external severities. Sinks and formatters
+// for syslog, GELF, OTLP, cloud logging or a SIEM take a SeverityMap option;
+// levels not present fall back to DefaultSeverities.
+type SeverityMap map[LogLevel]Severity
+
+// DefaultSeverities is the mapping used when a sink is not given one
+var DefaultSeverities = SeverityMap{
+	DEBUG: {Syslog: 7, GELF: 7, OTLP: 5, Text: "DEBUG", CEF: 1},
+	INFO:  {Syslog: 6, GELF: 6, OTLP: 9, Text: "INFO", CEF: 3},
+	ERROR: {Syslog: 3, GELF: 3, OTLP: 17, Text: "ERROR", CEF: 7},
+}
+
+// Lookup returns the severity for level. Levels missing from m are looked up
+// in DefaultSeverities; levels missing there too use the closest lower level.
+func (m SeverityMap) Lookup(level LogLevel) Severity {
+	if s, ok := m[level]; ok {
+		return s
+	}
+	if s, ok := DefaultSeverities[level]; ok {
+		return s
+	}
+
+	best, found := DEBUG, false
+	for l := range DefaultSeverities {
+		if l <= level && (!found || l > best) {
+			best, found = l, true
+		}
+	}
+	if !found {
+		return DefaultSeverities[DEBUG]
+	}
+	return m.Lookup(best)
+}
+
+// With returns a copy of m with the given levels overridden, e.g. to report
+// INFO as syslog notice:
+//
+//	sev := log4.DefaultSeverities.With(log4.SeverityMap{
+//		log4.INFO: {Syslog: 5, GELF: 5, OTLP: 10, Text: "NOTICE"},
+//	})
+func (m SeverityMap) With(overrides SeverityMap) SeverityMap {
+	out := make(SeverityMap, len(m)+len(overrides))
+	for l, s := range m {
+		out[l] = s
+	}
+	for l, s := range overrides {
+		out[l] = s
+	}
+	return out
+}
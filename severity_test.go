@@ -0,0 +1,31 @@
+package log4
+
+import "testing"
+
+func TestSeverityMap(t *testing.T) {
+	custom := DefaultSeverities.With(SeverityMap{
+		INFO: {Syslog: 5, GELF: 5, OTLP: 10, Text: "NOTICE"},
+	})
+
+	if got := custom.Lookup(INFO); got.Syslog != 5 || got.Text != "NOTICE" {
+		t.Errorf("Override not applied: %+v", got)
+	}
+	if got := custom.Lookup(ERROR); got != DefaultSeverities[ERROR] {
+		t.Errorf("Expected default ERROR severity, got %+v", got)
+	}
+	if got := DefaultSeverities[INFO]; got.Syslog != 6 {
+		t.Errorf("With modified the receiver: %+v", got)
+	}
+
+	// A nil map uses the defaults and unknown levels use the closest lower one
+	var none SeverityMap
+	if got := none.Lookup(DEBUG); got.OTLP != 5 {
+		t.Errorf("Expected default DEBUG severity, got %+v", got)
+	}
+	if got := none.Lookup(LogLevel(7)); got != DefaultSeverities[ERROR] {
+		t.Errorf("Expected ERROR severity for unknown high level, got %+v", got)
+	}
+	if got := custom.Lookup(LogLevel(-1)); got != DefaultSeverities[DEBUG] {
+		t.Errorf("Expected DEBUG severity for unknown low level, got %+v", got)
+	}
+}
@@ -0,0 +1,75 @@
+package log4
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSharedFiles(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// Two loggers stand in for two worker processes
+	const perWorker = 200
+	var wg sync.WaitGroup
+	for _, worker := range []string{"a", "b"} {
+		config := DefaultConfig()
+		config.LogDir = tempDir
+		config.SharedFiles = true
+		config.MaxFileSize = 2048
+		config.MaxFiles = 100
+		config.BufferSize = perWorker
+		logger := NewChannelLoggerWithConfig(config)
+		logger.stdout = io.Discard
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				logger.Info("shared", fmt.Sprintf("worker %s entry %d", worker, i))
+			}
+			logger.Close()
+		}()
+	}
+	wg.Wait()
+
+	files, _ := filepath.Glob(filepath.Join(tempDir, "shared.log*"))
+	if len(files) < 2 {
+		t.Errorf("Expected the shared file to be rotated, got %v", files)
+	}
+	line := regexp.MustCompile(`^\[[^]]+\] INFO: worker [ab] entry \d+$`)
+	seen := make(map[string]bool)
+	for _, file := range files {
+		for _, l := range strings.Split(strings.TrimSpace(readFile(t, file)), "\n") {
+			if !line.MatchString(l) {
+				t.Errorf("Corrupted line in %s: %q", file, l)
+			}
+			seen[l[strings.Index(l, "worker"):]] = true
+		}
+	}
+	if len(seen) != 2*perWorker {
+		t.Errorf("Expected %d distinct entries, got %d", 2*perWorker, len(seen))
+	}
+	if !fileExists(filepath.Join(tempDir, "shared.lock")) {
+		t.Error("Expected rotation to use the lock file")
+	}
+}
+
+func TestSharedFilesValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.SharedFiles = true
+	config.AppendOnly = true
+	if err := config.Validate(); err == nil {
+		t.Error("SharedFiles with AppendOnly should be rejected")
+	}
+	config.AppendOnly = false
+	config.Compression = GzipCodec{}
+	if err := config.Validate(); err == nil {
+		t.Error("SharedFiles with Compression should be rejected")
+	}
+}
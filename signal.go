@@ -0,0 +1,102 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// RotateOnSignal rotates all log files whenever one of sigs is received,
+// SIGHUP if none are given, which only exists on Unix. This lets external
+// tools such as logrotate or log4ctl trigger rotation. The returned function
+// stops signal handling.
+func (cl *ChannelLogger) RotateOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		if defaultRotate == nil {
+			return func() {}
+		}
+		sigs = []os.Signal{defaultRotate}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	quit := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := cl.Rotate(); err != nil {
+					return
+				}
+			case <-quit:
+				return
+			case <-cl.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+}
+
+// LevelOnSignal lowers the minimum level one step toward DEBUG when down is
+// received and raises it one step toward ERROR when up is received. Nil
+// selects SIGUSR1 and SIGUSR2, which do not exist on Windows. Every change is
+// logged to InternalPackage whatever the new level. The returned function
+// stops signal handling.
+func (cl *ChannelLogger) LevelOnSignal(down, up os.Signal) (stop func()) {
+	if down == nil {
+		down = defaultLevelDown
+	}
+	if up == nil {
+		up = defaultLevelUp
+	}
+	if down == nil || up == nil {
+		return func() {}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, down, up)
+	quit := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				old := cl.GetMinLevel()
+				level := old
+				if sig == down && level > DEBUG {
+					level--
+				} else if sig == up && level < ERROR {
+					level++
+				}
+				if level == old {
+					continue
+				}
+				cl.logNotice(fmt.Sprintf("minimum level changed from %s to %s by %s", old, level, sig),
+					map[string]interface{}{"old_level": old.String(), "new_level": level.String(), "signal": sig.String()})
+				cl.SetMinLevel(level)
+			case <-quit:
+				return
+			case <-cl.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package log4
+
+import "os"
+
+// RotateOnSignal and LevelOnSignal have no default signals on this platform
+var (
+	defaultRotate    os.Signal
+	defaultLevelDown os.Signal
+	defaultLevelUp   os.Signal
+)
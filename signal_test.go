@@ -0,0 +1,77 @@
+//go:build unix
+
+package log4
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotateOnSignal(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+	defer logger.Close()
+
+	stop := logger.RotateOnSignal(syscall.SIGHUP)
+	defer stop()
+
+	logger.Info("sig", "Before signal")
+	time.Sleep(50 * time.Millisecond)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	logger.Info("sig", "After signal")
+	time.Sleep(50 * time.Millisecond)
+
+	rotated := readFile(t, filepath.Join(tempDir, "sig.log.1"))
+	if countLines(rotated) != 1 {
+		t.Errorf("Expected rotated file with one line, got %q", rotated)
+	}
+	current := readFile(t, filepath.Join(tempDir, "sig.log"))
+	if countLines(current) != 1 {
+		t.Errorf("Expected fresh file with one line, got %q", current)
+	}
+}
+
+func TestLevelOnSignal(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+	logger.stdout = io.Discard
+	stop := logger.LevelOnSignal(nil, nil)
+	defer stop()
+
+	waitForLevel := func(want LogLevel) {
+		t.Helper()
+		for i := 0; i < 100 && logger.GetMinLevel() != want; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := logger.GetMinLevel(); got != want {
+			t.Fatalf("Expected level %s, got %s", want, got)
+		}
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitForLevel(INFO)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitForLevel(ERROR)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitForLevel(INFO)
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, InternalPackage+".log"))
+	if countLines(content) != 3 || !strings.Contains(content, "minimum level changed from INFO to ERROR") ||
+		!strings.Contains(content, "new_level=INFO") {
+		t.Errorf("Expected every change to be logged, got:\n%s", content)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package log4
+
+import (
+	"os"
+	"syscall"
+)
+
+// Default signals of RotateOnSignal and LevelOnSignal
+var (
+	defaultRotate    os.Signal = syscall.SIGHUP
+	defaultLevelDown os.Signal = syscall.SIGUSR1
+	defaultLevelUp   os.Signal = syscall.SIGUSR2
+)
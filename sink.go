@@ -0,0 +1,89 @@
+package log4
+
+import (
+	"io"
+)
+
+// Sink receives every entry written by the logger, in addition to the console
+// and the per-package log files. Sinks are only ever called from the logging
+// goroutine, so implementations do not need their own locking.
+//
+// line is the formatted entry followed by Config.RecordSeparator (a newline
+// by default), or preceded by its length for a LengthPrefixer with
+// Config.LengthPrefixSinks, and may not be retained after Write returns.
+// StreamSink and AlertSink receive the formatted entry alone. The
+// entry is reused once Write returns unless the sink calls entry.Retain (and
+// later entry.Release) or keeps entry.Clone().
+//
+// Entries logged with LogWithContext carry the caller's context in
+// entry.Context; sinks performing network I/O may use it to abandon work once
+// the caller's context is cancelled, as the built-in network sinks do with
+// BatchOptions.DropCancelled.
+type Sink interface {
+	Write(entry *LogEntry, line []byte) error
+	Close() error
+}
+
+// Flusher is implemented by sinks that buffer output. The logger flushes its
+// sinks whenever the queue runs empty and on Close.
+type Flusher interface {
+	Flush() error
+}
+
+// LengthPrefixer is implemented by sinks carrying a binary protocol. With
+// Config.LengthPrefixSinks, sinks whose LengthPrefixed returns true receive
+// each entry preceded by its length instead of followed by the separator;
+// other sinks, such as StreamSink, keep receiving lines.
+type LengthPrefixer interface {
+	LengthPrefixed() bool
+}
+
+// recordSink is implemented by sinks that keep each entry as a line of text
+// of their own, such as StreamSink, so they receive it without the separator
+type recordSink interface {
+	textRecords() bool
+}
+
+// WriterSink writes formatted entries to an io.Writer, optionally compressed
+type WriterSink struct {
+	w  io.Writer
+	fw *frameWriter
+}
+
+// NewWriterSink creates a sink writing to w. If codec is non-nil the stream is
+// compressed inline using framing that allows the output to be tailed.
+func NewWriterSink(w io.Writer, codec Codec) *WriterSink {
+	s := &WriterSink{w: w}
+	if codec != nil {
+		s.fw = newFrameWriter(w, codec, DefaultFrameSize)
+		s.w = s.fw
+	}
+	return s
+}
+
+// Write writes the formatted line
+func (s *WriterSink) Write(entry *LogEntry, line []byte) error {
+	_, err := s.w.Write(line)
+	return err
+}
+
+// LengthPrefixed returns true: with Config.LengthPrefixSinks the stream is
+// written as length-prefixed records
+func (s *WriterSink) LengthPrefixed() bool { return true }
+
+// Flush pushes the open compressed frame, if any, to the writer
+func (s *WriterSink) Flush() error {
+	if s.fw != nil {
+		return s.fw.Flush()
+	}
+	return nil
+}
+
+// Close finishes the compressed frame, if any. The underlying writer is owned
+// by the caller and is not closed.
+func (s *WriterSink) Close() error {
+	if s.fw != nil {
+		return s.fw.Close()
+	}
+	return nil
+}
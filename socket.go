@@ -0,0 +1,395 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// SocketOptions configures a SocketSink
+type SocketOptions struct {
+	Path string // Unix socket of the SocketServer
+
+	BatchOptions
+
+	ErrorHandler func(error) // Delivery errors (default: printed to stderr)
+}
+
+// SocketSink sends entries to a SocketServer over a unix socket, so that
+// several processes can log through one process owning the files. Each entry
+// is framed as a MessagePack map; entries are sent in batches from a
+// background goroutine and the connection is re-established after failures.
+type SocketSink struct {
+	opts    SocketOptions
+	batcher *batcher[socketEntry]
+	onError func(error)
+
+	conn net.Conn // Only used by the export goroutine
+}
+
+// socketEntry is an encoded frame waiting for export
+type socketEntry struct {
+	data  []byte
+	entry *LogEntry       // Copy kept for the dead-letter file, if one is set
+	ctx   context.Context // Context the entry was logged with, if any
+}
+
+// NewSocketSink creates a unix socket sink. The connection is opened lazily
+// by the first export.
+func NewSocketSink(opts SocketOptions) *SocketSink {
+	opts.BatchOptions = opts.BatchOptions.withDefaults()
+
+	s := &SocketSink{opts: opts}
+	s.onError = sinkErrorHandler(opts.ErrorHandler)
+	s.batcher = newBatcher(opts.BatchOptions, s.export, s.onError, func(e socketEntry) context.Context { return e.ctx })
+	return s
+}
+
+// Write encodes the entry and queues it for sending
+func (s *SocketSink) Write(entry *LogEntry, line []byte) error {
+	e := socketEntry{data: encodeSocketEntry(entry)}
+	if s.opts.DeadLetter != nil {
+		e.entry = entry.Clone()
+	}
+	e.ctx = entry.Context
+	s.batcher.add(e)
+	return nil
+}
+
+// Close sends the queued entries and closes the connection
+func (s *SocketSink) Close() error {
+	s.batcher.close()
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full or
+// they could not be delivered
+func (s *SocketSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// export writes a batch of frames, connecting first if needed
+func (s *SocketSink) export(ctx context.Context, batch []socketEntry) error {
+	var msg []byte
+	for _, e := range batch {
+		msg = append(msg, e.data...)
+	}
+
+	err := retryExport(ctx, s.opts.MaxRetries, s.opts.RetryBackoff, func() error {
+		if s.opts.Seal == nil {
+			return s.write(msg)
+		}
+		// Every attempt is sealed anew, so the server does not take it for a replay
+		sealed, err := s.sealFrames(batch)
+		if err != nil {
+			return err
+		}
+		return s.write(sealed)
+	})
+	if err == nil {
+		return nil
+	}
+
+	if s.opts.DeadLetter != nil {
+		undelivered := make([]*LogEntry, len(batch))
+		for i, e := range batch {
+			undelivered[i] = e.entry
+		}
+		s.opts.DeadLetter.record(undelivered, "socket", err, s.onError)
+	}
+	return fmt.Errorf("sending %d entries to %s failed: %w", len(batch), s.opts.Path, err)
+}
+
+// maxSealedPayload bounds the frames sealed together, so that a sealed frame
+// stays within what readMsgpack accepts
+const maxSealedPayload = maxMsgpackLen / 2
+
+// sealFrames seals the frames of a batch into frames of the form
+// {"sealed": payload, seal...}, each holding as many frames as fit in
+// maxSealedPayload
+func (s *SocketSink) sealFrames(batch []socketEntry) ([]byte, error) {
+	var msg, payload []byte
+	flush := func() error {
+		sealed, err := s.opts.Seal.seal(payload)
+		if err != nil {
+			return err
+		}
+		msg = appendMsgpackMapHeader(msg, 1+sealed.msgpackSealOptions())
+		msg = appendMsgpackString(msg, "sealed")
+		msg = appendMsgpackBinary(msg, sealed.Payload)
+		msg = sealed.appendMsgpackSeal(msg)
+		payload = nil
+		return nil
+	}
+	for _, e := range batch {
+		if len(payload) > 0 && len(payload)+len(e.data) > maxSealedPayload {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		payload = append(payload, e.data...)
+	}
+	if len(payload) > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// write sends msg, connecting first if needed
+func (s *SocketSink) write(msg []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("unix", s.opts.Path, s.opts.Timeout)
+		if err != nil {
+			return retryable(err)
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.opts.Timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return retryable(err)
+	}
+	return nil
+}
+
+// encodeSocketEntry encodes an entry as the frame read by SocketServer
+func encodeSocketEntry(entry *LogEntry) []byte {
+	n := 5
+	if entry.Tenant != "" {
+		n++
+	}
+	if len(entry.Labels) > 0 {
+		n++
+	}
+	b := appendMsgpackMapHeader(nil, n)
+	b = appendMsgpackString(b, "time")
+	b = appendMsgpackInt(b, entry.Timestamp.UnixNano())
+	b = appendMsgpackString(b, "package")
+	b = appendMsgpackString(b, entry.Package)
+	b = appendMsgpackString(b, "level")
+	b = appendMsgpackInt(b, int64(entry.Level))
+	b = appendMsgpackString(b, "message")
+	b = appendMsgpackString(b, entry.Message)
+	b = appendMsgpackString(b, "fields")
+	b = appendMsgpackValue(b, entry.Fields)
+	if entry.Tenant != "" {
+		b = appendMsgpackString(b, "tenant")
+		b = appendMsgpackString(b, entry.Tenant)
+	}
+	if len(entry.Labels) > 0 {
+		labels := make(map[string]interface{}, len(entry.Labels))
+		for k, v := range entry.Labels {
+			labels[k] = v
+		}
+		b = appendMsgpackString(b, "labels")
+		b = appendMsgpackValue(b, labels)
+	}
+	return b
+}
+
+// decodeSocketEntry fills entry from a frame decoded by readMsgpack
+func decodeSocketEntry(v interface{}, entry *LogEntry) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected frame %T", v)
+	}
+	switch t := m["time"].(type) {
+	case int64:
+		entry.Timestamp = time.Unix(0, t)
+	case uint64:
+		entry.Timestamp = time.Unix(0, int64(t))
+	default:
+		return fmt.Errorf("frame without time")
+	}
+	switch l := m["level"].(type) {
+	case int64:
+		entry.Level = LogLevel(l)
+	case uint64:
+		entry.Level = LogLevel(l)
+	}
+	entry.Package, _ = m["package"].(string)
+	entry.Tenant, _ = m["tenant"].(string)
+	entry.Message, _ = m["message"].(string)
+	if fields, ok := m["fields"].(map[string]interface{}); ok {
+		for k, v := range fields {
+			entry.Fields[k] = v
+		}
+	}
+	if labels, ok := m["labels"].(map[string]interface{}); ok {
+		for k, v := range labels {
+			if s, ok := v.(string); ok {
+				entry.SetLabel(k, s)
+			}
+		}
+	}
+	return nil
+}
+
+// SocketServer receives entries from SocketSinks over a unix socket and logs
+// them through a ChannelLogger, so one process owns the files, rotation and
+// sinks while others, such as pre-fork workers, only connect to it.
+type SocketServer struct {
+	logger   *ChannelLogger
+	listener net.Listener
+	opts     SocketServerOptions
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// SocketServerOptions configures a SocketServer
+type SocketServerOptions struct {
+	// Seal opens the sealed frames of SocketSinks with a Seal. If set, a
+	// connection sending a frame that is not sealed, or does not open, is
+	// closed.
+	Seal *Seal
+}
+
+// NewSocketServer listens on the unix socket at path, replacing a stale
+// socket file, and logs every entry received through logger. The logger's
+// levels, outputs and formatter apply as for any other entry; closing the
+// server does not close the logger.
+func NewSocketServer(path string, logger *ChannelLogger) (*SocketServer, error) {
+	return NewSocketServerWithOptions(path, logger, SocketServerOptions{})
+}
+
+// NewSocketServerWithOptions is NewSocketServer configured by opts
+func NewSocketServerWithOptions(path string, logger *ChannelLogger, opts SocketServerOptions) (*SocketServer, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SocketServer{logger: logger, listener: listener, opts: opts, conns: make(map[net.Conn]struct{})}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *SocketServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections, closes the open ones and removes the
+// socket file. Entries received before Close are passed to the logger.
+func (s *SocketServer) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *SocketServer) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // Listener closed
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+// serve logs the entries of one connection until it is closed or sends an
+// invalid frame
+func (s *SocketServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		v, err := readMsgpack(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.handleError(fmt.Errorf("socket server: reading frame: %w", err))
+			}
+			return
+		}
+		if err := s.receive(v); err != nil {
+			s.logger.handleError(fmt.Errorf("socket server: %w", err))
+			return
+		}
+	}
+}
+
+// receive logs the entry of a frame, or the entries of a sealed frame
+func (s *SocketServer) receive(v interface{}) error {
+	m, _ := v.(map[string]interface{})
+	payload, sealed := m["sealed"].([]byte)
+	switch {
+	case !sealed && s.opts.Seal != nil:
+		return errors.New("frame is not sealed")
+	case !sealed:
+		return s.receiveEntry(v)
+	case s.opts.Seal == nil:
+		return errors.New("sealed frame, but no Seal is set")
+	}
+
+	payload, err := s.opts.Seal.Open(sealedFromMsgpack(payload, m))
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(bytes.NewReader(payload))
+	for {
+		v, err := readMsgpack(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading sealed frame: %w", err)
+		}
+		if err := s.receiveEntry(v); err != nil {
+			return err
+		}
+	}
+}
+
+// receiveEntry logs the entry of a frame
+func (s *SocketServer) receiveEntry(v interface{}) error {
+	entry := getLogEntry()
+	if err := decodeSocketEntry(v, entry); err != nil {
+		entry.Release()
+		return err
+	}
+	s.logger.logEntry(entry)
+	return nil
+}
@@ -0,0 +1,87 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketServer(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.JSON = true
+	config.MinLevel = INFO
+	daemon := NewChannelLoggerWithConfig(config)
+	daemon.stdout = io.Discard
+	defer daemon.Close()
+
+	socketPath := filepath.Join(tempDir, "log4.sock")
+	server, err := NewSocketServer(socketPath, daemon)
+	if err != nil {
+		t.Fatalf("NewSocketServer failed: %v", err)
+	}
+	defer server.Close()
+
+	// Two workers log through the daemon
+	const perWorker = 50
+	for w := 0; w < 2; w++ {
+		worker := NewLogger(WithDir(t.TempDir()), WithSink(NewSocketSink(SocketOptions{
+			Path:         socketPath,
+			BatchOptions: BatchOptions{BatchTimeout: 10 * time.Millisecond},
+		})), func(c *Config) { c.DisableConsole = true })
+		for i := 0; i < perWorker; i++ {
+			worker.Tenant("acme").Package("api").InfoWithFields(fmt.Sprintf("Request %d", i),
+				map[string]interface{}{"worker": w, "status": 200})
+		}
+		worker.Debug("api", "Filtered by the daemon")
+		worker.Close()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for daemon.Stats().Written < 2*perWorker && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	server.Close()
+	daemon.Close()
+
+	content := readFile(t, filepath.Join(tempDir, "acme", "api.log"))
+	if n := countLines(content); n != 2*perWorker {
+		t.Errorf("Expected %d entries in the daemon's file, got %d", 2*perWorker, n)
+	}
+	if !strings.Contains(content, `"tenant":"acme"`) || !strings.Contains(content, `"status":200`) {
+		t.Errorf("Expected tenant and fields to survive the socket, got:\n%s", content)
+	}
+	if fileExists(filepath.Join(tempDir, "api.log")) {
+		t.Error("Debug entries should be filtered by the daemon's level")
+	}
+	if fileExists(socketPath) {
+		t.Error("Expected Close to remove the socket file")
+	}
+}
+
+func TestSocketEntryRoundTrip(t *testing.T) {
+	entry := &LogEntry{Tenant: "acme", Package: "db", Level: ERROR, Message: "Failed",
+		Timestamp: time.Unix(1700000000, 123456789), Fields: map[string]interface{}{"attempt": 3}}
+
+	decoded := getLogEntry()
+	defer decoded.Release()
+	v, err := readMsgpack(bufio.NewReader(bytes.NewReader(encodeSocketEntry(entry))))
+	if err != nil {
+		t.Fatalf("Decoding failed: %v", err)
+	}
+	if err := decodeSocketEntry(v, decoded); err != nil {
+		t.Fatalf("decodeSocketEntry failed: %v", err)
+	}
+	if decoded.Tenant != "acme" || decoded.Package != "db" || decoded.Level != ERROR ||
+		decoded.Message != "Failed" || !decoded.Timestamp.Equal(entry.Timestamp) || decoded.Fields["attempt"] != int64(3) {
+		t.Errorf("Unexpected round trip %+v", decoded)
+	}
+}
@@ -0,0 +1,145 @@
+package log4
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// spillChunk is the smallest capacity the spill buffer grows from and
+// shrinks to
+const spillChunk = 64
+
+// spillBuffer extends the shared queue beyond BufferSize up to
+// Config.MaxBufferSize. Entries that find the queue full are appended to it,
+// as are all entries while it holds any, so that they stay in order; a
+// forwarding goroutine moves them to the queue as room frees up. Its backing
+// array grows by doubling while bursts fill it and is halved whenever it
+// drains after a period in which it stayed below a quarter of its capacity,
+// so memory follows the observed occupancy.
+type spillBuffer struct {
+	mu      sync.Mutex
+	entries []*LogEntry // entries[head:] are waiting
+	head    int
+	limit   int  // Most entries held at once
+	peak    int  // Most entries held since it last drained
+	moving  bool // An entry was popped but is not in the queue yet
+	wake    chan struct{}
+	stop    chan struct{}
+
+	pushed    atomic.Uint64 // Entries appended
+	forwarded atomic.Uint64 // Entries moved to the queue
+}
+
+// newSpillBuffer creates the spill buffer of Config.MaxBufferSize, or
+// returns nil if the queue has a fixed size
+func newSpillBuffer(config *Config) *spillBuffer {
+	if config.MaxBufferSize <= config.BufferSize {
+		return nil
+	}
+	return &spillBuffer{
+		limit: config.MaxBufferSize - config.BufferSize,
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+}
+
+// len returns the number of entries waiting, including one being moved to
+// the queue, which later entries must not overtake
+func (s *spillBuffer) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.entries) - s.head
+	if s.moving {
+		n++
+	}
+	return n
+}
+
+// capacity returns the size of the backing array
+func (s *spillBuffer) capacity() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cap(s.entries)
+}
+
+// push appends an entry, reporting false if the buffer is at its limit
+func (s *spillBuffer) push(entry *LogEntry) bool {
+	s.mu.Lock()
+	n := len(s.entries) - s.head
+	if n >= s.limit {
+		s.mu.Unlock()
+		return false
+	}
+	if len(s.entries) == cap(s.entries) {
+		size := cap(s.entries)
+		if n >= size/2 {
+			size = min(max(2*size, spillChunk), s.limit)
+		}
+		grown := make([]*LogEntry, n, size)
+		copy(grown, s.entries[s.head:])
+		s.entries, s.head = grown, 0
+	}
+	s.entries = append(s.entries, entry)
+	s.peak = max(s.peak, n+1)
+	s.pushed.Add(1)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default: // Forwarder already woken
+	}
+	return true
+}
+
+// pop removes the oldest entry, reporting false if there is none. It counts
+// as waiting until moved is called.
+func (s *spillBuffer) pop() (*LogEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.head == len(s.entries) {
+		if c := cap(s.entries); c > spillChunk && s.peak < c/4 {
+			s.entries = make([]*LogEntry, 0, c/2)
+		} else {
+			s.entries = s.entries[:0]
+		}
+		s.head, s.peak = 0, 0
+		return nil, false
+	}
+	entry := s.entries[s.head]
+	s.entries[s.head] = nil
+	s.head++
+	s.moving = true
+	return entry, true
+}
+
+// moved records that the entry last popped is in the queue
+func (s *spillBuffer) moved() {
+	s.mu.Lock()
+	s.moving = false
+	s.forwarded.Add(1)
+	s.mu.Unlock()
+}
+
+// startSpill starts the goroutine moving spilled entries to the shared queue
+func (cl *ChannelLogger) startSpill() {
+	s := cl.spill
+	cl.queueWg.Add(1)
+	go func() {
+		defer cl.queueWg.Done()
+		for {
+			for entry, ok := s.pop(); ok; entry, ok = s.pop() {
+				cl.logChan <- entry
+				s.moved()
+			}
+			select {
+			case <-s.wake:
+			case <-s.stop:
+				for entry, ok := s.pop(); ok; entry, ok = s.pop() {
+					cl.logChan <- entry
+					s.moved()
+				}
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package log4
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxBufferSize(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.BufferSize = 10
+	config.MaxBufferSize = 510
+	config.Sinks = []Sink{sink}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 300; i++ {
+		logger.Info("app", fmt.Sprintf("burst %d", i))
+	}
+
+	stats := logger.Stats()
+	if stats.Dropped != 0 {
+		t.Errorf("Expected the queue to grow instead of dropping, dropped %d", stats.Dropped)
+	}
+	if stats.QueueLength != 300 || stats.QueueCapacity <= 300 || stats.QueueCapacity > 510 {
+		t.Errorf("Expected 300 entries within the grown capacity, got %d of %d", stats.QueueLength, stats.QueueCapacity)
+	}
+
+	for i := 0; i < 300; i++ {
+		logger.Info("app", "overflow")
+	}
+	if logger.Stats().Dropped == 0 {
+		t.Error("Expected entries beyond MaxBufferSize to be dropped")
+	}
+
+	close(sink.release)
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readFile(t, filepath.Join(tempDir, "app.log"))), "\n")
+	if len(lines) < 301 {
+		t.Fatalf("Expected at least 301 entries, got %d", len(lines))
+	}
+	for i := 0; i < 300; i++ {
+		if want := fmt.Sprintf("burst %d", i); !strings.HasSuffix(lines[i+1], want) {
+			t.Fatalf("Expected entry %d to be %q, got %q", i+1, want, lines[i+1])
+		}
+	}
+}
+
+func TestSpillBufferShrinks(t *testing.T) {
+	s := newSpillBuffer(&Config{BufferSize: 1, MaxBufferSize: 1025})
+	for i := 0; i < 1024; i++ {
+		if !s.push(&LogEntry{}) {
+			t.Fatalf("Expected push %d to fit", i)
+		}
+	}
+	if s.push(&LogEntry{}) {
+		t.Error("Expected push beyond the limit to fail")
+	}
+	if s.capacity() != 1024 {
+		t.Errorf("Expected capacity 1024, got %d", s.capacity())
+	}
+	for _, ok := s.pop(); ok; _, ok = s.pop() {
+	}
+
+	// Small bursts let the capacity fall back step by step
+	for s.capacity() > spillChunk {
+		before := s.capacity()
+		s.push(&LogEntry{})
+		for _, ok := s.pop(); ok; _, ok = s.pop() {
+		}
+		if s.capacity() != before/2 {
+			t.Fatalf("Expected capacity %d to halve, got %d", before, s.capacity())
+		}
+	}
+
+	if newSpillBuffer(&Config{BufferSize: 10, MaxBufferSize: 10}) != nil {
+		t.Error("Expected no spill buffer without room beyond BufferSize")
+	}
+}
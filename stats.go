@@ -0,0 +1,65 @@
+package log4
+
+import "time"
+
+// Stats is a point-in-time snapshot of logger activity
+type Stats struct {
+	Written       uint64 `json:"written"`        // Entries written
+	Dropped       uint64 `json:"dropped"`        // Entries dropped for any DropReason but DropClosed
+	Errors        uint64 `json:"errors"`         // Internal errors reported
+	ErrorOverflow uint64 `json:"error_overflow"` // Errors written to Config.ErrorOverflow while ErrorHandler fell behind
+	QueueLength   int    `json:"queue_length"`   // Entries waiting to be written
+	QueueCapacity int    `json:"queue_capacity"` // Size of the queue, as grown so far with MaxBufferSize
+	OpenFiles     int    `json:"open_files"`     // Package log files currently open
+	Throttled     bool   `json:"throttled"`      // Config.Throttle is in effect
+
+	Stale       uint64        `json:"stale"`            // Entries dropped after waiting longer than MaxEntryAge
+	MaxEntryAge time.Duration `json:"max_entry_age_ns"` // Config.MaxEntryAge, 0 if entries never expire
+
+	Violations uint64 `json:"schema_violations"` // Entries whose fields did not match their FieldSchema
+
+	Panics      uint64 `json:"panics"`       // Panics the logging goroutine recovered from
+	BreakerOpen bool   `json:"breaker_open"` // Entries are dropped after repeated panics
+
+	Queues   map[string]QueueStats `json:"queues,omitempty"`   // Config.Queues by pattern
+	Priority *QueueStats           `json:"priority,omitempty"` // Priority lane, if PriorityBufferSize is set
+
+	Latency *LatencyHistogram `json:"latency,omitempty"` // Queue-to-write latency, if LatencyHistogram is set
+}
+
+// Stats returns counters describing the logger's activity (thread-safe)
+func (cl *ChannelLogger) Stats() Stats {
+	cl.mu.RLock()
+	openFiles := len(cl.files)
+	cl.mu.RUnlock()
+
+	queueLength, queueCapacity := len(cl.logChan), cap(cl.logChan)
+	if cl.spill != nil {
+		queueLength += cl.spill.len()
+		queueCapacity += cl.spill.capacity()
+	}
+
+	var latency *LatencyHistogram
+	if cl.latency != nil {
+		latency = cl.latency.snapshot()
+	}
+
+	return Stats{
+		Written:       cl.written.Load(),
+		Dropped:       cl.dropped.Load(),
+		Errors:        cl.errCount.Load(),
+		ErrorOverflow: cl.errOver.Load(),
+		QueueLength:   queueLength,
+		QueueCapacity: queueCapacity,
+		OpenFiles:     openFiles,
+		Throttled:     cl.throttle != nil && cl.throttle.active.Load(),
+		Stale:         cl.stale.Load(),
+		MaxEntryAge:   cl.config.MaxEntryAge,
+		Violations:    cl.invalid.Load(),
+		Panics:        cl.breaker.count.Load(),
+		BreakerOpen:   cl.breaker.open.Load(),
+		Queues:        cl.queueStats(),
+		Priority:      cl.priorityStats(),
+		Latency:       latency,
+	}
+}
@@ -0,0 +1,390 @@
+package log4
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultStreamClientBuffer is the number of entries queued per streaming
+// client before entries for that client are dropped
+const DefaultStreamClientBuffer = 256
+
+// StreamSink fans written entries out to live subscribers, typically HTTP
+// clients connected through Handler. Slow subscribers never block the logger;
+// entries that do not fit in a subscriber's buffer are dropped for that
+// subscriber only.
+type StreamSink struct {
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	bufferSize  int
+	dropped     atomic.Uint64
+	closed      bool
+	origins     map[string]bool // Cross-origin WebSocket clients allowed
+}
+
+// streamSubscriber is a single live consumer with its own filter
+type streamSubscriber struct {
+	ch       chan RecentEntry
+	minLevel LogLevel
+	pkg      string
+}
+
+// NewStreamSink creates a streaming sink. bufferSize is the per-subscriber
+// queue length, DefaultStreamClientBuffer if not positive.
+func NewStreamSink(bufferSize int) *StreamSink {
+	if bufferSize <= 0 {
+		bufferSize = DefaultStreamClientBuffer
+	}
+	return &StreamSink{
+		subscribers: make(map[*streamSubscriber]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Write delivers the entry to every subscriber whose filter matches
+func (s *StreamSink) Write(entry *LogEntry, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.subscribers) == 0 {
+		return nil
+	}
+
+	e := RecentEntry{
+		Package:   entry.Package,
+		Tenant:    entry.Tenant,
+		Level:     entry.Level,
+		Timestamp: entry.Timestamp,
+		Line:      string(line),
+	}
+	for sub := range s.subscribers {
+		if e.Level < sub.minLevel || (sub.pkg != "" && sub.pkg != e.Package) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// textRecords asks the logger for entries without Config.RecordSeparator
+func (s *StreamSink) textRecords() bool { return true }
+
+// Close disconnects all subscribers
+func (s *StreamSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers {
+		close(sub.ch)
+		delete(s.subscribers, sub)
+	}
+	s.closed = true
+	return nil
+}
+
+// Subscribe registers a consumer for entries at or above minLevel, optionally
+// limited to one package. The channel is closed when the sink closes or the
+// returned cancel function is called.
+func (s *StreamSink) Subscribe(minLevel LogLevel, pkg string) (<-chan RecentEntry, func()) {
+	sub := &streamSubscriber{
+		ch:       make(chan RecentEntry, s.bufferSize),
+		minLevel: minLevel,
+		pkg:      pkg,
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		close(sub.ch)
+	} else {
+		s.subscribers[sub] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			if _, ok := s.subscribers[sub]; ok {
+				delete(s.subscribers, sub)
+				close(sub.ch)
+			}
+			s.mu.Unlock()
+		})
+	}
+}
+
+// Subscribers returns the number of connected subscribers
+func (s *StreamSink) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+// AllowOrigins lets web pages served from other origins, such as
+// "https://dashboard.example.com", open WebSocket streams. "*" allows every
+// origin. By default only pages served by the same host as the handler may
+// connect. Requests without an Origin header come from programs rather than
+// browsers and are not restricted.
+func (s *StreamSink) AllowOrigins(origins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.origins == nil {
+		s.origins = make(map[string]bool, len(origins))
+	}
+	for _, o := range origins {
+		s.origins[strings.ToLower(o)] = true
+	}
+}
+
+// allowOrigin reports whether the page that opened a WebSocket request may
+// read the stream: browsers do not apply the same-origin policy to WebSocket
+// connections, so any page could otherwise read the logs
+func (s *StreamSink) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.origins["*"] || s.origins[strings.ToLower(origin)]
+}
+
+// Dropped returns the number of entries dropped for slow subscribers
+func (s *StreamSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Handler returns an http.Handler streaming entries as JSON objects, one per
+// event. WebSocket upgrade requests receive one text message per entry; all
+// other requests receive a Server-Sent Events stream. WebSocket requests from
+// pages of another origin are rejected unless allowed with AllowOrigins. The
+// optional query parameters level and package filter the stream:
+//
+//	GET /stream?level=ERROR&package=db
+func (s *StreamSink) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var minLevel LogLevel
+		if lvl := r.URL.Query().Get("level"); lvl != "" {
+			if err := minLevel.UnmarshalText([]byte(lvl)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		websocket := isWebSocketUpgrade(r)
+		if websocket && !s.allowOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		entries, cancel := s.Subscribe(minLevel, r.URL.Query().Get("package"))
+		defer cancel()
+
+		if websocket {
+			s.serveWebSocket(w, r, entries)
+		} else {
+			s.serveSSE(w, r, entries)
+		}
+	})
+}
+
+func (s *StreamSink) serveSSE(w http.ResponseWriter, r *http.Request, entries <-chan RecentEntry) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(e)
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// websocketGUID is the fixed key suffix from RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes used by the server
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") &&
+		r.Header.Get("Sec-WebSocket-Key") != ""
+}
+
+// serveWebSocket implements the server side of RFC 6455 needed for a one-way
+// stream: the handshake, unfragmented text messages, ping and close
+func (s *StreamSink) serveWebSocket(w http.ResponseWriter, r *http.Request, entries <-chan RecentEntry) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket unsupported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	// Control frames from the client are answered by the writer loop
+	var writeMu sync.Mutex
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			op, payload, err := readWebSocketFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch op {
+			case wsOpClose:
+				writeMu.Lock()
+				writeWebSocketFrame(rw.Writer, wsOpClose, payload)
+				writeMu.Unlock()
+				return
+			case wsOpPing:
+				writeMu.Lock()
+				writeWebSocketFrame(rw.Writer, wsOpPong, payload)
+				writeMu.Unlock()
+			}
+		}
+	}()
+
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				writeMu.Lock()
+				writeWebSocketFrame(rw.Writer, wsOpClose, nil)
+				writeMu.Unlock()
+				return
+			}
+			data, _ := json.Marshal(e)
+			writeMu.Lock()
+			err := writeWebSocketFrame(rw.Writer, wsOpText, data)
+			writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// writeWebSocketFrame writes a single unmasked, unfragmented frame
+func writeWebSocketFrame(w *bufio.Writer, op byte, payload []byte) error {
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// readWebSocketFrame reads a single client frame and unmasks its payload.
+// Client frames are small control messages, so payloads over 64KiB are
+// rejected.
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > 64*1024 {
+		return 0, nil, fmt.Errorf("websocket frame too large: %d bytes", length)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return op, payload, nil
+}
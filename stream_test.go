@@ -0,0 +1,178 @@
+package log4
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStreamingLogger(t *testing.T, tempDir string) (*ChannelLogger, *StreamSink) {
+	stream := NewStreamSink(0)
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{stream}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	return logger, stream
+}
+
+func waitForSubscribers(t *testing.T, stream *StreamSink, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for stream.Subscribers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d subscribers", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamSinkSubscribe(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger, stream := newStreamingLogger(t, tempDir)
+	defer logger.Close()
+
+	errors, cancel := stream.Subscribe(ERROR, "db")
+	defer cancel()
+
+	logger.Error("api", "Wrong package")
+	logger.Info("db", "Wrong level")
+	logger.Error("db", "Match")
+
+	select {
+	case e := <-errors:
+		if e.Package != "db" || !strings.HasSuffix(e.Line, "ERROR: Match") {
+			t.Errorf("Unexpected entry: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No entry received")
+	}
+
+	cancel()
+	if stream.Subscribers() != 0 {
+		t.Error("Cancel should remove the subscriber")
+	}
+}
+
+func TestStreamSinkSSE(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger, stream := newStreamingLogger(t, tempDir)
+	defer logger.Close()
+
+	server := httptest.NewServer(stream.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?level=INFO")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	waitForSubscribers(t, stream, 1)
+
+	logger.Debug("api", "Filtered")
+	logger.Info("api", "Streamed")
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	var e RecentEntry
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+		t.Fatalf("Invalid event %q: %v", line, err)
+	}
+	if e.Level != INFO || !strings.Contains(e.Line, "Streamed") {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+}
+
+func TestStreamSinkWebSocket(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger, stream := newStreamingLogger(t, tempDir)
+	defer logger.Close()
+
+	server := httptest.NewServer(stream.Handler())
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Handshake example from RFC 6455
+	io.WriteString(conn, "GET /?package=ws HTTP/1.1\r\nHost: test\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response: %d %v", resp.StatusCode, resp.Header)
+	}
+	waitForSubscribers(t, stream, 1)
+
+	logger.Info("ws", "Over websocket")
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	op, payload, err := readWebSocketFrame(r)
+	if err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if op != wsOpText || !strings.Contains(string(payload), "Over websocket") {
+		t.Errorf("Unexpected frame %d %q", op, payload)
+	}
+
+	// A masked close frame from the client ends the stream
+	conn.Write([]byte{0x80 | wsOpClose, 0x80, 1, 2, 3, 4})
+	waitForSubscribers(t, stream, 0)
+}
+
+func TestStreamSinkWebSocketOrigin(t *testing.T) {
+	stream := NewStreamSink(0)
+	defer stream.Close()
+	h := stream.Handler()
+
+	upgrade := func(origin string) int {
+		req := httptest.NewRequest("GET", "http://logs.internal/stream", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := upgrade("https://evil.example"); code != http.StatusForbidden {
+		t.Errorf("Expected a cross-origin upgrade to be rejected, got %d", code)
+	}
+	// The recorder cannot be hijacked, so accepted upgrades fail later on
+	if code := upgrade("http://logs.internal"); code == http.StatusForbidden {
+		t.Error("Expected a same-origin upgrade to be accepted")
+	}
+	stream.AllowOrigins("https://dashboard.example")
+	if code := upgrade("https://dashboard.example"); code == http.StatusForbidden {
+		t.Error("Expected an allowed origin to be accepted")
+	}
+	if code := upgrade("https://evil.example"); code != http.StatusForbidden {
+		t.Errorf("Expected other origins to stay rejected, got %d", code)
+	}
+}
@@ -0,0 +1,91 @@
+package log4
+
+import "sort"
+
+// WithLabel returns a PackageLogger adding the label key=value to every
+// entry it logs, in addition to those bound to pl. Labels identify a stream
+// of entries and should take few distinct values, such as the service, the
+// environment or the region; use WithField for values that vary per entry,
+// such as user or request IDs, which would make indexing backends create a
+// stream for each.
+func (pl *PackageLogger) WithLabel(key, value string) *PackageLogger {
+	return pl.WithLabels(map[string]string{key: value})
+}
+
+// WithLabels returns a PackageLogger adding labels to every entry it logs;
+// see WithLabel
+func (pl *PackageLogger) WithLabels(labels map[string]string) *PackageLogger {
+	merged := make(map[string]string, len(pl.labels)+len(labels))
+	for k, v := range pl.labels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	with := *pl
+	with.labels = merged
+	return &with
+}
+
+// WithField returns a PackageLogger adding the field key=value to every entry
+// it logs; see With
+func (pl *PackageLogger) WithField(key string, value interface{}) *PackageLogger {
+	return pl.With(map[string]interface{}{key: value})
+}
+
+// Labels returns a copy of the labels bound to this logger
+func (pl *PackageLogger) Labels() map[string]string {
+	labels := make(map[string]string, len(pl.labels))
+	for k, v := range pl.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// addLabels copies the labels bound to pl into entry
+func (pl *PackageLogger) addLabels(entry *LogEntry) {
+	for k, v := range pl.labels {
+		entry.SetLabel(k, v)
+	}
+}
+
+// SetLabel sets the label key of the entry to value
+func (e *LogEntry) SetLabel(key, value string) {
+	if e.Labels == nil {
+		e.Labels = make(map[string]string)
+	}
+	e.Labels[key] = value
+}
+
+// unshadowedLabels returns the names of the labels of an entry that no field
+// of the same name replaces, sorted
+func (e *LogEntry) unshadowedLabels() []string {
+	keys := make([]string, 0, len(e.Labels))
+	for k := range e.Labels {
+		if _, shadowed := e.Fields[k]; !shadowed {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// withLabelFields returns entry for a formatter, which writes labels as
+// fields: entry itself if it has no labels, or else a copy whose fields are
+// those of entry and its labels, held in scratch. A field replaces the label
+// of the same name.
+func withLabelFields(entry *LogEntry, scratch map[string]interface{}) *LogEntry {
+	if len(entry.Labels) == 0 {
+		return entry
+	}
+	clear(scratch)
+	for k, v := range entry.Labels {
+		scratch[k] = v
+	}
+	for k, v := range entry.Fields {
+		scratch[k] = v
+	}
+	view := *entry
+	view.Fields = scratch
+	return &view
+}
@@ -0,0 +1,82 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLabels(t *testing.T) {
+	sink := &retainingSink{}
+	lines := &bufferSink{}
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.JSON = true
+	config.Sinks = []Sink{sink, lines}
+	logger := NewChannelLoggerWithConfig(config)
+
+	api := logger.Package("api").WithLabel("service", "checkout").WithLabels(map[string]string{"env": "prod", "region": "eu"})
+	api.WithField("user_id", 42).Info("Paid")
+	api.InfoWithFields("Moved", map[string]interface{}{"region": "us"}) // The field replaces the label when written
+	logger.Close()
+
+	if labels := api.Labels(); len(labels) != 3 || labels["service"] != "checkout" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+	if len(logger.Package("api").Labels()) != 0 {
+		t.Error("WithLabel should not change the parent logger")
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(sink.entries))
+	}
+	first := sink.entries[0]
+	if first.Labels["env"] != "prod" || first.Fields["user_id"] != 42 {
+		t.Errorf("Unexpected entry labels %v, fields %v", first.Labels, first.Fields)
+	}
+	if _, ok := first.Fields["env"]; ok {
+		t.Error("Labels should not be copied into the fields of the entry")
+	}
+
+	written := strings.Split(strings.TrimSpace(lines.buf.String()), "\n")
+	if len(written) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", written)
+	}
+	if !strings.Contains(written[0], `"service":"checkout"`) || !strings.Contains(written[0], `"user_id":42`) {
+		t.Errorf("Expected labels and fields in %s", written[0])
+	}
+	if !strings.Contains(written[1], `"region":"us"`) || strings.Contains(written[1], `"region":"eu"`) {
+		t.Errorf("Expected the field to replace the label in %s", written[1])
+	}
+
+	f, err := ParseFilter(`labels.env=="prod" && labels.service && !labels.tenant`)
+	if err != nil {
+		t.Fatalf("ParseFilter failed: %v", err)
+	}
+	if !f(first) {
+		t.Error("Expected the filter to match the labels of the entry")
+	}
+}
+
+func TestSocketEntryLabels(t *testing.T) {
+	entry := &LogEntry{Package: "db", Level: INFO, Message: "Ready", Timestamp: time.Unix(1700000000, 0),
+		Fields: map[string]interface{}{}, Labels: map[string]string{"service": "orders"}}
+
+	decoded := getLogEntry()
+	defer decoded.Release()
+	v, err := readMsgpack(bufio.NewReader(bytes.NewReader(encodeSocketEntry(entry))))
+	if err != nil {
+		t.Fatalf("Decoding failed: %v", err)
+	}
+	if err := decodeSocketEntry(v, decoded); err != nil {
+		t.Fatalf("decodeSocketEntry failed: %v", err)
+	}
+	if decoded.Labels["service"] != "orders" || len(decoded.Fields) != 0 {
+		t.Errorf("Unexpected labels %v, fields %v", decoded.Labels, decoded.Fields)
+	}
+}
@@ -0,0 +1,305 @@
+package log4
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultTailPollInterval is how often Tail checks a file for new data
+const DefaultTailPollInterval = 250 * time.Millisecond
+
+// TailOptions controls how Tail follows a log file
+type TailOptions struct {
+	TimestampFormat string          // Layout used by the writer (default: DefaultConfig's)
+	FromStart       bool            // Emit existing content instead of starting at the end
+	PollInterval    time.Duration   // How often to check for new data (default: DefaultTailPollInterval)
+	BufferSize      int             // Capacity of the returned channel (default: DefaultBufferSize)
+	Done            <-chan struct{} // Closing Done stops tailing and closes the channel
+	ErrorHandler    func(error)     // Optional callback for read and parse errors
+}
+
+// Tail follows a log file written by this package and emits every new line as
+// a parsed LogEntry, similar to tail -F. Rotation is followed: when the path
+// is replaced by a new file the remainder of the old file is read before
+// switching over, and truncation restarts from the beginning.
+//
+// Lines that cannot be parsed, such as continuation lines of multi-line
+// messages, are emitted with only Package and Message set. Compressed files
+// (see Codec) are supported; entries are read once the logger has flushed
+// their frame.
+//
+// The returned channel is closed when opts.Done is closed.
+func Tail(path string, opts *TailOptions) (<-chan *LogEntry, error) {
+	if opts == nil {
+		opts = &TailOptions{}
+	}
+	o := *opts
+	if o.TimestampFormat == "" {
+		o.TimestampFormat = DefaultConfig().TimestampFormat
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultTailPollInterval
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = DefaultBufferSize
+	}
+
+	t := &tailer{
+		path:  path,
+		pkg:   PackageFromFileName(path),
+		opts:  o,
+		codec: CodecForFile(path),
+		out:   make(chan *LogEntry, o.BufferSize),
+	}
+	if err := t.open(!o.FromStart); err != nil {
+		return nil, err
+	}
+
+	go t.run()
+	return t.out, nil
+}
+
+// tailer holds the state of a single Tail call
+type tailer struct {
+	path    string
+	pkg     string
+	opts    TailOptions
+	codec   Codec
+	out     chan *LogEntry
+	file    *os.File
+	info    os.FileInfo
+	offset  int64  // bytes consumed from the raw file
+	partial []byte // incomplete trailing line
+	lines   int    // decoded lines already emitted, compressed files only
+}
+
+// open opens the path, optionally positioning at the current end
+func (t *tailer) open(atEnd bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for tailing: %w", t.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", t.path, err)
+	}
+
+	t.file, t.info = f, info
+	t.offset, t.lines, t.partial = 0, 0, nil
+	if atEnd {
+		if t.codec != nil {
+			// Count the lines already present so they are skipped
+			t.readCompressed(false)
+		} else {
+			t.offset = info.Size()
+		}
+	}
+	return nil
+}
+
+func (t *tailer) run() {
+	defer close(t.out)
+	defer func() {
+		if t.file != nil {
+			t.file.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(t.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if !t.poll() {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-t.opts.Done:
+			return
+		}
+	}
+}
+
+// poll reads new data and handles rotation; it returns false once Done closes
+func (t *tailer) poll() bool {
+	if t.file != nil && !t.read() {
+		return false
+	}
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		// Between rename and re-creation during rotation; try again later
+		return true
+	}
+
+	switch {
+	case t.file == nil || !os.SameFile(info, t.info):
+		// Rotated: the old file was fully drained above
+		if t.file != nil {
+			t.file.Close()
+			t.file = nil
+		}
+		if err := t.open(false); err != nil {
+			t.handleError(err)
+			return true
+		}
+		return t.read()
+	case info.Size() < t.offset:
+		// Truncated in place
+		t.offset, t.lines, t.partial = 0, 0, nil
+		return t.read()
+	}
+	return true
+}
+
+// read emits everything appended since the last read
+func (t *tailer) read() bool {
+	if t.codec != nil {
+		return t.readCompressed(true)
+	}
+
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		t.handleError(fmt.Errorf("failed to seek %s: %w", t.path, err))
+		return true
+	}
+
+	r := bufio.NewReader(t.file)
+	for {
+		chunk, err := r.ReadBytes('\n')
+		t.offset += int64(len(chunk))
+		if err != nil {
+			// Keep the incomplete line until the writer finishes it
+			t.partial = append(t.partial, chunk...)
+			return true
+		}
+		if len(t.partial) > 0 {
+			chunk = append(t.partial, chunk...)
+			t.partial = nil
+		}
+		if !t.emit(string(chunk)) {
+			return false
+		}
+	}
+}
+
+// readCompressed decodes the frames of a compressed file and emits the lines
+// not seen yet. With a FrameCodec, offset is the start of the first frame not
+// fully read and lines counts the lines emitted from it, so only the frames
+// from there on are decoded; other codecs decode the whole file on every poll.
+func (t *tailer) readCompressed(emit bool) bool {
+	fc, ok := t.codec.(FrameCodec)
+	if !ok {
+		return t.readCompressedAll(emit)
+	}
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		t.handleError(fmt.Errorf("failed to seek %s: %w", t.path, err))
+		return true
+	}
+	data, err := io.ReadAll(t.file)
+	if err != nil {
+		return true
+	}
+
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		start := r.Len()
+		fr, err := fc.NewFrameReader(r)
+		if err != nil {
+			// Header of the next frame not written yet
+			return true
+		}
+		content, err := io.ReadAll(fr)
+		fr.Close()
+		if !t.emitLines(content, emit) {
+			return false
+		}
+		if err != nil {
+			// Frame still being written: resume from its start
+			return true
+		}
+		t.offset += int64(start - r.Len())
+		t.lines = 0
+	}
+	return true
+}
+
+// readCompressedAll decodes a compressed file from the start and emits the
+// lines not seen yet
+func (t *tailer) readCompressedAll(emit bool) bool {
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		t.handleError(fmt.Errorf("failed to seek %s: %w", t.path, err))
+		return true
+	}
+	info, err := t.file.Stat()
+	if err != nil {
+		return true
+	}
+	t.offset = info.Size()
+
+	r, err := t.codec.NewReader(t.file)
+	if err != nil {
+		// Empty file or first frame still being written
+		return true
+	}
+	defer r.Close()
+
+	// A decode error at the end just means the last frame is incomplete
+	content, _ := io.ReadAll(r)
+	return t.emitLines(content, emit)
+}
+
+// emitLines emits the complete lines of decoded content past the first
+// t.lines, counting them in t.lines
+func (t *tailer) emitLines(content []byte, emit bool) bool {
+	lineNo := 0
+	for len(content) > 0 {
+		idx := bytes.IndexByte(content, '\n')
+		if idx < 0 {
+			break
+		}
+		line := content[:idx+1]
+		content = content[idx+1:]
+		lineNo++
+		if lineNo <= t.lines {
+			continue
+		}
+		t.lines = lineNo
+		if emit && !t.emit(string(line)) {
+			return false
+		}
+	}
+	return true
+}
+
+// emit parses a line and delivers it; it returns false once Done closes
+func (t *tailer) emit(line string) bool {
+	if IsHeaderLine(line) {
+		return true
+	}
+	entry, err := ParseLine(line, t.opts.TimestampFormat)
+	if err != nil {
+		entry = &LogEntry{Message: strings.TrimRight(line, "\r\n"), Fields: make(map[string]interface{})}
+	}
+	if entry.Package == "" {
+		entry.Package = t.pkg
+	}
+
+	select {
+	case t.out <- entry:
+		return true
+	case <-t.opts.Done:
+		return false
+	}
+}
+
+func (t *tailer) handleError(err error) {
+	if t.opts.ErrorHandler != nil {
+		t.opts.ErrorHandler(err)
+	}
+}
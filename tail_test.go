@@ -0,0 +1,229 @@
+package log4
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	format := DefaultConfig().TimestampFormat
+
+	t.Run("Text with fields", func(t *testing.T) {
+		entry, err := ParseLine("[2025-06-23 18:10:15] ERROR: Order failed | order_id=ORD-1, amount=9.99\n", format)
+		if err != nil {
+			t.Fatalf("ParseLine failed: %v", err)
+		}
+		if entry.Level != ERROR || entry.Message != "Order failed" {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+		if entry.Fields["order_id"] != "ORD-1" || entry.Fields["amount"] != "9.99" {
+			t.Errorf("Unexpected fields: %v", entry.Fields)
+		}
+		if entry.Timestamp.Hour() != 18 || entry.Timestamp.Second() != 15 {
+			t.Errorf("Unexpected timestamp: %v", entry.Timestamp)
+		}
+	})
+
+	t.Run("Pipe in message", func(t *testing.T) {
+		entry, err := ParseLine("[2025-06-23 18:10:15] INFO: a | b", format)
+		if err != nil {
+			t.Fatalf("ParseLine failed: %v", err)
+		}
+		if entry.Message != "a | b" || len(entry.Fields) != 0 {
+			t.Errorf("Message should be kept intact, got %+v", entry)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		entry, err := ParseLine(`{"time":"2025-06-23T18:10:15Z","level":"debug","msg":"hello","package":"api","status":200}`, format)
+		if err != nil {
+			t.Fatalf("ParseLine failed: %v", err)
+		}
+		if entry.Level != DEBUG || entry.Message != "hello" || entry.Package != "api" {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+		if entry.Fields["status"] != float64(200) {
+			t.Errorf("Unexpected fields: %v", entry.Fields)
+		}
+	})
+
+	t.Run("Garbage", func(t *testing.T) {
+		if _, err := ParseLine("goroutine 1 [running]:", format); err == nil {
+			t.Error("Expected error for unrecognized line")
+		}
+	})
+}
+
+func TestPackageFromFileName(t *testing.T) {
+	tests := map[string]string{
+		"logs/app.log":      "app",
+		"logs/app.log.3":    "app",
+		"app.log.gz.1":      "app",
+		"/var/log/db-1.log": "db-1",
+	}
+	for input, expected := range tests {
+		if got := PackageFromFileName(input); got != expected {
+			t.Errorf("PackageFromFileName(%s) = %s, want %s", input, got, expected)
+		}
+	}
+}
+
+func receiveEntries(t *testing.T, ch <-chan *LogEntry, n int) []*LogEntry {
+	var entries []*LogEntry
+	timeout := time.After(2 * time.Second)
+	for len(entries) < n {
+		select {
+		case entry := <-ch:
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatalf("Timed out after receiving %d of %d entries", len(entries), n)
+		}
+	}
+	return entries
+}
+
+func TestTail(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+	defer logger.Close()
+
+	logger.Info("tailed", "Before tail")
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	entries, err := Tail(filepath.Join(tempDir, "tailed.log"), &TailOptions{
+		PollInterval: 10 * time.Millisecond,
+		Done:         done,
+	})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	logger.LogWithFields("tailed", ERROR, "After tail", map[string]interface{}{"attempt": 3})
+
+	got := receiveEntries(t, entries, 1)[0]
+	if got.Message != "After tail" || got.Level != ERROR || got.Package != "tailed" {
+		t.Errorf("Unexpected entry: %+v", got)
+	}
+	if got.Fields["attempt"] != "3" {
+		t.Errorf("Unexpected fields: %v", got.Fields)
+	}
+}
+
+func TestTailFollowsRotation(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MaxFileSize = 200
+	config.MaxFiles = 10
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Info("rot", "Create file")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	entries, err := Tail(filepath.Join(tempDir, "rot.log"), &TailOptions{
+		FromStart:    true,
+		PollInterval: 5 * time.Millisecond,
+		Done:         done,
+	})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	const total = 20
+	for i := 1; i < total; i++ {
+		logger.Info("rot", fmt.Sprintf("Rotating message number %d", i))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got := receiveEntries(t, entries, total)
+	if got[0].Message != "Create file" || got[total-1].Message != fmt.Sprintf("Rotating message number %d", total-1) {
+		t.Errorf("Unexpected first/last entries: %q, %q", got[0].Message, got[total-1].Message)
+	}
+	if !fileExists(filepath.Join(tempDir, "rot.log.1")) {
+		t.Error("Expected rotation to have happened")
+	}
+}
+
+func TestTailCompressed(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Compression = GzipCodec{}
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	logger.Info("gz", "Existing")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	entries, err := Tail(filepath.Join(tempDir, "gz.log.gz"), &TailOptions{
+		PollInterval: 10 * time.Millisecond,
+		Done:         done,
+	})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+
+	logger.Info("gz", "New")
+	if got := receiveEntries(t, entries, 1)[0]; got.Message != "New" {
+		t.Errorf("Expected only the new entry, got %q", got.Message)
+	}
+}
+
+func TestTailMissingFile(t *testing.T) {
+	if _, err := Tail(filepath.Join(os.TempDir(), "log4-does-not-exist.log"), nil); err == nil {
+		t.Error("Expected error tailing a missing file")
+	}
+}
+
+func TestTailCompressedResumesAfterFrames(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Compression = GzipCodec{}
+	config.FrameSize = 64 // A frame or two per entry
+	config.DisableConsole = true
+
+	logger := NewChannelLoggerWithConfig(config)
+	defer logger.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	path := filepath.Join(tempDir, "frames.log.gz")
+	logger.Info("frames", "Start")
+	logger.Flush()
+
+	entries, err := Tail(path, &TailOptions{PollInterval: 10 * time.Millisecond, Done: done, FromStart: true})
+	if err != nil {
+		t.Fatalf("Tail failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		logger.Info("frames", fmt.Sprintf("Entry %d with enough text to close a frame", i))
+	}
+	got := receiveEntries(t, entries, 6)
+	if got[0].Message != "Start" || got[5].Message != "Entry 4 with enough text to close a frame" {
+		t.Errorf("Unexpected entries %q ... %q", got[0].Message, got[5].Message)
+	}
+}
@@ -0,0 +1,74 @@
+package log4
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithTemporaryLevel sets the global minimum level for d and then restores
+// the previous level, unless the level was changed again in the meantime.
+// Both changes are logged to InternalPackage. The returned function restores
+// the previous level early.
+func (cl *ChannelLogger) WithTemporaryLevel(level LogLevel, d time.Duration) (revert func()) {
+	prev := LogLevel(cl.minLevel.Swap(int32(level)))
+	cl.logNotice(fmt.Sprintf("minimum level set to %s for %s", level, d),
+		map[string]interface{}{"old_level": prev.String(), "new_level": level.String(), "duration": d.String()})
+
+	return cl.afterTemporary(d, func() {
+		if cl.minLevel.CompareAndSwap(int32(level), int32(prev)) {
+			cl.logNotice(fmt.Sprintf("minimum level restored to %s", prev),
+				map[string]interface{}{"old_level": level.String(), "new_level": prev.String()})
+		}
+	})
+}
+
+// WithTemporaryPackageLevel overrides the level of one package for d and then
+// restores its previous override, or removes it if there was none, unless the
+// override was changed again in the meantime. See WithTemporaryLevel.
+func (cl *ChannelLogger) WithTemporaryPackageLevel(pkg string, level LogLevel, d time.Duration) (revert func()) {
+	var prev LogLevel
+	var hadPrev bool
+	cl.updateLevels(&cl.pkgLevels, func(levels map[string]LogLevel) {
+		prev, hadPrev = levels[pkg]
+		levels[pkg] = level
+	})
+	cl.logNotice(fmt.Sprintf("level of package %s set to %s for %s", pkg, level, d),
+		map[string]interface{}{"package": pkg, "new_level": level.String(), "duration": d.String()})
+
+	return cl.afterTemporary(d, func() {
+		restored := false
+		cl.updateLevels(&cl.pkgLevels, func(levels map[string]LogLevel) {
+			if current, ok := levels[pkg]; !ok || current != level {
+				return
+			}
+			if hadPrev {
+				levels[pkg] = prev
+			} else {
+				delete(levels, pkg)
+			}
+			restored = true
+		})
+		if restored {
+			cl.logNotice(fmt.Sprintf("temporary level of package %s expired", pkg),
+				map[string]interface{}{"package": pkg, "old_level": level.String()})
+		}
+	})
+}
+
+// WithTemporaryLevel overrides the level of this package for d; see
+// ChannelLogger.WithTemporaryPackageLevel
+func (pl *PackageLogger) WithTemporaryLevel(level LogLevel, d time.Duration) (revert func()) {
+	return pl.logger.WithTemporaryPackageLevel(pl.pkg, level, d)
+}
+
+// afterTemporary runs restore once, after d or when the returned function is
+// called, whichever comes first
+func (cl *ChannelLogger) afterTemporary(d time.Duration, restore func()) func() {
+	var once sync.Once
+	timer := time.AfterFunc(d, func() { once.Do(restore) })
+	return func() {
+		timer.Stop()
+		once.Do(restore)
+	}
+}
@@ -0,0 +1,90 @@
+package log4
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTemporaryLevel(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	logger.WithTemporaryLevel(DEBUG, 20*time.Millisecond)
+	if logger.GetMinLevel() != DEBUG {
+		t.Fatalf("Expected DEBUG, got %s", logger.GetMinLevel())
+	}
+	for i := 0; i < 100 && logger.GetMinLevel() != INFO; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if logger.GetMinLevel() != INFO {
+		t.Errorf("Expected INFO to be restored, got %s", logger.GetMinLevel())
+	}
+
+	// A level changed in the meantime is kept
+	revert := logger.WithTemporaryLevel(DEBUG, time.Hour)
+	logger.SetMinLevel(ERROR)
+	revert()
+	if logger.GetMinLevel() != ERROR {
+		t.Errorf("Expected the later change to be kept, got %s", logger.GetMinLevel())
+	}
+	logger.Close()
+
+	content := readFile(t, filepath.Join(tempDir, InternalPackage+".log"))
+	if !strings.Contains(content, "minimum level set to DEBUG for 20ms") ||
+		!strings.Contains(content, "minimum level restored to INFO") {
+		t.Errorf("Expected the changes to be logged, got:\n%s", content)
+	}
+}
+
+func TestWithTemporaryPackageLevel(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	logger := NewChannelLogger(10, tempDir)
+	logger.stdout = io.Discard
+	defer logger.Close()
+
+	logger.SetPackageLevel("db", ERROR)
+	revert := logger.Package("db").WithTemporaryLevel(DEBUG, time.Hour)
+	revertCache := logger.WithTemporaryPackageLevel("cache", DEBUG, time.Hour)
+	if levels := logger.PackageLevels(); levels["db"] != DEBUG || levels["cache"] != DEBUG {
+		t.Fatalf("Expected temporary overrides, got %v", levels)
+	}
+
+	revert()
+	revertCache()
+	revert() // Idempotent
+	levels := logger.PackageLevels()
+	if levels["db"] != ERROR {
+		t.Errorf("Expected the previous db override to be restored, got %v", levels)
+	}
+	if _, ok := levels["cache"]; ok {
+		t.Errorf("Expected the cache override to be removed, got %v", levels)
+	}
+
+	server := httptest.NewServer(logger.AdminHandler())
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/level?package=api&level=DEBUG&duration=10ms", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT with duration failed: %v %v", resp, err)
+	}
+	resp.Body.Close()
+	for i := 0; i < 100 && len(logger.PackageLevels()) > 1; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := logger.PackageLevels()["api"]; ok {
+		t.Error("Expected the admin override to expire")
+	}
+}
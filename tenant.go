@@ -0,0 +1,236 @@
+package log4
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tenantQuotaRecheck is how often the disk usage of a tenant over its quota
+// is measured again, so that space freed by retention or by an operator is
+// noticed
+const tenantQuotaRecheck = time.Minute
+
+// TenantConfig holds the limits of one tenant
+type TenantConfig struct {
+	// MaxBytes is the disk quota of the tenant's directory. Once it is
+	// reached, entries of the tenant are dropped with DropQuota until space is
+	// freed (default: 0, unlimited).
+	MaxBytes int64
+	// MaxFiles is the number of rotated files kept per package (default:
+	// Config.MaxFiles)
+	MaxFiles int
+	// Retention removes rotated files older than this when the tenant's files
+	// are rotated or its quota is re-checked (default: 0, kept)
+	Retention time.Duration
+}
+
+// TenantLogger scopes logging to one tenant. Its packages are written to
+// files in a subdirectory of LogDir named after the tenant, and each entry
+// carries the tenant in LogEntry.Tenant for sinks and formatters.
+type TenantLogger struct {
+	logger *ChannelLogger
+	tenant string
+}
+
+// tenantUsage tracks the disk usage of a tenant; guarded by ChannelLogger.mu
+type tenantUsage struct {
+	bytes    int64     // Bytes in the tenant directory, counted before compression since measured
+	measured time.Time // When bytes was last measured on disk
+	over     bool      // Quota exceeded and reported
+}
+
+// Tenant creates a TenantLogger for the specified tenant
+func (cl *ChannelLogger) Tenant(tenant string) *TenantLogger {
+	if tenant == "" {
+		panic(fmt.Errorf(ErrInvalidTenant))
+	}
+	return &TenantLogger{logger: cl, tenant: tenant}
+}
+
+// Package creates a PackageLogger for a package of this tenant
+func (tl *TenantLogger) Package(pkg string) *PackageLogger {
+	if pkg == "" {
+		panic(fmt.Errorf(ErrInvalidPackage))
+	}
+	return &PackageLogger{logger: tl.logger, tenant: tl.tenant, pkg: pkg}
+}
+
+// Name returns the tenant name
+func (tl *TenantLogger) Name() string {
+	return tl.tenant
+}
+
+// SetLevel overrides the minimum level for every package of this tenant
+func (tl *TenantLogger) SetLevel(level LogLevel) {
+	tl.logger.SetTenantLevel(tl.tenant, level)
+}
+
+// SetTenantLevel overrides the minimum level for a tenant (thread-safe). It
+// takes precedence over package overrides and the global level.
+func (cl *ChannelLogger) SetTenantLevel(tenant string, level LogLevel) {
+	cl.updateLevels(&cl.tenantLvl, func(levels map[string]LogLevel) {
+		levels[tenant] = level
+	})
+}
+
+// ClearTenantLevel removes a tenant override
+func (cl *ChannelLogger) ClearTenantLevel(tenant string) {
+	cl.updateLevels(&cl.tenantLvl, func(levels map[string]LogLevel) {
+		delete(levels, tenant)
+	})
+}
+
+// TenantLevels returns a copy of the per-tenant level overrides
+func (cl *ChannelLogger) TenantLevels() map[string]LogLevel {
+	return copyLevels(&cl.tenantLvl)
+}
+
+// levelForEntry returns the effective minimum level of an entry: its
+// tenant's override if set, otherwise that of its package
+func (cl *ChannelLogger) levelForEntry(entry *LogEntry) LogLevel {
+	return cl.levelForTenant(entry.Tenant, entry.Package)
+}
+
+// levelForTenant returns the effective minimum level of a package of a tenant
+func (cl *ChannelLogger) levelForTenant(tenant, pkg string) LogLevel {
+	if tenant != "" {
+		if levels := cl.tenantLvl.Load(); levels != nil {
+			if level, ok := (*levels)[tenant]; ok {
+				return level
+			}
+		}
+	}
+	return cl.levelFor(pkg)
+}
+
+// tenantConfig returns the limits of a tenant
+func (cl *ChannelLogger) tenantConfig(tenant string) TenantConfig {
+	if c, ok := cl.config.Tenants[tenant]; ok {
+		return c
+	}
+	return cl.config.DefaultTenant
+}
+
+// tenantDir returns the directory holding the files of a tenant
+func (cl *ChannelLogger) tenantDir(tenant string) string {
+	return filepath.Join(cl.config.LogDir, sanitizePackageName(tenant))
+}
+
+// usageFor returns the usage of a tenant, measuring it on first use; cl.mu
+// must be held
+func (cl *ChannelLogger) usageFor(tenant string) *tenantUsage {
+	u, ok := cl.tenants[tenant]
+	if !ok {
+		u = &tenantUsage{}
+		cl.tenants[tenant] = u
+		cl.measureTenant(tenant)
+	}
+	return u
+}
+
+// measureTenant sets the usage of a tenant to the size of its directory
+func (cl *ChannelLogger) measureTenant(tenant string) {
+	var size int64
+	walkDir(cl.config.fileSystem(), cl.tenantDir(tenant), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	u := cl.tenants[tenant]
+	u.bytes, u.measured = size, time.Now()
+}
+
+// overQuota reports whether entries of a tenant must be dropped. The first
+// entry dropped after the quota is reached is reported to the error handler.
+func (cl *ChannelLogger) overQuota(tenant string) bool {
+	limit := cl.tenantConfig(tenant).MaxBytes
+	if limit <= 0 {
+		return false
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	u := cl.usageFor(tenant)
+	if u.bytes >= limit && time.Since(u.measured) >= tenantQuotaRecheck {
+		cl.enforceRetention(tenant)
+		cl.measureTenant(tenant)
+	}
+	if u.bytes < limit {
+		u.over = false
+		return false
+	}
+	if !u.over {
+		u.over = true
+		cl.handleError(fmt.Errorf(ErrTenantQuota, tenant, limit))
+	}
+	return true
+}
+
+// enforceRetention removes rotated files of a tenant that are older than its
+// Retention; cl.mu must be held
+func (cl *ChannelLogger) enforceRetention(tenant string) {
+	retention := cl.tenantConfig(tenant).Retention
+	if retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-retention)
+
+	dir := cl.tenantDir(tenant)
+	fsys := cl.config.fileSystem()
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	// Files named by another RotationNamer are found through their active file
+	rotated := make(map[string]bool)
+	if _, numeric := cl.config.rotationNamer().(NumericNamer); !numeric {
+		active := ".log"
+		if cl.config.Compression != nil {
+			active += cl.config.Compression.Extension()
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), active) {
+				names, _ := cl.config.rotated(filepath.Join(dir, e.Name()))
+				for _, name := range names {
+					rotated[filepath.Base(name)] = true
+				}
+			}
+		}
+	}
+	removed := false
+	for _, e := range entries {
+		if e.IsDir() || !(isRotatedLogFile(e.Name()) || rotated[e.Name()]) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := fsys.Remove(filepath.Join(dir, e.Name())); err != nil {
+			cl.handleError(fmt.Errorf("failed to remove expired log file of tenant %s: %w", tenant, err))
+			continue
+		}
+		removed = true
+	}
+	if _, ok := cl.tenants[tenant]; ok && removed {
+		cl.measureTenant(tenant)
+	}
+}
+
+// isRotatedLogFile reports whether name is a rotated log file such as
+// "db.log.2" or "db.log.gz.1"
+func isRotatedLogFile(name string) bool {
+	idx := strings.LastIndexByte(name, '.')
+	if idx < 0 || !strings.Contains(name[:idx], ".log") {
+		return false
+	}
+	_, err := strconv.Atoi(name[idx+1:])
+	return err == nil
+}
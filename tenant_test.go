@@ -0,0 +1,134 @@
+package log4
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTenantLogger(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	acme := logger.Tenant("acme")
+	acme.SetLevel(DEBUG)
+	db := acme.Package("db")
+	db.Debug("Slow query")
+	db.InfoWithFields("Connected", map[string]interface{}{"pool": 4})
+	logger.Tenant("globex").Package("db").Debug("Hidden")
+	logger.Debug("db", "Hidden")
+	logger.Info("db", "Shared database")
+	logger.Close()
+
+	if db.GetTenantName() != "acme" || acme.Name() != "acme" {
+		t.Errorf("Unexpected tenant names %q, %q", db.GetTenantName(), acme.Name())
+	}
+
+	content := readFile(t, filepath.Join(tempDir, "acme", "db.log"))
+	if countLines(content) != 2 || !strings.Contains(content, "DEBUG: Slow query") {
+		t.Errorf("Unexpected tenant log:\n%s", content)
+	}
+	if fileExists(filepath.Join(tempDir, "globex", "db.log")) {
+		t.Error("Debug entry of a tenant without override should be filtered")
+	}
+	if content := readFile(t, filepath.Join(tempDir, "db.log")); countLines(content) != 1 {
+		t.Errorf("Expected only the shared entry in db.log, got:\n%s", content)
+	}
+	if levels := logger.TenantLevels(); levels["acme"] != DEBUG {
+		t.Errorf("Expected acme override, got %v", levels)
+	}
+}
+
+func TestTenantJSON(t *testing.T) {
+	entry := &LogEntry{Tenant: "acme", Package: "db", Level: INFO, Message: "Connected",
+		Timestamp: time.Now(), Fields: map[string]interface{}{"tenant": "other"}}
+	line := string((&JSONFormatter{}).Format(nil, entry))
+	if !strings.Contains(line, `"tenant":"acme"`) || !strings.Contains(line, `"fields.tenant":"other"`) {
+		t.Errorf("Unexpected JSON %s", line)
+	}
+
+	parsed, err := ParseLine(line, DefaultConfig().TimestampFormat)
+	if err != nil || parsed.Tenant != "acme" {
+		t.Errorf("Expected tenant to be parsed back, got %+v, %v", parsed, err)
+	}
+}
+
+func TestTenantQuota(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var quotaDrops atomic.Int32
+	var errs atomic.Int32
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Tenants = map[string]TenantConfig{"acme": {MaxBytes: 200}}
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		if reason == DropQuota && entry.Tenant == "acme" {
+			quotaDrops.Add(1)
+		}
+	}
+	config.ErrorHandler = func(error) { errs.Add(1) }
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	acme := logger.Tenant("acme").Package("api")
+	other := logger.Tenant("globex").Package("api")
+	for i := 0; i < 20; i++ {
+		acme.InfoF("Request %d handled", i)
+		other.InfoF("Request %d handled", i)
+	}
+	logger.Close()
+
+	acmeLines := countLines(readFile(t, filepath.Join(tempDir, "acme", "api.log")))
+	if acmeLines == 0 || acmeLines >= 20 || int(quotaDrops.Load()) != 20-acmeLines {
+		t.Errorf("Expected quota to cap acme, got %d lines and %d drops", acmeLines, quotaDrops.Load())
+	}
+	if errs.Load() != 1 {
+		t.Errorf("Expected the quota to be reported once, got %d errors", errs.Load())
+	}
+	if n := countLines(readFile(t, filepath.Join(tempDir, "globex", "api.log"))); n != 20 {
+		t.Errorf("Tenant without quota should be unaffected, got %d lines", n)
+	}
+}
+
+func TestTenantRetention(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DefaultTenant = TenantConfig{MaxFiles: 4, Retention: time.Hour}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	dir := filepath.Join(tempDir, "acme")
+	os.MkdirAll(dir, 0755)
+	expired := filepath.Join(dir, "api.log.2") // Becomes api.log.3 on rotation
+	os.WriteFile(expired, []byte("old\n"), 0644)
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(expired, old, old)
+
+	logger.Tenant("acme").Package("api").Info("First")
+	for i := 0; i < 100 && !fileExists(filepath.Join(dir, "api.log")); i++ {
+		time.Sleep(time.Millisecond) // Rotate only rotates files already opened
+	}
+	logger.Rotate()
+	logger.Close()
+
+	if fileExists(filepath.Join(dir, "api.log.3")) {
+		t.Error("Expired rotated file should have been removed")
+	}
+	if !fileExists(filepath.Join(dir, "api.log.1")) {
+		t.Error("Expected the active file to be rotated")
+	}
+}
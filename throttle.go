@@ -0,0 +1,123 @@
+package log4
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of Throttle
+const (
+	DefaultThrottleWindow   = time.Second
+	DefaultThrottleRecovery = 3
+)
+
+// Throttle makes logging degrade gracefully when files or sinks become slow.
+// The time taken to write each entry is averaged over every Window; when the
+// average exceeds Latency, the logger is throttled: entries below Level are
+// dropped with DropThrottled and, if Sampling is set, the others are sampled.
+// Throttling ends after Recovery consecutive windows below Latency. Both
+// transitions are logged to InternalPackage.
+type Throttle struct {
+	Latency  time.Duration // Average write time of an entry that triggers throttling
+	Window   time.Duration // default: DefaultThrottleWindow
+	Level    LogLevel      // Minimum level while throttled (default: ERROR unless Sampling is set)
+	Sampling *Sampling     // Applied to entries at or above Level while throttled
+	Recovery int           // default: DefaultThrottleRecovery
+}
+
+// throttler tracks write latency for Config.Throttle
+type throttler struct {
+	cfg     Throttle
+	sampler *sampler
+	active  atomic.Bool
+
+	// Only used by the logging goroutine
+	windowEnd time.Time
+	total     time.Duration
+	count     int
+	healthy   int // Consecutive windows below Latency while active
+}
+
+func newThrottler(cfg Throttle) *throttler {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultThrottleWindow
+	}
+	if cfg.Recovery <= 0 {
+		cfg.Recovery = DefaultThrottleRecovery
+	}
+	if cfg.Level == DEBUG && cfg.Sampling == nil {
+		cfg.Level = ERROR
+	}
+	t := &throttler{cfg: cfg}
+	if cfg.Sampling != nil {
+		t.sampler = newSampler(*cfg.Sampling)
+	}
+	return t
+}
+
+// allow reports whether entry is to be written while throttled
+func (t *throttler) allow(entry *LogEntry) bool {
+	if entry.Level < t.cfg.Level {
+		return false
+	}
+	return t.sampler == nil || t.sampler.allow(entry)
+}
+
+// observe records the time taken to write an entry. At the end of a window
+// it updates the state and reports whether it changed, with the average
+// latency of the window. Windows without writes count as below Latency.
+func (t *throttler) observe(now time.Time, d time.Duration) (changed bool, avg time.Duration) {
+	if t.windowEnd.IsZero() {
+		t.windowEnd = now.Add(t.cfg.Window)
+	}
+	if now.Before(t.windowEnd) {
+		t.total += d
+		t.count++
+		return false, 0
+	}
+
+	if t.count > 0 {
+		avg = t.total / time.Duration(t.count)
+	}
+	slow := t.count > 0 && avg > t.cfg.Latency
+	idle := int(now.Sub(t.windowEnd) / t.cfg.Window)
+	t.windowEnd = now.Add(t.cfg.Window)
+	t.total, t.count = d, 1
+
+	if !t.active.Load() {
+		if slow {
+			t.active.Store(true)
+			t.healthy = 0
+			return true, avg
+		}
+		return false, avg
+	}
+	if slow {
+		t.healthy = 0
+		return false, avg
+	}
+	t.healthy += 1 + idle
+	if t.healthy >= t.cfg.Recovery {
+		t.active.Store(false)
+		return true, avg
+	}
+	return false, avg
+}
+
+// observeWrite feeds the latency of a write to the throttler, logging a
+// notice when throttling starts or ends. Runs on the logging goroutine, so
+// the notice is queued from another one.
+func (cl *ChannelLogger) observeWrite(start time.Time) {
+	now := time.Now()
+	changed, avg := cl.throttle.observe(now, now.Sub(start))
+	if !changed {
+		return
+	}
+	fields := map[string]interface{}{"latency_ms": durationMillis(avg)}
+	if cl.throttle.active.Load() {
+		go cl.logNotice(fmt.Sprintf("logging throttled to %s: writes took %s on average", cl.throttle.cfg.Level, avg), fields)
+	} else {
+		go cl.logNotice("logging throttle lifted", fields)
+	}
+}
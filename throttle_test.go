@@ -0,0 +1,81 @@
+package log4
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowSink takes delay to write each entry
+type slowSink struct {
+	delay atomic.Int64
+}
+
+func (s *slowSink) Write(entry *LogEntry, line []byte) error {
+	time.Sleep(time.Duration(s.delay.Load()))
+	return nil
+}
+
+func (s *slowSink) Close() error { return nil }
+
+func TestThrottle(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &slowSink{}
+	sink.delay.Store(int64(5 * time.Millisecond))
+	var throttled atomic.Int64
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.DisableConsole = true
+	config.Throttle = &Throttle{Latency: time.Millisecond, Window: 20 * time.Millisecond, Recovery: 2}
+	config.OnDrop = func(entry *LogEntry, reason DropReason) {
+		if reason == DropThrottled {
+			throttled.Add(1)
+		}
+	}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+	defer logger.Close()
+
+	waitFor := func(what string, cond func() bool, log func()) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			log()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitFor("throttling", func() bool { return logger.Stats().Throttled }, func() {
+		logger.Info("app", "Slow write")
+	})
+	logger.Info("app", "Dropped")
+	if throttled.Load() == 0 {
+		t.Error("Expected INFO entries to be dropped with DropThrottled")
+	}
+	written := logger.Stats().Written
+	logger.Error("app", "Kept")
+	waitFor("the error to be written", func() bool { return logger.Stats().Written > written }, func() {})
+
+	sink.delay.Store(0)
+	waitFor("recovery", func() bool { return !logger.Stats().Throttled }, func() {
+		logger.Error("app", "Fast write")
+	})
+
+	// Notices are queued asynchronously
+	noticeFile := filepath.Join(tempDir, InternalPackage+".log")
+	waitFor("the notices", func() bool {
+		content, _ := os.ReadFile(noticeFile)
+		return strings.Contains(string(content), "logging throttled to ERROR") &&
+			strings.Contains(string(content), "logging throttle lifted")
+	}, func() {})
+}
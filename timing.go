@@ -0,0 +1,71 @@
+package log4
+
+import (
+	"strconv"
+	"time"
+)
+
+// FieldDuration is the field holding the elapsed time recorded by the timing
+// helpers, in milliseconds with microsecond precision
+const FieldDuration = "duration_ms"
+
+// FieldElapsed is the field holding the time since Config.ElapsedSince added
+// with Config.Elapsed, such as "+12.345s"
+const FieldElapsed = "elapsed"
+
+// processStart approximates the start of the process by the initialization
+// of this package
+var processStart = time.Now()
+
+// ProcessStart returns when this package was initialized, shortly after the
+// process started, for use as Config.ElapsedSince. It carries a monotonic
+// clock reading, so elapsed times are not affected by clock changes.
+func ProcessStart() time.Time {
+	return processStart
+}
+
+// formatElapsed renders d as the value of FieldElapsed
+func formatElapsed(d time.Duration) string {
+	return "+" + strconv.FormatFloat(d.Seconds(), 'f', 3, 64) + "s"
+}
+
+// durationMillis converts d to the value of FieldDuration
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// TimeOperation starts timing an operation and returns a function that logs
+// its name at info level with the elapsed time as FieldDuration:
+//
+//	defer pl.TimeOperation("load users")()
+func (pl *PackageLogger) TimeOperation(name string) func() {
+	start := time.Now()
+	return func() {
+		pl.InfoDuration(name, start)
+	}
+}
+
+// InfoDuration logs an info-level message with the time elapsed since start
+// as FieldDuration
+func (pl *PackageLogger) InfoDuration(message string, start time.Time) {
+	pl.logDuration(INFO, message, start)
+}
+
+// ErrorDuration logs an error-level message with the time elapsed since
+// start; see InfoDuration
+func (pl *PackageLogger) ErrorDuration(message string, start time.Time) {
+	pl.logDuration(ERROR, message, start)
+}
+
+// DebugDuration logs a debug-level message with the time elapsed since
+// start; see InfoDuration
+func (pl *PackageLogger) DebugDuration(message string, start time.Time) {
+	pl.logDuration(DEBUG, message, start)
+}
+
+func (pl *PackageLogger) logDuration(level LogLevel, message string, start time.Time) {
+	elapsed := time.Since(start)
+	if pl.logger.keeps(pl.tenant, pl.pkg, level) {
+		pl.log(nil, level, message, map[string]interface{}{FieldDuration: durationMillis(elapsed)})
+	}
+}
@@ -0,0 +1,75 @@
+package log4
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTimingHelpers(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	pl := logger.Package("users")
+	func() {
+		defer pl.TimeOperation("load users")()
+		time.Sleep(5 * time.Millisecond)
+	}()
+	pl.ErrorDuration("query failed", time.Now().Add(-1500*time.Microsecond))
+	pl.DebugDuration("hidden", time.Now())
+	logger.Close()
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(sink.entries))
+	}
+	if e := sink.entries[0]; e.Message != "load users" || e.Level != INFO {
+		t.Errorf("Unexpected entry %s %q", e.Level, e.Message)
+	}
+	if ms, ok := sink.entries[0].Fields[FieldDuration].(float64); !ok || ms < 5 {
+		t.Errorf("Expected a duration of at least 5ms, got %v", sink.entries[0].Fields)
+	}
+	if ms := sink.entries[1].Fields[FieldDuration].(float64); ms < 1.5 || ms > 1000 {
+		t.Errorf("Expected a duration in milliseconds, got %v", ms)
+	}
+	if ms := durationMillis(1234567 * time.Nanosecond); ms != 1.234 {
+		t.Errorf("durationMillis() = %v, want 1.234", ms)
+	}
+}
+
+func TestElapsedField(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.DisableConsole = true
+	config.Elapsed = true
+	config.ElapsedSince = time.Now().Add(-12345 * time.Millisecond)
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "ready")
+	logger.Close()
+
+	if len(sink.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(sink.entries))
+	}
+	if got := sink.entries[0].Fields[FieldElapsed]; got != "+12.345s" && got != "+12.346s" {
+		t.Errorf("Expected an elapsed time of about +12.345s, got %v", got)
+	}
+	if got := formatElapsed(1500 * time.Microsecond); got != "+0.002s" {
+		t.Errorf("formatElapsed() = %q", got)
+	}
+	if ProcessStart().After(time.Now()) || ProcessStart().IsZero() {
+		t.Error("Unexpected ProcessStart")
+	}
+}
@@ -0,0 +1,81 @@
+package log4
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOptions describes the TLS of the network sinks in terms of files; Config
+// builds the tls.Config to set as Config.TLS or BatchOptions.TLS
+type TLSOptions struct {
+	CertFile   string // PEM client certificate, for mutual TLS
+	KeyFile    string // PEM key of the client certificate
+	CAFile     string // PEM CAs trusted for servers instead of the system roots
+	ServerName string // Name expected in server certificates and sent as SNI (default: host of the address)
+
+	// PinnedKeys are PublicKeyPins of the certificates servers may present:
+	// the verified chain must contain at least one of them
+	PinnedKeys []string
+}
+
+// Config builds the tls.Config described by o, requiring TLS 1.2 or later
+func (o TLSOptions) Config() (*tls.Config, error) {
+	c := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: o.ServerName}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading CAs: %w", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", o.CAFile)
+		}
+	}
+	if len(o.PinnedKeys) > 0 {
+		c.VerifyConnection = verifyPins(o.PinnedKeys)
+	}
+	return c, nil
+}
+
+// PublicKeyPin returns the pin of a certificate for TLSOptions.PinnedKeys:
+// the base64 SHA-256 of its SubjectPublicKeyInfo, as printed by
+//
+//	openssl x509 -pubkey -noout -in cert.pem | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// errPinMismatch is returned when no certificate of a server matches a pin
+var errPinMismatch = errors.New("server certificate does not match any pinned key")
+
+// verifyPins returns a tls.Config.VerifyConnection accepting servers whose
+// verified chain holds a certificate with one of pins
+func verifyPins(pins []string) func(tls.ConnectionState) error {
+	pinned := make(map[string]bool, len(pins))
+	for _, p := range pins {
+		pinned[p] = true
+	}
+	return func(cs tls.ConnectionState) error {
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				if pinned[PublicKeyPin(cert)] {
+					return nil
+				}
+			}
+		}
+		return errPinMismatch
+	}
+}
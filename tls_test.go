@@ -0,0 +1,198 @@
+package log4
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testPKI is a CA with a server certificate for "logs.internal" and a client
+// certificate for "shipper", written as PEM files to dir
+type testPKI struct {
+	dir        string
+	pool       *x509.CertPool
+	server     tls.Certificate
+	serverCert *x509.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	dir := t.TempDir()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "log4 test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Creating the CA failed: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caDER)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (tls.Certificate, *x509.Certificate) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("Creating the certificate of %s failed: %v", name, err)
+		}
+		keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+		writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+		writePEM(t, filepath.Join(dir, name+"-key.pem"), "PRIVATE KEY", keyDER)
+		cert, _ := x509.ParseCertificate(der)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, cert
+	}
+
+	p := &testPKI{dir: dir, pool: x509.NewCertPool()}
+	p.pool.AddCert(ca)
+	p.server, p.serverCert = issue(2, "logs.internal", x509.ExtKeyUsageServerAuth)
+	issue(3, "shipper", x509.ExtKeyUsageClientAuth)
+	return p
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serverTLS requires clients to present a certificate of the test CA
+func (p *testPKI) serverTLS() *tls.Config {
+	return &tls.Config{Certificates: []tls.Certificate{p.server}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: p.pool}
+}
+
+// clientOptions are the TLSOptions of the "shipper" client
+func (p *testPKI) clientOptions() TLSOptions {
+	return TLSOptions{
+		CertFile:   filepath.Join(p.dir, "shipper.pem"),
+		KeyFile:    filepath.Join(p.dir, "shipper-key.pem"),
+		CAFile:     filepath.Join(p.dir, "ca.pem"),
+		ServerName: "logs.internal", // The servers listen on 127.0.0.1
+	}
+}
+
+func TestOTLPSinkMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+
+	var mu sync.Mutex
+	var clients []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		clients = append(clients, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+	}))
+	server.TLS = pki.serverTLS()
+	server.StartTLS()
+	defer server.Close()
+
+	opts := pki.clientOptions()
+	opts.PinnedKeys = []string{PublicKeyPin(pki.serverCert)}
+	tlsConfig, err := opts.Config()
+	if err != nil {
+		t.Fatalf("TLSOptions.Config failed: %v", err)
+	}
+
+	// Inherited from the logger through LevelSink
+	inherited, _ := NewOTLPSink(OTLPOptions{Endpoint: server.URL})
+	// Its own TLS, pinned to another key
+	wrongPin := opts
+	wrongPin.PinnedKeys = []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}
+	pinnedConfig, _ := wrongPin.Config()
+	var pinErr error
+	pinned, _ := NewOTLPSink(OTLPOptions{
+		Endpoint:     server.URL,
+		BatchOptions: BatchOptions{TLS: pinnedConfig, MaxRetries: -1},
+		ErrorHandler: func(err error) { pinErr = err },
+	})
+
+	config := DefaultConfig()
+	config.DryRun = true
+	config.DisableConsole = true
+	config.TLS = tlsConfig
+	config.Sinks = []Sink{LevelSink(inherited, INFO), pinned}
+	logger := NewChannelLoggerWithConfig(config)
+	logger.Info("api", "Shipped over mutual TLS")
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(clients) != 1 || clients[0] != "shipper" {
+		t.Errorf("Expected one export authenticated as shipper, got %v", clients)
+	}
+	if pinErr == nil || !strings.Contains(pinErr.Error(), errPinMismatch.Error()) || pinned.Dropped() != 1 {
+		t.Errorf("Expected the pinned sink to reject the server, got %v", pinErr)
+	}
+}
+
+func TestFluentSinkMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", pki.serverTLS())
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan interface{}, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		v, _ := readMsgpack(bufio.NewReader(conn))
+		received <- v
+	}()
+
+	opts := pki.clientOptions()
+	opts.ServerName = ""
+	tlsConfig, err := opts.Config()
+	if err != nil {
+		t.Fatalf("TLSOptions.Config failed: %v", err)
+	}
+	var exportErr error
+	sink := NewFluentSink(FluentOptions{
+		Address:      ln.Addr().String(),
+		BatchOptions: BatchOptions{TLS: tlsConfig, TLSServerName: "logs.internal"},
+		ErrorHandler: func(err error) { exportErr = err },
+	})
+	sink.Write(&LogEntry{Package: "orders", Level: INFO, Message: "created", Timestamp: time.Now()}, nil)
+	sink.Close()
+
+	if exportErr != nil {
+		t.Fatalf("Forwarding failed: %v", exportErr)
+	}
+	select {
+	case v := <-received:
+		if msg, ok := v.([]interface{}); !ok || msg[0] != "orders" {
+			t.Errorf("Unexpected message %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The server received nothing")
+	}
+}
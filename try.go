@@ -0,0 +1,91 @@
+package log4
+
+import "time"
+
+// The Try methods log like their counterparts without the prefix but wait
+// until the entry has been written and return what went wrong, for callers
+// that handle logging failures themselves rather than through ErrorHandler,
+// which still receives them:
+//
+//   - an *ErrDropped if the entry was dropped, e.g. because the queue stayed
+//     full (DropOverflow), by sampling or throttling, or after Close
+//   - the errors of writing the package file and sinks, joined
+//
+// An entry below the level of its package is not written and nil is
+// returned. The Try methods must not be called from sinks or callbacks run
+// by the logging goroutine, which would wait for itself.
+
+// TryInfo logs an info message and returns any failure to write it
+func (cl *ChannelLogger) TryInfo(pkg, message string) error {
+	return cl.TryLogWithFields(pkg, INFO, message, nil)
+}
+
+// TryError logs an error message and returns any failure to write it
+func (cl *ChannelLogger) TryError(pkg, message string) error {
+	return cl.TryLogWithFields(pkg, ERROR, message, nil)
+}
+
+// TryDebug logs a debug message and returns any failure to write it
+func (cl *ChannelLogger) TryDebug(pkg, message string) error {
+	return cl.TryLogWithFields(pkg, DEBUG, message, nil)
+}
+
+// TryLogWithFields logs a message with structured fields and returns any
+// failure to write it
+func (cl *ChannelLogger) TryLogWithFields(pkg string, level LogLevel, message string, fields map[string]interface{}) error {
+	entry := getLogEntry()
+	entry.Package = pkg
+	entry.Level = level
+	entry.Message = message
+	entry.Timestamp = time.Now()
+	for k, v := range fields {
+		entry.Fields[k] = v
+	}
+	return cl.tryLog(entry)
+}
+
+// TryInfo logs an info message for this package and returns any failure to
+// write it
+func (pl *PackageLogger) TryInfo(message string) error {
+	return pl.TryLogWithFields(INFO, message, nil)
+}
+
+// TryError logs an error message for this package and returns any failure
+// to write it
+func (pl *PackageLogger) TryError(message string) error {
+	return pl.TryLogWithFields(ERROR, message, nil)
+}
+
+// TryDebug logs a debug message for this package and returns any failure to
+// write it
+func (pl *PackageLogger) TryDebug(message string) error {
+	return pl.TryLogWithFields(DEBUG, message, nil)
+}
+
+// TryLogWithFields logs a message with structured fields for this package
+// and returns any failure to write it
+func (pl *PackageLogger) TryLogWithFields(level LogLevel, message string, fields map[string]interface{}) error {
+	entry := getLogEntry()
+	entry.Tenant = pl.tenant
+	entry.Package = pl.pkg
+	entry.Level = level
+	entry.Message = message
+	entry.Timestamp = time.Now()
+	for k, v := range pl.fields {
+		entry.Fields[k] = v
+	}
+	pl.addLabels(entry)
+	for k, v := range fields {
+		entry.Fields[k] = v
+	}
+	return pl.logger.tryLog(entry)
+}
+
+// tryLog logs an entry and waits for its outcome. Every path an entry can
+// take, filtered, dropped or written, sends exactly one outcome.
+func (cl *ChannelLogger) tryLog(entry *LogEntry) error {
+	ack := make(chan error, 1) // Never blocks the logging goroutine
+	entry.ack = ack
+	cl.logEntry(entry)
+	return <-ack
+}
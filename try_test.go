@@ -0,0 +1,108 @@
+package log4
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTryMethods(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.MinLevel = INFO
+	logger := NewChannelLoggerWithConfig(config)
+
+	if err := logger.TryInfo("app", "Started"); err != nil {
+		t.Errorf("TryInfo failed: %v", err)
+	}
+	if err := logger.TryDebug("app", "Filtered"); err != nil {
+		t.Errorf("Expected no error for a filtered entry, got %v", err)
+	}
+	pl := logger.Package("app").With(map[string]interface{}{"user": "alice"})
+	if err := pl.TryLogWithFields(ERROR, "Denied", map[string]interface{}{"path": "/admin"}); err != nil {
+		t.Errorf("PackageLogger.TryLogWithFields failed: %v", err)
+	}
+
+	// Written once the calls return, without a Flush
+	content := readFile(t, filepath.Join(tempDir, "app.log"))
+	if countLines(content) != 2 || strings.Contains(content, "Filtered") || !strings.Contains(content, "path=/admin") || !strings.Contains(content, "user=alice") {
+		t.Errorf("Unexpected log content %q", content)
+	}
+
+	logger.Close()
+	var dropped *ErrDropped
+	if err := pl.TryInfo("Too late"); !errors.As(err, &dropped) || dropped.Reason != DropClosed {
+		t.Errorf("Expected an *ErrDropped with DropClosed after Close, got %v", err)
+	}
+}
+
+func TestTryOverflow(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &blockingSink{release: make(chan struct{})}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.BufferSize = 1
+	config.Sinks = []Sink{sink}
+	config.ErrorHandler = func(error) {}
+	logger := NewChannelLoggerWithConfig(config)
+
+	logger.Info("app", "stalls the worker")
+	time.Sleep(20 * time.Millisecond)
+	logger.Info("app", "fills the queue")
+
+	err := logger.TryError("app", "overflows")
+	var dropped *ErrDropped
+	if !errors.As(err, &dropped) || dropped.Reason != DropOverflow || dropped.Message != "overflows" {
+		t.Errorf("Expected an *ErrDropped with DropOverflow, got %v", err)
+	}
+	if !errors.Is(err, &ErrDropped{}) || errors.Is(err, &ErrChannelFull{}) {
+		t.Errorf("Expected errors.Is to match *ErrDropped only, got %v", err)
+	}
+
+	close(sink.release)
+	logger.Close()
+}
+
+func TestTrySinkError(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	var mu sync.Mutex
+	var handled []error
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.Sinks = []Sink{failingSink{}}
+	config.ErrorHandler = func(err error) {
+		mu.Lock()
+		handled = append(handled, err)
+		mu.Unlock()
+	}
+	logger := NewChannelLoggerWithConfig(config)
+
+	err := logger.TryInfo("app", "Shipped")
+	var sinkErr *ErrSinkWrite
+	if !errors.As(err, &sinkErr) || sinkErr.Pkg != "app" {
+		t.Errorf("Expected the sink's *ErrSinkWrite, got %v", err)
+	}
+	logger.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) == 0 {
+		t.Error("Expected the error handler to receive the sink error as well")
+	}
+	if content := readFile(t, filepath.Join(tempDir, "app.log")); !strings.Contains(content, "Shipped") {
+		t.Errorf("Expected the file to be written, got %q", content)
+	}
+}
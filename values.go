@@ -0,0 +1,192 @@
+package log4
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// LogValuer is implemented by field values that control how they are
+// logged, e.g. to redact secrets or to summarize large structs:
+//
+//	func (t Token) LogValue() interface{} { return "token:" + t.ID }
+//
+// LogValue is called when the entry is formatted on the logging goroutine,
+// not when it is logged, so it must be safe to call from another goroutine;
+// it is not called at all for entries filtered out or dropped. Its result is
+// written by the rules of FieldEncoding and may itself be a LogValuer. Values
+// implementing slog.LogValuer are resolved the same way.
+type LogValuer interface {
+	LogValue() interface{}
+}
+
+// maxLogValues bounds the LogValue calls made to resolve one value, so that
+// a LogValuer returning itself cannot hang the logging goroutine
+const maxLogValues = 100
+
+// resolveValue returns the value a LogValuer is logged as, or v if it is not
+// one
+func resolveValue(v interface{}) interface{} {
+	switch v.(type) {
+	case LogValuer, slog.LogValuer:
+		return resolveLogValuer(v)
+	}
+	return v
+}
+
+// resolveLogValuer calls LogValue until the result is no LogValuer. A panic
+// in LogValue is logged as the value instead of stopping the logging
+// goroutine.
+func resolveLogValuer(v interface{}) (resolved interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			resolved = fmt.Sprintf("!PANIC: %v", r)
+		}
+	}()
+	for range maxLogValues {
+		switch lv := v.(type) {
+		case LogValuer:
+			v = lv.LogValue()
+		case slog.LogValuer:
+			v = lv.LogValue().Resolve().Any()
+		default:
+			return v
+		}
+	}
+	return fmt.Sprintf("!ERROR: LogValue called %d times", maxLogValues)
+}
+
+// DefaultMaxBytes is the number of bytes of a []byte field written before
+// the rest is cut off
+const DefaultMaxBytes = 256
+
+// FieldEncoding sets how formatters write field values of common Go types,
+// so that the text layout, JSON and every schema preset agree:
+//
+//   - time.Time: RFC 3339 with fractional seconds, e.g. "2024-05-01T12:00:00.5Z"
+//   - time.Duration: as formatted by its String method, e.g. "1.5s", or a
+//     number of milliseconds with DurationMillis, e.g. 1500
+//   - error: its message; errors of a type of their own, such as
+//     *fs.PathError, add the type as the field "<key>_type". Errors made by
+//     errors.New, errors.Join and fmt.Errorf carry nothing beyond the message.
+//   - []byte: base64, or hex with BytesHex, cut off after MaxBytes bytes with
+//     the full size appended, e.g. "AAECAw==...(1024 bytes)"
+//
+// A LogValuer is replaced by its LogValue first. Strings, numbers and
+// booleans are written as they are; other values are written as JSON by the
+// JSON formatters and with %v by TextFormatter.
+type FieldEncoding struct {
+	DurationMillis bool
+	BytesHex       bool
+	MaxBytes       int // default: DefaultMaxBytes
+}
+
+// value returns v, already resolved by resolveValue, as the encoding writes
+// it: a string or a number for the types it covers, v itself otherwise
+func (e FieldEncoding) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		if e.DurationMillis {
+			return durationMillis(v)
+		}
+		return v.String()
+	case error:
+		return v.Error()
+	case []byte:
+		return e.bytes(v)
+	}
+	return v
+}
+
+// bytes encodes b, cut off after MaxBytes
+func (e FieldEncoding) bytes(b []byte) string {
+	limit := e.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBytes
+	}
+	shown := b[:min(len(b), limit)]
+
+	var s string
+	if e.BytesHex {
+		s = hex.EncodeToString(shown)
+	} else {
+		s = base64.StdEncoding.EncodeToString(shown)
+	}
+	if len(shown) < len(b) {
+		s += "...(" + strconv.Itoa(len(b)) + " bytes)"
+	}
+	return s
+}
+
+// text returns v as written in the text layout
+func (e FieldEncoding) text(v interface{}) string {
+	switch v := e.value(v).(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// appendText appends v as written by text
+func (e FieldEncoding) appendText(dst []byte, v interface{}) []byte {
+	switch v := e.value(v).(type) {
+	case string:
+		return append(dst, v...)
+	case float64:
+		return strconv.AppendFloat(dst, v, 'f', -1, 64)
+	default:
+		return fmt.Appendf(dst, "%v", v)
+	}
+}
+
+// appendJSON appends v as JSON
+func (e FieldEncoding) appendJSON(dst []byte, v interface{}) []byte {
+	return appendJSONValue(dst, e.value(v))
+}
+
+// errorType returns the type of an error field worth recording next to its
+// message, or "" if v is not an error or one of the generic error types
+func errorType(v interface{}) string {
+	err, ok := v.(error)
+	if !ok {
+		return ""
+	}
+	switch t := fmt.Sprintf("%T", err); t {
+	case "*errors.errorString", "*errors.joinError", "*fmt.wrapError", "*fmt.wrapErrors":
+		return ""
+	default:
+		return t
+	}
+}
+
+// appendJSONFields appends the fields in key order as JSON members, each
+// preceded by a comma; rename gives the member name of a key. An error's
+// type follows it as "<name>_type".
+func (e FieldEncoding) appendJSONFields(dst []byte, fields map[string]interface{}, rename func(string) string) []byte {
+	for _, k := range sortedFieldKeys(fields) {
+		name := rename(k)
+		if name == "" {
+			continue
+		}
+		v := resolveValue(fields[k])
+		dst = append(dst, ',')
+		dst = appendJSONString(dst, name)
+		dst = append(dst, ':')
+		dst = e.appendJSON(dst, v)
+		if t := errorType(v); t != "" {
+			dst = append(dst, ',')
+			dst = appendJSONString(dst, name+"_type")
+			dst = append(dst, ':')
+			dst = appendJSONString(dst, t)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,78 @@
+package log4
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldEncoding(t *testing.T) {
+	_, pathErr := os.Open("/does/not/exist")
+	if !errors.As(pathErr, new(*fs.PathError)) {
+		t.Fatalf("Expected a *fs.PathError, got %T", pathErr)
+	}
+	entry := &LogEntry{
+		Package:   "api",
+		Level:     INFO,
+		Message:   "request",
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Fields: map[string]interface{}{
+			"at":      time.Date(2024, 5, 1, 11, 59, 59, 500000000, time.UTC),
+			"took":    1500 * time.Millisecond,
+			"err":     pathErr,
+			"plain":   errors.New("timeout"),
+			"payload": []byte{0, 1, 2, 3, 4, 5},
+		},
+	}
+
+	text := string((&TextFormatter{TimestampFormat: DefaultConfig().TimestampFormat}).Format(nil, entry))
+	for _, want := range []string{
+		"at=2024-05-01T11:59:59.5Z", "took=1.5s", "err=open /does/not/exist: no such file or directory",
+		"err_type=*fs.PathError", "plain=timeout", "payload=AAECAwQF",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in %q", want, text)
+		}
+	}
+	if strings.Contains(text, "plain_type") {
+		t.Errorf("Expected no type for errors.New, got %q", text)
+	}
+
+	// Every JSON formatter encodes the values alike
+	enc := FieldEncoding{DurationMillis: true, BytesHex: true, MaxBytes: 4}
+	for _, f := range []Formatter{&JSONFormatter{Encoding: enc}, &DatadogFormatter{Encoding: enc}, &GCPFormatter{Encoding: enc}} {
+		var got map[string]interface{}
+		if err := json.Unmarshal(f.Format(nil, entry), &got); err != nil {
+			t.Fatalf("%T output is not valid JSON: %v", f, err)
+		}
+		expected := map[string]interface{}{
+			"at":       "2024-05-01T11:59:59.5Z",
+			"took":     float64(1500),
+			"err_type": "*fs.PathError",
+			"plain":    "timeout",
+			"payload":  "00010203...(6 bytes)",
+		}
+		for k, v := range expected {
+			if got[k] != v {
+				t.Errorf("%T: key %s = %v, want %v", f, k, got[k], v)
+			}
+		}
+		if _, ok := got["plain_type"]; ok {
+			t.Errorf("%T: expected no type for errors.New", f)
+		}
+	}
+
+	var ecs struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal((&ECSFormatter{Encoding: enc}).Format(nil, entry), &ecs); err != nil {
+		t.Fatalf("ECS output is not valid JSON: %v", err)
+	}
+	if ecs.Labels["took"] != "1500" || ecs.Labels["err_type"] != "*fs.PathError" {
+		t.Errorf("Unexpected ECS labels %v", ecs.Labels)
+	}
+}
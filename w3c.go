@@ -0,0 +1,134 @@
+package log4
+
+import (
+	"strings"
+	"time"
+)
+
+// HeaderFormatter is implemented by formatters whose files start with header
+// lines of their own, such as the directives of W3CFormatter. The logger
+// writes them at the top of every new file, after the FileHeader line if
+// Config.FileHeader is set.
+type HeaderFormatter interface {
+	Formatter
+	// HeaderLines returns the header lines, without separators, of a file
+	// created at started
+	HeaderLines(started time.Time) []string
+}
+
+// DefaultW3CFields are the fields written by W3CFormatter unless Fields is
+// set, in the order IIS writes them
+var DefaultW3CFields = []string{
+	"date", "time", "c-ip", "cs-username", "cs-method", "cs-uri-stem", "cs-uri-query",
+	"sc-status", "sc-bytes", "time-taken", "cs-version", "cs(User-Agent)", "cs(Referer)",
+}
+
+// w3cFieldKeys maps W3C field identifiers to the fields of entries holding
+// their values
+var w3cFieldKeys = map[string]string{
+	"c-ip":           FieldRemoteAddr,
+	"cs-username":    FieldUser,
+	"cs-method":      FieldMethod,
+	"sc-status":      FieldStatus,
+	"sc-bytes":       FieldBytes,
+	"time-taken":     FieldDuration,
+	"cs-version":     FieldProto,
+	"cs(User-Agent)": FieldUserAgent,
+	"cs(Referer)":    FieldReferer,
+}
+
+// W3CFormatter renders access log entries in the W3C Extended Log File
+// Format, for analyzers that expect the files of IIS. Every new file starts
+// with the directives describing its columns:
+//
+//	#Software: log4
+//	#Version: 1.0
+//	#Date: 2024-05-01 12:00:00
+//	#Fields: date time c-ip cs-method cs-uri-stem sc-status
+//	2024-05-01 12:00:00 127.0.0.1 GET /index.html 200
+//
+// Fields lists the columns (default: DefaultW3CFields). date and time are
+// the entry's timestamp in UTC; cs-uri-stem and cs-uri-query are the path and
+// query of FieldPath; the other standard identifiers read the fields used by
+// CLFFormatter, and time-taken reads FieldDuration, in milliseconds as IIS
+// writes it. Any other identifier, such as "x-request_id" or "request_id",
+// reads the field of that name without its "x-" prefix; "x-message",
+// "x-level" and "x-package" write the message, level and package. Missing
+// values are written as "-", spaces as "+" and control characters as "_".
+type W3CFormatter struct {
+	Fields []string
+}
+
+func (f *W3CFormatter) fields() []string {
+	if len(f.Fields) == 0 {
+		return DefaultW3CFields
+	}
+	return f.Fields
+}
+
+// HeaderLines returns the #Software, #Version, #Date and #Fields directives
+func (f *W3CFormatter) HeaderLines(started time.Time) []string {
+	return []string{
+		"#Software: log4",
+		"#Version: 1.0",
+		"#Date: " + started.UTC().Format(time.DateTime),
+		"#Fields: " + strings.Join(f.fields(), " "),
+	}
+}
+
+// Format appends the W3C line of entry to dst
+func (f *W3CFormatter) Format(dst []byte, entry *LogEntry) []byte {
+	for i, field := range f.fields() {
+		if i > 0 {
+			dst = append(dst, ' ')
+		}
+		dst = appendW3CValue(dst, w3cValue(entry, field))
+	}
+	return dst
+}
+
+// w3cValue returns the value of a W3C field identifier for entry, or "" if
+// it has none
+func w3cValue(entry *LogEntry, field string) string {
+	switch field {
+	case "date":
+		return entry.Timestamp.UTC().Format(time.DateOnly)
+	case "time":
+		return entry.Timestamp.UTC().Format(time.TimeOnly)
+	case "cs-uri-stem":
+		path, _, _ := strings.Cut(clfValue(entry, FieldPath), "?")
+		return path
+	case "cs-uri-query":
+		_, query, _ := strings.Cut(clfValue(entry, FieldPath), "?")
+		return query
+	case "x-message":
+		return entry.Message
+	case "x-level":
+		return entry.Level.String()
+	case "x-package":
+		return entry.Package
+	}
+	if key, ok := w3cFieldKeys[field]; ok {
+		return clfValue(entry, key)
+	}
+	return clfValue(entry, strings.TrimPrefix(field, "x-"))
+}
+
+// appendW3CValue appends a value with the separators of the format replaced,
+// or "-" if it is empty
+func appendW3CValue(dst []byte, s string) []byte {
+	if s == "" {
+		return append(dst, '-')
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ':
+			dst = append(dst, '+')
+		case c < 0x20 || c == 0x7f:
+			dst = append(dst, '_')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,86 @@
+package log4
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestW3CFormatter(t *testing.T) {
+	entry := &LogEntry{
+		Package:   "access",
+		Level:     INFO,
+		Message:   "request",
+		Timestamp: time.Date(2024, 5, 1, 14, 0, 0, 0, time.FixedZone("", 2*3600)),
+		Fields: map[string]interface{}{
+			FieldRemoteAddr: "192.0.2.1",
+			FieldMethod:     "GET",
+			FieldPath:       "/search?q=log4",
+			FieldProto:      "HTTP/1.1",
+			FieldStatus:     200,
+			FieldBytes:      512,
+			FieldDuration:   12.5,
+			FieldUserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+			"request_id":    "abc",
+		},
+	}
+
+	got := string((&W3CFormatter{}).Format(nil, entry))
+	want := "2024-05-01 12:00:00 192.0.2.1 - GET /search q=log4 200 512 12.5 HTTP/1.1 Mozilla/5.0+(X11;+Linux+x86_64) -"
+	if got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+
+	custom := &W3CFormatter{Fields: []string{"time", "x-request_id", "x-level", "x-package", "x-message", "s-computername"}}
+	if got := string(custom.Format(nil, entry)); got != "12:00:00 abc INFO access request -" {
+		t.Errorf("Unexpected custom fields %q", got)
+	}
+
+	lines := custom.HeaderLines(entry.Timestamp)
+	if len(lines) != 4 || lines[2] != "#Date: 2024-05-01 12:00:00" || lines[3] != "#Fields: time x-request_id x-level x-package x-message s-computername" {
+		t.Errorf("Unexpected directives %q", lines)
+	}
+}
+
+func TestW3CFiles(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.DisableConsole = true
+	config.MaxFileSize = 200
+	config.Packages = map[string]PackageConfig{"access": {Formatter: &W3CFormatter{}}}
+	logger := NewChannelLoggerWithConfig(config)
+
+	access := logger.Package("access")
+	for i := 0; i < 3; i++ {
+		access.InfoWithFields("request", map[string]interface{}{FieldMethod: "GET", FieldPath: "/", FieldStatus: 200})
+	}
+	logger.Info("app", "Started")
+	logger.Close()
+
+	for _, name := range []string{"access.log", "access.log.1"} {
+		content := readFile(t, filepath.Join(tempDir, name))
+		if !strings.HasPrefix(content, "#Software: log4\n#Version: 1.0\n#Date: ") || strings.Count(content, "#Fields: ") != 1 {
+			t.Errorf("Expected %s to start with the directives once, got %q", name, content)
+		}
+	}
+
+	// Reopening a file keeps its directives from being repeated
+	logger = NewChannelLoggerWithConfig(config)
+	logger.Package("access").Info("request")
+	logger.Close()
+	if content := readFile(t, filepath.Join(tempDir, "access.log")); strings.Count(content, "#Fields: ") != 1 {
+		t.Errorf("Expected the directives once after reopening, got %q", content)
+	}
+
+	if content := readFile(t, filepath.Join(tempDir, "app.log")); strings.HasPrefix(content, "#") {
+		t.Errorf("Expected other packages without directives, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "access.log.1")); err != nil {
+		t.Errorf("Expected the access log to rotate: %v", err)
+	}
+}
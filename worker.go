@@ -0,0 +1,42 @@
+package log4
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// Fields set by PackageLogger.Worker and Config.GoroutineID
+const (
+	FieldWorker    = "worker"
+	FieldGoroutine = "goroutine"
+)
+
+// Worker returns a PackageLogger tagging every entry with the worker ID as
+// FieldWorker, so that the interleaved entries of a worker pool can be told
+// apart:
+//
+//	for i := 0; i < n; i++ {
+//		go work(pl.Worker(i))
+//	}
+func (pl *PackageLogger) Worker(id interface{}) *PackageLogger {
+	return pl.With(map[string]interface{}{FieldWorker: id})
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from the header
+// of its stack trace ("goroutine 42 [running]:"), or 0 if it cannot be read.
+// Go deliberately hides goroutine IDs; they are only meant for telling the
+// entries of concurrent goroutines apart and are reused once a goroutine exits.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b, ok := bytes.CutPrefix(b, []byte("goroutine "))
+	if !ok {
+		return 0
+	}
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
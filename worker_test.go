@@ -0,0 +1,54 @@
+package log4
+
+import (
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestWorkerTagging(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	sink := &retainingSink{}
+	config := DefaultConfig()
+	config.LogDir = tempDir
+	config.Sinks = []Sink{sink}
+	config.GoroutineID = true
+	logger := NewChannelLoggerWithConfig(config)
+	logger.stdout = io.Discard
+
+	pl := logger.Package("jobs")
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(w *PackageLogger) {
+			defer wg.Done()
+			w.Info("Job done")
+		}(pl.Worker(i))
+	}
+	wg.Wait()
+	logger.Close()
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(sink.entries))
+	}
+	workers := map[interface{}]bool{}
+	goroutines := map[interface{}]bool{}
+	for _, entry := range sink.entries {
+		workers[entry.Fields[FieldWorker]] = true
+		goroutines[entry.Fields[FieldGoroutine]] = true
+		if id, _ := entry.Fields[FieldGoroutine].(uint64); id == 0 {
+			t.Errorf("Expected a goroutine ID, got %v", entry.Fields)
+		}
+	}
+	if !workers[0] || !workers[1] {
+		t.Errorf("Expected worker IDs 0 and 1, got %v", workers)
+	}
+	if len(goroutines) != 2 {
+		t.Errorf("Expected entries of two goroutines, got %v", goroutines)
+	}
+	if id := goroutineID(); id == 0 {
+		t.Error("goroutineID() returned 0")
+	}
+}